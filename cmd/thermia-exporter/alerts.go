@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/mapper"
+)
+
+// newAlertActions creates the counter of mitigations the alert webhook
+// performed and registers it with reg. Results are ok, dry_run, failed and
+// skipped.
+func newAlertActions(reg prometheus.Registerer) *prometheus.CounterVec {
+	actions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thermia_alert_actions_total",
+		Help: "Mitigations performed for alerts received on the alert webhook, by result",
+	}, []string{"alertname", "action", "result"})
+	reg.MustRegister(actions)
+	return actions
+}
+
+// alertActionResult reports one mitigation in the alert webhook response.
+type alertActionResult struct {
+	Alert          string         `json:"alert"`
+	Action         string         `json:"action"`
+	Value          string         `json:"value"`
+	InstallationID int64          `json:"installation_id,omitempty"`
+	Result         string         `json:"result"`
+	Error          string         `json:"error,omitempty"`
+	Write          *control.Write `json:"write,omitempty"`
+}
+
+// alertsHandler receives Alertmanager and Grafana webhook notifications,
+// logs them and performs the configured mitigations for firing alerts.
+// With token set, requests must carry it as their bearer token. A
+// mitigation that fails upstream answers 502 so the notification is
+// retried; the writes are idempotent. The route must be wrapped in
+// withWriteDeadline.
+func alertsHandler(c collector.Group, token string, mitigations []control.Mitigation, actions *prometheus.CounterVec, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && !authorized(w, r, token) {
+			return
+		}
+
+		var n control.Notification
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&n); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, a := range n.Alerts {
+			logger.Info("Alert notification received", "alertname", a.Name(), "status", a.Status,
+				mapper.LabelHeatpumpID, a.Labels[mapper.LabelHeatpumpID])
+		}
+
+		planned := control.Plan(n, mitigations)
+		dryRun := control.IsDryRun(r.URL.Query())
+		// Each write may take up to writeTimeout. Don't send any if the
+		// answer could not reach the sender.
+		if err := extendWriteDeadline(r, time.Duration(len(planned))*writeTimeout+5*time.Second); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		results := make([]alertActionResult, 0, len(planned))
+		status := http.StatusOK
+		for _, a := range planned {
+			res := alertActionResult{Alert: a.Alert, Action: a.Action, Value: a.Value, InstallationID: a.InstallationID}
+			owner := c.ForInstallation(a.InstallationID)
+			switch {
+			case a.Err != nil:
+				res.Result, res.Error = "skipped", a.Err.Error()
+			case owner == nil:
+				res.Result, res.Error = "skipped", "installation not collected"
+			default:
+				target, _ := control.Target(a.Action)
+				ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+				write, err := owner.WriteRegister(ctx, a.InstallationID, target.Group, target.Register, a.Value, dryRun)
+				cancel()
+				switch {
+				case err != nil:
+					res.Result, res.Error = "failed", err.Error()
+					if writeStatus(err) >= http.StatusInternalServerError {
+						status = http.StatusBadGateway
+					}
+				case dryRun:
+					res.Result, res.Write = "dry_run", write
+				default:
+					res.Result, res.Write = "ok", write
+				}
+			}
+			actions.WithLabelValues(a.Alert, a.Action, res.Result).Inc()
+			logger.Info("Alert mitigation", "alertname", a.Alert, "action", a.Action, "value", a.Value,
+				"installation", a.InstallationID, "result", res.Result, "error", res.Error)
+			results = append(results, res)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	}
+}
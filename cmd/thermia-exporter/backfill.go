@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/config"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/relabel"
+	"thermia_exporter/internal/remotewrite"
+	"thermia_exporter/internal/types"
+)
+
+// backfillChunk is the time span requested from the history endpoint per call.
+const backfillChunk = 24 * time.Hour
+
+// runBackfill implements the "backfill" subcommand: it pulls historical
+// register data from Thermia Online and writes it to a Prometheus remote write
+// endpoint under the exporter's own metric names, so new dashboards don't
+// start empty.
+func runBackfill(args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	since := fs.String("since", "30d", "how far back to backfill (e.g. 30d, 12h)")
+	remoteWrite := fs.String("remote-write", "", "Prometheus remote write URL (required)")
+	tenant := fs.String("tenant", "", "X-Scope-OrgID header for multi-tenant receivers (Mimir, Cortex)")
+	all := fs.Bool("all", false, "also backfill every register as thermia_register_value, as THERMIA_EXPORT_RAW_REGISTERS exports it")
+	accountName := fs.String("account", "", "account to backfill when THERMIA_ACCOUNTS is set (default: the first)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *remoteWrite == "" {
+		fmt.Fprintln(os.Stderr, "backfill: -remote-write is required")
+		return 2
+	}
+	lookback, err := config.ParseDuration(*since)
+	if err != nil || lookback <= 0 {
+		fmt.Fprintf(os.Stderr, "backfill: invalid -since %q\n", *since)
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 1
+	}
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+
+	account, ok := findAccount(cfg.AccountList(), *accountName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "backfill: unknown account %q\n", *accountName)
+		return 2
+	}
+
+	ctx := context.Background()
+	authResult, err := authenticate(ctx, auth.NewPortalAuthClient(cfg.Portal, logger), credentials(account))
+	if err != nil {
+		logger.Error("Authentication failed", "error", err)
+		return 1
+	}
+
+	apiClient, err := api.NewAPIClient(ctx, cfg.Portal.ConfigURL, authResult.AccessToken, logger, nil, nil)
+	if err != nil {
+		logger.Error("Failed to create API client", "error", err)
+		return 1
+	}
+
+	installations, err := apiClient.GetInstallations(ctx)
+	if err != nil || len(installations) == 0 {
+		logger.Error("No installations available", "error", err)
+		return 1
+	}
+
+	writer := remotewrite.NewClient(*remoteWrite, cfg.RequestTimeout)
+	if *tenant != "" {
+		writer.SetHeader("X-Scope-OrgID", *tenant)
+	}
+
+	end := time.Now().UTC().Truncate(time.Minute)
+	start := end.Add(-lookback)
+
+	opts := seriesOptions{
+		account:   account.Name,
+		aliases:   cfg.RegisterAliases,
+		anonymize: cfg.Anonymize,
+		salt:      cfg.RedactSalt,
+		rules:     slices.Concat(cfg.MetricRules, cfg.RedactLabels),
+		all:       *all,
+	}
+	for _, inst := range installations {
+		if err := backfillInstallation(ctx, logger, apiClient, writer, inst, start, end, opts); err != nil {
+			logger.Error("Backfill failed", "id", inst.ID, "error", err)
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// seriesOptions make backfilled series match the ones the exporter serves
+// with the same configuration.
+type seriesOptions struct {
+	account   string
+	aliases   mapper.Aliases
+	anonymize bool
+	salt      string
+	rules     []relabel.Rule
+	all       bool
+}
+
+// backfillInstallation backfills all mapped history registers of one installation.
+func backfillInstallation(ctx context.Context, logger *slog.Logger, apiClient *api.APIClient, writer *remotewrite.Client, inst types.Installation, start, end time.Time, opts seriesOptions) error {
+	labels := map[string]string{
+		mapper.LabelHeatpumpID:   fmt.Sprint(inst.ID),
+		mapper.LabelHeatpumpName: inst.Name,
+	}
+	if info, err := apiClient.GetInstallationInfo(ctx, inst.ID); err == nil {
+		labels[mapper.LabelHeatpumpName] = mapper.Safe(info.Name, inst.Name)
+		labels[mapper.LabelModel] = mapper.Safe(info.Model, info.Profile.Name)
+	} else {
+		logger.Warn("Failed to get installation info", "id", inst.ID, "error", err)
+	}
+	if opts.anonymize {
+		labels[mapper.LabelHeatpumpName] = collector.AnonymizedName(labels[mapper.LabelHeatpumpName], opts.salt)
+	}
+	if opts.account != "" {
+		labels[mapper.LabelAccount] = opts.account
+	}
+
+	registers, err := apiClient.GetHistoryRegisters(ctx, inst.ID)
+	if err != nil {
+		return fmt.Errorf("list history registers: %w", err)
+	}
+	names := make([]string, len(registers))
+	for i, reg := range registers {
+		names[i] = reg.RegisterName
+		if canonical, ok := opts.aliases[reg.RegisterName]; ok {
+			names[i] = canonical
+		}
+	}
+	temps := mapper.PreferredTemperatureRegisters(names)
+
+	// thermia_register_value is labelled with the group and unit of each
+	// register group the register is exported from
+	var groups map[string][]types.GroupItem
+	if opts.all {
+		groups = make(map[string][]types.GroupItem)
+		for _, group := range mapper.GroupPrecedence {
+			items, err := apiClient.GetRegisterGroup(ctx, inst.ID, group)
+			if err != nil {
+				logger.Warn("Failed to get register group", "id", inst.ID, "group", group, "error", err)
+				continue
+			}
+			groups[group] = opts.aliases.Rename(items)
+		}
+	}
+
+	for i, reg := range registers {
+		series := historySeries(names[i], labels, temps, groups)
+		if len(series) == 0 {
+			logger.Debug("Skipping history register without a live series", "register", reg.RegisterName)
+		}
+		for _, s := range series {
+			if !relabelSeries(opts.rules, s.labels) {
+				logger.Debug("Skipping history series dropped by metric rules", "register", reg.RegisterName)
+				continue
+			}
+			total, err := backfillSeries(ctx, apiClient, writer, inst.ID, reg, s, start, end)
+			if err != nil {
+				return err
+			}
+			logger.Info("Backfilled register", "id", inst.ID, "register", reg.RegisterName,
+				"metric", s.labels["__name__"], "samples", total)
+		}
+	}
+
+	return nil
+}
+
+// backfillSeries writes the history of one register between start and end
+// as series s and returns the number of samples written.
+func backfillSeries(ctx context.Context, apiClient *api.APIClient, writer *remotewrite.Client, id int64, reg types.HistoryRegister, s historyTarget, start, end time.Time) (int, error) {
+	total := 0
+	for from := start; from.Before(end); from = from.Add(backfillChunk) {
+		to := from.Add(backfillChunk)
+		if to.After(end) {
+			to = end
+		}
+
+		history, err := apiClient.GetHistory(ctx, id, reg.RegisterID, from, to)
+		if err != nil {
+			return total, fmt.Errorf("get history for %s: %w", reg.RegisterName, err)
+		}
+
+		samples := make([]remotewrite.Sample, 0, len(history))
+		for _, h := range history {
+			ts := mapper.ParseTimeToUnix(h.At)
+			if ts == 0 {
+				continue
+			}
+			samples = append(samples, remotewrite.Sample{Timestamp: time.Unix(ts, 0), Value: h.Value * s.scale})
+		}
+
+		if err := writer.Write(ctx, []remotewrite.TimeSeries{{Labels: s.labels, Samples: samples}}); err != nil {
+			return total, fmt.Errorf("remote write for %s: %w", reg.RegisterName, err)
+		}
+		total += len(samples)
+	}
+	return total, nil
+}
+
+// historyTarget is a live series a history register is backfilled into.
+type historyTarget struct {
+	labels map[string]string
+
+	// scale converts register values to the unit of the series
+	scale float64
+}
+
+// historySeries returns the live series a history register is backfilled
+// into: its temperature metric if it is the preferred register in temps,
+// and with groups (-all) a thermia_register_value in the base unit for
+// every group it is exported from. It returns none for a register without
+// a live series.
+func historySeries(register string, base map[string]string, temps map[string]string, groups map[string][]types.GroupItem) []historyTarget {
+	with := func(extra map[string]string) map[string]string {
+		labels := make(map[string]string, len(base)+len(extra))
+		for k, v := range base {
+			labels[k] = v
+		}
+		for k, v := range extra {
+			labels[k] = v
+		}
+		return labels
+	}
+
+	var series []historyTarget
+	if key, ok := temps[register]; ok {
+		series = append(series, historyTarget{labels: with(map[string]string{"__name__": "thermia_" + key + "_temperature_celsius"}), scale: 1})
+	}
+	for _, group := range mapper.GroupPrecedence {
+		for _, it := range groups[group] {
+			if it.RegisterName != register || it.RegisterValue == nil {
+				continue
+			}
+			unit, scale := mapper.RegisterUnit(it.Unit)
+			series = append(series, historyTarget{labels: with(map[string]string{
+				"__name__":               "thermia_register_value",
+				mapper.LabelRegisterName: it.RegisterName,
+				mapper.LabelGroup:        group,
+				mapper.LabelUnit:         unit,
+			}), scale: scale})
+			break
+		}
+	}
+	return series
+}
+
+// relabelSeries applies the metric rules to series labels in place, as the
+// exporter applies them to its live metrics. It reports whether the series
+// is kept.
+func relabelSeries(rules []relabel.Rule, labels map[string]string) bool {
+	name := labels["__name__"]
+	delete(labels, "__name__")
+	name, keep := relabel.ApplyLabels(rules, name, labels)
+	labels["__name__"] = name
+	return keep
+}
+
+// findAccount returns the account named name, or the first account if name
+// is empty.
+func findAccount(accounts []config.Account, name string) (config.Account, bool) {
+	if name == "" {
+		return accounts[0], true
+	}
+	for _, a := range accounts {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return config.Account{}, false
+}
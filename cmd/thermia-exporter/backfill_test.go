@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+func TestHistorySeries(t *testing.T) {
+	base := map[string]string{mapper.LabelHeatpumpID: "1"}
+	temps := mapper.PreferredTemperatureRegisters([]string{mapper.RegOperDataOutdoorTempMaSa, mapper.RegOutdoorTemperature, "REG_OPER_DATA_BRINE_PUMP"})
+	v := 21.5
+	groups := map[string][]types.GroupItem{
+		mapper.RegGroupTemperatures:      {{RegisterName: mapper.RegOutdoorTemperature, RegisterValue: &v, Unit: "°C "}},
+		mapper.RegGroupOperationalStatus: {{RegisterName: mapper.RegOutdoorTemperature, RegisterValue: &v, Unit: "0.1°C"}},
+	}
+
+	tests := []struct {
+		name     string
+		register string
+		groups   map[string][]types.GroupItem
+		want     []string
+	}{
+		{"preferred candidate", mapper.RegOutdoorTemperature, nil, []string{"thermia_outdoor_temperature_celsius"}},
+		{"other candidate", mapper.RegOperDataOutdoorTempMaSa, nil, nil},
+		{"unmapped", "REG_OPER_DATA_BRINE_PUMP", nil, nil},
+		{"all", mapper.RegOutdoorTemperature, groups, []string{
+			"thermia_outdoor_temperature_celsius",
+			"thermia_register_value/" + mapper.RegGroupTemperatures + "/celsius*1",
+			"thermia_register_value/" + mapper.RegGroupOperationalStatus + "/celsius*0.1",
+		}},
+		{"all, not in a group", "REG_OPER_DATA_BRINE_PUMP", groups, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range historySeries(tt.register, base, temps, tt.groups) {
+				if s.labels[mapper.LabelHeatpumpID] != "1" {
+					t.Errorf("series %v lost the base labels", s.labels)
+				}
+				id := s.labels["__name__"]
+				if group, ok := s.labels[mapper.LabelGroup]; ok {
+					id += "/" + group + "/" + s.labels[mapper.LabelUnit] + "*" + strconv.FormatFloat(s.scale, 'g', -1, 64)
+				} else if s.scale != 1 {
+					t.Errorf("temperature series scale = %v, want 1", s.scale)
+				}
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historySeries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/sink"
+	"thermia_exporter/internal/snapshot"
+)
+
+// runCheck implements the "check" subcommand: it logs in to every
+// configured account, runs the same self-test as POST /-/selftest and
+// prints each stage. It exits 0 only if every account passed, so it can
+// gate a deployment before the exporter is started.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", selfTestBudget, "overall check timeout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check: %v\n", err)
+		return 1
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, account := range cfg.AccountList() {
+		c := newCollector(cfg, account, snapshot.NewStore(), sink.NewDispatcher(nil, logger, nil), collectorServices{meter: prometheusMeter(cfg)}, logger)
+		report := c.SelfTest(ctx)
+		if !report.Passed {
+			status = 1
+		}
+		name := report.Account
+		if name == "" {
+			name = "default"
+		}
+		fmt.Fprintf(w, "Account %s:\n", name)
+		for _, stage := range report.Stages {
+			result := stage.Detail
+			if stage.Status == collector.StageFail {
+				result = stage.Error
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", stage.Name, stage.Status,
+				time.Duration(stage.DurationSeconds*float64(time.Second)).Round(time.Millisecond), result)
+		}
+	}
+	w.Flush()
+
+	if status == 0 {
+		fmt.Println("OK: the Thermia API is reachable")
+	} else {
+		fmt.Println("FAILED: see the failed stages above")
+	}
+	return status
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/config"
+	"thermia_exporter/internal/consul"
+)
+
+// consulRegistration keeps the exporter registered in Consul, tagged with
+// the IDs of the collected installations.
+type consulRegistration struct {
+	client  *consul.Client
+	service consul.Service
+	logger  *slog.Logger
+
+	// registered is set once a registration succeeded, so shutdown only
+	// deregisters a service that exists
+	registered atomic.Bool
+}
+
+// newConsulRegistration builds the service registration from cfg. The
+// service is advertised at THERMIA_CONSUL_ADVERTISE_ADDR, or else at the
+// hostname and the port of THERMIA_ADDR.
+func newConsulRegistration(cfg *config.Config, logger *slog.Logger) (*consulRegistration, error) {
+	advertise := cfg.ConsulAdvertise
+	if advertise == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("hostname: %w", err)
+		}
+		_, port, err := net.SplitHostPort(cfg.ListenAddr)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ADDR: %w", err)
+		}
+		advertise = net.JoinHostPort(hostname, port)
+	}
+	host, portStr, err := net.SplitHostPort(advertise)
+	if err != nil {
+		return nil, fmt.Errorf("THERMIA_CONSUL_ADVERTISE_ADDR: %w", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("THERMIA_CONSUL_ADVERTISE_ADDR: invalid port %q", portStr)
+	}
+
+	return &consulRegistration{
+		client: consul.NewClient(cfg.ConsulAddr, cfg.ConsulToken, cfg.RequestTimeout),
+		service: consul.Service{
+			ID:            cfg.ConsulService + "-" + advertise,
+			Name:          cfg.ConsulService,
+			Address:       host,
+			Port:          port,
+			CheckURL:      "http://" + advertise + "/ready",
+			CheckInterval: 30 * time.Second,
+		},
+		logger: logger,
+	}, nil
+}
+
+// run registers the service once the first collection has finished, so
+// the tags list the installations, and registers it again every interval
+// so it comes back after a Consul agent restart or a failed attempt, with
+// the current installations. It returns when ctx is done.
+func (r *consulRegistration) run(ctx context.Context, collectors collector.Group, interval time.Duration) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var registered []string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !collectors.Ready() {
+			continue
+		}
+		ticker.Reset(interval)
+
+		tags := installationTags(collectors)
+		r.service.Tags = tags
+		r.service.Meta = map[string]string{"heatpump_ids": strings.Join(tagIDs(tags), ",")}
+		if err := r.client.Register(ctx, r.service); err != nil {
+			r.logger.Warn("Consul registration failed", "error", err)
+			continue
+		}
+		r.registered.Store(true)
+		if registered == nil || !slices.Equal(tags, registered) {
+			r.logger.Info("Registered in Consul", "service", r.service.Name, "id", r.service.ID, "installations", len(tags))
+		}
+		registered = tags
+	}
+}
+
+// deregister removes the service from Consul, unless it was never
+// registered.
+func (r *consulRegistration) deregister(ctx context.Context) {
+	if !r.registered.Load() {
+		return
+	}
+	if err := r.client.Deregister(ctx, r.service.ID); err != nil {
+		r.logger.Warn("Consul deregistration failed", "error", err)
+	}
+}
+
+// installationTags returns one sorted installation-<id> tag per collected
+// installation.
+func installationTags(collectors collector.Group) []string {
+	tags := []string{}
+	for _, inst := range collectors.Installations() {
+		tags = append(tags, "installation-"+strconv.FormatInt(inst.ID, 10))
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+func tagIDs(tags []string) []string {
+	ids := make([]string, len(tags))
+	for i, tag := range tags {
+		ids[i] = strings.TrimPrefix(tag, "installation-")
+	}
+	return ids
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/consul"
+)
+
+func TestConsulRegistration_DeregisterOnlyWhenRegistered(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	r := &consulRegistration{
+		client:  consul.NewClient(srv.URL, "", time.Second),
+		service: consul.Service{ID: "thermia-exporter-host:9808", Name: "thermia-exporter"},
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	r.deregister(context.Background())
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("deregister sent %d requests without a registration", n)
+	}
+
+	r.registered.Store(true)
+	r.deregister(context.Background())
+	if n := requests.Load(); n != 1 {
+		t.Errorf("deregister sent %d requests after a registration, want 1", n)
+	}
+}
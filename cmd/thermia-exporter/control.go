@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/control"
+)
+
+// capabilitiesHandler serves the controls each collected installation
+// exposes as writable for this account: the operation mode and every
+// register a write could be validated for, with its allowed values or range.
+func capabilitiesHandler(c collector.Group) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(c.ControlCapabilities())
+	}
+}
+
+// writeTimeout bounds a control write: authentication, a fresh fetch of the
+// register group and the write itself.
+const writeTimeout = 30 * time.Second
+
+// writeRequest is the body of a control write: a number or the name of an
+// enumerated value, e.g. {"value": "AUTO"} or {"value": 1}.
+type writeRequest struct {
+	Value any `json:"value"`
+}
+
+// writeHandler sets register in group of the installation in the path
+// ({id}) through the Thermia API. Requests must carry the configured bearer
+// token; ?dry_run=true validates the write and returns it without sending.
+// The route must be wrapped in withWriteDeadline.
+func writeHandler(c collector.Group, token, group, register string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(w, r, token) {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid installation ID", http.StatusBadRequest)
+			return
+		}
+		owner := c.ForInstallation(id)
+		if owner == nil {
+			http.Error(w, fmt.Sprintf("installation %d not collected", id), http.StatusNotFound)
+			return
+		}
+
+		var req writeRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var value string
+		switch v := req.Value.(type) {
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			http.Error(w, `body must be {"value": <number or value name>}`, http.StatusBadRequest)
+			return
+		}
+
+		// The write may outlast the server's write timeout. Don't send it if
+		// its result could not reach the caller.
+		if err := extendWriteDeadline(r, writeTimeout+5*time.Second); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+		defer cancel()
+
+		write, err := owner.WriteRegister(ctx, id, group, register, value, control.IsDryRun(r.URL.Query()))
+		if err != nil {
+			http.Error(w, err.Error(), writeStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(write)
+	}
+}
+
+// authorized reports whether r carries token as its bearer token, and
+// answers 401 if not.
+func authorized(w http.ResponseWriter, r *http.Request, token string) bool {
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// writeStatus maps a WriteRegister error to an HTTP status: refused writes
+// are the client's fault, everything else an upstream failure.
+func writeStatus(err error) int {
+	var throttled *api.ThrottledError
+	switch {
+	case errors.Is(err, control.ErrUnknownRegister):
+		return http.StatusNotFound
+	case errors.Is(err, control.ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, control.ErrNotAllowed), errors.Is(err, control.ErrOutOfRange), errors.Is(err, control.ErrNoRange):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &throttled), errors.Is(err, api.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
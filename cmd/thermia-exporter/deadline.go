@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// The server's response writers support write deadlines, but the
+// instrumentation's wrapper around them does not, so deadlines must be set
+// outside of it.
+
+// writeDeadlineKey is the request context key of the server's response
+// controller.
+type writeDeadlineKey struct{}
+
+// withoutWriteDeadline lifts the server's write timeout for long-lived
+// responses. It must wrap the instrumented handler.
+func withoutWriteDeadline(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		h.ServeHTTP(w, r)
+	})
+}
+
+// withWriteDeadline lets h extend the server's write timeout with
+// extendWriteDeadline once it knows how long its response may take. It
+// must wrap the instrumented handler.
+func withWriteDeadline(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), writeDeadlineKey{}, http.NewResponseController(w))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// extendWriteDeadline moves the write deadline of the response to r to d
+// from now. It fails unless the route is wrapped in withWriteDeadline.
+func extendWriteDeadline(r *http.Request, d time.Duration) error {
+	rc, ok := r.Context().Value(writeDeadlineKey{}).(*http.ResponseController)
+	if !ok {
+		return errors.New("write deadline cannot be extended")
+	}
+	return rc.SetWriteDeadline(time.Now().Add(d))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/collector"
+)
+
+// modelHandler serves the model report of every collected installation:
+// which metrics are emitted and which registers were used, unmapped or not
+// found. Attach its output to issues about unsupported models.
+func modelHandler(c collector.Group) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(c.ModelReports())
+	}
+}
+
+// registerFetchTimeout bounds a raw register group fetch: authentication
+// and the request itself.
+const registerFetchTimeout = 30 * time.Second
+
+// registerGroupName matches register group names. The name is part of the
+// upstream URL path, so nothing else is passed on.
+var registerGroupName = regexp.MustCompile(`^REG_GROUP_[A-Z0-9_]+$`)
+
+// registersHandler serves one register group of an installation exactly as
+// the Thermia API returns it, fetched fresh on every request. People with
+// unsupported models can attach its output to issues, so registers are
+// mapped from what the heat pump really reports. The route must be wrapped
+// in withWriteDeadline.
+func registersHandler(c collector.Group, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(w, r, token) {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.URL.Query().Get("installation"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid installation ID", http.StatusBadRequest)
+			return
+		}
+		group := r.URL.Query().Get("group")
+		if !registerGroupName.MatchString(group) {
+			http.Error(w, fmt.Sprintf("invalid register group %q", group), http.StatusBadRequest)
+			return
+		}
+		owner := c.ForInstallation(id)
+		if owner == nil {
+			http.Error(w, fmt.Sprintf("installation %d not collected", id), http.StatusNotFound)
+			return
+		}
+
+		// The fetch may outlast the server's write timeout
+		if err := extendWriteDeadline(r, registerFetchTimeout+5*time.Second); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), registerFetchTimeout)
+		defer cancel()
+
+		data, err := owner.RawRegisterGroup(ctx, id, group)
+		if err != nil {
+			status := http.StatusBadGateway
+			var throttled *api.ThrottledError
+			var upstream *api.StatusError
+			switch {
+			case errors.As(err, &throttled), errors.Is(err, api.ErrCircuitOpen):
+				status = http.StatusServiceUnavailable
+			case errors.As(err, &upstream) && upstream.Status == http.StatusNotFound:
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
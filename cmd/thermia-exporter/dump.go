@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/sink"
+	"thermia_exporter/internal/snapshot"
+)
+
+// runDump implements the "dump" subcommand: it prints the raw JSON of the
+// info, every register group and the events of each installation, for
+// debugging registers a model names differently. The output contains
+// serial numbers and addresses; review it before attaching it to an issue.
+func runDump(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	out := fs.String("out", "", "write the dump to this file instead of stdout")
+	timeout := fs.Duration("timeout", 2*time.Minute, "overall dump timeout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+		return 1
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status := 0
+	dumps := []collector.AccountDump{}
+	for _, account := range cfg.AccountList() {
+		c := newCollector(cfg, account, snapshot.NewStore(), sink.NewDispatcher(nil, logger, nil), collectorServices{meter: prometheusMeter(cfg)}, logger)
+		dump, err := c.Dump(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dump: account %q: %v\n", account.Name, err)
+			status = 1
+			continue
+		}
+		dumps = append(dumps, dump)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dumps); err != nil {
+		fmt.Fprintf(os.Stderr, "dump: %v\n", err)
+		return 1
+	}
+	return status
+}
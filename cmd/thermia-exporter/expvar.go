@@ -0,0 +1,71 @@
+package main
+
+import (
+	"expvar"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"thermia_exporter/internal/collector"
+)
+
+// publishExpvars publishes the latest installation summaries and the
+// exporter's own counters and gauges as expvars, served with the Go
+// runtime's memstats on /debug/vars. Must be called at most once.
+func publishExpvars(c collector.Group, internal prometheus.Gatherer, logger *slog.Logger) {
+	expvar.Publish("thermia_summaries", expvar.Func(func() any {
+		snaps := c.Snapshots()
+		summaries := make([]installationSummary, 0, len(snaps))
+		for _, snap := range snaps {
+			summaries = append(summaries, newInstallationSummary(snap))
+		}
+		return summaries
+	}))
+	expvar.Publish("thermia_counters", expvar.Func(func() any {
+		families, err := internal.Gather()
+		if err != nil {
+			logger.Warn("Gathering metrics for expvar failed", "error", err)
+		}
+		return flattenMetrics(families)
+	}))
+}
+
+// flattenMetrics returns the value of every thermia_* counter and gauge
+// keyed by its name and labels, e.g. thermia_api_requests_total{endpoint="status"}.
+func flattenMetrics(families []*dto.MetricFamily) map[string]float64 {
+	values := make(map[string]float64)
+	for _, mf := range families {
+		if !strings.HasPrefix(mf.GetName(), "thermia_") {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			var v float64
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				v = m.GetGauge().GetValue()
+			default:
+				continue
+			}
+			values[mf.GetName()+formatLabels(m.GetLabel())] = v
+		}
+	}
+	return values
+}
+
+func formatLabels(labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = l.GetName() + "=" + strconv.Quote(l.GetValue())
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/sink"
+	"thermia_exporter/internal/snapshot"
+	"thermia_exporter/internal/types"
+)
+
+// fleetRow is one installation in the fleet report.
+type fleetRow struct {
+	Account       string   `json:"account,omitempty"`
+	HeatpumpID    int64    `json:"heatpump_id"`
+	HeatpumpName  string   `json:"heatpump_name"`
+	Model         string   `json:"model"`
+	Site          string   `json:"site,omitempty"`
+	Serial        string   `json:"serial,omitempty"`
+	Online        bool     `json:"online"`
+	LastOnline    string   `json:"last_online"`
+	OperationMode string   `json:"operation_mode"`
+	ActiveAlerts  []string `json:"active_alerts"`
+	Indoor        *float64 `json:"indoor_celsius"`
+	Outdoor       *float64 `json:"outdoor_celsius"`
+	SupplyLine    *float64 `json:"supply_line_celsius"`
+	ReturnLine    *float64 `json:"return_line_celsius"`
+	HotWater      *float64 `json:"hot_water_celsius"`
+	Compressor    *int     `json:"compressor_hours"`
+	AuxHeater     *int     `json:"aux_heater_hours"`
+}
+
+var fleetColumns = []string{
+	"account", "heatpump_id", "heatpump_name", "model", "site", "serial", "online", "last_online",
+	"operation_mode", "active_alerts", "indoor_celsius", "outdoor_celsius", "supply_line_celsius",
+	"return_line_celsius", "hot_water_celsius", "compressor_hours", "aux_heater_hours",
+}
+
+func newFleetRow(account string, s types.ThermiaSummary) fleetRow {
+	temp := func(sensor string) *float64 {
+		if v, ok := s.Temperatures[sensor]; ok {
+			return &v
+		}
+		return nil
+	}
+	row := fleetRow{
+		Account:       account,
+		HeatpumpID:    s.HeatpumpID,
+		HeatpumpName:  s.HeatpumpName,
+		Model:         s.HeatpumpModel,
+		Site:          s.Site,
+		Serial:        s.Serial,
+		Online:        s.Online,
+		LastOnline:    s.LastOnline,
+		OperationMode: s.OperationMode.String(),
+		ActiveAlerts:  s.ActiveAlerts,
+		Indoor:        temp("indoor"),
+		Outdoor:       temp("outdoor"),
+		SupplyLine:    temp("supply_line"),
+		ReturnLine:    temp("return_line"),
+		HotWater:      temp("hot_water"),
+	}
+	if v, ok := s.OperationalTimeHours[mapper.RegOperTimeCompressor]; ok {
+		row.Compressor = &v
+	}
+	for _, reg := range []string{mapper.RegOperTimeImm1, mapper.RegOperTimeImm2, mapper.RegOperTimeImm3} {
+		if v, ok := s.OperationalTimeHours[reg]; ok {
+			if row.AuxHeater == nil {
+				row.AuxHeater = new(int)
+			}
+			*row.AuxHeater += v
+		}
+	}
+	return row
+}
+
+// record returns the row's CSV fields in fleetColumns order. Missing
+// readings are left empty.
+func (r fleetRow) record() []string {
+	float := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	hours := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+	return []string{
+		r.Account, strconv.FormatInt(r.HeatpumpID, 10), r.HeatpumpName, r.Model, r.Site, r.Serial,
+		strconv.FormatBool(r.Online), r.LastOnline, r.OperationMode, strings.Join(r.ActiveAlerts, ";"),
+		float(r.Indoor), float(r.Outdoor), float(r.SupplyLine), float(r.ReturnLine), float(r.HotWater),
+		hours(r.Compressor), hours(r.AuxHeater),
+	}
+}
+
+// runFleetReport implements the "fleet-report" subcommand: it collects every
+// installation of every configured account once and writes a CSV or JSON
+// report, for installers reviewing a fleet outside Prometheus.
+func runFleetReport(args []string) int {
+	fs := flag.NewFlagSet("fleet-report", flag.ContinueOnError)
+	format := fs.String("format", "csv", "report format: csv or json")
+	out := fs.String("out", "", "write the report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "csv" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "fleet-report: invalid -format %q\n", *format)
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fleet-report: %v\n", err)
+		return 1
+	}
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+
+	ctx := context.Background()
+	var rows []fleetRow
+	status := 0
+	for _, account := range cfg.AccountList() {
+		store := snapshot.NewStore()
+		c := newCollector(cfg, account, store, sink.NewDispatcher(nil, logger, nil), collectorServices{meter: prometheusMeter(cfg)}, logger)
+		if err := c.CollectOnce(ctx); err != nil {
+			logger.Error("Collection failed", "account", account.Name, "error", err)
+			status = 1
+			continue
+		}
+		snaps := store.All()
+		sort.Slice(snaps, func(i, j int) bool { return snaps[i].InstallationID < snaps[j].InstallationID })
+		for _, snap := range snaps {
+			rows = append(rows, newFleetRow(account.Name, snap.Summary))
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fleet-report: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeFleetReport(w, *format, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "fleet-report: %v\n", err)
+		return 1
+	}
+	return status
+}
+
+// writeFleetReport writes rows to w as CSV with a header line, or as an
+// indented JSON list.
+func writeFleetReport(w io.Writer, format string, rows []fleetRow) error {
+	if format == "json" {
+		if rows == nil {
+			rows = []fleetRow{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fleetColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(row.record()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// httpMetrics instruments the exporter's own HTTP endpoints.
+type httpMetrics struct {
+	inFlight prometheus.Gauge
+	duration *prometheus.HistogramVec
+}
+
+// newHTTPMetrics creates the HTTP server metrics and registers them with reg.
+func newHTTPMetrics(reg prometheus.Registerer) *httpMetrics {
+	m := &httpMetrics{
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served by the exporter",
+		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "thermia_http_request_duration_seconds",
+			Help:    "Duration of HTTP requests served by the exporter by handler",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"handler", "method", "code"}),
+	}
+	reg.MustRegister(m.inFlight, m.duration)
+	return m
+}
+
+// instrument wraps h so its requests are counted under the given handler
+// name. The name is fixed per route to keep the label bounded.
+func (m *httpMetrics) instrument(handler string, h http.Handler) http.Handler {
+	duration := m.duration.MustCurryWith(prometheus.Labels{"handler": handler})
+	return promhttp.InstrumentHandlerInFlight(m.inFlight,
+		promhttp.InstrumentHandlerDuration(duration, h))
+}
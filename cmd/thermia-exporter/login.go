@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/config"
+)
+
+// runLogin implements the "login" subcommand: it performs the B2C login once,
+// verifies the token by listing installations, and outputs an encrypted
+// token bundle for THERMIA_REFRESH_TOKEN. The account password never needs
+// to be stored alongside the running exporter.
+func runLogin(args []string) int {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := fs.String("username", os.Getenv("THERMIA_USERNAME"), "Thermia Online username (email)")
+	out := fs.String("out", "", "write the token bundle to this file instead of stdout")
+	key := fs.String("key", os.Getenv("THERMIA_BUNDLE_KEY"), "base64 bundle key (generated if empty)")
+	timeout := fs.Duration("timeout", 2*time.Minute, "overall login timeout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	stdin := bufio.NewReader(os.Stdin)
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+
+	if *username == "" {
+		if !interactive {
+			fmt.Fprintln(os.Stderr, "login: set -username or THERMIA_USERNAME when stdin is not a terminal")
+			return 2
+		}
+		*username = prompt(stdin, "Username: ")
+	}
+	password, err := readPassword(stdin, interactive)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: read password: %v\n", err)
+		return 2
+	}
+	if *username == "" || password == "" {
+		fmt.Fprintln(os.Stderr, "login: username and password are required")
+		return 2
+	}
+
+	portal, err := config.LoadPortal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	result, err := auth.NewPortalAuthClient(portal, logger).Authenticate(ctx, auth.Credentials{
+		Username: *username,
+		Password: password,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: authentication failed: %v\n", err)
+		if errors.Is(err, auth.ErrClientRejected) {
+			fmt.Fprintln(os.Stderr, "login: Thermia no longer accepts this exporter's client ID or scopes; upgrade thermia-exporter or set THERMIA_PORTAL_CLIENT_ID")
+		}
+		return 1
+	}
+	if result.RefreshToken == "" {
+		fmt.Fprintln(os.Stderr, "login: no refresh token issued (offline_access scope missing?)")
+		return 1
+	}
+
+	// Verify the token is accepted by the API before handing it out
+	apiClient, err := api.NewAPIClient(ctx, portal.ConfigURL, result.AccessToken, logger, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: token verification failed: %v\n", err)
+		return 1
+	}
+	installations, err := apiClient.GetInstallations(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: listing installations failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Verified: %d installation(s) visible\n", len(installations))
+	for _, inst := range installations {
+		fmt.Fprintf(os.Stderr, "  %d  %s\n", inst.ID, inst.Name)
+	}
+
+	if *key == "" {
+		if *key, err = auth.GenerateBundleKey(); err != nil {
+			fmt.Fprintf(os.Stderr, "login: generate key: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Generated bundle key (set as THERMIA_BUNDLE_KEY):\n%s\n", *key)
+	}
+
+	bundle, err := auth.SealBundle(auth.TokenBundle{
+		Username:     *username,
+		RefreshToken: result.RefreshToken,
+		CreatedAt:    time.Now().UTC(),
+	}, *key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "login: seal bundle: %v\n", err)
+		return 1
+	}
+
+	if *out != "" {
+		if err := os.WriteFile(*out, []byte(bundle+"\n"), 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "login: write bundle: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Token bundle written to %s (set as THERMIA_REFRESH_TOKEN)\n", *out)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, "Token bundle (set as THERMIA_REFRESH_TOKEN):")
+	fmt.Println(bundle)
+	return 0
+}
+
+// readPassword returns THERMIA_PASSWORD, else prompts for the password
+// without echoing it if stdin is a terminal, else reads the first line of
+// the piped stdin.
+func readPassword(stdin *bufio.Reader, interactive bool) (string, error) {
+	if password := os.Getenv("THERMIA_PASSWORD"); password != "" {
+		return password, nil
+	}
+	if !interactive {
+		line, err := stdin.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(string(password)), err
+}
+
+// prompt prints label to stderr and reads one trimmed line from r.
+func prompt(r *bufio.Reader, label string) string {
+	fmt.Fprint(os.Stderr, label)
+	line, _ := r.ReadString('\n')
+	return strings.TrimSpace(line)
+}
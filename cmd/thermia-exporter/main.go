@@ -2,60 +2,392 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"thermia_exporter/internal/api"
 	"thermia_exporter/internal/auth"
 	"thermia_exporter/internal/collector"
 	"thermia_exporter/internal/config"
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/instance"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/meter"
+	"thermia_exporter/internal/remotewrite"
+	"thermia_exporter/internal/sink"
+	"thermia_exporter/internal/snapshot"
+	"thermia_exporter/internal/statefile"
+	"thermia_exporter/internal/tlswatch"
+	"thermia_exporter/internal/tracing"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		slog.Error("Failed to load config", "error", err)
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "login":
+			os.Exit(runLogin(os.Args[2:]))
+		case "backfill":
+			os.Exit(runBackfill(os.Args[2:]))
+		case "fleet-report":
+			os.Exit(runFleetReport(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "dump":
+			os.Exit(runDump(os.Args[2:]))
+		case "version":
+			os.Exit(runVersion(os.Args[2:]))
+		}
 	}
 
-	if err := cfg.Validate(); err != nil {
+	// Load configuration
+	lifecycle := newLifecycleMetrics(nil)
+	cfg, err := loadConfig()
+	if err != nil {
 		slog.Error("Invalid config", "error", err)
 		os.Exit(1)
 	}
+	lifecycle.reloaded(true)
 
 	// Setup logging
 	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+	if err := statefile.Check(cfg.InstanceIDFile); err != nil {
+		logger.Error("Invalid instance ID file", "error", err)
+		os.Exit(1)
+	}
+	instanceID, err := instance.LoadID(cfg.InstanceIDFile)
+	if err != nil {
+		logger.Error("Invalid instance ID file", "error", err)
+		os.Exit(1)
+	}
+	logger = logger.With("instance_id", instanceID)
+	buildInfo := newBuildInfo(instanceID)
+	accounts := cfg.AccountList()
 	logger.Info("Starting Thermia Exporter",
-		"listen_addr", cfg.ListenAddr, "collect_interval", cfg.CollectInterval)
+		"mode", cfg.Mode, "listen_addr", cfg.ListenAddr, "collect_interval", cfg.CollectInterval,
+		"split_metrics", cfg.SplitMetrics, "sinks", cfg.SinkCount(), "accounts", len(accounts))
+
+	// One snapshot store per account, shared by /metrics and every other
+	// reader of the account's collected data.
+	stores := make(snapshot.Set, len(accounts))
+	for i := range stores {
+		stores[i] = snapshot.NewStore()
+	}
+
+	// Push sinks publish every new collection (or every THERMIA_PUSH_INTERVAL)
+	// and are flushed on shutdown.
+	var pushSinks []sink.Sink
+	switch {
+	case cfg.PushURL == "":
+	case cfg.PushProtocol == config.PushPushgateway:
+		pushSinks = append(pushSinks, sink.NewPushgateway(cfg.PushURL, cfg.PushJob, cfg.RequestTimeout))
+	default:
+		var queue *sink.Queue
+		if cfg.PushQueueSize > 0 {
+			queue = sink.NewQueue("remote_write", cfg.PushQueueSize, cfg.PushQueueDrop)
+		}
+		var deadband *sink.Deadband
+		if len(cfg.PushDeadband) > 0 {
+			deadband = sink.NewDeadband(cfg.PushDeadband[""], cfg.PushFullInterval, nil)
+			for suffix, threshold := range cfg.PushDeadband {
+				if suffix != "" {
+					deadband.SetThreshold(suffix, threshold)
+				}
+			}
+		}
+		pushSinks = append(pushSinks, sink.NewRemoteWrite(remotewrite.NewClient(cfg.PushURL, cfg.RequestTimeout), deadband, queue))
+	}
+	sinks := sink.NewDispatcher(stores, logger, nil, pushSinks...)
+	sinks.SetInfo(buildInfo)
+
+	// The energy meter and tracer are shared by the accounts
+	services := collectorServices{meter: prometheusMeter(cfg)}
+	var mqttMeter *meter.MQTTSource
+	if cfg.MeterMQTTURL != "" {
+		mqttMeter, err = meter.NewMQTTSource(cfg.MeterMQTTURL, cfg.MeterTopic, logger, nil)
+		if err != nil {
+			logger.Error("Invalid MQTT energy meter", "error", err)
+			os.Exit(1)
+		}
+		services.meter = mqttMeter
+	}
+	if cfg.OTLPEndpoint != "" {
+		services.tracer = tracing.NewTracer(cfg.OTLPEndpoint, "thermia_exporter", logger, nil)
+	}
 
-	// Create authentication client
-	authClient := auth.NewAuthClient(logger)
-	creds := auth.Credentials{
-		Username: cfg.Username,
-		Password: cfg.Password,
+	// One collector per account, each with its own login and token cache
+	collectors := make(collector.Group, len(accounts))
+	for i, account := range accounts {
+		collectors[i] = newCollector(cfg, account, stores[i], sinks, services, logger)
+		if err := collectors[i].CheckTokenCache(); err != nil {
+			logger.Error("Invalid token cache file", "account", account.Name, "error", err)
+			os.Exit(1)
+		}
 	}
 
-	// Create and register Prometheus collector
-	thermiaCollector := collector.NewThermiaCollector(authClient, creds, cfg.RequestTimeout, logger)
-	prometheus.MustRegister(thermiaCollector)
+	// Pending operation mode overrides, restored once they expire
+	var overrides *overrideManager
+	if cfg.EnableWrite {
+		if err := statefile.Check(cfg.OverrideFile); err != nil {
+			logger.Error("Invalid override file", "error", err)
+			os.Exit(1)
+		}
+		pending, err := control.LoadOverrides(cfg.OverrideFile)
+		if err != nil {
+			logger.Error("Invalid override file", "error", err)
+			os.Exit(1)
+		}
+		overrides = newOverrideManager(groupWriters(collectors), pending, nil, logger)
+	}
+
+	// Fail fast on an unusable account instead of retrying in the background
+	if cfg.StartupProbe {
+		for i, c := range collectors {
+			probeCtx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+			_, err := c.Probe(probeCtx)
+			cancel()
+			if err != nil {
+				logger.Error("Startup probe failed", "account", accounts[i].Name, "error", err)
+				os.Exit(1)
+			}
+		}
+	}
 
 	// Collect from the Thermia API in the background; /metrics serves the
 	// cached result so slow upstream responses never fail a scrape.
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
-	go thermiaCollector.Run(ctx, cfg.CollectInterval)
+	go watchReload(ctx, logger, collectors, lifecycle)
+	if mqttMeter != nil {
+		go mqttMeter.Run(ctx)
+	}
+	var running sync.WaitGroup
+	for _, c := range collectors {
+		running.Add(1)
+		go func(c *collector.ThermiaCollector) {
+			defer running.Done()
+			c.Run(ctx, cfg.CollectInterval)
+		}(c)
+	}
+	if overrides != nil {
+		go overrides.run(ctx)
+	}
+	if cfg.PushInterval > 0 && sinks.Len() > 0 {
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			sinks.Run(ctx, cfg.PushInterval)
+		}()
+	}
+	collectorDone := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(collectorDone)
+	}()
+
+	// Agent mode never opens a port: collected data only leaves via sinks
+	var srv *http.Server
+	if cfg.Mode != config.ModeAgent {
+		srv = startServer(cfg, logger, collectors, overrides, lifecycle, buildInfo)
+	}
+
+	// Register in Consul for users whose Prometheus uses Consul SD
+	var registration *consulRegistration
+	if cfg.ConsulAddr != "" {
+		registration, err = newConsulRegistration(cfg, logger)
+		if err != nil {
+			logger.Error("Invalid Consul registration", "error", err)
+			os.Exit(1)
+		}
+		go registration.run(ctx, collectors, cfg.CollectInterval)
+	}
 
-	// Setup HTTP server
+	// Wait for shutdown signal (cancels the collection loop too)
+	<-ctx.Done()
+
+	logger.Info("Shutting down gracefully...")
+
+	// Graceful shutdown with timeout
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Deregister first so Prometheus stops scraping before the port closes
+	if registration != nil {
+		registration.deregister(shutdownCtx)
+	}
+	if srv != nil {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Shutdown error", "error", err)
+		}
+	}
+
+	// Let an in-flight collection and publish finish before flushing sinks
+	select {
+	case <-collectorDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Collection still running at shutdown timeout")
+	}
+	if err := sinks.Close(shutdownCtx); err != nil {
+		logger.Error("Sink shutdown error", "error", err)
+	}
+	if services.tracer != nil {
+		if err := services.tracer.Close(shutdownCtx); err != nil {
+			logger.Warn("Spans still unexported at shutdown", "error", err)
+		}
+	}
+
+	logger.Info("Exporter stopped")
+}
+
+// collectorServices are shared by the collectors of all accounts.
+type collectorServices struct {
+	meter  meter.Source
+	tracer *tracing.Tracer
+}
+
+// prometheusMeter returns the Prometheus energy meter, or nil if none is
+// configured. One-off commands only use this one: the MQTT meter needs a
+// running subscription.
+func prometheusMeter(cfg *config.Config) meter.Source {
+	if cfg.MeterURL == "" {
+		return nil
+	}
+	return meter.NewPrometheusSource(cfg.MeterURL, cfg.MeterQuery)
+}
+
+// newCollector creates the collector for one account. Collections publish
+// to sinks, which read every account's store.
+func newCollector(cfg *config.Config, account config.Account, store *snapshot.Store, sinks *sink.Dispatcher, services collectorServices, logger *slog.Logger) *collector.ThermiaCollector {
+	opts := collector.Options{
+		Account:                 account.Name,
+		HeatOutputRegister:      cfg.HeatOutputRegister,
+		SpikeMaxDelta:           cfg.SpikeMaxDelta,
+		HoldTTL:                 cfg.ValueHoldTTL,
+		IndoorOffsets:           cfg.IndoorOffsets,
+		ShortCycleStartsPerHour: cfg.ShortCycleStartsPerHour,
+		AuxShareWindow:          cfg.AuxShareWindow,
+		Refrigerant:             cfg.Refrigerant,
+		InstallationIntervals:   cfg.InstallationIntervals,
+		QuietHours:              cfg.QuietHours,
+		QuietInterval:           cfg.QuietInterval,
+		EventsSince:             cfg.EventsSince,
+		Anonymize:               cfg.Anonymize,
+		RedactSalt:              cfg.RedactSalt,
+		NormalizeLabels:         cfg.NormalizeLabels,
+		RestartAfterFailures:    cfg.RestartAfterFailures,
+		Schedules:               cfg.Schedules,
+		ExportRawRegisters:      cfg.ExportRawRegisters,
+		MaxSeries:               cfg.MaxSeries,
+		NoOperTimeGauges:        !cfg.OperTimeGauges,
+		StatusPriority:          cfg.StatusPriority,
+		APIRateLimit:            cfg.APIRateLimit,
+		BreakerFailures:         cfg.BreakerFailures,
+		BreakerCooldown:         cfg.BreakerCooldown,
+		MetricRules:             slices.Concat(cfg.MetricRules, cfg.RedactLabels),
+		RegisterAliases:         cfg.RegisterAliases,
+		PrewarmTimeout:          cfg.PrewarmTimeout,
+		FetchConcurrency:        cfg.FetchConcurrency,
+		HedgeDelay:              cfg.HedgeDelay,
+		HedgeMax:                cfg.HedgeMax,
+		StartupJitter:           cfg.StartupJitter,
+		PollJitter:              cfg.PollJitter,
+		TokenCacheFile:          cfg.TokenCacheFile,
+		Store:                   store,
+		Meter:                   services.meter,
+		Tracer:                  services.tracer,
+	}
+	if sinks.Len() > 0 && cfg.PushInterval == 0 {
+		opts.OnCollect = sinks.Publish
+	}
+	if account.Name != "" {
+		logger = logger.With("account", account.Name)
+	}
+	return collector.NewThermiaCollector(auth.NewPortalAuthClient(cfg.Portal, logger), credentials(account), cfg.RequestTimeout, logger, opts)
+}
+
+// startServer registers the collectors and starts the HTTP server in the
+// background. Heat pump metrics and exporter self-metrics (collection stats,
+// Go runtime, process) live in separate registries so they can be served
+// from separate endpoints.
+func startServer(cfg *config.Config, logger *slog.Logger, thermiaCollectors collector.Group, overrides *overrideManager, lifecycle *lifecycleMetrics, buildInfo prometheus.Gauge) *http.Server {
+	pumpRegistry := prometheus.NewRegistry()
+	internalRegistry := prometheus.NewRegistry()
+	internalRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	for i, c := range thermiaCollectors {
+		pumpRegistry.MustRegister(c)
+		// Heat pump metrics carry the account label already
+		var internal prometheus.Registerer = internalRegistry
+		if name := cfg.AccountList()[i].Name; name != "" {
+			internal = prometheus.WrapRegistererWith(prometheus.Labels{mapper.LabelAccount: name}, internalRegistry)
+		}
+		internal.MustRegister(c.Internal())
+	}
+	internalRegistry.MustRegister(api.Metrics()...)
+	internalRegistry.MustRegister(tlswatch.Metrics()...)
+	internalRegistry.MustRegister(sink.Metrics()...)
+	internalRegistry.MustRegister(lifecycle.collectors()...)
+	internalRegistry.MustRegister(buildInfo)
+
+	metricsGatherer := prometheus.Gatherers{pumpRegistry, internalRegistry}
+	if cfg.SplitMetrics {
+		metricsGatherer = prometheus.Gatherers{pumpRegistry}
+	}
+
+	// Setup HTTP server; every route is instrumented under its own name
+	httpMetrics := newHTTPMetrics(internalRegistry)
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
-	mux.HandleFunc("/health", healthHandler)
+	// Exemplars are only exposed in the OpenMetrics format
+	handlerOpts := promhttp.HandlerOpts{EnableOpenMetrics: thermiaCollectors[0].Exemplars()}
+	mux.Handle("/metrics", httpMetrics.instrument("metrics", promhttp.InstrumentMetricHandler(internalRegistry,
+		metricsHandler(metricsGatherer, pumpRegistry, handlerOpts))))
+	mux.Handle("/metrics/internal", httpMetrics.instrument("metrics_internal",
+		promhttp.HandlerFor(internalRegistry, handlerOpts)))
+	healthPolicy := collector.HealthPolicy{MaxFailures: cfg.ReadyMaxFailures, MaxAge: cfg.ReadyMaxAge}
+	mux.Handle("/health", httpMetrics.instrument("health", healthHandler(thermiaCollectors, healthPolicy, cfg.HealthMode == config.HealthStrict)))
+	mux.Handle("/ready", httpMetrics.instrument("ready", readyHandler(thermiaCollectors, healthPolicy)))
+	mux.Handle("/sd", httpMetrics.instrument("sd", sdHandler(thermiaCollectors)))
+	mux.Handle("/config", httpMetrics.instrument("config", configHandler(cfg)))
+	mux.Handle("/debug/model", httpMetrics.instrument("debug_model", modelHandler(thermiaCollectors)))
+	if cfg.DebugToken != "" {
+		mux.Handle("/debug/registers", withWriteDeadline(httpMetrics.instrument("debug_registers", registersHandler(thermiaCollectors, cfg.DebugToken))))
+	}
+	if cfg.Expvar {
+		publishExpvars(thermiaCollectors, internalRegistry, logger)
+		mux.Handle("/debug/vars", httpMetrics.instrument("debug_vars", expvar.Handler()))
+	}
+	mux.Handle("/api/v1/summary", httpMetrics.instrument("summary", summaryHandler(thermiaCollectors)))
+	mux.Handle("/api/v1/summary/{installation_id}", httpMetrics.instrument("summary", summaryHandler(thermiaCollectors)))
+	streamsDone := make(chan struct{})
+	mux.Handle("/stream", withoutWriteDeadline(httpMetrics.instrument("stream", streamHandler(thermiaCollectors, streamsDone))))
+	mux.Handle("/api/v1/meta", httpMetrics.instrument("meta", metaHandler(cfg, thermiaCollectors)))
+	mux.Handle("/-/selftest", withWriteDeadline(httpMetrics.instrument("selftest", selfTestHandler(thermiaCollectors))))
+	mux.Handle("/control/capabilities", httpMetrics.instrument("control_capabilities", capabilitiesHandler(thermiaCollectors)))
+	mux.Handle("/api/v1/alerts", withWriteDeadline(httpMetrics.instrument("alerts",
+		alertsHandler(thermiaCollectors, cfg.WriteToken, cfg.AlertMitigations, newAlertActions(internalRegistry), logger))))
+	if cfg.EnableWrite {
+		mux.Handle("/api/v1/heatpump/{id}/operation_mode", withWriteDeadline(httpMetrics.instrument("write_operation_mode",
+			writeHandler(thermiaCollectors, cfg.WriteToken, mapper.RegGroupOperationalOperation, mapper.RegOperationMode))))
+		mux.Handle("/api/v1/heatpump/{id}/operation_mode/override", withWriteDeadline(httpMetrics.instrument("write_operation_mode_override",
+			overrides.handler(cfg.WriteToken))))
+		mux.Handle("/api/v1/heatpump/{id}/hot_water_boost", withWriteDeadline(httpMetrics.instrument("write_hot_water_boost",
+			writeHandler(thermiaCollectors, cfg.WriteToken, mapper.RegGroupHotWater, mapper.RegHotWaterBoost))))
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.ListenAddr,
@@ -64,6 +396,7 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	srv.RegisterOnShutdown(func() { close(streamsDone) })
 
 	// Start server in goroutine
 	go func() {
@@ -74,20 +407,67 @@ func main() {
 		}
 	}()
 
-	// Wait for shutdown signal (cancels the collection loop too)
-	<-ctx.Done()
+	return srv
+}
 
-	logger.Info("Shutting down gracefully...")
+// loadConfig loads and validates configuration and decrypts a token bundle
+// produced by the login command.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
 
-	// Graceful shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		logger.Error("Shutdown error", "error", err)
+	if auth.IsBundle(cfg.RefreshToken) {
+		bundle, err := auth.OpenBundle(cfg.RefreshToken, cfg.BundleKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token bundle (check THERMIA_BUNDLE_KEY): %w", err)
+		}
+		cfg.RefreshToken = bundle.RefreshToken
+		if cfg.Username == "" {
+			cfg.Username = bundle.Username
+		}
+	}
+	for i, a := range cfg.Accounts {
+		if !auth.IsBundle(a.RefreshToken) {
+			continue
+		}
+		bundle, err := auth.OpenBundle(a.RefreshToken, cfg.BundleKey)
+		if err != nil {
+			return nil, fmt.Errorf("account %q: invalid token bundle (check THERMIA_BUNDLE_KEY): %w", a.Name, err)
+		}
+		cfg.Accounts[i].RefreshToken = bundle.RefreshToken
+		if a.Username == "" {
+			cfg.Accounts[i].Username = bundle.Username
+		}
 	}
 
-	logger.Info("Exporter stopped")
+	return cfg, nil
+}
+
+// credentials returns the authentication credentials of an account.
+func credentials(account config.Account) auth.Credentials {
+	return auth.Credentials{
+		Username:     account.Username,
+		Password:     account.Password,
+		RefreshToken: account.RefreshToken,
+	}
+}
+
+// authenticate obtains an access token for one-off commands, preferring the
+// refresh-token grant over a full login.
+func authenticate(ctx context.Context, authClient *auth.AuthClient, creds auth.Credentials) (*auth.AuthResult, error) {
+	if creds.RefreshToken != "" {
+		result, err := authClient.Refresh(ctx, creds.RefreshToken)
+		if err == nil || creds.Password == "" || errors.Is(err, auth.ErrClientRejected) {
+			return result, err
+		}
+	}
+	return authClient.Authenticate(ctx, creds)
 }
 
 // setupLogger creates a structured logger based on configuration.
@@ -124,8 +504,75 @@ func parseLevel(s string) slog.Level {
 	}
 }
 
-// healthHandler responds to health check requests.
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK\n"))
+// configHandler serves the effective configuration with credentials
+// redacted, including the source of every value.
+func configHandler(cfg *config.Config) http.HandlerFunc {
+	settings := cfg.Effective()
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(settings)
+	}
+}
+
+// readyHandler reports ready once the first collection attempt of every
+// account has finished, so the first scrape after a deployment gets data,
+// and as long as every account is healthy under policy. The body lists
+// each account's last successful collection and token state.
+func readyHandler(c collector.Group, policy collector.HealthPolicy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := c.Health(policy)
+		code := http.StatusOK
+		for _, s := range statuses {
+			if !s.Healthy {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(statuses)
+	}
+}
+
+// healthResponse is the body of /health.
+type healthResponse struct {
+	// Status is "ok", "degraded" (an account fails the checks but the
+	// liveness mode ignores it) or "failing"
+	Status   string                   `json:"status"`
+	Mode     string                   `json:"mode"`
+	Accounts []collector.HealthStatus `json:"accounts"`
+}
+
+// healthHandler reports each account's dependencies: whether the identity
+// provider and the API answered, the token state and the age of the last
+// poll. In liveness mode it only fails when a collection is wedged, so a
+// restart can help; strict mode fails whenever /ready would.
+func healthHandler(c collector.Group, policy collector.HealthPolicy, strict bool) http.HandlerFunc {
+	mode := config.HealthLiveness
+	if strict {
+		mode = config.HealthStrict
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := healthResponse{Status: "ok", Mode: mode, Accounts: c.Health(policy)}
+		for _, s := range resp.Accounts {
+			switch {
+			case s.Wedged || (strict && !s.Healthy):
+				resp.Status = "failing"
+			case !s.Healthy && resp.Status == "ok":
+				resp.Status = "degraded"
+			}
+		}
+		code := http.StatusOK
+		if resp.Status == "failing" {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(resp)
+	}
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sort"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/config"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = ""
+
+// metricNamespace prefixes every metric the exporter exposes.
+const metricNamespace = "thermia"
+
+// exporterVersion returns the build version: the linker-set version, else
+// the module version from the build info, else "dev".
+func exporterVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// meta is the /api/v1/meta response.
+type meta struct {
+	Version         string             `json:"version"`
+	MetricNamespace string             `json:"metric_namespace"`
+	Mode            string             `json:"mode"`
+	Features        []string           `json:"features"`
+	Installations   []metaInstallation `json:"installations"`
+}
+
+// metaInstallation is an installation with its configured poll interval.
+type metaInstallation struct {
+	ID              int64   `json:"id"`
+	Name            string  `json:"name"`
+	Model           string  `json:"model"`
+	Account         string  `json:"account,omitempty"`
+	IntervalSeconds float64 `json:"interval_seconds"`
+}
+
+// features lists the optional features enabled in cfg. Background
+// collection and the read-only control capabilities are always on.
+func features(cfg *config.Config) []string {
+	f := []string{"background", "control_capabilities"}
+	for name, on := range map[string]bool{
+		"push":             cfg.PushURL != "",
+		"meter":            cfg.MeterURL != "" || cfg.MeterMQTTURL != "",
+		"schedules":        cfg.Schedules,
+		"split_metrics":    cfg.SplitMetrics,
+		"anonymize":        cfg.Anonymize,
+		"normalize_labels": cfg.NormalizeLabels,
+		"metric_rules":     len(cfg.MetricRules) > 0,
+		"redact_labels":    len(cfg.RedactLabels) > 0,
+		"register_aliases": cfg.AliasesFile != "",
+		"quiet_hours":      !cfg.QuietHours.IsZero(),
+		"refrigerant":      cfg.Refrigerant != "",
+		"multi_account":    len(cfg.Accounts) > 0,
+		"write":            cfg.EnableWrite,
+	} {
+		if on {
+			f = append(f, name)
+		}
+	}
+	sort.Strings(f)
+	return f
+}
+
+// metaHandler serves a machine-readable description of this instance for
+// companion tools: version, metric namespace, enabled features and the
+// installations seen by the last collection.
+func metaHandler(cfg *config.Config, c collector.Group) http.HandlerFunc {
+	enabled := features(cfg)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		collected := c.Installations()
+		installations := make([]metaInstallation, 0, len(collected))
+		for _, inst := range collected {
+			interval, ok := cfg.InstallationIntervals[inst.ID]
+			if !ok {
+				interval = cfg.CollectInterval
+			}
+			installations = append(installations, metaInstallation{
+				ID:              inst.ID,
+				Name:            inst.Name,
+				Model:           inst.Model,
+				Account:         inst.Account,
+				IntervalSeconds: interval.Seconds(),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(meta{
+			Version:         exporterVersion(),
+			MetricNamespace: metricNamespace,
+			Mode:            cfg.Mode,
+			Features:        enabled,
+			Installations:   installations,
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/mapper"
+)
+
+// overrideCheckInterval is how often expired overrides are looked for.
+const overrideCheckInterval = 30 * time.Second
+
+// registerWriter writes registers of the installations it collects.
+type registerWriter interface {
+	WriteRegister(ctx context.Context, installationID int64, group, register, value string, dryRun bool) (*control.Write, error)
+}
+
+// writerLookup returns the registerWriter collecting an installation, if
+// any.
+type writerLookup func(installationID int64) (registerWriter, bool)
+
+// groupWriters looks up installations in the collectors of c.
+func groupWriters(c collector.Group) writerLookup {
+	return func(installationID int64) (registerWriter, bool) {
+		owner := c.ForInstallation(installationID)
+		return owner, owner != nil
+	}
+}
+
+// overrideRequest is the body of an operation mode override: the mode, as
+// for the operation mode endpoint, and either how long it lasts or when it
+// ends, e.g. {"value": "HOLIDAY", "duration": "48h"}.
+type overrideRequest struct {
+	Value    any       `json:"value"`
+	Duration string    `json:"duration"`
+	Until    time.Time `json:"until"`
+}
+
+// overrideResponse reports a pending override and the write that set or
+// restored the operation mode.
+type overrideResponse struct {
+	Override *control.Override `json:"override,omitempty"`
+	Write    *control.Write    `json:"write,omitempty"`
+}
+
+// overrideManager sets temporary operation mode overrides and restores the
+// previous mode once they expire. Changes to an installation's override
+// and its writes hold the installation's lock, so an expiring override is
+// never restored over one that replaced it.
+type overrideManager struct {
+	writers   writerLookup
+	overrides *control.Overrides
+	clock     clock.Clock
+	logger    *slog.Logger
+
+	mu    sync.Mutex
+	locks map[int64]*sync.Mutex
+}
+
+// newOverrideManager creates a manager of the overrides of the
+// installations writers finds. A nil clock uses the wall clock.
+func newOverrideManager(writers writerLookup, overrides *control.Overrides, clk clock.Clock, logger *slog.Logger) *overrideManager {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &overrideManager{
+		writers:   writers,
+		overrides: overrides,
+		clock:     clk,
+		logger:    logger,
+		locks:     make(map[int64]*sync.Mutex),
+	}
+}
+
+// lock locks the overrides of an installation and returns the unlock
+// function.
+func (m *overrideManager) lock(installationID int64) func() {
+	m.mu.Lock()
+	l, ok := m.locks[installationID]
+	if !ok {
+		l = new(sync.Mutex)
+		m.locks[installationID] = l
+	}
+	m.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// handler manages the temporary operation mode override of the
+// installation in the path ({id}). POST sets the mode until an expiry and
+// records the mode to restore, GET returns the pending override and DELETE
+// restores the previous mode right away. Requests must carry the configured
+// bearer token; a POST with ?dry_run=true validates the override without
+// writing or recording it. The route must be wrapped in withWriteDeadline.
+func (m *overrideManager) handler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(w, r, token) {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid installation ID", http.StatusBadRequest)
+			return
+		}
+		owner, ok := m.writers(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("installation %d not collected", id), http.StatusNotFound)
+			return
+		}
+
+		var resp overrideResponse
+		switch r.Method {
+		case http.MethodGet:
+			ov, ok := m.overrides.Get(id)
+			if !ok {
+				http.Error(w, fmt.Sprintf("installation %d has no override", id), http.StatusNotFound)
+				return
+			}
+			resp.Override = &ov
+		case http.MethodPost:
+			if resp, err = m.set(w, r, owner, id); err != nil {
+				return
+			}
+		case http.MethodDelete:
+			if err := extendWriteDeadline(r, writeTimeout+5*time.Second); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			unlock := m.lock(id)
+			defer unlock()
+			ov, ok := m.overrides.Get(id)
+			if !ok {
+				http.Error(w, fmt.Sprintf("installation %d has no override", id), http.StatusNotFound)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+			defer cancel()
+			if resp.Write, err = m.restore(ctx, owner, ov); err != nil {
+				http.Error(w, err.Error(), writeStatus(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(resp)
+	}
+}
+
+// set handles an override POST. Both the mode and the one to restore are
+// validated first. The override is then recorded before the mode is
+// written, so a mode is never changed without the one to restore being
+// persisted, and dropped again if the write fails. Errors have already
+// been answered.
+func (m *overrideManager) set(w http.ResponseWriter, r *http.Request, owner registerWriter, id int64) (overrideResponse, error) {
+	var req overrideRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return overrideResponse{}, err
+	}
+	var value string
+	switch v := req.Value.(type) {
+	case string:
+		value = v
+	case float64:
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		err := errors.New(`body must be {"value": <number or value name>, "duration": <duration>} or with "until": <time>`)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return overrideResponse{}, err
+	}
+	until, err := overrideUntil(req, m.clock.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return overrideResponse{}, err
+	}
+
+	// Two validations and the write may outlast the server's write timeout
+	if err := extendWriteDeadline(r, 3*writeTimeout+5*time.Second); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return overrideResponse{}, err
+	}
+	unlock := m.lock(id)
+	defer unlock()
+
+	// A dry run validates the mode and reports the one it replaces
+	ctx, cancel := context.WithTimeout(r.Context(), writeTimeout)
+	defer cancel()
+	write, err := owner.WriteRegister(ctx, id, mapper.RegGroupOperationalOperation, mapper.RegOperationMode, value, true)
+	if err != nil {
+		http.Error(w, err.Error(), writeStatus(err))
+		return overrideResponse{}, err
+	}
+	if write.CurrentValue == nil {
+		err := errors.New("current operation mode unknown, it could not be restored")
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return overrideResponse{}, err
+	}
+	ov := control.Override{InstallationID: id, Value: write.Value, Restore: *write.CurrentValue, Until: until}
+	if pending, ok := m.overrides.Get(id); ok {
+		ov.Restore = pending.Restore
+	}
+
+	// A mode that cannot be written back, such as a hidden or read-only
+	// one, would leave the pump in the override forever
+	ctx, cancel = context.WithTimeout(r.Context(), writeTimeout)
+	defer cancel()
+	if _, err := owner.WriteRegister(ctx, id, mapper.RegGroupOperationalOperation, mapper.RegOperationMode, formatMode(ov.Restore), true); err != nil {
+		status := writeStatus(err)
+		if status < http.StatusInternalServerError {
+			err = fmt.Errorf("current operation mode %s could not be restored: %w", formatMode(ov.Restore), err)
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return overrideResponse{}, err
+	}
+	if control.IsDryRun(r.URL.Query()) {
+		return overrideResponse{Override: &ov, Write: write}, nil
+	}
+
+	ov, revert, err := m.overrides.Set(ov)
+	if err != nil {
+		err = fmt.Errorf("persist override: %w", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return overrideResponse{}, err
+	}
+	ctx, cancel = context.WithTimeout(r.Context(), writeTimeout)
+	defer cancel()
+	write, err = owner.WriteRegister(ctx, id, mapper.RegGroupOperationalOperation, mapper.RegOperationMode, value, false)
+	if err != nil {
+		if rerr := revert(); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("persist override: %w", rerr))
+		}
+		http.Error(w, err.Error(), writeStatus(err))
+		return overrideResponse{}, err
+	}
+	return overrideResponse{Override: &ov, Write: write}, nil
+}
+
+// overrideUntil returns when a requested override ends: after its duration
+// from now, or at its until time, which must be in the future.
+func overrideUntil(req overrideRequest, now time.Time) (time.Time, error) {
+	switch {
+	case req.Duration != "" && !req.Until.IsZero():
+		return time.Time{}, errors.New(`set either "duration" or "until", not both`)
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid duration %q", req.Duration)
+		}
+		return now.Add(d), nil
+	case !req.Until.IsZero():
+		if !req.Until.After(now) {
+			return time.Time{}, fmt.Errorf("until %s is in the past", req.Until.Format(time.RFC3339))
+		}
+		return req.Until, nil
+	default:
+		return time.Time{}, errors.New(`an override needs a "duration" or an "until" time`)
+	}
+}
+
+// formatMode formats an operation mode value for WriteRegister.
+func formatMode(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// restore writes back the operation mode ov replaced and drops ov. Caller
+// must hold the installation's lock.
+func (m *overrideManager) restore(ctx context.Context, owner registerWriter, ov control.Override) (*control.Write, error) {
+	write, err := owner.WriteRegister(ctx, ov.InstallationID, mapper.RegGroupOperationalOperation, mapper.RegOperationMode, formatMode(ov.Restore), false)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.overrides.Remove(ov); err != nil {
+		return write, fmt.Errorf("persist override: %w", err)
+	}
+	return write, nil
+}
+
+// run restores the operation mode of expired overrides until ctx is done,
+// including those that expired while the exporter was down.
+func (m *overrideManager) run(ctx context.Context) {
+	ticker := time.NewTicker(overrideCheckInterval)
+	defer ticker.Stop()
+	for {
+		m.restoreDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// restoreDue restores the operation mode of the overrides expired now. A
+// restore that fails, or whose installation has not been collected yet, is
+// retried on the next check.
+func (m *overrideManager) restoreDue(ctx context.Context) {
+	for _, ov := range m.overrides.Due(m.clock.Now()) {
+		owner, ok := m.writers(ov.InstallationID)
+		if !ok {
+			m.logger.Debug("Override expired for an installation not collected yet", "installation", ov.InstallationID)
+			continue
+		}
+		m.restoreExpired(ctx, owner, ov)
+	}
+}
+
+// restoreExpired restores ov unless it was replaced or cancelled since it
+// was found expired.
+func (m *overrideManager) restoreExpired(ctx context.Context, owner registerWriter, ov control.Override) {
+	unlock := m.lock(ov.InstallationID)
+	defer unlock()
+	if current, ok := m.overrides.Get(ov.InstallationID); !ok || current != ov {
+		return
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	if _, err := m.restore(writeCtx, owner, ov); err != nil {
+		m.logger.Warn("Failed to restore operation mode after override", "installation", ov.InstallationID, "error", err)
+		return
+	}
+	m.logger.Info("Override expired, operation mode restored", "installation", ov.InstallationID, "value", ov.Restore)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/control"
+)
+
+func TestOverrideUntil(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	if got, err := overrideUntil(overrideRequest{Duration: "48h"}, now); err != nil || !got.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("duration 48h = %v, %v, want %v", got, err, now.Add(48*time.Hour))
+	}
+	until := now.Add(time.Hour)
+	if got, err := overrideUntil(overrideRequest{Until: until}, now); err != nil || !got.Equal(until) {
+		t.Errorf("until = %v, %v, want %v", got, err, until)
+	}
+
+	for name, req := range map[string]overrideRequest{
+		"neither":           {},
+		"both":              {Duration: "1h", Until: until},
+		"negative duration": {Duration: "-1h"},
+		"invalid duration":  {Duration: "tomorrow"},
+		"past until":        {Until: now.Add(-time.Minute)},
+	} {
+		if _, err := overrideUntil(req, now); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// fakeModeWriter is the operation mode of one installation. Modes not in
+// writable are rejected like hidden or read-only modes.
+type fakeModeWriter struct {
+	mu       sync.Mutex
+	mode     float64
+	writable map[string]float64
+	fail     bool
+	writes   []float64
+}
+
+func (f *fakeModeWriter) WriteRegister(ctx context.Context, installationID int64, group, register, value string, dryRun bool) (*control.Write, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.writable[value]
+	if !ok {
+		for _, w := range f.writable {
+			if formatMode(w) == value {
+				v, ok = w, true
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", control.ErrNotAllowed, value)
+	}
+	current := f.mode
+	write := &control.Write{InstallationID: installationID, CurrentValue: &current, Value: v, DryRun: dryRun}
+	if dryRun {
+		return write, nil
+	}
+	if f.fail {
+		return nil, errors.New("upstream failed")
+	}
+	f.mode = v
+	f.writes = append(f.writes, v)
+	return write, nil
+}
+
+func (f *fakeModeWriter) current() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mode
+}
+
+// newOverrideServer serves the override endpoint of installation 1, whose
+// operation mode is pump.
+func newOverrideServer(t *testing.T, pump *fakeModeWriter, clk clock.Clock) (*overrideManager, *httptest.Server) {
+	t.Helper()
+	pending, err := control.LoadOverrides("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writers := func(id int64) (registerWriter, bool) { return pump, id == 1 }
+	m := newOverrideManager(writers, pending, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/heatpump/{id}/operation_mode/override", withWriteDeadline(m.handler("s3cret")))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+func overrideRequestTo(t *testing.T, srv *httptest.Server, method, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+"/api/v1/heatpump/1/operation_mode/override", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestOverride_RestoresPreviousModeOnExpiry(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	pump := &fakeModeWriter{mode: 1, writable: map[string]float64{"AUTO": 1, "HOLIDAY": 5}}
+	m, srv := newOverrideServer(t, pump, clk)
+
+	if status := overrideRequestTo(t, srv, http.MethodPost, `{"value": "HOLIDAY", "duration": "2h"}`); status != http.StatusOK {
+		t.Fatalf("POST status = %d, want 200", status)
+	}
+	if pump.current() != 5 {
+		t.Fatalf("mode = %v, want the override 5", pump.current())
+	}
+
+	clk.Advance(time.Hour)
+	m.restoreDue(context.Background())
+	if pump.current() != 5 {
+		t.Errorf("mode before expiry = %v, want the override 5", pump.current())
+	}
+
+	clk.Advance(time.Hour)
+	m.restoreDue(context.Background())
+	if pump.current() != 1 {
+		t.Errorf("mode after expiry = %v, want the previous 1", pump.current())
+	}
+	if _, ok := m.overrides.Get(1); ok {
+		t.Error("restored override still pending")
+	}
+}
+
+func TestOverride_Delete(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	pump := &fakeModeWriter{mode: 1, writable: map[string]float64{"AUTO": 1, "HOLIDAY": 5}}
+	m, srv := newOverrideServer(t, pump, clk)
+
+	if status := overrideRequestTo(t, srv, http.MethodDelete, ""); status != http.StatusNotFound {
+		t.Errorf("DELETE without override status = %d, want 404", status)
+	}
+	overrideRequestTo(t, srv, http.MethodPost, `{"value": "HOLIDAY", "duration": "2h"}`)
+	if status := overrideRequestTo(t, srv, http.MethodDelete, ""); status != http.StatusOK {
+		t.Fatalf("DELETE status = %d, want 200", status)
+	}
+	if pump.current() != 1 {
+		t.Errorf("mode = %v, want the previous 1", pump.current())
+	}
+	if _, ok := m.overrides.Get(1); ok {
+		t.Error("cancelled override still pending")
+	}
+}
+
+func TestOverride_FailedWriteRevertsPending(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	pump := &fakeModeWriter{mode: 1, writable: map[string]float64{"AUTO": 1, "HOLIDAY": 5}, fail: true}
+	m, srv := newOverrideServer(t, pump, clk)
+
+	if status := overrideRequestTo(t, srv, http.MethodPost, `{"value": "HOLIDAY", "duration": "2h"}`); status != http.StatusBadGateway {
+		t.Errorf("POST status = %d, want 502", status)
+	}
+	if _, ok := m.overrides.Get(1); ok {
+		t.Error("override of a failed write still pending")
+	}
+}
+
+func TestOverride_RefusesUnrestorableMode(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	// The pump runs in a mode that cannot be written back
+	pump := &fakeModeWriter{mode: 9, writable: map[string]float64{"AUTO": 1, "HOLIDAY": 5}}
+	m, srv := newOverrideServer(t, pump, clk)
+
+	if status := overrideRequestTo(t, srv, http.MethodPost, `{"value": "HOLIDAY", "duration": "2h"}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("POST status = %d, want 422", status)
+	}
+	if pump.current() != 9 || len(pump.writes) != 0 {
+		t.Errorf("mode = %v after writes %v, want the untouched 9", pump.current(), pump.writes)
+	}
+	if _, ok := m.overrides.Get(1); ok {
+		t.Error("refused override is pending")
+	}
+}
+
+func TestOverride_ReplacedOverrideIsNotRestored(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	pump := &fakeModeWriter{mode: 1, writable: map[string]float64{"AUTO": 1, "HOLIDAY": 5, "ECO": 3}}
+	m, srv := newOverrideServer(t, pump, clk)
+
+	overrideRequestTo(t, srv, http.MethodPost, `{"value": "HOLIDAY", "duration": "1h"}`)
+	clk.Advance(time.Hour)
+	expired := m.overrides.Due(clk.Now())
+	// Replaced after the expiry check found it, before its restore
+	overrideRequestTo(t, srv, http.MethodPost, `{"value": "ECO", "duration": "1h"}`)
+	m.restoreExpired(context.Background(), pump, expired[0])
+
+	if pump.current() != 3 {
+		t.Errorf("mode = %v, want the replacing override 3", pump.current())
+	}
+	if ov, ok := m.overrides.Get(1); !ok || ov.Value != 3 || ov.Restore != 1 {
+		t.Errorf("pending = %+v, want the replacing override restoring 1", ov)
+	}
+}
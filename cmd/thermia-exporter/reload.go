@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/config"
+)
+
+// lifecycleMetrics expose exporter restarts and configuration reloads.
+type lifecycleMetrics struct {
+	startTime       prometheus.Gauge
+	reloadSuccess   prometheus.Gauge
+	reloadSuccessAt prometheus.Gauge
+	clock           clock.Clock
+}
+
+// newLifecycleMetrics creates the lifecycle metrics for an exporter started
+// now, as read from clk; nil uses the wall clock.
+func newLifecycleMetrics(clk clock.Clock) *lifecycleMetrics {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	m := &lifecycleMetrics{
+		startTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_exporter_start_time_seconds",
+			Help: "Unix timestamp the exporter was started at",
+		}),
+		reloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt succeeded",
+		}),
+		reloadSuccessAt: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_config_last_reload_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful configuration load",
+		}),
+		clock: clk,
+	}
+	m.startTime.Set(float64(clk.Now().Unix()))
+	return m
+}
+
+// collectors returns the metrics for registration.
+func (m *lifecycleMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.startTime, m.reloadSuccess, m.reloadSuccessAt}
+}
+
+// reloaded records a configuration (re)load attempt made now.
+func (m *lifecycleMetrics) reloaded(ok bool) {
+	if !ok {
+		m.reloadSuccess.Set(0)
+		return
+	}
+	m.reloadSuccess.Set(1)
+	m.reloadSuccessAt.Set(float64(m.clock.Now().Unix()))
+}
+
+// watchReload reloads the configuration on SIGHUP until ctx is done. Only
+// the credentials (e.g. a rotated Kubernetes secret) and register aliases are
+// applied at runtime; other settings take effect after a restart.
+func watchReload(ctx context.Context, logger *slog.Logger, collectors collector.Group, m *lifecycleMetrics) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			cfg, err := loadConfig()
+			var accounts map[string]config.Account
+			if err == nil {
+				accounts, err = reloadAccounts(cfg, collectors)
+			}
+			m.reloaded(err == nil)
+			if err != nil {
+				logger.Error("Config reload failed, keeping the current configuration", "error", err)
+				continue
+			}
+			for _, c := range collectors {
+				c.SetCredentials(credentials(accounts[c.Account()]))
+				c.SetAliases(cfg.RegisterAliases)
+			}
+			logger.Info("Config reloaded; credentials and register aliases applied, other settings take effect after a restart",
+				"aliases", len(cfg.RegisterAliases))
+		}
+	}
+}
+
+// reloadAccounts returns the reloaded accounts by name. Adding, removing or
+// renaming an account takes a restart.
+func reloadAccounts(cfg *config.Config, collectors collector.Group) (map[string]config.Account, error) {
+	accounts := make(map[string]config.Account)
+	for _, a := range cfg.AccountList() {
+		accounts[a.Name] = a
+	}
+	if len(accounts) != len(collectors) {
+		return nil, errors.New("the set of accounts changed (restart to apply)")
+	}
+	for _, c := range collectors {
+		if _, ok := accounts[c.Account()]; !ok {
+			return nil, fmt.Errorf("account %q removed (restart to apply)", c.Account())
+		}
+	}
+	return accounts, nil
+}
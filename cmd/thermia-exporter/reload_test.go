@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestLifecycleMetrics_Reloaded(t *testing.T) {
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	m := newLifecycleMetrics(clk)
+	m.reloaded(true)
+
+	clk.Advance(time.Hour)
+	m.reloaded(false)
+	if got := testutil.ToFloat64(m.startTime); got != float64(start.Unix()) {
+		t.Errorf("start time = %v, want %v", got, start.Unix())
+	}
+	if got := testutil.ToFloat64(m.reloadSuccess); got != 0 {
+		t.Errorf("last reload successful = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(m.reloadSuccessAt); got != float64(start.Unix()) {
+		t.Errorf("last reload success at = %v, want %v", got, start.Unix())
+	}
+
+	m.reloaded(true)
+	if got := testutil.ToFloat64(m.reloadSuccessAt); got != float64(start.Add(time.Hour).Unix()) {
+		t.Errorf("last reload success at = %v, want %v", got, start.Add(time.Hour).Unix())
+	}
+}
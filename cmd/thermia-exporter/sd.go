@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/mapper"
+)
+
+// targetGroup is one entry of a Prometheus http_sd response.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// sdHandler serves Prometheus HTTP service discovery (http_sd) JSON with
+// one target per collected installation, described by __meta_thermia_*
+// labels for relabel rules. Each target scrapes /metrics?installation={id},
+// so every installation's metrics are scraped once, by its own target. All
+// targets share the exporter's address, so each gets its own instance
+// label ({host}/{id}) to keep their up and scrape_* series apart. Until
+// every account has been collected once it answers 503, so Prometheus keeps
+// its last target list instead of dropping every target on a restart.
+func sdHandler(c collector.Group) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.Collected() {
+			http.Error(w, "installations not collected yet", http.StatusServiceUnavailable)
+			return
+		}
+		groups := []targetGroup{}
+		for _, inst := range c.Installations() {
+			id := strconv.FormatInt(inst.ID, 10)
+			labels := map[string]string{
+				"__meta_thermia_heatpump_id":   id,
+				"__meta_thermia_heatpump_name": inst.Name,
+				"__meta_thermia_model":         inst.Model,
+				"__param_installation":         id,
+				"instance":                     r.Host + "/" + id,
+			}
+			if inst.Account != "" {
+				labels["__meta_thermia_account"] = inst.Account
+			}
+			groups = append(groups, targetGroup{Targets: []string{r.Host}, Labels: labels})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(groups)
+	}
+}
+
+// installationGatherer gathers only the series of one installation, those
+// with its heatpump_id label.
+type installationGatherer struct {
+	gatherer prometheus.Gatherer
+	id       string
+}
+
+// Gather implements prometheus.Gatherer.
+func (g installationGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	var out []*dto.MetricFamily
+	for _, mf := range families {
+		var metrics []*dto.Metric
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == mapper.LabelHeatpumpID && lp.GetValue() == g.id {
+					metrics = append(metrics, m)
+					break
+				}
+			}
+		}
+		if len(metrics) > 0 {
+			mf.Metric = metrics
+			out = append(out, mf)
+		}
+	}
+	return out, err
+}
+
+// metricsHandler serves all, or with ?installation={id} (as set by the /sd
+// targets) only that installation's series from pump.
+func metricsHandler(all, pump prometheus.Gatherer, opts promhttp.HandlerOpts) http.Handler {
+	full := promhttp.HandlerFor(all, opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("installation")
+		if id == "" {
+			full.ServeHTTP(w, r)
+			return
+		}
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			http.Error(w, "invalid installation ID", http.StatusBadRequest)
+			return
+		}
+		promhttp.HandlerFor(installationGatherer{gatherer: pump, id: id}, opts).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"thermia_exporter/internal/types"
+)
+
+func TestSDHandler(t *testing.T) {
+	g := newTestGroup(1)
+	g[0].Store().Put(2, time.Now(), types.ThermiaSummary{HeatpumpName: "Cabin", HeatpumpModel: "Atec"}, nil)
+	g[0].Store().Put(1, time.Now(), types.ThermiaSummary{HeatpumpName: "Home", HeatpumpModel: "Diplomat"}, nil)
+
+	rec := httptest.NewRecorder()
+	sdHandler(g).ServeHTTP(rec, httptest.NewRequest("GET", "http://exporter:9808/sd", nil))
+
+	var got []targetGroup
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []targetGroup{
+		{Targets: []string{"exporter:9808"}, Labels: map[string]string{
+			"__meta_thermia_heatpump_id":   "1",
+			"__meta_thermia_heatpump_name": "Home",
+			"__meta_thermia_model":         "Diplomat",
+			"__param_installation":         "1",
+			"instance":                     "exporter:9808/1",
+		}},
+		{Targets: []string{"exporter:9808"}, Labels: map[string]string{
+			"__meta_thermia_heatpump_id":   "2",
+			"__meta_thermia_heatpump_name": "Cabin",
+			"__meta_thermia_model":         "Atec",
+			"__param_installation":         "2",
+			"instance":                     "exporter:9808/2",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("/sd = %+v, want %+v", got, want)
+	}
+}
+
+func TestSDHandler_NoInstallations(t *testing.T) {
+	g := newTestGroup(1)
+	g[0].Store().Put(1, time.Now(), types.ThermiaSummary{}, nil)
+	g[0].Store().Retain(nil)
+
+	rec := httptest.NewRecorder()
+	sdHandler(g).ServeHTTP(rec, httptest.NewRequest("GET", "/sd", nil))
+	if body := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || body != "[]" {
+		t.Errorf("/sd = %d %s, want 200 []", rec.Code, body)
+	}
+}
+
+func TestSDHandler_NotCollectedYet(t *testing.T) {
+	g := newTestGroup(2)
+	g[0].Store().Put(1, time.Now(), types.ThermiaSummary{HeatpumpName: "Home"}, nil)
+
+	// One account collected is not enough: the other's targets would drop
+	rec := httptest.NewRecorder()
+	sdHandler(g).ServeHTTP(rec, httptest.NewRequest("GET", "/sd", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/sd status before the first collection = %d, want 503", rec.Code)
+	}
+}
+
+func TestMetricsHandler_Installation(t *testing.T) {
+	pump := prometheus.NewRegistry()
+	temp := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "thermia_indoor_temperature_celsius", Help: "Indoor"}, []string{"heatpump_id"})
+	temp.WithLabelValues("1").Set(21)
+	temp.WithLabelValues("2").Set(19)
+	pump.MustRegister(temp)
+	internal := prometheus.NewRegistry()
+	internal.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "thermia_scrape_errors_total", Help: "Errors"}))
+	h := metricsHandler(prometheus.Gatherers{pump, internal}, pump, promhttp.HandlerOpts{})
+
+	get := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+
+	_, all := get("/metrics")
+	if !strings.Contains(all, `heatpump_id="2"`) || !strings.Contains(all, "thermia_scrape_errors_total") {
+		t.Errorf("/metrics is missing series:\n%s", all)
+	}
+
+	_, one := get("/metrics?installation=1")
+	if !strings.Contains(one, `heatpump_id="1"`) || strings.Contains(one, `heatpump_id="2"`) || strings.Contains(one, "thermia_scrape_errors_total") {
+		t.Errorf("/metrics?installation=1 is not limited to installation 1:\n%s", one)
+	}
+
+	if code, _ := get("/metrics?installation=abc"); code != http.StatusBadRequest {
+		t.Errorf("invalid installation status = %d, want 400", code)
+	}
+}
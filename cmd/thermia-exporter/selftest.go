@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/collector"
+)
+
+// selfTestBudget bounds a whole self-test run across all accounts.
+const selfTestBudget = 30 * time.Second
+
+// selfTestResponse is the /-/selftest response.
+type selfTestResponse struct {
+	Passed   bool                       `json:"passed"`
+	Accounts []collector.SelfTestReport `json:"accounts"`
+}
+
+// selfTester runs the self-test of every account.
+type selfTester interface {
+	SelfTest(ctx context.Context) []collector.SelfTestReport
+}
+
+// selfTestHandler runs an end-to-end check against the Thermia API on POST
+// and reports each stage. It answers 200 if every stage passed and 503
+// otherwise, so deploy hooks can use the status code alone. Only one
+// self-test runs at a time. The route must be wrapped in withWriteDeadline.
+func selfTestHandler(c selfTester) http.HandlerFunc {
+	var running sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !running.TryLock() {
+			http.Error(w, "a self-test is already running", http.StatusConflict)
+			return
+		}
+		defer running.Unlock()
+
+		// The budget exceeds the server's write timeout
+		if err := extendWriteDeadline(r, selfTestBudget+5*time.Second); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), selfTestBudget)
+		defer cancel()
+
+		resp := selfTestResponse{Passed: true, Accounts: c.SelfTest(ctx)}
+		for _, report := range resp.Accounts {
+			resp.Passed = resp.Passed && report.Passed
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !resp.Passed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(resp)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/collector"
+)
+
+// slowSelfTester passes after a delay.
+type slowSelfTester time.Duration
+
+func (d slowSelfTester) SelfTest(ctx context.Context) []collector.SelfTestReport {
+	time.Sleep(time.Duration(d))
+	return []collector.SelfTestReport{{Passed: true}}
+}
+
+func TestSelfTestHandler_OutlastsWriteTimeout(t *testing.T) {
+	httpMetrics := newHTTPMetrics(prometheus.NewRegistry())
+	srv := httptest.NewUnstartedServer(withWriteDeadline(httpMetrics.instrument("selftest",
+		selfTestHandler(slowSelfTester(300*time.Millisecond)))))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "", nil)
+	if err != nil {
+		t.Fatalf("self-test response lost: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var body selfTestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.Passed {
+		t.Error("passed = false, want true")
+	}
+}
+
+func TestSelfTestHandler_RequiresWriteDeadline(t *testing.T) {
+	rec := httptest.NewRecorder()
+	selfTestHandler(slowSelfTester(0)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/selftest", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
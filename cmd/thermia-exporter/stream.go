@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/snapshot"
+)
+
+// maxStreams bounds the concurrent /stream clients.
+const maxStreams = 32
+
+// streamKeepalive is how often an idle stream sends a comment, so proxies
+// don't close it between collections.
+var streamKeepalive = 30 * time.Second
+
+// streamHandler serves /stream, a server-sent events stream of installation
+// summaries for live displays that don't poll Prometheus. A new client
+// first receives the summary of every installation, then each summary again
+// once a collection updates it, as "summary" events with the same JSON as
+// /api/v1/summary/{installation_id}. An installation that is no longer
+// collected is announced as a "removed" event. Like /api/v1/summary the
+// stream is fed from the snapshot store and never triggers an API call.
+// ?installation={id} limits the stream to one installation. Streams end
+// when done is closed, so they don't hold up a graceful shutdown.
+func streamHandler(c collector.Group, done <-chan struct{}) http.HandlerFunc {
+	var clients atomic.Int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var only int64
+		if raw := r.URL.Query().Get("installation"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid installation ID", http.StatusBadRequest)
+				return
+			}
+			only = id
+		}
+		if clients.Add(1) > maxStreams {
+			clients.Add(-1)
+			http.Error(w, "too many streams", http.StatusServiceUnavailable)
+			return
+		}
+		defer clients.Add(-1)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		rc := http.NewResponseController(w)
+
+		keepalive := time.NewTicker(streamKeepalive)
+		defer keepalive.Stop()
+		sent := make(map[int64]uint64)
+		for {
+			// Take the channel before reading, so no change is missed
+			stop := make(chan struct{})
+			changed := c.Changed(stop)
+			ok := sendUpdates(w, c.Snapshots(), only, sent) == nil &&
+				rc.Flush() == nil &&
+				waitChanged(w, rc, changed, keepalive.C, r.Context().Done(), done)
+			close(stop)
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// waitChanged blocks until changed is closed, writing a keepalive comment
+// on every tick meanwhile. It returns false when the stream must end.
+func waitChanged(w http.ResponseWriter, rc *http.ResponseController, changed <-chan struct{}, tick <-chan time.Time, gone, done <-chan struct{}) bool {
+	for {
+		select {
+		case <-changed:
+			return true
+		case <-tick:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return false
+			}
+			rc.Flush()
+		case <-gone:
+			return false
+		case <-done:
+			return false
+		}
+	}
+}
+
+// sendUpdates writes an event for every snapshot newer than the version in
+// sent and for every installation in sent that has no snapshot anymore, and
+// records what was sent. only limits the events to one installation (0:
+// all).
+func sendUpdates(w http.ResponseWriter, snaps []snapshot.Snapshot, only int64, sent map[int64]uint64) error {
+	current := make(map[int64]bool, len(snaps))
+	for _, snap := range snaps {
+		if only != 0 && snap.InstallationID != only {
+			continue
+		}
+		current[snap.InstallationID] = true
+		if version, ok := sent[snap.InstallationID]; ok && version == snap.Version {
+			continue
+		}
+		if err := writeEvent(w, "summary", newInstallationSummary(snap)); err != nil {
+			return err
+		}
+		sent[snap.InstallationID] = snap.Version
+	}
+	for id := range sent {
+		if current[id] {
+			continue
+		}
+		if err := writeEvent(w, "removed", map[string]int64{"installation_id": id}); err != nil {
+			return err
+		}
+		delete(sent, id)
+	}
+	return nil
+}
+
+// writeEvent writes one server-sent event with v as single-line JSON data.
+func writeEvent(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/snapshot"
+)
+
+// newTestGroup returns a group of collectors for accounts with empty
+// stores, which never poll.
+func newTestGroup(accounts int) collector.Group {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	g := make(collector.Group, accounts)
+	for i := range g {
+		g[i] = collector.NewThermiaCollector(auth.NewAuthClient(logger), auth.Credentials{}, time.Minute, logger, collector.Options{Store: snapshot.NewStore()})
+	}
+	return g
+}
+
+func TestStreamHandler_KeepaliveDoesNotLeak(t *testing.T) {
+	defer func(d time.Duration) { streamKeepalive = d }(streamKeepalive)
+	streamKeepalive = time.Millisecond
+
+	done := make(chan struct{})
+	defer close(done)
+	srv := httptest.NewServer(streamHandler(newTestGroup(2), done))
+	defer srv.Close()
+	before := runtime.NumGoroutine()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	for keepalives := 0; keepalives < 100; {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, ": keepalive") {
+			keepalives++
+		}
+	}
+
+	// The client and server connection goroutines, the handler and the
+	// two waiting on the stores
+	if n := runtime.NumGoroutine(); n > before+8 {
+		t.Errorf("%d goroutines after 100 keepalives, started with %d", n, before)
+	}
+
+	resp.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after the client went away, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"thermia_exporter/internal/collector"
+	"thermia_exporter/internal/snapshot"
+	"thermia_exporter/internal/types"
+)
+
+// installationSummary is an installation's summary with the time it was
+// collected.
+type installationSummary struct {
+	CollectedAt time.Time `json:"collected_at"`
+	types.ThermiaSummary
+}
+
+func newInstallationSummary(snap snapshot.Snapshot) installationSummary {
+	return installationSummary{CollectedAt: snap.CollectedAt, ThermiaSummary: snap.Summary}
+}
+
+// summaryHandler serves the summaries of the last collection as JSON for
+// consumers that don't speak Prometheus: all installations, or one with
+// /api/v1/summary/{installation_id}. It never triggers an API fetch.
+func summaryHandler(c collector.Group) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body any
+		if raw := r.PathValue("installation_id"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid installation ID", http.StatusBadRequest)
+				return
+			}
+			snap, ok := c.Snapshot(id)
+			if !ok {
+				http.Error(w, fmt.Sprintf("installation %d not collected", id), http.StatusNotFound)
+				return
+			}
+			body = newInstallationSummary(snap)
+		} else {
+			snaps := c.Snapshots()
+			summaries := make([]installationSummary, 0, len(snaps))
+			for _, snap := range snaps {
+				summaries = append(summaries, newInstallationSummary(snap))
+			}
+			body = summaries
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(body)
+	}
+}
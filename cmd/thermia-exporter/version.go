@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runVersion implements the "version" subcommand: it prints the exporter
+// version, the Go version and, for builds from a git checkout, the commit.
+func runVersion(args []string) int {
+	fmt.Printf("thermia-exporter %s\n", exporterVersion())
+	fmt.Printf("  go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	revision, built := vcsInfo()
+	if revision != "" {
+		fmt.Printf("  revision: %s\n", revision)
+	}
+	if built != "" {
+		fmt.Printf("  time:     %s\n", built)
+	}
+	return 0
+}
+
+// vcsInfo returns the git commit the binary was built from, suffixed with
+// " (modified)" for a dirty checkout, and the commit time. Both are empty
+// for builds outside a git checkout.
+func vcsInfo() (revision, built string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.time":
+			built = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += " (modified)"
+	}
+	return revision, built
+}
+
+// newBuildInfo creates thermia_exporter_build_info, which carries the
+// build and the instance ID as labels so series from one exporter instance
+// can be joined across restarts and told apart between replicas.
+func newBuildInfo(instanceID string) prometheus.Gauge {
+	revision, _ := vcsInfo()
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "thermia_exporter_build_info",
+		Help: "Build and instance of the exporter (always 1)",
+		ConstLabels: prometheus.Labels{
+			"version":     exporterVersion(),
+			"revision":    revision,
+			"goversion":   runtime.Version(),
+			"instance_id": instanceID,
+		},
+	})
+	g.Set(1)
+	return g
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"thermia_exporter/internal/types"
+)
+
+// GetCalendarFunctions lists the functions that can be scheduled for an
+// installation. Installations without calendar support return an error.
+func (c *APIClient) GetCalendarFunctions(ctx context.Context, installationID int64) ([]types.CalendarFunction, error) {
+	path := fmt.Sprintf("/api/v1/installationcalendar/%d/functions", installationID)
+
+	data, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []types.CalendarFunction
+	if err := json.Unmarshal(data, &functions); err != nil {
+		return nil, fmt.Errorf("unmarshal calendar functions: %w", err)
+	}
+
+	return functions, nil
+}
+
+// GetCalendarSchedules retrieves the schedules of one calendar function.
+func (c *APIClient) GetCalendarSchedules(ctx context.Context, installationID, functionID int64) ([]types.CalendarSchedule, error) {
+	path := fmt.Sprintf("/api/v1/installationcalendar/%d/%d/schedules", installationID, functionID)
+
+	data, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []types.CalendarSchedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("unmarshal calendar schedules: %w", err)
+	}
+
+	return schedules, nil
+}
@@ -4,38 +4,89 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/tlswatch"
 	"thermia_exporter/internal/types"
 )
 
-const thermiaConfigURL = "https://online.thermia.se/api/configuration"
+// defaultConfigURL is the Thermia Online configuration endpoint.
+const defaultConfigURL = "https://online.thermia.se/api/configuration"
+
+// maxRedirects caps redirect chains. A logged-out or region-redirected
+// session can bounce between portal pages indefinitely.
+const maxRedirects = 5
+
+// ErrTokenNotAccepted is returned when the portal answers the configuration
+// request with something other than JSON (typically a login page), which
+// means the access token was not accepted.
+var ErrTokenNotAccepted = errors.New("token not accepted by portal")
+
+// ErrMalformedResponse is returned when a response body cannot be decoded
+// into the expected shape.
+var ErrMalformedResponse = errors.New("malformed response")
 
 // APIClient handles HTTP requests to the Thermia API.
 type APIClient struct {
+	configURL  string
 	baseURL    string
 	token      string
 	httpClient *http.Client
 	logger     *slog.Logger
+	clock      clock.Clock
+
+	// No requests are sent before throttledUntil
+	throttledUntil time.Time
+	throttleMu     sync.Mutex
+
+	// Second requests for slow GETs (disabled unless configured)
+	hedge hedging
+
+	// Rate limit and circuit breaker shared with the account's other
+	// clients (nil: none)
+	guard *Guard
 }
 
 // NewAPIClient creates a new Thermia API client.
-// It automatically discovers the API base URL from the configuration endpoint.
-func NewAPIClient(ctx context.Context, token string, logger *slog.Logger) (*APIClient, error) {
+// It automatically discovers the API base URL from the configuration endpoint
+// at configURL, or Thermia Online's when empty. clk is the time source for
+// throttling and request durations (nil: real time). Every request, the
+// discovery included, waits for and is reported to guard (nil: none).
+func NewAPIClient(ctx context.Context, configURL, token string, logger *slog.Logger, clk clock.Clock, guard *Guard) (*APIClient, error) {
+	if configURL == "" {
+		configURL = defaultConfigURL
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
 	client := &APIClient{
-		token:  token,
-		logger: logger,
+		configURL: configURL,
+		token:     token,
+		logger:    logger,
+		clock:     clk,
+		guard:     guard,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
+			Transport: tlswatch.NewTransport(&http.Transport{
 				MaxIdleConns:        10,
 				MaxIdleConnsPerHost: 5,
 				IdleConnTimeout:     90 * time.Second,
+			}, logger),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("%w: stopped after %d redirects (last: %s)",
+						ErrTokenNotAccepted, len(via), req.URL.Redacted())
+				}
+				return nil
 			},
 		},
 	}
@@ -53,65 +104,177 @@ func NewAPIClient(ctx context.Context, token string, logger *slog.Logger) (*APIC
 }
 
 // doRequest performs an HTTP request with authentication and error handling.
+//
+// Throttled requests (429 or 503) are retried once if the API asks for a
+// short enough wait that fits the context deadline. Otherwise they fail
+// with a ThrottledError, and further requests fail without being sent
+// until the wait is over, so a throttled collection does not keep hammering
+// the API.
 func (c *APIClient) doRequest(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	data, _, err := c.doPageRequest(ctx, method, path, body)
+	return data, err
+}
+
+// doPageRequest is doRequest that also returns the link to the next page of
+// the response, or "" if there is none (see getPages).
+func (c *APIClient) doPageRequest(ctx context.Context, method, path string, body io.Reader) ([]byte, string, error) {
+	if wait := c.ThrottledUntil().Sub(c.clock.Now()); wait > 0 {
+		return nil, "", &ThrottledError{Status: http.StatusTooManyRequests, RetryAfter: wait}
+	}
+
+	var r response
+	var err error
+	if body == nil {
+		r, err = c.sendHedged(ctx, method, path)
+	} else {
+		r, err = c.send(ctx, method, path, body)
+	}
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter <= 0 ||
+		throttled.RetryAfter > maxRetryWait || !fitsDeadline(ctx, c.clock.Now(), throttled.RetryAfter) || body != nil {
+		return r.data, r.next, err
+	}
+
+	c.logger.Warn("Throttled by API, retrying", "method", method, "path", path, "retry_after", throttled.RetryAfter)
+	if !sleepCtx(ctx, throttled.RetryAfter) {
+		return nil, "", err
+	}
+	c.clearThrottle()
+	r, err = c.send(ctx, method, path, nil)
+	return r.data, r.next, err
+}
+
+// response is the body of a successful request and the link to its next
+// page ("" if none).
+type response struct {
+	data []byte
+	next string
+}
+
+// send performs a single HTTP request.
+func (c *APIClient) send(ctx context.Context, method, path string, body io.Reader) (response, error) {
 	url := c.baseURL + path
 
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return response{}, fmt.Errorf("create request: %w", err)
+	}
+	if err := c.guard.acquire(ctx); err != nil {
+		return response{}, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/json")
+	acceptGzip(req)
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
 	c.logger.Debug("API request", "method", method, "path", path)
 
+	start := c.clock.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		c.logger.Error("Request failed", "method", method, "path", path, "error", err)
-		return nil, fmt.Errorf("do request: %w", err)
+		c.guard.record(ctx, 0)
+		if errors.Is(context.Cause(ctx), errHedgeLost) {
+			observeRequest(method, path, statusCanceled, c.clock.Now().Sub(start))
+		} else {
+			observeRequest(method, path, statusError, c.clock.Now().Sub(start))
+			c.logger.Error("Request failed", "method", method, "path", path, "error", err)
+		}
+		return response{}, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
+	c.guard.record(ctx, resp.StatusCode)
 
-	data, err := io.ReadAll(resp.Body)
+	data, wire, err := readBody(resp)
 	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+		observeRequest(method, path, statusError, c.clock.Now().Sub(start))
+		return response{}, fmt.Errorf("read body: %w", err)
+	}
+	observeRequest(method, path, strconv.Itoa(resp.StatusCode), c.clock.Now().Sub(start))
+	observeResponse(path, len(data), wire)
+
+	if isThrottled(resp.StatusCode) {
+		now := c.clock.Now()
+		wait, _ := retryAfter(resp.Header, now)
+		throttledTotal.WithLabelValues(endpointLabel(path)).Inc()
+		c.throttle(now.Add(wait))
+		c.logger.Warn("Throttled by API", "method", method, "path", path, "status", resp.StatusCode, "retry_after", wait)
+		return response{}, &ThrottledError{Status: resp.StatusCode, RetryAfter: wait}
+	}
+	if rateLimitExhausted(resp.Header) {
+		now := c.clock.Now()
+		if wait, ok := retryAfter(resp.Header, now); ok {
+			c.throttle(now.Add(wait))
+		}
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Warn("Non-200 status", "method", method, "path", path, "status", resp.StatusCode)
-		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(data))
+		return response{}, &StatusError{Status: resp.StatusCode, Body: string(data)}
 	}
 
 	c.logger.Debug("API response", "method", method, "path", path, "bytes", len(data))
 
-	return data, nil
+	return response{data: data, next: nextLink(resp.Header)}, nil
 }
 
 // getConfiguration retrieves the API configuration (base URL discovery).
 func (c *APIClient) getConfiguration(ctx context.Context) (*types.Config, error) {
-	req, _ := http.NewRequestWithContext(ctx, "GET", thermiaConfigURL, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.configURL, nil)
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/json")
+	acceptGzip(req)
+	if err := c.guard.acquire(ctx); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.guard.record(ctx, 0)
 		return nil, err
 	}
 	defer resp.Body.Close()
+	c.guard.record(ctx, resp.StatusCode)
 
-	data, _ := io.ReadAll(resp.Body)
+	data, wire, err := readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	observeResponse(req.URL.Path, len(data), wire)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: status %d", ErrTokenNotAccepted, resp.StatusCode)
+	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(data))
+		return nil, &StatusError{Status: resp.StatusCode, Body: string(data)}
+	}
+
+	// A login or landing page comes back as 200 text/html; report that
+	// instead of a confusing unmarshal error.
+	if !isJSON(resp) {
+		return nil, fmt.Errorf("%w: got %q from %s", ErrTokenNotAccepted,
+			resp.Header.Get("Content-Type"), resp.Request.URL.Redacted())
 	}
 
 	var cfg types.Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
+	if cfg.APIBaseURL == "" {
+		return nil, errors.New("configuration has no apiBaseUrl")
+	}
 
 	return &cfg, nil
 }
+
+// isJSON reports whether the response declares a JSON content type.
+// A missing Content-Type is tolerated since the body is still validated by
+// the JSON decoder.
+func isJSON(resp *http.Response) bool {
+	ct := resp.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(ct), "json")
+}
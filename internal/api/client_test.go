@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewAPIClient_Configuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		notAccepted bool
+	}{
+		{"unauthorized", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}, true},
+		{"forbidden", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}, true},
+		{"login page", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, "<html><title>Sign in</title></html>")
+		}, true},
+		{"redirect loop", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		}, true},
+		{"no api base URL", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"apiBaseUrl": ""}`)
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			_, err := NewAPIClient(context.Background(), srv.URL+"/api/configuration", "token", slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil)
+			if err == nil {
+				t.Fatal("NewAPIClient() expected error")
+			}
+			if got := errors.Is(err, ErrTokenNotAccepted); got != tt.notAccepted {
+				t.Errorf("errors.Is(%v, ErrTokenNotAccepted) = %v, want %v", err, got, tt.notAccepted)
+			}
+		})
+	}
+}
+
+func TestNewAPIClient_DiscoversBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"apiBaseUrl": "https://api.example.com/"}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewAPIClient(context.Background(), srv.URL, "token", slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.baseURL != "https://api.example.com" {
+		t.Errorf("baseURL = %q, want https://api.example.com", c.baseURL)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrMaintenance is wrapped by errors for responses that mean the Thermia
+// API is down or in maintenance (502, 503 and 504), which usually lasts
+// longer than a throttled request.
+var ErrMaintenance = errors.New("API unavailable or in maintenance")
+
+// maxErrorBody caps how much of a response body an error message quotes.
+const maxErrorBody = 200
+
+// StatusError is a response with an unexpected HTTP status.
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.Status, summarizeBody(e.Body))
+}
+
+// Unwrap returns ErrMaintenance for gateway and availability errors.
+func (e *StatusError) Unwrap() error {
+	if isMaintenance(e.Status) {
+		return ErrMaintenance
+	}
+	return nil
+}
+
+// isMaintenance reports whether status means the API is unavailable.
+func isMaintenance(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+var titleRE = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// summarizeBody shortens a response body for an error message. HTML pages,
+// which the portal and its load balancer return for most failures, are
+// reduced to their title.
+func summarizeBody(body string) string {
+	body = strings.TrimSpace(body)
+	if strings.HasPrefix(body, "<") {
+		if m := titleRE.FindStringSubmatch(body); m != nil {
+			return fmt.Sprintf("HTML page %q", strings.Join(strings.Fields(m[1]), " "))
+		}
+		return "HTML page"
+	}
+	if len(body) > maxErrorBody {
+		return body[:maxErrorBody] + "..."
+	}
+	return body
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err         *StatusError
+		want        string
+		maintenance bool
+	}{
+		{&StatusError{Status: 400, Body: "<!DOCTYPE html>\n<html><head><title>\n  Bad Request\n</title></head><body>...</body></html>"}, `status 400: HTML page "Bad Request"`, false},
+		{&StatusError{Status: 502, Body: "<html><body>upstream</body></html>"}, "status 502: HTML page", true},
+		{&StatusError{Status: 504, Body: `{"message":"timeout"}`}, `status 504: {"message":"timeout"}`, true},
+		{&StatusError{Status: 404, Body: strings.Repeat("x", 300)}, "status 404: " + strings.Repeat("x", 200) + "...", false},
+	}
+	for _, tc := range tests {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("Error() = %q, want %q", got, tc.want)
+		}
+		if got := errors.Is(tc.err, ErrMaintenance); got != tc.maintenance {
+			t.Errorf("status %d: errors.Is(ErrMaintenance) = %v, want %v", tc.err.Status, got, tc.maintenance)
+		}
+	}
+}
+
+func TestThrottledError_Maintenance(t *testing.T) {
+	err := error(&ThrottledError{Status: 503})
+	if !errors.Is(err, ErrThrottled) || !errors.Is(err, ErrMaintenance) {
+		t.Errorf("503 %v: want both throttled and maintenance", err)
+	}
+	err = &ThrottledError{Status: 429}
+	if !errors.Is(err, ErrThrottled) || errors.Is(err, ErrMaintenance) {
+		t.Errorf("429 %v: want throttled only", err)
+	}
+}
@@ -11,17 +11,20 @@ import (
 // GetEvents retrieves events/alarms for an installation.
 // If onlyActive is true, returns only currently active alarms.
 // If onlyActive is false, returns all alarms (active and historical).
+// The pages of a paginated response are followed.
 func (c *APIClient) GetEvents(ctx context.Context, installationID int64, onlyActive bool) ([]types.Event, error) {
-	path := fmt.Sprintf("/api/v1/installation/%d/events?onlyActiveAlarms=%v", installationID, onlyActive)
-
-	data, err := c.doRequest(ctx, "GET", path, nil)
+	pages, err := c.getPages(ctx, EventsPath(installationID, onlyActive))
 	if err != nil {
 		return nil, err
 	}
 
 	var events []types.Event
-	if err := json.Unmarshal(data, &events); err != nil {
-		return nil, fmt.Errorf("unmarshal events: %w", err)
+	for _, data := range pages {
+		var page []types.Event
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("unmarshal events: %w", err)
+		}
+		events = append(events, page...)
 	}
 
 	return events, nil
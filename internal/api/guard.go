@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+// ErrCircuitOpen is returned without sending a request while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open, Thermia API failing")
+
+// BreakerState is the state of a Guard's circuit breaker, as exported by
+// thermia_circuit_breaker_state.
+type BreakerState int
+
+const (
+	// BreakerClosed sends requests normally.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails requests without sending them until the cooldown
+	// has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets one probe request through; its result closes or
+	// reopens the breaker.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Guard limits the requests an account sends to the Thermia API: a token
+// bucket bounds the request rate, and a circuit breaker stops requests
+// after consecutive failed responses (5xx, 429 or none at all) until a
+// cooldown has passed. API clients only live for one collection, so the
+// Guard is kept by the collector and handed to each client by NewAPIClient.
+// A nil Guard limits nothing.
+type Guard struct {
+	mu sync.Mutex
+
+	// Token bucket refilled at perMinute requests per minute, holding at
+	// most burst tokens (perMinute 0: unlimited)
+	perMinute float64
+	burst     float64
+	tokens    float64
+	filled    time.Time
+
+	// Circuit breaker opening after threshold consecutive failures
+	// (threshold 0: disabled)
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     BreakerState
+	openedAt  time.Time
+	probing   bool
+
+	clock clock.Clock
+}
+
+// NewGuard creates a Guard allowing perMinute requests per minute (0: no
+// limit) in bursts of up to ten seconds' worth, and opening its circuit
+// breaker for cooldown after threshold consecutive failures (0: never). clk
+// is the time source (nil: real time).
+func NewGuard(perMinute, threshold int, cooldown time.Duration, clk clock.Clock) *Guard {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	burst := max(float64(perMinute)/6, 1)
+	return &Guard{
+		perMinute: float64(perMinute),
+		burst:     burst,
+		tokens:    burst,
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     clk,
+	}
+}
+
+// State returns the circuit breaker state. An open breaker whose cooldown
+// has passed reports half-open: the next request is the probe.
+func (g *Guard) State() BreakerState {
+	if g == nil {
+		return BreakerClosed
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state == BreakerOpen && !g.clock.Now().Before(g.openedAt.Add(g.cooldown)) {
+		return BreakerHalfOpen
+	}
+	return g.state
+}
+
+// OpenUntil returns when the open circuit breaker lets a probe through,
+// and false if it is not open.
+func (g *Guard) OpenUntil() (time.Time, bool) {
+	if g == nil {
+		return time.Time{}, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	until := g.openedAt.Add(g.cooldown)
+	return until, g.state == BreakerOpen && g.clock.Now().Before(until)
+}
+
+// acquire waits until a request may be sent. It fails with ErrCircuitOpen
+// while the breaker is open or its probe is in flight, and with ctx's error
+// if ctx ends first.
+func (g *Guard) acquire(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	now := g.clock.Now()
+	switch g.state {
+	case BreakerOpen:
+		if now.Before(g.openedAt.Add(g.cooldown)) {
+			g.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		g.state, g.probing = BreakerHalfOpen, true
+	case BreakerHalfOpen:
+		if g.probing {
+			g.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		g.probing = true
+	}
+
+	if g.perMinute <= 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	// Refill, then reserve a token; a negative balance is a wait
+	if !g.filled.IsZero() {
+		g.tokens = min(g.tokens+now.Sub(g.filled).Minutes()*g.perMinute, g.burst)
+	}
+	g.filled = now
+	g.tokens--
+	wait := time.Duration(-g.tokens / g.perMinute * float64(time.Minute))
+	g.mu.Unlock()
+
+	if wait <= 0 || sleepCtx(ctx, wait) {
+		return nil
+	}
+	g.mu.Lock()
+	g.tokens++
+	g.probing = false
+	g.mu.Unlock()
+	return ctx.Err()
+}
+
+// record reports the outcome of a request acquire let through: its HTTP
+// status, or 0 if it got no response. Canceled requests say nothing about
+// the API and only release the probe.
+func (g *Guard) record(ctx context.Context, status int) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	probe := g.probing
+	g.probing = false
+	if status == 0 && ctx.Err() != nil {
+		return
+	}
+	if g.threshold <= 0 {
+		return
+	}
+
+	if status != 0 && status < http.StatusInternalServerError && status != http.StatusTooManyRequests {
+		g.failures = 0
+		g.state = BreakerClosed
+		return
+	}
+	g.failures++
+	if (probe && g.state == BreakerHalfOpen) || g.failures >= g.threshold {
+		g.state = BreakerOpen
+		g.openedAt = g.clock.Now()
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestGuard_CircuitBreaker(t *testing.T) {
+	status := http.StatusBadGateway
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	g := NewGuard(0, 2, 5*time.Minute, clk)
+	c := newTestClient(srv)
+	c.guard = g
+
+	for i := 0; i < 2; i++ {
+		c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations", nil)
+	}
+	if g.State() != BreakerOpen {
+		t.Fatalf("state = %v after 2 failures, want open", g.State())
+	}
+	if _, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations", nil); !errors.Is(err, ErrCircuitOpen) || hits != 2 {
+		t.Errorf("error = %v, hits = %d, want ErrCircuitOpen without a request", err, hits)
+	}
+	if until, open := g.OpenUntil(); !open || !until.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("OpenUntil() = %v, %v, want %v, true", until, open, now.Add(5*time.Minute))
+	}
+
+	// After the cooldown a failing probe reopens the breaker at once
+	clk.Advance(5 * time.Minute)
+	if g.State() != BreakerHalfOpen {
+		t.Errorf("state = %v after the cooldown, want half_open", g.State())
+	}
+	c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations", nil)
+	if g.State() != BreakerOpen || hits != 3 {
+		t.Errorf("state = %v, hits = %d after a failed probe, want open and 3", g.State(), hits)
+	}
+
+	// A successful probe closes it
+	clk.Advance(5 * time.Minute)
+	status = http.StatusOK
+	if _, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations", nil); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if g.State() != BreakerClosed {
+		t.Errorf("state = %v after a successful probe, want closed", g.State())
+	}
+}
+
+func TestGuard_RateLimit(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	g := NewGuard(60, 0, 0, clk) // 1 per second, bursts of 10
+
+	for i := 0; i < 10; i++ {
+		if err := g.acquire(context.Background()); err != nil {
+			t.Fatalf("acquire %d error = %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() error = %v past the burst, want to wait beyond the deadline", err)
+	}
+
+	clk.Advance(time.Second)
+	if err := g.acquire(context.Background()); err != nil {
+		t.Errorf("acquire() error = %v after a refill", err)
+	}
+}
+
+func TestNewAPIClient_DiscoveryIsGuarded(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	g := NewGuard(0, 2, 5*time.Minute, clk)
+	for i := 0; i < 2; i++ {
+		if _, err := NewAPIClient(context.Background(), srv.URL, "token", slog.New(slog.NewTextHandler(io.Discard, nil)), clk, g); err == nil {
+			t.Fatal("NewAPIClient() expected error for a failing configuration endpoint")
+		}
+	}
+	if g.State() != BreakerOpen {
+		t.Fatalf("state = %v after 2 failed discoveries, want open", g.State())
+	}
+	if _, err := NewAPIClient(context.Background(), srv.URL, "token", slog.New(slog.NewTextHandler(io.Discard, nil)), clk, g); !errors.Is(err, ErrCircuitOpen) || hits != 2 {
+		t.Errorf("error = %v, hits = %d, want ErrCircuitOpen without a request", err, hits)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptGzip asks the API for a gzip-compressed response. Setting the header
+// ourselves disables the transport's transparent decompression, so readBody
+// sees (and can measure) the bytes actually transferred.
+func acceptGzip(req *http.Request) {
+	req.Header.Set("Accept-Encoding", "gzip")
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+// readBody reads and, if needed, decompresses a response body. It returns
+// the decoded body and the number of bytes transferred.
+func readBody(resp *http.Response) (data []byte, wire int, err error) {
+	body := &countingReader{r: resp.Body}
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		data, err = io.ReadAll(body)
+		return data, body.n, err
+	}
+
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, body.n, fmt.Errorf("gzip: %w", err)
+	}
+	defer zr.Close()
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, body.n, fmt.Errorf("gzip: %w", err)
+	}
+	return data, body.n, nil
+}
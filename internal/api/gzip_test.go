@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoRequest_Gzip(t *testing.T) {
+	payload := `[` + strings.Repeat(`{"registerName":"REG_SUPPLY_LINE","registerValue":35.2},`, 200) + `{}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(payload))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(payload))
+		zw.Close()
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	data, err := c.doRequest(context.Background(), "GET", "/api/v1/registers/installations/7/groups/REG_GROUP_TEMPERATURES", nil)
+	if err != nil {
+		t.Fatalf("doRequest() error = %v", err)
+	}
+	if string(data) != payload {
+		t.Fatalf("body was not decompressed: %.40q", data)
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	payload := strings.Repeat(`{"registerName":"REG_SUPPLY_LINE"},`, 100)
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(bytes.NewReader(compressed.Bytes())),
+	}
+	data, wire, err := readBody(resp)
+	if err != nil || string(data) != payload {
+		t.Fatalf("readBody() = %.40q, %v", data, err)
+	}
+	if wire != compressed.Len() {
+		t.Errorf("wire = %d, want the compressed size %d", wire, compressed.Len())
+	}
+
+	resp = &http.Response{
+		Header: http.Header{"Content-Encoding": {"gzip"}},
+		Body:   io.NopCloser(strings.NewReader("not gzip")),
+	}
+	if _, _, err := readBody(resp); err == nil {
+		t.Error("readBody() accepted an invalid gzip body")
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hedgedTotal counts hedged requests by which of the two answered first.
+var hedgedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "thermia_api_hedged_requests_total",
+	Help: "Thermia API GET requests sent a second time after the hedge delay, by endpoint and which request answered first (original, hedge or none)",
+}, []string{"endpoint", "winner"})
+
+// errHedgeLost cancels the slower of two hedged requests.
+var errHedgeLost = errors.New("other hedged request answered first")
+
+// hedging sends a second copy of a GET that has not answered within delay
+// and uses whichever copy succeeds first. remaining bounds the hedges per
+// client, and so per collection, to keep the extra API load predictable.
+type hedging struct {
+	delay     time.Duration
+	remaining atomic.Int64
+}
+
+// SetHedging enables request hedging for GET requests: a request without
+// a response after delay is sent again, at most max times over the
+// client's lifetime. A delay of 0 disables hedging.
+func (c *APIClient) SetHedging(delay time.Duration, max int) {
+	c.hedge.delay = delay
+	c.hedge.remaining.Store(int64(max))
+}
+
+// take reserves one hedge from the budget.
+func (h *hedging) take() bool {
+	if h.remaining.Add(-1) >= 0 {
+		return true
+	}
+	h.remaining.Add(1)
+	return false
+}
+
+// sendHedged sends a GET request, hedged if enabled and budget is left.
+// A request that fails before the hedge delay is not hedged; after it, the
+// first success wins and the error of the original is returned only if
+// both fail.
+func (c *APIClient) sendHedged(ctx context.Context, method, path string) (response, error) {
+	if c.hedge.delay <= 0 || method != http.MethodGet {
+		return c.send(ctx, method, path, nil)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(errHedgeLost)
+
+	type result struct {
+		resp  response
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	start := func(hedge bool) {
+		go func() {
+			resp, err := c.send(ctx, method, path, nil)
+			results <- result{resp, err, hedge}
+		}()
+	}
+
+	start(false)
+	timer := time.NewTimer(c.hedge.delay)
+	defer timer.Stop()
+	select {
+	case r := <-results:
+		return r.resp, r.err
+	case <-timer.C:
+	}
+	if !c.hedge.take() {
+		r := <-results
+		return r.resp, r.err
+	}
+
+	c.logger.Debug("Hedging slow API request", "method", method, "path", path, "delay", c.hedge.delay)
+	start(true)
+	var original result
+	for range 2 {
+		r := <-results
+		if r.err == nil {
+			winner := "original"
+			if r.hedge {
+				winner = "hedge"
+			}
+			hedgedTotal.WithLabelValues(endpointLabel(path), winner).Inc()
+			return r.resp, nil
+		}
+		if !r.hedge {
+			original = r
+		}
+	}
+	hedgedTotal.WithLabelValues(endpointLabel(path), "none").Inc()
+	return original.resp, original.err
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendHedged(t *testing.T) {
+	var hits atomic.Int64
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			// The original request hangs until the test ends
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`"hedge"`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestClient(srv)
+	c.SetHedging(10*time.Millisecond, 1)
+
+	data, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations/1/events", nil)
+	if err != nil || string(data) != `"hedge"` {
+		t.Fatalf("doRequest() = %q, %v, want the hedged response", data, err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, want 2", hits.Load())
+	}
+
+	// The budget is used up: the next slow request is not hedged
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	hits.Store(0)
+	if _, err := c.doRequest(ctx, http.MethodGet, "/api/v1/installations/1/events", nil); err == nil {
+		t.Error("doRequest() = nil error, want the timeout of the unhedged request")
+	}
+	if hits.Load() != 1 {
+		t.Errorf("hits = %d, want 1 once the hedge budget is used up", hits.Load())
+	}
+}
+
+func TestSendHedged_Disabled(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv)
+	if _, err := c.doRequest(context.Background(), http.MethodGet, "/api/v1/installations/1/events", nil); err != nil {
+		t.Fatalf("doRequest() error = %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("hits = %d, want 1 without hedging", hits.Load())
+	}
+}
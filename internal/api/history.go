@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"thermia_exporter/internal/types"
+)
+
+// historyTimeLayout is the timestamp format used by the data history endpoints.
+const historyTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// GetHistoryRegisters lists the registers that have historical data for an installation.
+func (c *APIClient) GetHistoryRegisters(ctx context.Context, installationID int64) ([]types.HistoryRegister, error) {
+	path := fmt.Sprintf("/api/v1/datahistory/installation/%d", installationID)
+
+	data, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try parsing as wrapped response first
+	var wrap struct {
+		Registers []types.HistoryRegister `json:"registers"`
+	}
+	if err := json.Unmarshal(data, &wrap); err == nil && len(wrap.Registers) > 0 {
+		return wrap.Registers, nil
+	}
+
+	var registers []types.HistoryRegister
+	if err := json.Unmarshal(data, &registers); err != nil {
+		return nil, fmt.Errorf("unmarshal history registers: %w", err)
+	}
+
+	return registers, nil
+}
+
+// GetHistory retrieves minute-resolution historical values for one register
+// between start and end.
+func (c *APIClient) GetHistory(ctx context.Context, installationID, registerID int64, start, end time.Time) ([]types.HistorySample, error) {
+	q := url.Values{}
+	q.Set("periodStart", start.UTC().Format(historyTimeLayout))
+	q.Set("periodEnd", end.UTC().Format(historyTimeLayout))
+	path := fmt.Sprintf("/api/v1/datahistory/installation/%d/register/%d/minute?%s", installationID, registerID, q.Encode())
+
+	data, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try parsing as wrapped response first
+	var wrap struct {
+		Data []types.HistorySample `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrap); err == nil && wrap.Data != nil {
+		return wrap.Data, nil
+	}
+
+	var samples []types.HistorySample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("unmarshal history: %w", err)
+	}
+
+	return samples, nil
+}
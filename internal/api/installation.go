@@ -8,36 +8,44 @@ import (
 	"thermia_exporter/internal/types"
 )
 
-// GetInstallations retrieves all heat pump installations for the authenticated user.
+// GetInstallations retrieves all heat pump installations for the authenticated user,
+// following the pages of a paginated response.
 func (c *APIClient) GetInstallations(ctx context.Context) ([]types.Installation, error) {
-	data, err := c.doRequest(ctx, "GET", "/api/v1/installationsInfo", nil)
+	pages, err := c.getPages(ctx, "/api/v1/installationsInfo")
 	if err != nil {
 		return nil, err
 	}
 
+	installations := []types.Installation{}
+	for _, data := range pages {
+		installations = append(installations, parseInstallations(data)...)
+	}
+	return installations, nil
+}
+
+// parseInstallations decodes one page of the installation list.
+func parseInstallations(data []byte) []types.Installation {
 	// Try parsing as wrapped response first
 	var wrap struct {
 		Items []types.Installation `json:"items"`
 	}
 	if err := json.Unmarshal(data, &wrap); err == nil && len(wrap.Items) > 0 {
-		return wrap.Items, nil
+		return wrap.Items
 	}
 
 	// Try parsing as direct array
 	var installations []types.Installation
 	if err := json.Unmarshal(data, &installations); err == nil {
-		return installations, nil
+		return installations
 	}
 
-	// Return empty list if no installations found
-	return []types.Installation{}, nil
+	// No installations on this page
+	return nil
 }
 
 // GetInstallationInfo retrieves detailed information about a specific installation.
 func (c *APIClient) GetInstallationInfo(ctx context.Context, id int64) (*types.InstallationInfo, error) {
-	path := fmt.Sprintf("/api/v1/installations/%d", id)
-
-	data, err := c.doRequest(ctx, "GET", path, nil)
+	data, err := c.doRequest(ctx, "GET", InstallationInfoPath(id), nil)
 	if err != nil {
 		return nil, err
 	}
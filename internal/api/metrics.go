@@ -0,0 +1,85 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// responseBytes tracks the body size of every Thermia API response, which
+// matters for installations on metered (e.g. LTE) connections.
+var responseBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "thermia_api_response_bytes",
+	Help:    "Size of Thermia API response bodies by endpoint",
+	Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256 B to 4 MiB
+}, []string{"endpoint"})
+
+// responseWireBytes tracks the bytes actually transferred per response,
+// which is less than responseBytes when the API compresses the response.
+var responseWireBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "thermia_api_response_wire_bytes",
+	Help:    "Bytes transferred for Thermia API response bodies (compressed size, if compressed) by endpoint",
+	Buckets: prometheus.ExponentialBuckets(256, 4, 8), // 256 B to 4 MiB
+}, []string{"endpoint"})
+
+// throttledTotal counts throttled responses (429 or 503).
+var throttledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "thermia_api_throttled_total",
+	Help: "Thermia API responses that throttled the exporter (429 or 503) by endpoint",
+}, []string{"endpoint"})
+
+// requestDuration and requestsTotal track every HTTP request sent to the
+// API, including hedged copies and retries. status_code is the HTTP status,
+// "error" for transport errors or "canceled" for hedged copies that lost.
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "thermia_api_request_duration_seconds",
+	Help:    "Duration of Thermia API requests by endpoint, method and status code",
+	Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+}, []string{"endpoint", "method", "status_code"})
+
+var requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "thermia_api_requests_total",
+	Help: "Thermia API requests by endpoint, method and status code",
+}, []string{"endpoint", "method", "status_code"})
+
+// Status codes recorded for requests without an HTTP response
+const (
+	statusError    = "error"
+	statusCanceled = "canceled"
+)
+
+// Metrics returns the API client's self-metrics, to be registered alongside
+// the other exporter internals.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{responseBytes, responseWireBytes, throttledTotal, hedgedTotal, requestDuration, requestsTotal}
+}
+
+// observeRequest records a request to path that took d and ended with
+// status (an HTTP status code, statusError or statusCanceled).
+func observeRequest(method, path, status string, d time.Duration) {
+	endpoint := endpointLabel(path)
+	requestDuration.WithLabelValues(endpoint, method, status).Observe(d.Seconds())
+	requestsTotal.WithLabelValues(endpoint, method, status).Inc()
+}
+
+// observeResponse records the decoded and transferred size of a response
+// body for path.
+func observeResponse(path string, n, wire int) {
+	endpoint := endpointLabel(path)
+	responseBytes.WithLabelValues(endpoint).Observe(float64(n))
+	responseWireBytes.WithLabelValues(endpoint).Observe(float64(wire))
+}
+
+// endpointLabel normalizes a request path into a low-cardinality label by
+// dropping the query string and replacing numeric IDs with "{id}".
+func endpointLabel(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s != "" && strings.Trim(s, "0123456789") == "" {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
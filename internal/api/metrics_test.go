@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEndpointLabel(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/installations/1234567", "/api/v1/installations/{id}"},
+		{"/api/v1/installation/42/events?onlyActiveAlarms=true", "/api/v1/installation/{id}/events"},
+		{"/api/v1/Registers/Installations/42/Groups/REG_GROUP_TEMPERATURES", "/api/v1/Registers/Installations/{id}/Groups/REG_GROUP_TEMPERATURES"},
+		{"/api/v1/datahistory/installation/42/register/7/minute?periodStart=x", "/api/v1/datahistory/installation/{id}/register/{id}/minute"},
+		{"/api/configuration", "/api/configuration"},
+	}
+
+	for _, tt := range tests {
+		if got := endpointLabel(tt.path); got != tt.want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics-test/fail/1" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	if _, err := c.doRequest(context.Background(), "GET", "/metrics-test/ok/1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.doRequest(context.Background(), "GET", "/metrics-test/fail/1", nil); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+
+	for _, tt := range []struct {
+		endpoint, status string
+	}{
+		{"/metrics-test/ok/{id}", "200"},
+		{"/metrics-test/fail/{id}", "500"},
+	} {
+		if got := testutil.ToFloat64(requestsTotal.WithLabelValues(tt.endpoint, "GET", tt.status)); got != 1 {
+			t.Errorf("thermia_api_requests_total{endpoint=%q,status_code=%q} = %v, want 1", tt.endpoint, tt.status, got)
+		}
+	}
+	if n := testutil.CollectAndCount(requestDuration, "thermia_api_request_duration_seconds"); n < 2 {
+		t.Errorf("thermia_api_request_duration_seconds has %d series, want at least 2", n)
+	}
+}
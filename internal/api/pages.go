@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxPages bounds the pages fetched for one paginated response, so a
+// server that keeps linking to the same page cannot loop a collection.
+const maxPages = 50
+
+// getPages fetches path and every following page the API links to with a
+// Link: <url>; rel="next" header (RFC 8288), and returns the bodies in
+// order. Without the header it is a single doRequest. Links to another
+// host are refused rather than followed with the bearer token.
+func (c *APIClient) getPages(ctx context.Context, path string) ([][]byte, error) {
+	first := path
+	var pages [][]byte
+	for len(pages) < maxPages {
+		data, next, err := c.doPageRequest(ctx, "GET", path, nil)
+		if err != nil {
+			if len(pages) > 0 {
+				return nil, fmt.Errorf("page %d: %w", len(pages)+1, err)
+			}
+			return nil, err
+		}
+		pages = append(pages, data)
+		if next == "" {
+			return pages, nil
+		}
+		if path, err = c.pagePath(path, next); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%s: more than %d pages", first, maxPages)
+}
+
+// pagePath resolves link, relative to the request of path, to a path below
+// the base URL.
+func (c *APIClient) pagePath(path, link string) (string, error) {
+	current, err := url.Parse(c.baseURL + path)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid next page link %q: %w", link, err)
+	}
+	next := current.ResolveReference(ref).String()
+	rest, ok := strings.CutPrefix(next, strings.TrimRight(c.baseURL, "/"))
+	if !ok || rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", fmt.Errorf("next page link %q is outside the API", link)
+	}
+	return rest, nil
+}
+
+// nextLink returns the target of the rel="next" link in the Link headers
+// of h, or "".
+func nextLink(h http.Header) string {
+	for _, header := range h.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetInstallations_Pages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</api/v1/installationsInfo?page=2>; rel="next", </api/v1/installationsInfo?page=2>; rel="last"`)
+			w.Write([]byte(`{"items":[{"id":1,"name":"Villa"}]}`))
+		case "2":
+			w.Write([]byte(`{"items":[{"id":2,"name":"Cabin"}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := newTestClient(srv).GetInstallations(context.Background())
+	if err != nil {
+		t.Fatalf("GetInstallations() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Errorf("GetInstallations() = %+v, want installations 1 and 2", got)
+	}
+}
+
+func TestGetEvents_Pages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", "<"+srv.URL+EventsPath(7, false)+"&page=2>; rel=next")
+			w.Write([]byte(`[{"eventTitle":"HIGH_PRESSURE"}]`))
+			return
+		}
+		w.Write([]byte(`[{"eventTitle":"LOW_PRESSURE"}]`))
+	}))
+	defer srv.Close()
+
+	got, err := newTestClient(srv).GetEvents(context.Background(), 7, false)
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("GetEvents() = %+v, want both pages", got)
+	}
+}
+
+func TestGetPages_ForeignLink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<https://attacker.example/steal>; rel="next"`)
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv).GetEvents(context.Background(), 7, true); err == nil {
+		t.Error("GetEvents() followed a next link to another host")
+	}
+}
+
+func TestGetPages_Loop(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Link", `<`+r.URL.String()+`>; rel="next"`)
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv).GetEvents(context.Background(), 7, true); err == nil {
+		t.Error("GetEvents() should fail on a page linking to itself")
+	}
+	if hits != maxPages {
+		t.Errorf("fetched %d pages, want at most %d", hits, maxPages)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{`</p?page=2>; rel="next"`, "/p?page=2"},
+		{`</p?page=1>; rel="prev", </p?page=3>; rel="next"`, "/p?page=3"},
+		{`</p?page=3>; rel="next last"`, "/p?page=3"},
+		{`</p?page=9>; rel="last"`, ""},
+		{`/p?page=2; rel="next"`, ""},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.header != "" {
+			h.Set("Link", tt.header)
+		}
+		if got := nextLink(h); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
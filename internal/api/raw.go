@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// InstallationInfoPath is the API path of an installation's info.
+func InstallationInfoPath(id int64) string {
+	return fmt.Sprintf("/api/v1/installations/%d", id)
+}
+
+// RegisterGroupPath is the API path of one register group of an
+// installation.
+func RegisterGroupPath(installationID int64, group string) string {
+	return fmt.Sprintf("/api/v1/Registers/Installations/%d/Groups/%s", installationID, group)
+}
+
+// EventsPath is the API path of an installation's events, only the active
+// alarms if onlyActive is set.
+func EventsPath(installationID int64, onlyActive bool) string {
+	return fmt.Sprintf("/api/v1/installation/%d/events?onlyActiveAlarms=%v", installationID, onlyActive)
+}
+
+// GetRaw retrieves path and returns the response body undecoded, for
+// inspecting responses the typed getters drop fields of. It fails with
+// ErrMalformedResponse if the body is not JSON.
+func (c *APIClient) GetRaw(ctx context.Context, path string) (json.RawMessage, error) {
+	data, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%w: %s is not JSON", ErrMalformedResponse, path)
+	}
+	return json.RawMessage(data), nil
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRaw(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == RegisterGroupPath(7, "REG_GROUP_POOL") {
+			w.Write([]byte("<html>maintenance</html>"))
+			return
+		}
+		w.Write([]byte(`[{"registerName":"REG_OUTDOOR_TEMPERATURE","unknownField":1}]`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	data, err := c.GetRaw(context.Background(), RegisterGroupPath(7, "REG_GROUP_TEMPERATURES"))
+	if err != nil {
+		t.Fatalf("GetRaw() error = %v", err)
+	}
+	if want := `[{"registerName":"REG_OUTDOOR_TEMPERATURE","unknownField":1}]`; string(data) != want {
+		t.Errorf("GetRaw() = %s, want the body as is: %s", data, want)
+	}
+
+	if _, err := c.GetRaw(context.Background(), RegisterGroupPath(7, "REG_GROUP_POOL")); !errors.Is(err, ErrMalformedResponse) {
+		t.Errorf("GetRaw() error = %v for an HTML body, want ErrMalformedResponse", err)
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 
@@ -11,17 +13,51 @@ import (
 // GetRegisterGroup retrieves a specific register group for an installation.
 // Register groups contain configuration and operational data.
 func (c *APIClient) GetRegisterGroup(ctx context.Context, installationID int64, group string) ([]types.GroupItem, error) {
-	path := fmt.Sprintf("/api/v1/Registers/Installations/%d/Groups/%s", installationID, group)
-
-	data, err := c.doRequest(ctx, "GET", path, nil)
+	data, err := c.doRequest(ctx, "GET", RegisterGroupPath(installationID, group), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var items []types.GroupItem
 	if err := json.Unmarshal(data, &items); err != nil {
-		return nil, fmt.Errorf("unmarshal register group: %w", err)
+		return nil, fmt.Errorf("%w: unmarshal register group: %v", ErrMalformedResponse, err)
 	}
 
 	return items, nil
 }
+
+// setRegisterRequest is the body of a register write.
+type setRegisterRequest struct {
+	RegisterSpecificationID int64   `json:"registerSpecificationId"`
+	RegisterValue           float64 `json:"registerValue"`
+	ClientUUID              string  `json:"clientUuid"`
+}
+
+// SetRegister writes value to a register of an installation. registerID is
+// the registerId reported in the register group. The value is sent as is;
+// callers must validate it first (see control.ValidateWrite).
+func (c *APIClient) SetRegister(ctx context.Context, installationID, registerID int64, value float64) error {
+	path := fmt.Sprintf("/api/v1/Registers/Installations/%d/Registers", installationID)
+
+	body, err := json.Marshal(setRegisterRequest{
+		RegisterSpecificationID: registerID,
+		RegisterValue:           value,
+		ClientUUID:              newClientUUID(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal register write: %w", err)
+	}
+
+	_, err = c.doRequest(ctx, "POST", path, bytes.NewReader(body))
+	return err
+}
+
+// newClientUUID returns a random version 4 UUID identifying the writer, as
+// the Thermia apps send with every write.
+func newClientUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
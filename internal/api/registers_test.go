@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetRegister(t *testing.T) {
+	var got setRegisterRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/Registers/Installations/42/Registers" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := newTestClient(srv).SetRegister(context.Background(), 42, 7, 1); err != nil {
+		t.Fatalf("SetRegister() error = %v", err)
+	}
+	if got.RegisterSpecificationID != 7 || got.RegisterValue != 1 || len(got.ClientUUID) != 36 {
+		t.Errorf("body = %+v", got)
+	}
+}
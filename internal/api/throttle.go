@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryWait is the longest Retry-After the client waits for before
+// retrying a throttled request once. Longer waits fail the request and
+// make the collection back off instead.
+const maxRetryWait = 30 * time.Second
+
+// ErrThrottled is wrapped by errors for requests the API throttled.
+var ErrThrottled = errors.New("throttled by Thermia API")
+
+// ThrottledError reports a throttled request and how long the API asked
+// clients to wait (0 if it did not say).
+type ThrottledError struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v: status %d, retry after %v", ErrThrottled, e.Status, e.RetryAfter)
+	}
+	return fmt.Sprintf("%v: status %d", ErrThrottled, e.Status)
+}
+
+// Unwrap returns ErrThrottled, and ErrMaintenance too for a 503.
+func (e *ThrottledError) Unwrap() []error {
+	if isMaintenance(e.Status) {
+		return []error{ErrThrottled, ErrMaintenance}
+	}
+	return []error{ErrThrottled}
+}
+
+// isThrottled reports whether status means the request was throttled.
+func isThrottled(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfter returns how long the response asks clients to wait, from
+// Retry-After (seconds or HTTP date) or the RateLimit-Reset and
+// X-RateLimit-Reset headers (seconds, or a Unix time for the latter).
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0), true
+		}
+	}
+
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		seconds, err := strconv.ParseInt(h.Get(name), 10, 64)
+		if err != nil || seconds < 0 {
+			continue
+		}
+		// Values this large are Unix times rather than delays
+		if seconds > 1_000_000_000 {
+			return max(time.Unix(seconds, 0).Sub(now), 0), true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// rateLimitExhausted reports whether a successful response says no
+// requests are left in the current window.
+func rateLimitExhausted(h http.Header) bool {
+	for _, name := range []string{"RateLimit-Remaining", "X-RateLimit-Remaining"} {
+		if v := h.Get(name); v != "" {
+			remaining, err := strconv.Atoi(v)
+			return err == nil && remaining <= 0
+		}
+	}
+	return false
+}
+
+// sleepCtx waits for d or until ctx is done, reporting whether d elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// fitsDeadline reports whether waiting d from now leaves time before ctx's
+// deadline.
+func fitsDeadline(ctx context.Context, now time.Time, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || deadline.Sub(now) > d
+}
+
+// ThrottledUntil returns until when the API asked this client to stop
+// sending requests, or the zero time.
+func (c *APIClient) ThrottledUntil() time.Time {
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	return c.throttledUntil
+}
+
+// clearThrottle drops a recorded wait once it has been waited for.
+func (c *APIClient) clearThrottle() {
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	c.throttledUntil = time.Time{}
+}
+
+// throttle records that no requests should be sent before until.
+func (c *APIClient) throttle(until time.Time) {
+	c.throttleMu.Lock()
+	defer c.throttleMu.Unlock()
+	if until.After(c.throttledUntil) {
+		c.throttledUntil = until
+	}
+}
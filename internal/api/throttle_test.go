@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{"seconds", http.Header{"Retry-After": {"120"}}, 2 * time.Minute, true},
+		{"http date", http.Header{"Retry-After": {"Fri, 16 Oct 2026 09:00:30 GMT"}}, 30 * time.Second, true},
+		{"ratelimit reset", http.Header{"Ratelimit-Reset": {"15"}}, 15 * time.Second, true},
+		{"x-ratelimit reset unix", http.Header{"X-Ratelimit-Reset": {"1792141260"}}, time.Minute, true},
+		{"none", http.Header{}, 0, false},
+		{"garbage", http.Header{"Retry-After": {"soon"}}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfter(tt.header, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("retryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func newTestClient(srv *httptest.Server) *APIClient {
+	return &APIClient{
+		baseURL:    srv.URL,
+		httpClient: srv.Client(),
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		clock:      clock.Real{},
+	}
+}
+
+func TestDoRequest_ThrottledFailsFast(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	_, err := c.doRequest(context.Background(), "GET", "/api/v1/installations/1", nil)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter != 10*time.Minute {
+		t.Fatalf("doRequest() error = %v, want ThrottledError with 10m retry", err)
+	}
+
+	// The wait is too long to retry; later requests must not be sent
+	if _, err := c.doRequest(context.Background(), "GET", "/api/v1/installations/1/status", nil); !errors.Is(err, ErrThrottled) {
+		t.Errorf("second doRequest() error = %v, want ErrThrottled", err)
+	}
+	if hits != 1 {
+		t.Errorf("server got %d requests, want 1", hits)
+	}
+	if c.ThrottledUntil().IsZero() {
+		t.Error("ThrottledUntil() should report the wait")
+	}
+}
+
+func TestDoRequest_ThrottleEndsOnClock(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", "600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	c := newTestClient(srv)
+	c.clock = clk
+
+	c.doRequest(context.Background(), "GET", "/api/v1/installations/1", nil)
+	if want := clk.Now().Add(10 * time.Minute); !c.ThrottledUntil().Equal(want) {
+		t.Errorf("ThrottledUntil() = %v, want %v", c.ThrottledUntil(), want)
+	}
+
+	clk.Advance(10 * time.Minute)
+	if _, err := c.doRequest(context.Background(), "GET", "/api/v1/installations/1", nil); err != nil || hits != 2 {
+		t.Errorf("doRequest() error = %v, hits = %d after the wait, want a sent request", err, hits)
+	}
+}
+
+func TestDoRequest_RetriesShortWait(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	data, err := c.doRequest(context.Background(), "GET", "/api/v1/installations/1", nil)
+	if err != nil || string(data) != "{}" {
+		t.Fatalf("doRequest() = %q, %v, want retried success", data, err)
+	}
+	if hits != 2 {
+		t.Errorf("server got %d requests, want 2", hits)
+	}
+	if !c.ThrottledUntil().IsZero() {
+		t.Error("throttle should be cleared after a successful retry")
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// bundlePrefix marks an encrypted token bundle and its format version.
+const bundlePrefix = "thermia-bundle.v1."
+
+// bundleKeySize is the AES-256 key size in bytes.
+const bundleKeySize = 32
+
+// TokenBundle is a refresh token packaged by the login command for transport
+// to a running exporter.
+type TokenBundle struct {
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// IsBundle reports whether s is an encrypted token bundle rather than a raw
+// refresh token.
+func IsBundle(s string) bool {
+	return strings.HasPrefix(s, bundlePrefix)
+}
+
+// GenerateBundleKey returns a new random base64-encoded bundle key.
+func GenerateBundleKey() (string, error) {
+	key := make([]byte, bundleKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// SealBundle encrypts b with AES-256-GCM using a base64-encoded key.
+func SealBundle(b TokenBundle, key string) (string, error) {
+	gcm, err := bundleCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(bundlePrefix))
+	return bundlePrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// OpenBundle decrypts a bundle produced by SealBundle.
+func OpenBundle(s, key string) (TokenBundle, error) {
+	var b TokenBundle
+
+	if !IsBundle(s) {
+		return b, errors.New("not a token bundle")
+	}
+
+	gcm, err := bundleCipher(key)
+	if err != nil {
+		return b, err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, bundlePrefix))
+	if err != nil {
+		return b, fmt.Errorf("decode bundle: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return b, errors.New("bundle too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(bundlePrefix))
+	if err != nil {
+		return b, errors.New("decrypt bundle: wrong key or corrupted bundle")
+	}
+
+	if err := json.Unmarshal(plain, &b); err != nil {
+		return b, fmt.Errorf("parse bundle: %w", err)
+	}
+	if b.RefreshToken == "" {
+		return b, errors.New("bundle has no refresh token")
+	}
+
+	return b, nil
+}
+
+// bundleCipher builds the AES-GCM cipher for a base64-encoded key.
+func bundleCipher(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("decode bundle key: %w", err)
+	}
+	if len(raw) != bundleKeySize {
+		return nil, fmt.Errorf("bundle key must be %d bytes, got %d", bundleKeySize, len(raw))
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
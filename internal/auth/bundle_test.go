@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBundle_RoundTrip(t *testing.T) {
+	key, err := GenerateBundleKey()
+	if err != nil {
+		t.Fatalf("GenerateBundleKey() error = %v", err)
+	}
+
+	in := TokenBundle{
+		Username:     "user@example.com",
+		RefreshToken: "rt-123",
+		CreatedAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	sealed, err := SealBundle(in, key)
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+	if !IsBundle(sealed) {
+		t.Fatalf("IsBundle(%q) = false, want true", sealed)
+	}
+
+	out, err := OpenBundle(sealed, key)
+	if err != nil {
+		t.Fatalf("OpenBundle() error = %v", err)
+	}
+	if out != in {
+		t.Errorf("OpenBundle() = %+v, want %+v", out, in)
+	}
+}
+
+func TestBundle_WrongKey(t *testing.T) {
+	key1, _ := GenerateBundleKey()
+	key2, _ := GenerateBundleKey()
+
+	sealed, err := SealBundle(TokenBundle{RefreshToken: "rt"}, key1)
+	if err != nil {
+		t.Fatalf("SealBundle() error = %v", err)
+	}
+
+	if _, err := OpenBundle(sealed, key2); err == nil {
+		t.Error("OpenBundle() with wrong key expected error, got nil")
+	}
+}
+
+func TestBundle_InvalidKey(t *testing.T) {
+	if _, err := SealBundle(TokenBundle{RefreshToken: "rt"}, "c2hvcnQ="); err == nil {
+		t.Error("SealBundle() with short key expected error, got nil")
+	}
+	if IsBundle("plain-refresh-token") {
+		t.Error("IsBundle() = true for a raw refresh token")
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecodeClaims(t *testing.T) {
+	seg := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	token := seg(`{"alg":"RS256","typ":"JWT"}`) + "." +
+		seg(`{"sub":"3f2a","tid":"b2c-tenant","name":"Jane"}`) + ".c2ln"
+
+	got, err := DecodeClaims(token)
+	if err != nil {
+		t.Fatalf("DecodeClaims() error = %v", err)
+	}
+	want := Claims{Subject: "3f2a", Tenant: "b2c-tenant", Type: "JWT"}
+	if got != want {
+		t.Errorf("DecodeClaims() = %+v, want %+v", got, want)
+	}
+
+	if _, err := DecodeClaims("opaque-token"); err == nil {
+		t.Error("DecodeClaims() of an opaque token: want an error")
+	}
+}
@@ -12,30 +12,25 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
-)
-
-// Azure B2C OAuth2 constants
-const (
-	clientID    = "09ea4903-9e95-45fe-ae1f-e3b7d32fa385"
-	policy      = "b2c_1a_signuporsigninonline"
-	redirectURI = "https://online.thermia.se/login"
-	scope       = clientID + " offline_access openid"
-
-	baseB2C      = "https://thermialogin.b2clogin.com"
-	tenantDomain = "thermialogin.onmicrosoft.com"
+	"sync"
 
-	authorizeURL = baseB2C + "/" + tenantDomain + "/" + policy + "/oauth2/v2.0/authorize"
-	tokenURL     = baseB2C + "/" + tenantDomain + "/" + policy + "/oauth2/v2.0/token"
-	selfURL      = baseB2C + "/" + tenantDomain + "/" + policy + "/SelfAsserted"
-	confirmURL   = baseB2C + "/" + tenantDomain + "/" + policy + "/api/CombinedSigninAndSignup/confirmed"
+	"thermia_exporter/internal/tlswatch"
 )
 
 var errNeedSelfAsserted = errors.New("need SelfAsserted step")
 
+// ErrInvalidCredentials is wrapped by login errors where the portal rejected
+// the username or password. Logging in again with the same credentials
+// fails the same way and risks locking the account.
+var ErrInvalidCredentials = errors.New("username or password rejected")
+
 // Credentials holds authentication credentials.
+// RefreshToken is an optional pre-provisioned refresh token; when Password
+// is empty it is the only way the exporter obtains access tokens.
 type Credentials struct {
-	Username string
-	Password string
+	Username     string
+	Password     string
+	RefreshToken string
 }
 
 // AuthResult contains the result of a successful authentication.
@@ -55,33 +50,109 @@ type authState struct {
 
 // AuthClient handles OAuth2 authentication with Azure B2C.
 type AuthClient struct {
+	portal     Portal
 	httpClient *http.Client
+	jar        *sessionJar
 	logger     *slog.Logger
+
+	mu        sync.Mutex
+	loginPage *LoginPage
 }
 
-// NewAuthClient creates a new authentication client.
+// NewAuthClient creates a new authentication client for Thermia Online.
 func NewAuthClient(logger *slog.Logger) *AuthClient {
-	jar, _ := cookiejar.New(nil)
+	return NewPortalAuthClient(ThermiaPortal, logger)
+}
+
+// NewPortalAuthClient creates a new authentication client that logs in to
+// portal. Empty portal fields default to Thermia Online.
+func NewPortalAuthClient(portal Portal, logger *slog.Logger) *AuthClient {
+	jar := newSessionJar()
 
 	return &AuthClient{
+		portal: portal.WithDefaults(),
+		jar:    jar,
 		httpClient: &http.Client{
 			Timeout: 30 * 1000 * 1000 * 1000, // 30 seconds in nanoseconds
 			Jar:     jar,
-			Transport: &http.Transport{
+			Transport: tlswatch.NewTransport(&http.Transport{
 				MaxIdleConns:        10,
 				MaxIdleConnsPerHost: 5,
 				IdleConnTimeout:     90 * 1000 * 1000 * 1000, // 90 seconds
-			},
+			}, logger),
 		},
 		logger: logger,
 	}
 }
 
+// Portal returns the portal the client logs in to.
+func (a *AuthClient) Portal() Portal {
+	return a.portal
+}
+
+// LoginPage returns the version identifiers of the login page seen by the
+// last password login, and false if there has been none.
+func (a *AuthClient) LoginPage() (LoginPage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.loginPage == nil {
+		return LoginPage{}, false
+	}
+	return *a.loginPage, true
+}
+
+func (a *AuthClient) setLoginPage(p LoginPage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.loginPage = &p
+}
+
+// Reset closes idle connections and drops the portal session cookies, so
+// the next login starts like one from a new client. The client stays
+// usable meanwhile and keeps its portal.
+func (a *AuthClient) Reset() {
+	a.httpClient.CloseIdleConnections()
+	a.jar.reset()
+}
+
+// sessionJar is a cookie jar that can be emptied while requests use it.
+type sessionJar struct {
+	mu  sync.Mutex
+	jar *cookiejar.Jar
+}
+
+func newSessionJar() *sessionJar {
+	jar, _ := cookiejar.New(nil)
+	return &sessionJar{jar: jar}
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+func (j *sessionJar) reset() {
+	jar, _ := cookiejar.New(nil)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar = jar
+}
+
 // Authenticate performs the full OAuth2 PKCE authentication flow.
 func (a *AuthClient) Authenticate(ctx context.Context, creds Credentials) (*AuthResult, error) {
 	a.logger.Debug("Starting authentication", "username", creds.Username)
 
-	verifier := generatePKCEVerifier()
+	verifier, err := generatePKCEVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("generate PKCE verifier: %w", err)
+	}
 	challenge := generatePKCEChallenge(verifier)
 
 	// Step 1: Start authorization
@@ -121,14 +192,14 @@ func (a *AuthClient) Authenticate(ctx context.Context, creds Credentials) (*Auth
 // startAuthorize initiates the OAuth2 authorization flow.
 func (a *AuthClient) startAuthorize(ctx context.Context, challenge string) (*authState, error) {
 	q := url.Values{}
-	q.Set("client_id", clientID)
-	q.Set("scope", scope)
-	q.Set("redirect_uri", redirectURI)
+	q.Set("client_id", a.portal.ClientID)
+	q.Set("scope", a.portal.scope())
+	q.Set("redirect_uri", a.portal.RedirectURI)
 	q.Set("response_type", "code")
 	q.Set("code_challenge", challenge)
 	q.Set("code_challenge_method", "S256")
 
-	req, _ := http.NewRequestWithContext(ctx, "GET", authorizeURL+"?"+q.Encode(), nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", a.portal.authorizeURL()+"?"+q.Encode(), nil)
 	res, err := a.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -143,13 +214,11 @@ func (a *AuthClient) startAuthorize(ctx context.Context, challenge string) (*aut
 		return nil, errors.New("SETTINGS JSON not found in response")
 	}
 
-	var settings struct {
-		TransId string `json:"transId"`
-		Csrf    string `json:"csrf"`
-	}
+	var settings pageSettings
 	if err := json.Unmarshal([]byte(setJSON), &settings); err != nil {
 		return nil, fmt.Errorf("parse settings: %w", err)
 	}
+	a.setLoginPage(loginPage(string(body), settings))
 
 	parts := strings.Split(settings.TransId, "=")
 	if len(parts) != 2 {
@@ -178,10 +247,10 @@ func (a *AuthClient) doSelfAsserted(ctx context.Context, creds Credentials, stat
 	form.Set("signInName", creds.Username)
 	form.Set("password", creds.Password)
 
-	u, _ := url.Parse(selfURL)
+	u, _ := url.Parse(a.portal.selfURL())
 	q := u.Query()
 	q.Set("tx", "StateProperties="+state.StateProps)
-	q.Set("p", "B2C_1A_SignUpOrSigninOnline")
+	q.Set("p", a.portal.Policy)
 	u.RawQuery = q.Encode()
 
 	req, _ := http.NewRequestWithContext(ctx, "POST", u.String(), strings.NewReader(form.Encode()))
@@ -198,20 +267,28 @@ func (a *AuthClient) doSelfAsserted(ctx context.Context, creds Credentials, stat
 	defer res.Body.Close()
 
 	b, _ := io.ReadAll(res.Body)
-	if res.StatusCode/100 != 2 || strings.Contains(string(b), `"status":"400"`) {
+	if res.StatusCode/100 != 2 {
 		return fmt.Errorf("self-asserted failed (status %d): %s", res.StatusCode, string(b))
 	}
+	// B2C answers a rejected login with 200 and a status in the body
+	var result struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(b, &result) == nil && result.Status == "400" {
+		return fmt.Errorf("%w: %s", ErrInvalidCredentials, result.Message)
+	}
 
 	return nil
 }
 
 // confirmAndGetCode confirms the login and retrieves the authorization code.
 func (a *AuthClient) confirmAndGetCode(ctx context.Context, state *authState) (string, error) {
-	u, _ := url.Parse(confirmURL)
+	u, _ := url.Parse(a.portal.confirmURL())
 	q := u.Query()
 	q.Set("csrf_token", state.CSRF)
 	q.Set("tx", "StateProperties="+state.StateProps)
-	q.Set("p", "B2C_1A_SignUpOrSigninOnline")
+	q.Set("p", a.portal.Policy)
 	u.RawQuery = q.Encode()
 
 	req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
@@ -227,7 +304,7 @@ func (a *AuthClient) confirmAndGetCode(ctx context.Context, state *authState) (s
 
 	// Check if we got redirected to the callback URL with a code
 	final := res.Request.URL
-	if strings.HasPrefix(final.String(), redirectURI) {
+	if strings.HasPrefix(final.String(), a.portal.RedirectURI) {
 		if code := final.Query().Get("code"); code != "" {
 			return code, nil
 		}
@@ -240,7 +317,7 @@ func (a *AuthClient) confirmAndGetCode(ctx context.Context, state *authState) (s
 	}
 	defer r2.Body.Close()
 
-	if strings.HasPrefix(r2.Request.URL.String(), redirectURI) {
+	if strings.HasPrefix(r2.Request.URL.String(), a.portal.RedirectURI) {
 		if code := r2.Request.URL.Query().Get("code"); code != "" {
 			return code, nil
 		}
@@ -254,8 +331,8 @@ func (a *AuthClient) confirmAndGetCode(ctx context.Context, state *authState) (s
 func (a *AuthClient) Refresh(ctx context.Context, refreshToken string) (*AuthResult, error) {
 	form := url.Values{}
 	form.Set("grant_type", "refresh_token")
-	form.Set("client_id", clientID)
-	form.Set("scope", scope)
+	form.Set("client_id", a.portal.ClientID)
+	form.Set("scope", a.portal.scope())
 	form.Set("refresh_token", refreshToken)
 
 	return a.requestToken(ctx, form)
@@ -265,9 +342,9 @@ func (a *AuthClient) Refresh(ctx context.Context, refreshToken string) (*AuthRes
 func (a *AuthClient) exchangeCode(ctx context.Context, code, verifier string) (*AuthResult, error) {
 	form := url.Values{}
 	form.Set("grant_type", "authorization_code")
-	form.Set("client_id", clientID)
-	form.Set("redirect_uri", redirectURI)
-	form.Set("scope", scope)
+	form.Set("client_id", a.portal.ClientID)
+	form.Set("redirect_uri", a.portal.RedirectURI)
+	form.Set("scope", a.portal.scope())
 	form.Set("code", code)
 	form.Set("code_verifier", verifier)
 
@@ -276,7 +353,7 @@ func (a *AuthClient) exchangeCode(ctx context.Context, code, verifier string) (*
 
 // requestToken posts a grant request to the token endpoint and parses the result.
 func (a *AuthClient) requestToken(ctx context.Context, form url.Values) (*AuthResult, error) {
-	req, _ := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	req, _ := http.NewRequestWithContext(ctx, "POST", a.portal.tokenURL(), strings.NewReader(form.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
 
 	res, err := a.httpClient.Do(req)
@@ -287,7 +364,7 @@ func (a *AuthClient) requestToken(ctx context.Context, form url.Values) (*AuthRe
 
 	b, _ := io.ReadAll(res.Body)
 	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("token endpoint returned %d: %s", res.StatusCode, string(b))
+		return nil, parseTokenError(res.StatusCode, b)
 	}
 
 	var tokenResp struct {
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticate_InvalidCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/authorize"):
+			io.WriteString(w, testLoginHTML)
+		case strings.HasSuffix(r.URL.Path, "/SelfAsserted"):
+			io.WriteString(w, `{"status":"400","message":"Your password is incorrect."}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	a := NewPortalAuthClient(Portal{B2CBaseURL: srv.URL}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	_, err := a.Authenticate(context.Background(), Credentials{Username: "a@example.com", Password: "wrong"})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want ErrInvalidCredentials", err)
+	}
+	if !strings.Contains(err.Error(), "Your password is incorrect.") {
+		t.Errorf("error %q does not carry the portal's message", err)
+	}
+}
+
+func TestAuthenticate_PortalPolicy(t *testing.T) {
+	const policy = "b2c_1a_signin_othersite"
+	var selfPolicy, confirmPolicy string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/"+policy+"/") {
+			t.Errorf("request to %s is not under policy %s", r.URL.Path, policy)
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/authorize"):
+			io.WriteString(w, testLoginHTML)
+		case strings.HasSuffix(r.URL.Path, "/SelfAsserted"):
+			selfPolicy = r.URL.Query().Get("p")
+			io.WriteString(w, `{"status":"200"}`)
+		case strings.HasSuffix(r.URL.Path, "/confirmed"):
+			confirmPolicy = r.URL.Query().Get("p")
+			http.Error(w, "stop here", http.StatusBadRequest)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	a := NewPortalAuthClient(Portal{B2CBaseURL: srv.URL, Policy: policy}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	a.Authenticate(context.Background(), Credentials{Username: "a@example.com", Password: "secret"})
+	if selfPolicy != policy {
+		t.Errorf("self-asserted step used policy %q, want %q", selfPolicy, policy)
+	}
+	if confirmPolicy != policy {
+		t.Errorf("confirm step used policy %q, want %q", confirmPolicy, policy)
+	}
+}
+
+func TestReset_DropsCookies(t *testing.T) {
+	a := NewPortalAuthClient(Portal{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u, _ := url.Parse("https://login.example.com/")
+	a.jar.SetCookies(u, []*http.Cookie{{Name: "x-ms-cpim-sso", Value: "session"}})
+
+	a.Reset()
+	if cookies := a.jar.Cookies(u); len(cookies) != 0 {
+		t.Errorf("cookies after Reset() = %v, want none", cookies)
+	}
+	if a.Portal() != ThermiaPortal.WithDefaults() {
+		t.Error("Reset() changed the portal")
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LoginPage identifies the version of the B2C login page the password login
+// scrapes. The login depends on the page's layout, so a change here is the
+// earliest sign that it may break.
+type LoginPage struct {
+	// Policy is the B2C policy that served the page.
+	Policy string
+	// Template is the custom page template the policy renders.
+	Template string
+	// Contract is the B2C page layout and its version, like "unifiedssp:2.1.5".
+	Contract string
+}
+
+// contractRE matches the page layout URN B2C embeds in its pages.
+var contractRE = regexp.MustCompile(`urn:com:microsoft:aad:b2c:elements:contract:([a-z]+):(\d+(?:\.\d+)*)`)
+
+// pageSettings is the part of the SETTINGS JSON of the login page the login
+// uses or tracks.
+type pageSettings struct {
+	TransId        string `json:"transId"`
+	Csrf           string `json:"csrf"`
+	RemoteResource string `json:"remoteResource"`
+	Hosts          struct {
+		Policy string `json:"policy"`
+	} `json:"hosts"`
+}
+
+// loginPage returns the version identifiers of a login page.
+func loginPage(html string, settings pageSettings) LoginPage {
+	p := LoginPage{
+		Policy:   strings.ToLower(settings.Hosts.Policy),
+		Template: settings.RemoteResource,
+	}
+	if m := contractRE.FindStringSubmatch(html); m != nil {
+		p.Contract = m[1] + ":" + m[2]
+	}
+	return p
+}
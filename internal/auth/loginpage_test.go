@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testLoginHTML = `<!DOCTYPE html>
+<html><head>
+<script>var SETTINGS = {"remoteResource":"https://online.thermia.se/b2c/unified.html","csrf":"c","transId":"StateProperties=abc","hosts":{"tenant":"/thermialogin.onmicrosoft.com/B2C_1A_SignUpOrSigninOnline","policy":"B2C_1A_SignUpOrSigninOnline"}};</script>
+<meta name="contract" content="urn:com:microsoft:aad:b2c:elements:contract:unifiedssp:2.1.5">
+</head></html>`
+
+func TestAuthenticate_LoginPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/authorize") {
+			io.WriteString(w, testLoginHTML)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	a := NewPortalAuthClient(Portal{B2CBaseURL: srv.URL}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, ok := a.LoginPage(); ok {
+		t.Fatal("LoginPage() reported before any login")
+	}
+	if _, err := a.Authenticate(context.Background(), Credentials{Username: "a@example.com", Password: "p"}); err == nil {
+		t.Fatal("Authenticate() succeeded, want the self-asserted step to fail")
+	}
+
+	got, ok := a.LoginPage()
+	want := LoginPage{
+		Policy:   "b2c_1a_signuporsigninonline",
+		Template: "https://online.thermia.se/b2c/unified.html",
+		Contract: "unifiedssp:2.1.5",
+	}
+	if !ok || got != want {
+		t.Errorf("LoginPage() = %+v, %v, want %+v even when the login fails", got, ok, want)
+	}
+}
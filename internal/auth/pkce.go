@@ -2,14 +2,14 @@
 package auth
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"strings"
-	"time"
 )
 
 // generatePKCEVerifier generates a random PKCE code verifier.
-func generatePKCEVerifier() string {
+func generatePKCEVerifier() (string, error) {
 	return randomChallenge(43)
 }
 
@@ -19,19 +19,26 @@ func generatePKCEChallenge(verifier string) string {
 	return base64.RawURLEncoding.EncodeToString(h[:])
 }
 
-// randomChallenge generates a random string suitable for PKCE verifier.
-// Uses a simple PRNG based on current time - sufficient for PKCE where security
-// comes from the one-time use and server-side validation.
-func randomChallenge(n int) string {
+// randomChallenge generates a random string of n characters from the
+// unreserved set PKCE verifiers may use. The verifier must be unguessable,
+// so it comes from crypto/rand rather than anything derived from the time.
+func randomChallenge(n int) (string, error) {
 	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~"
+	// Bytes at or above limit are skipped so every character is equally likely
+	const limit = 256 - 256%len(alphabet)
+
 	var sb strings.Builder
 	sb.Grow(n)
-
-	x := time.Now().UnixNano()
-	for i := 0; i < n; i++ {
-		x = (x*1664525 + 1013904223) & 0x7fffffff
-		sb.WriteByte(alphabet[int(x)%len(alphabet)])
+	buf := make([]byte, n)
+	for sb.Len() < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && sb.Len() < n {
+				sb.WriteByte(alphabet[int(b)%len(alphabet)])
+			}
+		}
 	}
-
-	return sb.String()
+	return sb.String(), nil
 }
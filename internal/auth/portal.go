@@ -0,0 +1,59 @@
+package auth
+
+import "strings"
+
+// Portal describes a Thermia-branded online portal: the Azure B2C tenant and
+// app registration used to log in, and the portal's API configuration
+// endpoint. Other brands run the same software under their own tenant.
+type Portal struct {
+	ClientID     string
+	Policy       string
+	RedirectURI  string
+	B2CBaseURL   string
+	TenantDomain string
+	ConfigURL    string
+}
+
+// ThermiaPortal is Thermia Online, used unless configured otherwise.
+var ThermiaPortal = Portal{
+	ClientID:     "09ea4903-9e95-45fe-ae1f-e3b7d32fa385",
+	Policy:       "b2c_1a_signuporsigninonline",
+	RedirectURI:  "https://online.thermia.se/login",
+	B2CBaseURL:   "https://thermialogin.b2clogin.com",
+	TenantDomain: "thermialogin.onmicrosoft.com",
+	ConfigURL:    "https://online.thermia.se/api/configuration",
+}
+
+// WithDefaults returns p with empty fields taken from ThermiaPortal.
+func (p Portal) WithDefaults() Portal {
+	fill := func(v *string, def string) {
+		if *v == "" {
+			*v = def
+		}
+	}
+	fill(&p.ClientID, ThermiaPortal.ClientID)
+	fill(&p.Policy, ThermiaPortal.Policy)
+	fill(&p.RedirectURI, ThermiaPortal.RedirectURI)
+	fill(&p.B2CBaseURL, ThermiaPortal.B2CBaseURL)
+	fill(&p.TenantDomain, ThermiaPortal.TenantDomain)
+	fill(&p.ConfigURL, ThermiaPortal.ConfigURL)
+	p.B2CBaseURL = strings.TrimRight(p.B2CBaseURL, "/")
+	return p
+}
+
+func (p Portal) scope() string {
+	return p.ClientID + " offline_access openid"
+}
+
+// endpoint returns the URL of a B2C policy endpoint such as
+// "/oauth2/v2.0/token".
+func (p Portal) endpoint(path string) string {
+	return p.B2CBaseURL + "/" + p.TenantDomain + "/" + p.Policy + path
+}
+
+func (p Portal) authorizeURL() string { return p.endpoint("/oauth2/v2.0/authorize") }
+func (p Portal) tokenURL() string     { return p.endpoint("/oauth2/v2.0/token") }
+func (p Portal) selfURL() string      { return p.endpoint("/SelfAsserted") }
+func (p Portal) confirmURL() string {
+	return p.endpoint("/api/CombinedSigninAndSignup/confirmed")
+}
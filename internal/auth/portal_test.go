@@ -0,0 +1,18 @@
+package auth
+
+import "testing"
+
+func TestPortal_WithDefaults(t *testing.T) {
+	p := Portal{ClientID: "brand-client", B2CBaseURL: "https://brandlogin.b2clogin.com/"}.WithDefaults()
+
+	if p.Policy != ThermiaPortal.Policy || p.ConfigURL != ThermiaPortal.ConfigURL {
+		t.Errorf("WithDefaults() = %+v, want Thermia defaults for empty fields", p)
+	}
+	want := "https://brandlogin.b2clogin.com/thermialogin.onmicrosoft.com/b2c_1a_signuporsigninonline/oauth2/v2.0/token"
+	if got := p.tokenURL(); got != want {
+		t.Errorf("tokenURL() = %q, want %q", got, want)
+	}
+	if got := p.scope(); got != "brand-client offline_access openid" {
+		t.Errorf("scope() = %q", got)
+	}
+}
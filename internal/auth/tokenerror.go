@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrClientRejected is wrapped by token endpoint errors that reject the
+// exporter itself rather than the user: the client ID or the requested
+// scopes are no longer accepted. Retrying or logging in again cannot fix
+// these; the portal settings, usually the exporter, must be updated.
+var ErrClientRejected = errors.New("client ID or scope rejected by the token endpoint")
+
+// clientErrors are the OAuth2 error codes that mean the portal no longer
+// accepts the client ID or scopes the exporter sends.
+var clientErrors = map[string]bool{
+	"invalid_client":      true,
+	"unauthorized_client": true,
+	"invalid_scope":       true,
+}
+
+// TokenError is a failed token endpoint request. Code and Description are
+// the OAuth2 error fields of the response, empty if it carried none.
+type TokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+	Body        string
+}
+
+func (e *TokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("token endpoint returned %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("token endpoint returned %d: %s: %s", e.StatusCode, e.Code, e.Description)
+}
+
+// Unwrap returns ErrClientRejected for client and scope errors.
+func (e *TokenError) Unwrap() error {
+	if clientErrors[e.Code] {
+		return ErrClientRejected
+	}
+	return nil
+}
+
+// RejectionCode returns the OAuth2 error code of err if it wraps
+// ErrClientRejected, and "" otherwise.
+func RejectionCode(err error) string {
+	var te *TokenError
+	if !errors.As(err, &te) || !errors.Is(te, ErrClientRejected) {
+		return ""
+	}
+	return te.Code
+}
+
+// parseTokenError builds the error for a non-200 token endpoint response.
+func parseTokenError(status int, body []byte) *TokenError {
+	var resp struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &resp)
+	return &TokenError{
+		StatusCode:  status,
+		Code:        resp.Error,
+		Description: resp.Description,
+		Body:        string(body),
+	}
+}
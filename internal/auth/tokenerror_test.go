@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRefresh_ClientRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		rejected string
+	}{
+		{"invalid scope", `{"error":"invalid_scope","error_description":"AADB2C90205: scope not allowed"}`, "invalid_scope"},
+		{"invalid client", `{"error":"invalid_client","error_description":"AADB2C90081: client not found"}`, "invalid_client"},
+		{"expired refresh token", `{"error":"invalid_grant","error_description":"AADB2C90080: token expired"}`, ""},
+		{"no oauth error", `<html>Bad Request</html>`, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			a := NewPortalAuthClient(Portal{B2CBaseURL: srv.URL}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			_, err := a.Refresh(context.Background(), "refresh")
+			if err == nil {
+				t.Fatal("Refresh() succeeded, want error")
+			}
+			if got := errors.Is(err, ErrClientRejected); got != (tc.rejected != "") {
+				t.Errorf("errors.Is(ErrClientRejected) = %v for %v", got, err)
+			}
+			if got := RejectionCode(err); got != tc.rejected {
+				t.Errorf("RejectionCode() = %q, want %q", got, tc.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DailyWindow is a time-of-day window in local time, such as 02:00-05:00.
+// End may be before Start for windows spanning midnight. The zero value is
+// an empty window.
+type DailyWindow struct {
+	// Start and End are offsets from local midnight.
+	Start, End time.Duration
+}
+
+// ParseDailyWindow parses a window of the form "HH:MM-HH:MM".
+func ParseDailyWindow(s string) (DailyWindow, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return DailyWindow{}, fmt.Errorf("invalid window %q (use HH:MM-HH:MM)", s)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return DailyWindow{}, err
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return DailyWindow{}, err
+	}
+	if start == end {
+		return DailyWindow{}, fmt.Errorf("empty window %q", s)
+	}
+	return DailyWindow{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (use HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsZero reports whether the window is empty.
+func (w DailyWindow) IsZero() bool {
+	return w.Start == w.End
+}
+
+// Contains reports whether t falls inside the window.
+func (w DailyWindow) Contains(t time.Time) bool {
+	if w.IsZero() {
+		return false
+	}
+	offset := sinceMidnight(t)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// EndAfter returns the first end of the window after t.
+func (w DailyWindow) EndAfter(t time.Time) time.Time {
+	t = t.Local()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := midnight.Add(w.End)
+	if !end.After(t) {
+		end = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()).Add(w.End)
+	}
+	return end
+}
+
+// String formats the window in the ParseDailyWindow syntax.
+func (w DailyWindow) String() string {
+	if w.IsZero() {
+		return ""
+	}
+	return formatTimeOfDay(w.Start) + "-" + formatTimeOfDay(w.End)
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	t = t.Local()
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
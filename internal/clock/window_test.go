@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 10, 16, hour, minute, 0, 0, time.Local)
+}
+
+func TestDailyWindow(t *testing.T) {
+	night, err := ParseDailyWindow("22:30-05:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if night.String() != "22:30-05:00" {
+		t.Errorf("String() = %q", night.String())
+	}
+
+	for _, tc := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{at(22, 29), false},
+		{at(22, 30), true},
+		{at(2, 0), true},
+		{at(4, 59), true},
+		{at(5, 0), false},
+		{at(12, 0), false},
+	} {
+		if got := night.Contains(tc.t); got != tc.want {
+			t.Errorf("Contains(%s) = %v, want %v", tc.t.Format("15:04"), got, tc.want)
+		}
+	}
+
+	if got, want := night.EndAfter(at(23, 0)), at(5, 0).AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("EndAfter(23:00) = %v, want %v", got, want)
+	}
+	if got, want := night.EndAfter(at(3, 0)), at(5, 0); !got.Equal(want) {
+		t.Errorf("EndAfter(03:00) = %v, want %v", got, want)
+	}
+
+	if (DailyWindow{}).Contains(at(2, 0)) {
+		t.Error("zero window should be empty")
+	}
+	for _, bad := range []string{"02:00", "2-5", "02:00-02:00", "25:00-05:00"} {
+		if _, err := ParseDailyWindow(bad); err == nil {
+			t.Errorf("ParseDailyWindow(%q) expected error", bad)
+		}
+	}
+}
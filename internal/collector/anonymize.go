@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"thermia_exporter/internal/relabel"
+)
+
+// AnonymizedName replaces a heat pump name with a short stable hash keyed by
+// salt, so dashboards keep one series per heat pump without showing its
+// name, and short names cannot be recovered by hashing candidates. It is
+// exported for backfill, which must label history the same way.
+func AnonymizedName(name, salt string) string {
+	return "hp-" + relabel.HashValue(name, salt)
+}
+
+// subjectHash returns a short stable hash of a token subject.
+func subjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:6])
+}
+
+// redact removes identifying details from d before anything is derived from
+// it when anonymization is enabled: the site and group (often an address or
+// owner name) and the last-online timestamp. The name is hashed by
+// installationLabels and the serial omitted by serial.
+func (c *ThermiaCollector) redact(d *installationData) {
+	if !c.anonymize {
+		return
+	}
+	d.inst.Site = ""
+	d.inst.Group = ""
+	if d.info != nil {
+		info := *d.info
+		info.LastOnline = ""
+		d.info = &info
+	}
+}
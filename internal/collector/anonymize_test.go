@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+func TestRedact(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+	c.anonymize = true
+	c.redactSalt = "s3cret"
+
+	info := &types.InstallationInfo{Name: "Villa Svensson", LastOnline: "2026-01-01T11:59:00Z", IsOnline: true, SerialNumber: "DG3-1"}
+	d := &installationData{
+		inst: types.Installation{ID: 1, Name: "Villa Svensson", Site: "Storgatan 1", Group: "Svensson"},
+		info: info,
+	}
+	c.redact(d)
+	labels := c.installationLabels(d)
+
+	if labels[1] == "Villa Svensson" || !strings.HasPrefix(labels[1], "hp-") {
+		t.Errorf("name label = %q, want a hashed name", labels[1])
+	}
+	if labels[1] != AnonymizedName("Villa Svensson", "s3cret") {
+		t.Errorf("name label = %q, want it stable across collections", labels[1])
+	}
+	if labels[1] == AnonymizedName("Villa Svensson", "other") {
+		t.Errorf("name label = %q, want it keyed by the salt", labels[1])
+	}
+	if d.inst.Site != "" || d.inst.Group != "" || d.info.LastOnline != "" {
+		t.Errorf("site %q, group %q, last online %q should be removed", d.inst.Site, d.inst.Group, d.info.LastOnline)
+	}
+	if serial := c.serial(d); serial != "" {
+		t.Errorf("serial = %q, want it omitted", serial)
+	}
+	if info.LastOnline == "" {
+		t.Error("redact must not modify the fetched info in place")
+	}
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/mapper"
+)
+
+type runHoursSample struct {
+	at         time.Time
+	aux        float64
+	compressor float64
+}
+
+// auxShareTracker computes the share of auxiliary heater operating time in
+// the total heat production time (auxiliary heater plus compressor) over a
+// rolling window, from the pump's operating hour counters.
+//
+// Only accessed from the collection loop.
+type auxShareTracker struct {
+	window  time.Duration
+	history map[int64][]runHoursSample
+}
+
+// newAuxShareTracker creates a tracker. A window of 0 disables it.
+func newAuxShareTracker(window time.Duration) *auxShareTracker {
+	return &auxShareTracker{
+		window:  window,
+		history: make(map[int64][]runHoursSample),
+	}
+}
+
+// observe records the operating hours for an installation at now and sets
+// d.auxShare once a full window of history is available.
+func (t *auxShareTracker) observe(now time.Time, d *installationData) {
+	if t.window <= 0 {
+		return
+	}
+	aux, compressor, ok := mapper.ExtractRunHours(d.items)
+	if !ok {
+		return
+	}
+	id := d.inst.ID
+	hist := append(t.history[id], runHoursSample{at: now, aux: aux, compressor: compressor})
+
+	// Counter reset (e.g. controller replaced): start over
+	if n := len(hist); n > 1 && (aux < hist[n-2].aux || compressor < hist[n-2].compressor) {
+		hist = hist[n-1:]
+	}
+
+	// Keep the newest sample that is at least a window old as the baseline
+	for len(hist) > 1 && now.Sub(hist[1].at) >= t.window {
+		hist = hist[1:]
+	}
+	t.history[id] = hist
+
+	if now.Sub(hist[0].at) < t.window {
+		return
+	}
+	auxHours := aux - hist[0].aux
+	total := auxHours + compressor - hist[0].compressor
+	if total <= 0 {
+		// No heat produced in the window: the share is undefined
+		return
+	}
+	share := auxHours / total
+	d.auxShare = &share
+}
+
+// emitAuxShareMetrics emits the auxiliary heat share, if known.
+func (c *ThermiaCollector) emitAuxShareMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	if d.auxShare != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.auxHeatShare, prometheus.GaugeValue, *d.auxShare, labels...)
+	}
+}
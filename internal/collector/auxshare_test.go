@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+func TestAuxShareTracker(t *testing.T) {
+	tr := newAuxShareTracker(24 * time.Hour)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	observe := func(at time.Duration, aux, compressor float64) *installationData {
+		d := &installationData{
+			inst: types.Installation{ID: 1},
+			items: []types.GroupItem{
+				{RegisterName: mapper.RegOperTimeCompressor, RegisterValue: ptrFloat(compressor)},
+				{RegisterName: mapper.RegOperTimeImm1, RegisterValue: ptrFloat(aux)},
+			},
+		}
+		tr.observe(now.Add(at), d)
+		return d
+	}
+
+	if d := observe(0, 100, 1000); d.auxShare != nil {
+		t.Fatal("share should be absent before a full window")
+	}
+	if d := observe(12*time.Hour, 101, 1009); d.auxShare != nil {
+		t.Fatal("share should be absent before a full window")
+	}
+	// 4 aux hours and 16 compressor hours in the last 24 h
+	d := observe(24*time.Hour, 104, 1016)
+	if d.auxShare == nil || *d.auxShare != 0.2 {
+		t.Fatalf("share = %v, want 0.2", d.auxShare)
+	}
+	// The window moved on: baseline is now the 12 h sample
+	d = observe(36*time.Hour, 104, 1025)
+	if d.auxShare == nil || *d.auxShare != 3.0/19 {
+		t.Errorf("share = %v, want %v", d.auxShare, 3.0/19)
+	}
+
+	// Counter reset starts over
+	if d := observe(48*time.Hour, 0, 0); d.auxShare != nil {
+		t.Error("share should be absent after a counter reset")
+	}
+}
+
+func TestAuxShareTracker_Idle(t *testing.T) {
+	tr := newAuxShareTracker(time.Hour)
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		d := &installationData{
+			inst: types.Installation{ID: 1},
+			items: []types.GroupItem{
+				{RegisterName: mapper.RegOperTimeCompressor, RegisterValue: ptrFloat(500)},
+				{RegisterName: mapper.RegOperTimeImm1, RegisterValue: ptrFloat(10)},
+			},
+		}
+		tr.observe(now.Add(time.Duration(i)*time.Hour), d)
+		if d.auxShare != nil {
+			t.Errorf("step %d: share = %v, want none without heat production", i, *d.auxShare)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package collector
+
+import "math"
+
+// calibratedIndoorKey is the temperature key of the calibrated indoor series.
+const calibratedIndoorKey = "indoor_calibrated"
+
+// calibrate adds the calibrated indoor temperature to d.temps if an offset
+// is configured for the installation. The raw reading is kept as is.
+func (c *ThermiaCollector) calibrate(d *installationData) {
+	offset, ok := c.indoorOffsets[d.inst.ID]
+	if !ok {
+		offset, ok = c.indoorOffsets[0]
+	}
+	if !ok {
+		return
+	}
+
+	if raw, ok := d.temps["indoor"]; ok {
+		d.temps[calibratedIndoorKey] = math.Round((raw+offset)*10) / 10
+	}
+}
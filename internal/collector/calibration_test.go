@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+func TestCalibrate(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+	c.indoorOffsets = map[int64]float64{1: -0.7, 0: 0.2}
+
+	d := &installationData{inst: types.Installation{ID: 1}, temps: map[string]float64{"indoor": 21.4}}
+	c.calibrate(d)
+	if d.temps["indoor"] != 21.4 || d.temps[calibratedIndoorKey] != 20.7 {
+		t.Errorf("installation 1 temps = %v, want raw 21.4 and calibrated 20.7", d.temps)
+	}
+
+	d = &installationData{inst: types.Installation{ID: 2}, temps: map[string]float64{"indoor": 21.4}}
+	c.calibrate(d)
+	if d.temps[calibratedIndoorKey] != 21.6 {
+		t.Errorf("installation 2 calibrated = %v, want default offset applied (21.6)", d.temps[calibratedIndoorKey])
+	}
+
+	c.indoorOffsets = nil
+	d = &installationData{inst: types.Installation{ID: 1}, temps: map[string]float64{"indoor": 21.4}}
+	c.calibrate(d)
+	if _, ok := d.temps[calibratedIndoorKey]; ok {
+		t.Error("calibrated series should only exist with a configured offset")
+	}
+}
@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+// emitCircuitMetrics emits per-circuit supply temperature and mixing valve
+// position for installations with mixing valve distribution circuits. Models
+// report them in different groups, so all merged items are scanned.
+func (c *ThermiaCollector) emitCircuitMetrics(ch chan<- prometheus.Metric, labels []string, items []types.GroupItem) {
+	for _, circuit := range mapper.ExtractCircuits(items) {
+		labelsWithCircuit := append(labels, circuit.Circuit)
+
+		if circuit.SupplyTemperature != nil {
+			ch <- prometheus.MustNewConstMetric(c.metrics.circuitSupplyTemp, prometheus.GaugeValue, *circuit.SupplyTemperature, labelsWithCircuit...)
+		}
+		if circuit.ValvePosition != nil {
+			ch <- prometheus.MustNewConstMetric(c.metrics.mixingValvePosition, prometheus.GaugeValue, *circuit.ValvePosition, labelsWithCircuit...)
+		}
+	}
+}
@@ -3,119 +3,675 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
 	"thermia_exporter/internal/api"
 	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/control"
 	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/meter"
+	"thermia_exporter/internal/relabel"
+	"thermia_exporter/internal/snapshot"
+	"thermia_exporter/internal/tracing"
 	"thermia_exporter/internal/types"
 )
 
 // ThermiaCollector implements prometheus.Collector for Thermia heat pumps.
-// Collection from the Thermia cloud API runs in a background loop (Run);
-// Prometheus scrapes are served from the cached result so slow upstream
-// responses never delay or time out a scrape.
+// Collection from the Thermia cloud API runs in a background loop (Run) that
+// writes per-installation snapshots to a snapshot.Store; Prometheus scrapes
+// are served from the store so slow upstream responses never delay or time
+// out a scrape.
 type ThermiaCollector struct {
 	authClient   *auth.AuthClient
 	creds        auth.Credentials
 	logger       *slog.Logger
 	metrics      *MetricSet
 	fetchTimeout time.Duration
+	clock        clock.Clock
+
+	// Requests an installation's fetch runs concurrently (1: sequential)
+	fetchConcurrency int
+
+	// Hedge GETs without a response after hedgeDelay, at most hedgeMax
+	// times per collection (0 delay: disabled)
+	hedgeDelay time.Duration
+	hedgeMax   int
+
+	// Rate limit and circuit breaker for every API client of the account
+	apiGuard *api.Guard
+
+	// Measured COP inputs
+	meter               meter.Source
+	heatOutputRegisters []string
+
+	// Temperature spike rejection (disabled unless configured)
+	spikes *spikeFilter
+	hold   *valueHold
+
+	// Indoor sensor calibration offsets per installation (0: default)
+	indoorOffsets map[int64]float64
+
+	// Compressor start counting and short-cycling heuristic
+	starts *startsTracker
+
+	// Auxiliary heater share of heat production time
+	auxShare *auxShareTracker
+
+	// Operating time counters that went backwards
+	resets *counterResets
+
+	// Account label value ("" for a single account)
+	account string
+
+	// Refrigerant for superheat and subcooling estimates ("": disabled)
+	refrigerant mapper.Refrigerant
+
+	// Which installations are due in a collection round
+	polls *pollPlan
+
+	// Event history window (0: everything the portal returns)
+	eventsSince time.Duration
+
+	// Hash heat pump names, keyed by redactSalt, and drop identifying details
+	anonymize  bool
+	redactSalt string
+
+	// Lowercase status, mode and priority label values
+	normalizeLabels bool
+
+	// Fetch operation mode schedules
+	schedules bool
+
+	// Export every numeric register as thermia_register_value
+	rawRegisters bool
+
+	// Series a scrape emits at most (0: unlimited), and whether the last
+	// scrape was over the cap
+	maxSeries    int
+	seriesCapped atomic.Bool
+
+	// Leave out the thermia_oper_time_*_hours gauges
+	noOperTimeGauges bool
+
+	// Operational statuses by relevance, for thermia_operational_status_running
+	statusPriority []types.OperationalStatus
+
+	// Drop and rename rules applied to every emitted metric
+	relabel []relabel.Rule
+
+	// Authentication and installation list warm-up before the first
+	// collection (0: disabled)
+	prewarmTimeout time.Duration
+
+	// Random delay up to startupJitter before the first collection
+	startupJitter time.Duration
+
+	// ready is set once the first collection attempt has finished
+	ready atomic.Bool
+
+	// Last successful collection (Unix nanoseconds, 0: none yet) and
+	// failed collections since, for health checks from other goroutines.
+	// Unlike failures this is not reset by a poller restart.
+	lastSuccessAt atomic.Int64
+	failedInRow   atomic.Int64
+
+	// Set while the account lists no installations, which fails health
+	// checks: unlike API errors, waiting does not fix it.
+	noInstallations atomic.Bool
+
+	// Set while the account lists several installations, which a meter
+	// that is not per installation cannot tell apart.
+	sharedMeter atomic.Bool
+
+	// Start of the last collection attempt and of the running one (Unix
+	// nanoseconds, 0: none), and whether the last requests to the identity
+	// provider and the API got a response, for health checks.
+	lastPollAt      atomic.Int64
+	collectingSince atomic.Int64
+	idp, apiProbe   probe
+
+	// No collection starts before backoffUntil, set when the API throttles
+	// the exporter. Only accessed from the collection loop.
+	backoffUntil time.Time
+
+	// Rebuild the auth client and token cache after this many consecutive
+	// failed collections (0: never). failures is only accessed from the
+	// collection loop.
+	restartAfter int
+	failures     int
+
+	// Duration of each fetch stage in the last collection, to skip stages
+	// that no longer fit before the deadline. Only accessed from the
+	// collection loop.
+	stageDurations map[string]time.Duration
+
+	onCollect func(ctx context.Context)
+	traceID   func(ctx context.Context) string
+	tracer    *tracing.Tracer
 
 	// Token cache to minimize login attempts
 	tokenCache     *auth.AuthResult
 	tokenCacheMu   sync.RWMutex
 	tokenExpiresAt time.Time
+	tokenCacheFile string
+
+	// The error of a password login the portal rejected the credentials
+	// of. No further password login is attempted until the credentials are
+	// replaced. Guarded by tokenCacheMu.
+	credentialsRejected error
+
+	// Login page version seen by the last password login, guarded by
+	// tokenCacheMu
+	loginPage *auth.LoginPage
+
+	// Register name aliases for localized or older firmwares, replaceable
+	// on reload
+	aliases   mapper.Aliases
+	aliasesMu sync.RWMutex
+
+	// Snapshots from the last successful collection per installation
+	store *snapshot.Store
+
+	// Model reports and control capabilities from the last collection per
+	// installation
+	reports      map[int64]ModelReport
+	capabilities map[int64]control.Capabilities
+	reportsMu    sync.RWMutex
+
+	// Last known model per installation, used to keep labels stable when
+	// the info fetch fails. Only accessed from the collection loop.
+	knownModels map[int64]string
+
+	// Last known serial per installation, for the same reason. Only
+	// accessed from the collection loop.
+	knownSerials map[int64]string
+
+	// Last unmapped register discovery per installation. Only accessed from
+	// the collection loop.
+	lastDiscovery map[int64]time.Time
+
+	// Register groups found per installation: fetched with registers, or
+	// found by the startup probe or the unmapped register discovery. Only
+	// accessed from the collection loop.
+	foundGroups map[int64]map[string]bool
+
+	// Installations listed by the last collection. Only accessed from the
+	// collection loop.
+	listed []int64
+}
 
-	// Cached metrics from the last successful background collection
-	cacheMu sync.RWMutex
-	cached  []prometheus.Metric
+// Installation identifies a collected installation and the labels its
+// metrics are exported under.
+type Installation struct {
+	ID      int64
+	Name    string
+	Model   string
+	Account string
+}
+
+// scrapeModeBackground is the thermia_scrape_mode label for collection in
+// the background loop with scrapes served from cache.
+const scrapeModeBackground = "background"
+
+// coreGroups are fetched together with the installation info and status
+// on every collection, however close to the deadline.
+var coreGroups = []string{
+	mapper.RegGroupTemperatures,
+}
+
+// statusGroups are fetched after the core data if the deadline allows.
+var statusGroups = []string{
+	mapper.RegGroupOperationalOperation,
+	mapper.RegGroupOperationalStatus,
+	mapper.RegGroupOperationalTime,
+	mapper.RegGroupHotWater,
+	mapper.RegGroupHeatingCurve,
+}
+
+// optionalGroups are fetched with the status groups, but only for
+// installations the startup probe or the unmapped register discovery found
+// them on, as most models do not expose them.
+var optionalGroups = []string{
+	mapper.RegGroupEnergy,
+}
+
+// registerGroups lists the register groups fetched for an installation.
+var registerGroups = append(append(append([]string{}, coreGroups...), statusGroups...), optionalGroups...)
+
+// Options holds optional collector settings. The zero value uses defaults.
+type Options struct {
+	// Clock is the time source for token expiry and timestamps (default: real time).
+	Clock clock.Clock
+
+	// Account names the Thermia account this collector polls. When set it
+	// is added as the account label of every heat pump metric, so several
+	// collectors can serve one registry (default: "", no label).
+	Account string
+
+	// Meter is an external electrical power source for measured COP
+	// (optional). Unless it is per installation, it is only used while the
+	// account has a single installation.
+	Meter meter.Source
+
+	// HeatOutputRegister overrides the heat output register candidates.
+	HeatOutputRegister string
+
+	// IndoorOffsets are added to the indoor temperature per installation ID
+	// and exported as a separate calibrated series. Key 0 applies to
+	// installations without their own entry (optional).
+	IndoorOffsets map[int64]float64
+
+	// ShortCycleStartsPerHour enables thermia_short_cycling_suspected when
+	// compressor starts per hour exceed it (default: 0, disabled).
+	ShortCycleStartsPerHour float64
+
+	// InstallationIntervals overrides the collection interval passed to Run
+	// per installation ID (optional).
+	InstallationIntervals map[int64]time.Duration
+
+	// During QuietHours (local time) installations are polled at most every
+	// QuietInterval (default: no quiet hours).
+	QuietHours    clock.DailyWindow
+	QuietInterval time.Duration
+
+	// AuxShareWindow is the rolling window thermia_aux_heat_share_ratio is
+	// computed over (default: 0, disabled).
+	AuxShareWindow time.Duration
+
+	// Refrigerant selects the saturation table superheat and subcooling
+	// are estimated with (default: "", no estimates).
+	Refrigerant mapper.Refrigerant
+
+	// SpikeMaxDelta rejects temperature readings that moved more than this
+	// many degrees since the previous collection (default: 0, disabled).
+	SpikeMaxDelta float64
+
+	// HoldTTL keeps emitting the last reading of a temperature sensor or
+	// register missing from a collection for up to this long, flagged by
+	// thermia_value_held and thermia_register_held (default: 0, disabled).
+	HoldTTL time.Duration
+
+	// EventsSince drops events older than this from the event history. The
+	// events API has no time filter, so this is applied client-side; active
+	// events are always kept (default: 0, no limit).
+	EventsSince time.Duration
+
+	// Anonymize exports a hash of the heat pump name instead of the name
+	// and leaves out the site, group and last-online time, for dashboards
+	// that are shared publicly (default: false).
+	Anonymize bool
+
+	// RedactSalt keys the anonymized heat pump name hashes; required with
+	// Anonymize.
+	RedactSalt string
+
+	// NormalizeLabels lowercases status, mode and priority label values and
+	// strips their STATUS_, POWER_ and OPERATION_MODE_ prefixes
+	// (default: false, values as reported by the API).
+	NormalizeLabels bool
+
+	// RestartAfterFailures resets the authentication HTTP client and drops
+	// the cached access token after this many consecutive failed collections
+	// (default: 0, never).
+	RestartAfterFailures int
+
+	// Schedules fetches the installation's operation mode schedule and
+	// exports the next scheduled mode change (default: false; costs two
+	// extra API requests per collection).
+	Schedules bool
+
+	// ExportRawRegisters exports every numeric register of the fetched
+	// groups as thermia_register_value, for registers no metric is mapped
+	// from yet (default: false; adds a series per register).
+	ExportRawRegisters bool
+
+	// MaxSeries caps the heat pump series a scrape emits; the excess is
+	// dropped, the same series on every scrape (default: 0, unlimited).
+	MaxSeries int
+
+	// NoOperTimeGauges leaves out the thermia_oper_time_*_hours gauges,
+	// which predate the thermia_oper_time_*_hours_total counters and are
+	// kept for existing dashboards (default: false, both are exported).
+	NoOperTimeGauges bool
+
+	// StatusPriority orders operational statuses by relevance, highest
+	// first, to pick the one thermia_operational_status_running reports
+	// when several run at once (default: mapper.DefaultStatusPriority).
+	StatusPriority []types.OperationalStatus
+
+	// RegisterAliases rename registers reported under unknown names onto
+	// canonical ones before any metric is derived (optional).
+	RegisterAliases mapper.Aliases
+
+	// MetricRules drop and rename metrics and label values before they are
+	// stored. With rules set the collector is unchecked, since renamed
+	// metrics no longer match the described descriptors (optional).
+	MetricRules []relabel.Rule
+
+	// PrewarmTimeout bounds an authentication and installation list fetch
+	// before the first collection, so token and connection setup do not
+	// count against the first collection's deadline (default: 0, disabled).
+	PrewarmTimeout time.Duration
+
+	// FetchConcurrency is the number of API requests (register groups,
+	// info, status, events) an installation's fetch runs concurrently
+	// (default: 0, sequential).
+	FetchConcurrency int
+
+	// HedgeDelay sends a second copy of an API GET request that has not
+	// answered within this delay and uses whichever answers first, at most
+	// HedgeMax times per collection (default: 0, disabled).
+	HedgeDelay time.Duration
+	HedgeMax   int
+
+	// APIRateLimit bounds the account's API requests per minute (default:
+	// 0, unlimited). BreakerFailures consecutive 5xx, 429 or
+	// unanswered requests stop all requests for BreakerCooldown,
+	// and collections pause meanwhile (default: 0, disabled).
+	APIRateLimit    int
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	// StartupJitter delays the first collection by a random duration up to
+	// this long, and PollJitter every later poll, so exporters deployed
+	// together do not poll the API in lockstep (default: 0, disabled).
+	StartupJitter time.Duration
+	PollJitter    time.Duration
+
+	// TokenCacheFile persists the access and refresh token, so a restart
+	// reuses a still valid token instead of logging in again (default: "",
+	// tokens are kept in memory only).
+	TokenCacheFile string
+
+	// Store receives collected snapshots so other readers can share them
+	// (default: a private store).
+	Store *snapshot.Store
+
+	// OnCollect is called after every successful collection, e.g. to
+	// publish the new snapshots to push sinks (optional).
+	OnCollect func(ctx context.Context)
+
+	// TraceID returns the trace ID of the span in ctx, or "" if there is
+	// none. When set, collection duration and error metrics carry the trace
+	// ID as an exemplar (optional; default: the Tracer's).
+	TraceID func(ctx context.Context) string
+
+	// Tracer traces every collection and installation (optional).
+	Tracer *tracing.Tracer
 }
 
 // NewThermiaCollector creates a new Thermia collector.
-func NewThermiaCollector(authClient *auth.AuthClient, creds auth.Credentials, fetchTimeout time.Duration, logger *slog.Logger) *ThermiaCollector {
-	return &ThermiaCollector{
+func NewThermiaCollector(authClient *auth.AuthClient, creds auth.Credentials, fetchTimeout time.Duration, logger *slog.Logger, opts Options) *ThermiaCollector {
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	traceID := opts.TraceID
+	if traceID == nil && opts.Tracer != nil {
+		traceID = tracing.TraceID
+	}
+	store := opts.Store
+	if store == nil {
+		store = snapshot.NewStore()
+	}
+
+	metrics := newMetricSet(opts.Account)
+	c := &ThermiaCollector{
 		authClient:   authClient,
 		creds:        creds,
 		logger:       logger,
-		metrics:      newMetricSet(),
+		metrics:      metrics,
 		fetchTimeout: fetchTimeout,
+		clock:        clk,
+		store:        store,
+		knownModels:  make(map[int64]string),
+		knownSerials: make(map[int64]string),
+		reports:      make(map[int64]ModelReport),
+		capabilities: make(map[int64]control.Capabilities),
+
+		lastDiscovery:  make(map[int64]time.Time),
+		foundGroups:    make(map[int64]map[string]bool),
+		stageDurations: make(map[string]time.Duration),
+
+		meter:               opts.Meter,
+		heatOutputRegisters: mapper.HeatOutputCandidates,
+		statusPriority:      mapper.DefaultStatusPriority,
+		spikes:              newSpikeFilter(opts.SpikeMaxDelta, metrics.rejectedSamples),
+		hold:                newValueHold(opts.HoldTTL),
+		onCollect:           opts.OnCollect,
+		indoorOffsets:       opts.IndoorOffsets,
+		starts:              newStartsTracker(opts.ShortCycleStartsPerHour),
+		auxShare:            newAuxShareTracker(opts.AuxShareWindow),
+		resets:              newCounterResets(metrics.counterResets),
+		account:             opts.Account,
+		refrigerant:         opts.Refrigerant,
+		polls:               newPollPlan(0, opts.InstallationIntervals),
+		eventsSince:         opts.EventsSince,
+		anonymize:           opts.Anonymize,
+		redactSalt:          opts.RedactSalt,
+		normalizeLabels:     opts.NormalizeLabels,
+		restartAfter:        opts.RestartAfterFailures,
+		schedules:           opts.Schedules,
+		rawRegisters:        opts.ExportRawRegisters,
+		maxSeries:           opts.MaxSeries,
+		noOperTimeGauges:    opts.NoOperTimeGauges,
+		relabel:             opts.MetricRules,
+		aliases:             opts.RegisterAliases,
+		prewarmTimeout:      opts.PrewarmTimeout,
+		startupJitter:       opts.StartupJitter,
+		fetchConcurrency:    opts.FetchConcurrency,
+		hedgeDelay:          opts.HedgeDelay,
+		hedgeMax:            opts.HedgeMax,
+		apiGuard:            api.NewGuard(opts.APIRateLimit, opts.BreakerFailures, opts.BreakerCooldown, clk),
+		tokenCacheFile:      tokenCachePath(opts.TokenCacheFile, opts.Account),
+		traceID:             traceID,
+		tracer:              opts.Tracer,
+	}
+
+	c.polls.quiet = opts.QuietHours
+	c.polls.quietInterval = opts.QuietInterval
+	c.polls.jitter = opts.PollJitter
+
+	if opts.HeatOutputRegister != "" {
+		c.heatOutputRegisters = []string{opts.HeatOutputRegister}
+	}
+	if opts.StatusPriority != nil {
+		c.statusPriority = opts.StatusPriority
 	}
+
+	// Seed the cache with a pre-provisioned refresh token so the first
+	// collection uses the refresh grant instead of a password login.
+	if creds.RefreshToken != "" {
+		c.tokenCache = &auth.AuthResult{RefreshToken: creds.RefreshToken}
+	}
+
+	return c
 }
 
-// Run starts the background collection loop. It collects once immediately,
-// then every interval until ctx is cancelled.
+// Run starts the background collection loop. It collects every installation
+// once immediately, then each one every interval (or its own interval, see
+// Options.InstallationIntervals) until ctx is cancelled.
 func (c *ThermiaCollector) Run(ctx context.Context, interval time.Duration) {
 	c.logger.Info("Starting background collection loop", "interval", interval)
+	c.metrics.pollInterval.Set(interval.Seconds())
+	c.metrics.scrapeMode.WithLabelValues(scrapeModeBackground).Set(1)
+	if delay := c.polls.randomDelay(c.startupJitter); delay > 0 {
+		c.logger.Info("Delaying first collection", "delay", delay.Round(time.Second))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	if c.tokenCacheFile != "" {
+		restoreCtx, cancel := context.WithTimeout(ctx, c.fetchTimeout)
+		c.restoreToken(restoreCtx)
+		cancel()
+	}
+	if c.prewarmTimeout > 0 {
+		c.prewarm(ctx)
+	}
+	c.polls.interval = interval
 	c.refresh(ctx)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	c.ready.Store(true)
 
 	for {
+		now := c.clock.Now()
+		c.recordPollPolicy(now)
+		wait := c.polls.wait(now)
+		if throttled := c.backoffUntil.Sub(now); throttled > wait {
+			c.logger.Info("Delaying collection, backing off", "resume_in", throttled.Round(time.Second))
+			wait = throttled
+		}
+
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			c.logger.Info("Background collection loop stopped")
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			c.refresh(ctx)
 		}
 	}
 }
 
-// refresh performs one collection from the Thermia API and replaces the
-// cache on success. On failure the previous cache is kept and served.
-func (c *ThermiaCollector) refresh(ctx context.Context) {
+// CollectOnce collects every installation once into the store, outside the
+// background loop, for one-shot commands.
+func (c *ThermiaCollector) CollectOnce(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, c.fetchTimeout)
 	defer cancel()
+	if c.tokenCacheFile != "" {
+		c.restoreToken(ctx)
+	}
+	_, err := c.collect(ctx)
+	return err
+}
 
-	start := time.Now()
-	collected, err := c.fetch(ctx)
-	duration := time.Since(start)
-	c.metrics.scrapeDuration.Observe(duration.Seconds())
+// prewarm authenticates and lists the installations once, bounded by the
+// pre-warm timeout. Failures are logged; the first collection retries.
+func (c *ThermiaCollector) prewarm(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, c.prewarmTimeout)
+	defer cancel()
 
+	start := c.clock.Now()
+	apiClient, err := c.newAPIClient(ctx)
+	if err == nil {
+		_, err = apiClient.GetInstallations(ctx)
+	}
 	if err != nil {
-		c.metrics.scrapeErrors.Inc()
-		c.logger.Error("Collection failed, serving previous cached metrics",
-			"error", err, "duration", duration.Round(time.Millisecond))
+		c.logger.Warn("Pre-warm failed, continuing with the first collection", "error", err)
 		return
 	}
+	c.logger.Info("Pre-warm complete", "duration", c.clock.Now().Sub(start).Round(time.Millisecond))
+}
 
-	c.cacheMu.Lock()
-	c.cached = collected
-	c.cacheMu.Unlock()
-	c.metrics.lastSuccess.SetToCurrentTime()
+// Ready reports whether the first collection attempt has finished, so
+// /metrics serves data (or the first failure has been logged).
+func (c *ThermiaCollector) Ready() bool {
+	return c.ready.Load()
+}
 
-	c.logger.Debug("Collection complete",
-		"metrics", len(collected), "duration", duration.Round(time.Millisecond))
+// Collected reports whether a collection has succeeded or stored snapshots
+// since the exporter started, so the installations it returns are complete.
+func (c *ThermiaCollector) Collected() bool {
+	return c.lastSuccessAt.Load() != 0 || c.store.Version() > 0
 }
 
-// fetch runs a full collection and returns the gathered metrics as a slice.
-func (c *ThermiaCollector) fetch(ctx context.Context) ([]prometheus.Metric, error) {
-	ch := make(chan prometheus.Metric, 64)
-	var collected []prometheus.Metric
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		for m := range ch {
-			collected = append(collected, m)
+// refresh performs one collection from the Thermia API and stores the
+// results. On failure the previous snapshots are kept and served.
+func (c *ThermiaCollector) refresh(ctx context.Context) {
+	fetchCtx, cancel := context.WithTimeout(ctx, c.fetchTimeout)
+	defer cancel()
+
+	var span *tracing.Span
+	if c.tracer != nil {
+		fetchCtx, span = c.tracer.Start(fetchCtx, "collect")
+		if c.account != "" {
+			span.SetAttribute("account", c.account)
 		}
-	}()
+	}
 
-	err := c.collect(ctx, ch)
-	close(ch)
-	<-done
+	start := c.clock.Now()
+	c.lastPollAt.Store(start.UnixNano())
+	c.collectingSince.Store(start.UnixNano())
+	n, err := c.collect(fetchCtx)
+	c.collectingSince.Store(0)
+	if span != nil {
+		span.SetAttribute("installations", strconv.Itoa(n))
+		span.End(err)
+	}
+	c.metrics.breakerState.Set(float64(c.apiGuard.State()))
+	duration := c.clock.Now().Sub(start)
+	c.observeCollection(fetchCtx, duration, err)
 
 	if err != nil {
-		return nil, err
+		c.logger.Error("Collection failed, serving previous snapshots",
+			"error", err, "duration", duration.Round(time.Millisecond))
+		// Retry on the regular schedule, and never sooner than
+		// retryBackoff, so a failing login cannot spin
+		now := c.clock.Now()
+		c.polls.postpone(now)
+		if until := now.Add(retryBackoff); until.After(c.backoffUntil) {
+			c.backoffUntil = until
+		}
+		c.failedInRow.Add(1)
+		c.failed()
+		return
+	}
+	c.failures = 0
+	c.failedInRow.Store(0)
+	c.lastSuccessAt.Store(c.clock.Now().UnixNano())
+	c.metrics.consecutiveFailures.Set(0)
+
+	c.metrics.lastSuccess.Set(float64(c.clock.Now().Unix()))
+
+	c.logger.Debug("Collection complete",
+		"installations", n, "version", c.store.Version(), "duration", duration.Round(time.Millisecond))
+
+	if c.onCollect != nil {
+		c.onCollect(ctx)
 	}
-	return collected, nil
+}
+
+// failed counts a failed collection and restarts the poller once
+// restartAfter collections in a row have failed.
+func (c *ThermiaCollector) failed() {
+	c.failures++
+	c.metrics.consecutiveFailures.Set(float64(c.failures))
+	if c.restartAfter <= 0 || c.failures < c.restartAfter {
+		return
+	}
+
+	c.logger.Warn("Collections keep failing, restarting poller", "consecutive_failures", c.failures)
+	c.restartPoller()
+	c.failures = 0
+	c.metrics.pollerRestarts.Inc()
+}
+
+// restartPoller resets the authentication client's connections and
+// cookies and drops the cached access token. The refresh token is kept, so
+// the restart costs no password login. The client itself is never
+// replaced: HTTP handlers read it without holding tokenCacheMu.
+func (c *ThermiaCollector) restartPoller() {
+	c.authClient.Reset()
+
+	c.tokenCacheMu.Lock()
+	defer c.tokenCacheMu.Unlock()
+	c.tokenExpiresAt = time.Time{}
 }
 
 // getOrRefreshToken returns a cached token if valid, or authenticates to get a new one.
@@ -123,9 +679,9 @@ func (c *ThermiaCollector) fetch(ctx context.Context) ([]prometheus.Metric, erro
 func (c *ThermiaCollector) getOrRefreshToken(ctx context.Context) (*auth.AuthResult, error) {
 	// Try to use cached token first
 	c.tokenCacheMu.RLock()
-	if c.tokenCache != nil && time.Now().Before(c.tokenExpiresAt) {
+	if c.tokenValid() {
 		c.logger.Debug("Using cached authentication token",
-			"expires_in", time.Until(c.tokenExpiresAt).Round(time.Second))
+			"expires_in", c.tokenExpiresIn())
 		token := c.tokenCache
 		c.tokenCacheMu.RUnlock()
 		return token, nil
@@ -137,7 +693,7 @@ func (c *ThermiaCollector) getOrRefreshToken(ctx context.Context) (*auth.AuthRes
 	defer c.tokenCacheMu.Unlock()
 
 	// Double-check after acquiring write lock (another goroutine might have refreshed)
-	if c.tokenCache != nil && time.Now().Before(c.tokenExpiresAt) {
+	if c.tokenValid() {
 		c.logger.Debug("Using cached token (acquired after lock)")
 		return c.tokenCache, nil
 	}
@@ -145,6 +701,11 @@ func (c *ThermiaCollector) getOrRefreshToken(ctx context.Context) (*auth.AuthRes
 	// Try the lightweight refresh-token grant before a full password login
 	if c.tokenCache != nil && c.tokenCache.RefreshToken != "" {
 		authResult, err := c.authClient.Refresh(ctx, c.tokenCache.RefreshToken)
+		c.countRenewal(grantRefreshToken, err)
+		c.idp.record(err)
+		if c.clientRejected(err) {
+			return nil, err
+		}
 		if err == nil {
 			// Keep the old refresh token if the server didn't rotate it
 			if authResult.RefreshToken == "" {
@@ -152,26 +713,94 @@ func (c *ThermiaCollector) getOrRefreshToken(ctx context.Context) (*auth.AuthRes
 			}
 			c.cacheToken(authResult)
 			c.logger.Info("Token refreshed", "expires_in",
-				time.Until(c.tokenExpiresAt).Round(time.Second))
+				c.tokenExpiresIn())
 			return authResult, nil
 		}
+		if c.creds.Password == "" {
+			return nil, fmt.Errorf("refresh token rejected and no password configured (run 'thermia-exporter login' to obtain a new one): %w", err)
+		}
 		c.logger.Warn("Token refresh failed, falling back to full login", "error", err)
 	}
 
 	// Perform full authentication
+	if c.credentialsRejected != nil {
+		return nil, fmt.Errorf("not logging in again until the credentials are changed: %w", c.credentialsRejected)
+	}
 	c.logger.Info("Authenticating to Thermia API", "reason", "no valid token or refresh failed")
 	authResult, err := c.authClient.Authenticate(ctx, c.creds)
+	c.countRenewal(grantPassword, err)
+	c.idp.record(err)
+	c.recordLoginPage()
+	c.clientRejected(err)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		c.credentialsRejected = err
+		c.logger.Error("Thermia rejected the username or password; no further logins are attempted until they are corrected and the configuration reloaded (SIGHUP)",
+			"username", c.creds.Username, "error", err)
+	}
 	if err != nil {
 		return nil, err
 	}
 	c.cacheToken(authResult)
 
 	c.logger.Info("Authentication successful, token cached",
-		"expires_in", time.Until(c.tokenExpiresAt).Round(time.Second))
+		"expires_in", c.tokenExpiresIn())
 
 	return authResult, nil
 }
 
+// Token renewal grants, as exported in thermia_token_renewals_total.
+const (
+	grantRefreshToken = "refresh_token"
+	grantPassword     = "password"
+)
+
+// countRenewal records a token renewal attempt with grant.
+func (c *ThermiaCollector) countRenewal(grant string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	c.metrics.tokenRenewals.WithLabelValues(grant, result).Inc()
+}
+
+// clientRejected reports whether err is the token endpoint rejecting the
+// exporter's client ID or scopes, which no retry or other grant can fix, and
+// records it in thermia_auth_client_rejected. A successful renewal clears it.
+func (c *ThermiaCollector) clientRejected(err error) bool {
+	if err == nil {
+		c.metrics.clientRejected.Reset()
+		return false
+	}
+	code := auth.RejectionCode(err)
+	if code == "" {
+		return false
+	}
+	c.metrics.clientRejected.Reset()
+	c.metrics.clientRejected.WithLabelValues(code).Set(1)
+	c.logger.Error("Thermia rejected the exporter's OAuth client settings; upgrade thermia-exporter, or override THERMIA_PORTAL_CLIENT_ID and THERMIA_PORTAL_POLICY if Thermia changed them",
+		"error_code", code, "client_id", c.authClient.Portal().ClientID, "error", err)
+	return true
+}
+
+// recordLoginPage exports the version of the login page the last password
+// login saw and warns when it changed: the login scrapes that page, and
+// Thermia or Microsoft changing it has preceded every past login breakage.
+// Caller must hold tokenCacheMu.
+func (c *ThermiaCollector) recordLoginPage() {
+	page, ok := c.authClient.LoginPage()
+	if !ok {
+		return
+	}
+	if prev := c.loginPage; prev != nil && *prev != page {
+		c.logger.Warn("Thermia login page changed; if logins start failing, check for an exporter update",
+			"policy", page.Policy, "template", page.Template, "contract", page.Contract,
+			"previous_policy", prev.Policy, "previous_template", prev.Template, "previous_contract", prev.Contract)
+	}
+	c.loginPage = &page
+	c.metrics.loginPageInfo.Reset()
+	c.metrics.loginPageInfo.WithLabelValues(page.Policy, page.Template, page.Contract).Set(1)
+}
+
 // cacheToken stores the auth result and computes its expiry with a safety
 // margin. Caller must hold tokenCacheMu.
 func (c *ThermiaCollector) cacheToken(authResult *auth.AuthResult) {
@@ -181,13 +810,119 @@ func (c *ThermiaCollector) cacheToken(authResult *auth.AuthResult) {
 	if expiresIn > 5*time.Minute {
 		expiresIn -= 5 * time.Minute
 	}
-	c.tokenExpiresAt = time.Now().Add(expiresIn)
+	c.tokenExpiresAt = c.clock.Now().Add(expiresIn)
+	c.observeClaims(authResult.AccessToken)
+	c.persistToken()
+}
+
+// observeClaims exports the identity claims of an access token as
+// thermia_auth_info. The subject is hashed like heat pump names so the
+// metric can be shared; tokens that are not JWTs are reported as opaque.
+func (c *ThermiaCollector) observeClaims(accessToken string) {
+	claims, err := auth.DecodeClaims(accessToken)
+	if err != nil {
+		claims = auth.Claims{Type: "opaque"}
+	}
+	subject := ""
+	if claims.Subject != "" {
+		subject = subjectHash(claims.Subject)
+	}
+	c.metrics.authInfo.Reset()
+	c.metrics.authInfo.WithLabelValues(subject, claims.Tenant, claims.Type).Set(1)
+}
+
+// tokenValid reports whether the cached access token can still be used.
+// Caller must hold tokenCacheMu (read or write).
+func (c *ThermiaCollector) tokenValid() bool {
+	return c.tokenCache != nil && c.clock.Now().Before(c.tokenExpiresAt)
+}
+
+// tokenExpiresIn returns the remaining lifetime of the cached token.
+// Caller must hold tokenCacheMu (read or write).
+func (c *ThermiaCollector) tokenExpiresIn() time.Duration {
+	return c.tokenExpiresAt.Sub(c.clock.Now()).Round(time.Second)
 }
 
-// Describe implements prometheus.Collector.
+// Installations returns the installations seen by the last collection.
+func (c *ThermiaCollector) Installations() []Installation {
+	snaps := c.store.All()
+	installations := make([]Installation, 0, len(snaps))
+	for _, snap := range snaps {
+		installations = append(installations, Installation{
+			ID:      snap.InstallationID,
+			Name:    snap.Summary.HeatpumpName,
+			Model:   snap.Summary.HeatpumpModel,
+			Account: c.account,
+		})
+	}
+	return installations
+}
+
+// Account returns the account label value ("" for a single account).
+func (c *ThermiaCollector) Account() string {
+	return c.account
+}
+
+// Store returns the snapshot store the collector writes to.
+func (c *ThermiaCollector) Store() *snapshot.Store {
+	return c.store
+}
+
+// SetCredentials replaces the credentials used for future logins, e.g. after
+// a configuration reload. A new refresh token replaces the cached token; a
+// new account drops the cached token.
+func (c *ThermiaCollector) SetCredentials(creds auth.Credentials) {
+	c.tokenCacheMu.Lock()
+	defer c.tokenCacheMu.Unlock()
+
+	old := c.creds
+	c.creds = creds
+	if creds != old {
+		c.credentialsRejected = nil
+	}
+	switch {
+	case creds.RefreshToken != "" && creds.RefreshToken != old.RefreshToken:
+		c.tokenCache = &auth.AuthResult{RefreshToken: creds.RefreshToken}
+		c.tokenExpiresAt = time.Time{}
+	case creds.Username != old.Username:
+		c.tokenCache = nil
+		c.tokenExpiresAt = time.Time{}
+	}
+}
+
+// SetAliases replaces the register name aliases from the next collection on,
+// e.g. after a configuration reload.
+func (c *ThermiaCollector) SetAliases(aliases mapper.Aliases) {
+	c.aliasesMu.Lock()
+	defer c.aliasesMu.Unlock()
+	c.aliases = aliases
+}
+
+// registerAliases returns the current register name aliases.
+func (c *ThermiaCollector) registerAliases() mapper.Aliases {
+	c.aliasesMu.RLock()
+	defer c.aliasesMu.RUnlock()
+	return c.aliases
+}
+
+// invalidateToken drops the cached access token so the next collection
+// re-authenticates. The refresh token is kept for the lightweight grant.
+func (c *ThermiaCollector) invalidateToken() {
+	c.tokenCacheMu.Lock()
+	defer c.tokenCacheMu.Unlock()
+	c.tokenExpiresAt = time.Time{}
+}
+
+// Describe implements prometheus.Collector. With metric rules it describes
+// nothing, making the collector unchecked.
 func (c *ThermiaCollector) Describe(ch chan<- *prometheus.Desc) {
+	if len(c.relabel) > 0 {
+		return
+	}
+
 	// Temperature metrics
 	ch <- c.metrics.indoorTemp
+	ch <- c.metrics.indoorTempCalibrated
 	ch <- c.metrics.outdoorTemp
 	ch <- c.metrics.supplyLineTemp
 	ch <- c.metrics.desiredSupplyTemp
@@ -201,14 +936,19 @@ func (c *ThermiaCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.metrics.coolingSupplyTemp
 
 	// Status metrics
+	ch <- c.metrics.installationInfo
 	ch <- c.metrics.online
 	ch <- c.metrics.lastOnlineUnix
+	ch <- c.metrics.cloudDataLag
+	ch <- c.metrics.valueHeld
+	ch <- c.metrics.registerHeld
 
 	// Mode/status metrics
 	ch <- c.metrics.operationMode
 	ch <- c.metrics.operationModeAvail
 	ch <- c.metrics.operationalStatus
 	ch <- c.metrics.operationalStatusAvail
+	ch <- c.metrics.operationalStatusActive
 	ch <- c.metrics.powerStatus
 	ch <- c.metrics.powerStatusAvail
 
@@ -223,150 +963,770 @@ func (c *ThermiaCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.metrics.operTimeImm1
 	ch <- c.metrics.operTimeImm2
 	ch <- c.metrics.operTimeImm3
+	ch <- c.metrics.operTimeCompressorTotal
+	ch <- c.metrics.operTimeHeatingTotal
+	ch <- c.metrics.operTimeHotWaterTotal
+	ch <- c.metrics.operTimeImm1Total
+	ch <- c.metrics.operTimeImm2Total
+	ch <- c.metrics.operTimeImm3Total
 
 	// Alert metrics
 	ch <- c.metrics.activeAlerts
 	ch <- c.metrics.archivedAlerts
 
-	// Scrape metrics
-	c.metrics.scrapeErrors.Describe(ch)
-	c.metrics.scrapeDuration.Describe(ch)
-	c.metrics.lastSuccess.Describe(ch)
+	// Mixing valve circuit metrics
+	ch <- c.metrics.circuitSupplyTemp
+	ch <- c.metrics.mixingValvePosition
+
+	// Efficiency metrics
+	ch <- c.metrics.heatOutput
+	ch <- c.metrics.powerConsumption
+	ch <- c.metrics.energyConsumed
+	ch <- c.metrics.meterPower
+	ch <- c.metrics.measuredCOP
+
+	// Compressor metrics
+	ch <- c.metrics.compressorStarts
+	ch <- c.metrics.shortCycling
+	ch <- c.metrics.auxHeatShare
+
+	// Refrigerant circuit estimates
+	ch <- c.metrics.superheat
+	ch <- c.metrics.dischargeSuperheat
+	ch <- c.metrics.subcooling
+
+	// Heating curve metrics
+	ch <- c.metrics.heatingCurve
+	ch <- c.metrics.heatingCurveMin
+	ch <- c.metrics.heatingCurveMax
+	ch <- c.metrics.heatingCurveOffset
+	ch <- c.metrics.roomFactor
+	ch <- c.metrics.systemPressure
+	ch <- c.metrics.registerValue
+
+	// Schedule metrics
+	ch <- c.metrics.nextOperationMode
+	ch <- c.metrics.scheduleConflict
+
+	// Priority metrics
+	ch <- c.metrics.prioritySetting
+	ch <- c.metrics.priorityCurrent
 }
 
 // Collect implements prometheus.Collector.
-// It serves the cached metrics from the background collection loop and never
-// performs network calls, so scrapes complete instantly.
+// It serves the stored snapshots from the background collection loop and
+// never performs network calls, so scrapes complete instantly. Exporter
+// self-metrics are served separately by Internal.
 func (c *ThermiaCollector) Collect(ch chan<- prometheus.Metric) {
-	c.cacheMu.RLock()
-	cached := c.cached
-	c.cacheMu.RUnlock()
-
-	for _, m := range cached {
+	var metrics []prometheus.Metric
+	for _, snap := range c.store.All() {
+		metrics = append(metrics, snap.Metrics...)
+	}
+	metrics = c.capSeries(metrics)
+	c.metrics.exportedSeries.Set(float64(len(metrics)))
+	for _, m := range metrics {
 		ch <- m
 	}
-
-	c.metrics.scrapeErrors.Collect(ch)
-	c.metrics.scrapeDuration.Collect(ch)
-	c.metrics.lastSuccess.Collect(ch)
 }
 
-// collect performs one full collection from the Thermia API, emitting metrics
-// on ch. It returns an error if nothing useful could be collected.
-func (c *ThermiaCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) error {
-	// Get or refresh authentication token
-	authResult, err := c.getOrRefreshToken(ctx)
-	if err != nil {
-		return fmt.Errorf("authentication: %w", err)
-	}
+// ErrNoInstallations is returned by collections of an account that lists no
+// installations.
+var ErrNoInstallations = errors.New("no installations found")
 
-	// Create API client
-	apiClient, err := api.NewAPIClient(ctx, authResult.AccessToken, c.logger)
+// ErrNothingCollected is returned by collections in which no due
+// installation returned any data, so every snapshot is stale.
+var ErrNothingCollected = errors.New("no installation returned data")
+
+// collect performs one full collection from the Thermia API and stores a
+// snapshot per installation. It returns the number of installations
+// collected, or an error if nothing useful could be collected.
+func (c *ThermiaCollector) collect(ctx context.Context) (int, error) {
+	apiClient, err := c.newAPIClient(ctx)
 	if err != nil {
-		return fmt.Errorf("create API client: %w", err)
+		c.backoffMaintenance(err)
+		c.backoffCircuit()
+		return 0, err
 	}
 
 	// Get installations
 	installations, err := apiClient.GetInstallations(ctx)
+	c.apiProbe.record(err)
 	if err != nil {
-		return fmt.Errorf("get installations: %w", err)
+		c.backoff(apiClient.ThrottledUntil())
+		c.backoffMaintenance(err)
+		c.backoffCircuit()
+		return 0, fmt.Errorf("get installations: %w", err)
 	}
 
+	c.noInstallations.Store(len(installations) == 0)
+	c.sharedMeter.Store(len(installations) > 1)
 	if len(installations) == 0 {
-		return fmt.Errorf("no installations found")
+		c.logger.Error("The account has no installations; check that the heat pump is registered to this account in Thermia Online")
+		return 0, ErrNoInstallations
+	}
+
+	ids := make([]int64, 0, len(installations))
+	for _, inst := range installations {
+		ids = append(ids, inst.ID)
+	}
+
+	due := c.polls.due(c.clock.Now(), installations)
+	stored := 0
+	for _, inst := range due {
+		if c.collectInstallation(ctx, apiClient, inst) {
+			stored++
+		}
 	}
+	c.store.Retain(ids)
+	c.forgetInstallations(ids)
+	c.backoff(apiClient.ThrottledUntil())
+	c.backoffCircuit()
 
-	// Collect metrics for the first installation (as per requirements)
-	return c.collectInstallation(ctx, ch, apiClient, installations[0])
+	if len(due) > 0 && stored == 0 {
+		return 0, fmt.Errorf("%w: %d installations due", ErrNothingCollected, len(due))
+	}
+	return stored, nil
 }
 
-// collectInstallation collects all metrics for a single installation.
-func (c *ThermiaCollector) collectInstallation(ctx context.Context, ch chan<- prometheus.Metric, apiClient *api.APIClient, inst types.Installation) error {
-	// Fetch installation info
-	info, err := apiClient.GetInstallationInfo(ctx, inst.ID)
-	if err != nil {
-		return fmt.Errorf("get installation info (id %d): %w", inst.ID, err)
+// forgetInstallations deletes the per-installation gauges and state of
+// installations listed by the previous collection but not in ids, so a
+// removed heat pump does not leave stale series behind.
+func (c *ThermiaCollector) forgetInstallations(ids []int64) {
+	for _, id := range c.listed {
+		if slices.Contains(ids, id) {
+			continue
+		}
+		match := prometheus.Labels{mapper.LabelHeatpumpID: strconv.FormatInt(id, 10)}
+		c.metrics.sectionSuccess.DeletePartialMatch(match)
+		c.metrics.groupItems.DeletePartialMatch(match)
+		c.metrics.unmappedRegisters.DeletePartialMatch(match)
+		c.metrics.groupSupported.DeletePartialMatch(match)
+		c.metrics.writableRegister.DeletePartialMatch(match)
+		delete(c.foundGroups, id)
+		delete(c.lastDiscovery, id)
+		c.hold.forget(id)
+	}
+	c.listed = ids
+}
+
+// backoff delays the next collections until the time the API asked the
+// exporter to wait for, if that is in the future.
+func (c *ThermiaCollector) backoff(until time.Time) {
+	if until.After(c.clock.Now()) && until.After(c.backoffUntil) {
+		c.backoffUntil = until
+		c.logger.Warn("Throttled by API, backing off", "until", until)
 	}
+}
 
-	// Fetch installation status
-	status, err := apiClient.GetInstallationStatus(ctx, inst.ID)
-	if err != nil {
-		return fmt.Errorf("get installation status (id %d): %w", inst.ID, err)
+// retryBackoff is the least time between a failed collection and the next
+// attempt.
+const retryBackoff = 30 * time.Second
+
+// maintenanceBackoff is how long collections pause when the API reports
+// being unavailable or in maintenance.
+const maintenanceBackoff = 5 * time.Minute
+
+// backoffMaintenance delays the next collections by maintenanceBackoff if
+// err means the API is unavailable or in maintenance.
+func (c *ThermiaCollector) backoffMaintenance(err error) {
+	if !errors.Is(err, api.ErrMaintenance) {
+		return
 	}
+	c.logger.Warn("Thermia API unavailable or in maintenance, pausing collections", "pause", maintenanceBackoff)
+	c.backoff(c.clock.Now().Add(maintenanceBackoff))
+}
 
-	// Fetch register groups (with error logging, but continue with partial data)
-	grpOperation, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupOperationalOperation)
-	if err != nil {
-		c.logger.Warn("Failed to get operation registers", "id", inst.ID, "error", err)
+// backoffCircuit delays the next collections while the circuit breaker is
+// open; the stored snapshots keep being served meanwhile.
+func (c *ThermiaCollector) backoffCircuit() {
+	if until, open := c.apiGuard.OpenUntil(); open {
+		c.backoff(until)
 	}
+}
 
-	grpStatus, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupOperationalStatus)
-	if err != nil {
-		c.logger.Warn("Failed to get status registers", "id", inst.ID, "error", err)
+// newAPIClient authenticates (reusing the cached token if possible) and
+// creates an API client.
+func (c *ThermiaCollector) newAPIClient(ctx context.Context) (*api.APIClient, error) {
+	if until, open := c.apiGuard.OpenUntil(); open {
+		return nil, fmt.Errorf("%w until %s", api.ErrCircuitOpen, until.Format(time.RFC3339))
 	}
 
-	grpTemps, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupTemperatures)
+	// Get or refresh authentication token
+	authResult, err := c.getOrRefreshToken(ctx)
 	if err != nil {
-		c.logger.Warn("Failed to get temperature registers", "id", inst.ID, "error", err)
+		return nil, fmt.Errorf("authentication: %w", err)
 	}
 
-	grpTime, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupOperationalTime)
+	// Create API client
+	apiClient, err := api.NewAPIClient(ctx, c.authClient.Portal().ConfigURL, authResult.AccessToken, c.logger, c.clock, c.apiGuard)
+	c.apiProbe.record(err)
 	if err != nil {
-		c.logger.Warn("Failed to get operational time registers", "id", inst.ID, "error", err)
+		if errors.Is(err, api.ErrTokenNotAccepted) {
+			// Force a fresh login on the next collection
+			c.invalidateToken()
+		}
+		return nil, fmt.Errorf("create API client: %w", err)
 	}
+	apiClient.SetHedging(c.hedgeDelay, c.hedgeMax)
+	return apiClient, nil
+}
 
-	grpHot, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupHotWater)
-	if err != nil {
-		c.logger.Warn("Failed to get hot water registers", "id", inst.ID, "error", err)
+// installationData holds the raw API responses for one installation.
+// Every sub-fetch is independent: a nil or missing field means that call
+// failed and only the metrics derived from it are skipped.
+type installationData struct {
+	inst         types.Installation
+	info         *types.InstallationInfo
+	status       *types.InstallationStatus
+	groups       map[string][]types.GroupItem
+	activeEvents []types.Event
+	allEvents    []types.Event
+	eventsOK     bool
+	meterWatts   *float64
+
+	// items are the fetched registers merged across groups, one per
+	// register name (see mapper.MergeGroups)
+	items []types.GroupItem
+
+	// Compressor starts (from a register or derived) and the short-cycling
+	// flag (nil when the heuristic is disabled)
+	compressorStarts *float64
+	startsSource     string
+	shortCycling     *bool
+
+	// Auxiliary heater share of heat production time over the configured
+	// window (nil until a full window is available)
+	auxShare *float64
+
+	// Device serial number or MAC address ("" if unknown)
+	serial string
+
+	// Operation mode schedules (nil when not fetched or not available)
+	schedules []types.CalendarSchedule
+
+	// Legionella, EVU block and hot water block schedules, keyed by
+	// calendar function (nil unless the installation has all three)
+	blockSchedules map[string][]types.CalendarSchedule
+
+	// temps are the temperature readings left after spike rejection
+	temps map[string]float64
+
+	// held are the sensors in temps whose last reading was held
+	held []string
+
+	// heldRegisters are the registers in groups whose last reading was held
+	heldRegisters []heldRegister
+}
+
+// empty reports whether neither the info, the status nor any register
+// group could be fetched, which leaves nothing to derive metrics from.
+func (d *installationData) empty() bool {
+	return d.info == nil && d.status == nil && len(d.groups) == 0
+}
+
+// temperatures returns the temperature readings keyed by metric name.
+func (d *installationData) temperatures() map[string]float64 {
+	return mapper.TemperaturesToMap(mapper.ExtractTemperatures(d.status, d.groups[mapper.RegGroupTemperatures]))
+}
+
+// collectInstallation collects all metrics for a single installation and
+// stores them as a snapshot. Only the installation ID and name (from the
+// installation list) and one of the info, status or register groups are
+// required; everything else contributes whatever it can. It reports
+// whether a snapshot was stored: if nothing could be fetched the previous
+// snapshot is kept.
+func (c *ThermiaCollector) collectInstallation(ctx context.Context, apiClient *api.APIClient, inst types.Installation) bool {
+	if c.tracer != nil {
+		var span *tracing.Span
+		ctx, span = c.tracer.Start(ctx, "collect_installation")
+		span.SetAttribute(mapper.LabelHeatpumpID, strconv.FormatInt(inst.ID, 10))
+		defer span.End(nil)
+	}
+	d := c.fetchInstallation(ctx, apiClient, inst)
+	if d.empty() {
+		c.logger.Warn("No data returned for installation, keeping the previous snapshot", "id", inst.ID)
+		return false
+	}
+	c.discoverUnmapped(ctx, apiClient, d)
+	return c.storeInstallation(d)
+}
+
+// storeInstallation derives metrics and the summary from fetched data and
+// stores them as the installation's snapshot. It reports whether the
+// snapshot was stored.
+func (c *ThermiaCollector) storeInstallation(d *installationData) bool {
+	c.recordSections(d)
+	c.redact(d)
+	c.registerAliases().RenameGroups(d.groups)
+	labels := c.installationLabels(d)
+	d.serial = c.serial(d)
+
+	// Spikes are rejected before missing readings are held, so a rejected
+	// spike is never held and the spike filter only sees fresh readings
+	d.temps = d.temperatures()
+	if rejected := c.spikes.filter(d.inst.ID, d.temps); len(rejected) > 0 {
+		c.logger.Warn("Rejected temperature spikes", "id", d.inst.ID, "sensors", rejected)
+	}
+	if d.held = c.hold.fill(c.clock.Now(), d.inst.ID, d.temps); len(d.held) > 0 {
+		c.logger.Info("Holding last temperature readings", "id", d.inst.ID, "sensors", d.held)
+	}
+	if d.heldRegisters = c.hold.fillRegisters(c.clock.Now(), d.inst.ID, d.groups); len(d.heldRegisters) > 0 {
+		c.logger.Info("Holding last register readings", "id", d.inst.ID, "registers", len(d.heldRegisters))
+	}
+
+	items, conflicts := mapper.MergeGroups(d.groups, mapper.ConflictTolerance)
+	d.items = items
+	for _, conflict := range conflicts {
+		c.metrics.registerConflicts.WithLabelValues(conflict.RegisterName).Inc()
+		c.logger.Debug("Register values disagree across groups", "id", d.inst.ID,
+			"register", conflict.RegisterName, "group", conflict.Group, "value", conflict.Value,
+			"other_group", conflict.OtherGroup, "other_value", conflict.OtherValue)
+	}
+
+	c.calibrate(d)
+	c.starts.observe(c.clock.Now(), d)
+	c.auxShare.observe(c.clock.Now(), d)
+	opTime := mapper.ExtractOperationalTime(d.groups[mapper.RegGroupOperationalTime])
+	if reset := c.resets.observe(d.inst.ID, opTime); len(reset) > 0 {
+		c.logger.Warn("Operating time counters went backwards, increase() over them is wrong across this point",
+			"id", d.inst.ID, "registers", reset)
+	}
+
+	var metrics []prometheus.Metric
+	ch := make(chan prometheus.Metric, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+	}()
+	c.emitInstallation(ch, labels, d)
+	close(ch)
+	<-done
+
+	relabeled, err := relabel.Apply(c.relabel, metrics)
+	switch {
+	case err == nil:
+		metrics = relabeled
+	case relabel.Redacts(c.relabel):
+		// Never export labels the redaction was meant to hide
+		c.logger.Error("Metric rules failed, keeping the previous snapshot to not export unredacted labels", "id", d.inst.ID, "error", err)
+		return false
+	default:
+		c.logger.Error("Metric rules failed, storing metrics unchanged", "id", d.inst.ID, "error", err)
+	}
+
+	c.store.Put(d.inst.ID, c.clock.Now(), buildSummary(d, labels), metrics)
+	c.setModelReport(buildModelReport(d, labels, metrics, c.clock.Now()))
+	c.setCapabilities(buildCapabilities(d, labels))
+	return true
+}
+
+// fetchInstallation fetches all data for an installation, logging (but
+// otherwise tolerating) individual failures.
+func (c *ThermiaCollector) fetchInstallation(ctx context.Context, apiClient *api.APIClient, inst types.Installation) *installationData {
+	d := &installationData{
+		inst:   inst,
+		groups: make(map[string][]types.GroupItem),
+	}
+
+	// Highest-value data first; later stages are skipped when the previous
+	// collection suggests they would not finish before the deadline.
+	c.parallel(
+		func() {
+			info, err := apiClient.GetInstallationInfo(ctx, inst.ID)
+			if err != nil {
+				c.logger.Warn("Failed to get installation info", "id", inst.ID, "error", err)
+			} else {
+				d.info = info
+			}
+		},
+		func() {
+			status, err := apiClient.GetInstallationStatus(ctx, inst.ID)
+			if err != nil {
+				c.logger.Warn("Failed to get installation status", "id", inst.ID, "error", err)
+			} else {
+				d.status = status
+			}
+		},
+		func() {
+			c.fetchGroups(ctx, apiClient, d, coreGroups)
+		},
+	)
+	c.runStage(ctx, stageStatuses, func() {
+		c.fetchGroups(ctx, apiClient, d, c.statusGroups(inst.ID))
+	})
+	c.runStage(ctx, stageEvents, func() {
+		c.fetchEvents(ctx, apiClient, d)
+	})
+	if c.schedules {
+		c.runStage(ctx, stageSchedules, func() {
+			c.fetchSchedules(ctx, apiClient, d)
+		})
+	}
+
+	c.readMeter(ctx, d)
+
+	return d
+}
+
+// statusGroups returns statusGroups and the optionalGroups found on
+// installation id.
+func (c *ThermiaCollector) statusGroups(id int64) []string {
+	groups := statusGroups
+	for _, group := range optionalGroups {
+		if c.foundGroups[id][group] {
+			groups = append(slices.Clip(groups), group)
+		}
+	}
+	return groups
+}
+
+// setGroupFound records whether group returned registers for installation
+// id.
+func (c *ThermiaCollector) setGroupFound(id int64, group string, found bool) {
+	if c.foundGroups[id] == nil {
+		c.foundGroups[id] = make(map[string]bool)
+	}
+	c.foundGroups[id][group] = found
+}
+
+// fetchGroups fetches the given register groups into d.groups,
+// concurrently up to the fetch concurrency.
+func (c *ThermiaCollector) fetchGroups(ctx context.Context, apiClient *api.APIClient, d *installationData, groups []string) {
+	var mu sync.Mutex
+	fetches := make([]func(), len(groups))
+	for i, group := range groups {
+		fetches[i] = func() {
+			items, err := apiClient.GetRegisterGroup(ctx, d.inst.ID, group)
+			if errors.Is(err, api.ErrMalformedResponse) {
+				c.metrics.parseFailures.WithLabelValues(fmt.Sprint(d.inst.ID), group).Inc()
+			}
+			if err != nil {
+				c.logger.Warn("Failed to get register group", "id", d.inst.ID, "group", group, "error", err)
+				return
+			}
+			c.recordGroupShape(d.inst.ID, group, items)
+			mu.Lock()
+			d.groups[group] = items
+			mu.Unlock()
+		}
+	}
+	c.parallel(fetches...)
+}
+
+// recordGroupShape exports the item count of a fetched register group,
+// counts its items without a register name and flags hand-mapped registers
+// reporting an unexpected unit, so a firmware update that changes the
+// payload shows up before series go missing or jump tenfold.
+func (c *ThermiaCollector) recordGroupShape(id int64, group string, items []types.GroupItem) {
+	idLabel := fmt.Sprint(id)
+	c.metrics.groupItems.WithLabelValues(idLabel, group).Set(float64(len(items)))
+	unnamed := 0
+	for _, item := range items {
+		if item.RegisterName == "" {
+			unnamed++
+		}
+	}
+	if unnamed > 0 {
+		c.metrics.parseFailures.WithLabelValues(idLabel, group).Add(float64(unnamed))
+	}
+	for _, item := range mapper.UnitMismatches(items) {
+		c.metrics.unitMismatches.WithLabelValues(idLabel, item.RegisterName).Inc()
+		c.logger.Debug("Register reports unexpected unit", "id", id, "register", item.RegisterName, "unit", item.Unit)
+	}
+}
+
+// fetchEvents fetches the active events and the event history into d.
+func (c *ThermiaCollector) fetchEvents(ctx context.Context, apiClient *api.APIClient, d *installationData) {
+	var (
+		activeEvents, allEvents []types.Event
+		err, err2               error
+	)
+	c.parallel(
+		func() {
+			activeEvents, err = apiClient.GetEvents(ctx, d.inst.ID, true)
+			if err != nil {
+				c.logger.Warn("Failed to get active events", "id", d.inst.ID, "error", err)
+			}
+		},
+		func() {
+			allEvents, err2 = apiClient.GetEvents(ctx, d.inst.ID, false)
+			if err2 != nil {
+				c.logger.Warn("Failed to get all events", "id", d.inst.ID, "error", err2)
+			}
+		},
+	)
+
+	if c.eventsSince > 0 {
+		allEvents = mapper.EventsSince(allEvents, c.clock.Now().Add(-c.eventsSince))
 	}
 
-	// Fetch events/alerts
-	activeEvents, err := apiClient.GetEvents(ctx, inst.ID, true)
+	d.activeEvents = activeEvents
+	d.allEvents = allEvents
+	d.eventsOK = err == nil && err2 == nil
+}
+
+// fetchSchedules fetches the operation mode schedules and the schedules
+// checked for conflicts into d.
+func (c *ThermiaCollector) fetchSchedules(ctx context.Context, apiClient *api.APIClient, d *installationData) {
+	functions, err := apiClient.GetCalendarFunctions(ctx, d.inst.ID)
 	if err != nil {
-		c.logger.Warn("Failed to get active events", "id", inst.ID, "error", err)
+		c.logger.Warn("Failed to get calendar functions", "id", d.inst.ID, "error", err)
+		return
 	}
+	c.fetchBlockSchedules(ctx, apiClient, d, functions)
 
-	allEvents, err := apiClient.GetEvents(ctx, inst.ID, false)
+	function, ok := mapper.FindOperationModeFunction(functions)
+	if !ok {
+		c.logger.Debug("No operation mode schedule available", "id", d.inst.ID)
+		return
+	}
+
+	schedules, err := apiClient.GetCalendarSchedules(ctx, d.inst.ID, function.FunctionID)
 	if err != nil {
-		c.logger.Warn("Failed to get all events", "id", inst.ID, "error", err)
+		c.logger.Warn("Failed to get operation mode schedules", "id", d.inst.ID, "error", err)
+		return
 	}
+	d.schedules = schedules
+}
 
-	// Build base labels
-	model := mapper.Safe(info.Model, info.Profile.Name)
+// fetchBlockSchedules fetches the legionella, EVU block and hot water block
+// schedules into d. Nothing is fetched unless the installation's calendar
+// has all three functions, as a conflict needs all of them.
+func (c *ThermiaCollector) fetchBlockSchedules(ctx context.Context, apiClient *api.APIClient, d *installationData, functions []types.CalendarFunction) {
+	names := []string{mapper.LegionellaFunction, mapper.EVUBlockFunction, mapper.HotWaterBlockFunction}
+	ids := make([]int64, len(names))
+	for i, name := range names {
+		f, ok := mapper.FindCalendarFunction(functions, name)
+		if !ok {
+			return
+		}
+		ids[i] = f.FunctionID
+	}
+
+	results := make([][]types.CalendarSchedule, len(names))
+	errs := make([]error, len(names))
+	fetches := make([]func(), len(names))
+	for i := range names {
+		fetches[i] = func() {
+			results[i], errs[i] = apiClient.GetCalendarSchedules(ctx, d.inst.ID, ids[i])
+		}
+	}
+	c.parallel(fetches...)
+
+	schedules := make(map[string][]types.CalendarSchedule, len(names))
+	for i, name := range names {
+		if errs[i] != nil {
+			c.logger.Warn("Failed to get calendar schedules", "id", d.inst.ID, "function", name, "error", errs[i])
+			return
+		}
+		schedules[name] = results[i]
+	}
+	d.blockSchedules = schedules
+}
+
+// installationLabels returns the id, name and model labels an
+// installation's metrics are exported under.
+func (c *ThermiaCollector) installationLabels(d *installationData) []string {
 	labels := []string{
-		fmt.Sprint(inst.ID),
-		mapper.Safe(info.Name, inst.Name),
-		model,
+		fmt.Sprint(d.inst.ID),
+		d.inst.Name,
+		c.modelLabel(d),
+	}
+	if d.info != nil {
+		labels[1] = mapper.Safe(d.info.Name, d.inst.Name)
+	}
+	if c.anonymize {
+		labels[1] = AnonymizedName(labels[1], c.redactSalt)
+	}
+	return labels
+}
+
+// emitInstallation emits all metrics that can be derived from d.
+func (c *ThermiaCollector) emitInstallation(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	ch <- prometheus.MustNewConstMetric(c.metrics.installationInfo, prometheus.GaugeValue, 1,
+		append(labels, d.inst.Site, d.inst.Group, d.serial)...)
+	c.emitTemperatureMetrics(ch, labels, d.temps)
+	c.emitHeldMetrics(ch, labels, d)
+	if d.info != nil {
+		c.emitStatusMetrics(ch, labels, d.info)
+	}
+	c.emitCloudLagMetrics(ch, labels, d)
+	c.emitModeMetrics(ch, labels, d.groups[mapper.RegGroupOperationalOperation])
+	c.emitOperationalStatusMetrics(ch, labels, d.groups[mapper.RegGroupOperationalStatus])
+	c.emitPowerStatusMetrics(ch, labels, d.groups[mapper.RegGroupOperationalStatus])
+	c.emitHotWaterMetrics(ch, labels, d.groups[mapper.RegGroupHotWater])
+	c.emitOperationalTimeMetrics(ch, labels, d.groups[mapper.RegGroupOperationalTime])
+	c.emitHeatingCurveMetrics(ch, labels, d.items)
+	c.emitSystemPressureMetrics(ch, labels, d.items)
+	c.emitCircuitMetrics(ch, labels, d.items)
+	c.emitCOPMetrics(ch, labels, d)
+	c.emitCompressorMetrics(ch, labels, d)
+	c.emitAuxShareMetrics(ch, labels, d)
+	c.emitRefrigerantMetrics(ch, labels, d.items)
+	c.emitScheduleMetrics(ch, labels, d)
+	c.emitPriorityMetrics(ch, labels, d.items)
+	if c.rawRegisters {
+		c.emitRegisterValueMetrics(ch, labels, d.groups)
+	}
+	if d.eventsOK {
+		c.emitAlertMetrics(ch, labels, d.activeEvents, d.allEvents)
+	}
+}
+
+// emitCloudLagMetrics emits how old the data returned by the cloud was at
+// collection time. The cloud sometimes serves hours-old values for a pump
+// that works fine, which otherwise looks like flatlined sensors.
+func (c *ThermiaCollector) emitCloudLagMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	newest := mapper.LatestRegisterTime(d.items)
+	if newest == 0 && d.info != nil {
+		newest = mapper.ParseTimeToUnix(d.info.LastOnline)
 	}
+	if newest == 0 {
+		return
+	}
+	lag := max(c.clock.Now().Sub(time.Unix(newest, 0)).Seconds(), 0)
+	ch <- prometheus.MustNewConstMetric(c.metrics.cloudDataLag, prometheus.GaugeValue, lag, labels...)
+}
 
-	// Extract and emit metrics
-	c.emitTemperatureMetrics(ch, labels, status, grpTemps)
-	c.emitStatusMetrics(ch, labels, info)
-	c.emitModeMetrics(ch, labels, grpOperation)
-	c.emitOperationalStatusMetrics(ch, labels, grpStatus)
-	c.emitPowerStatusMetrics(ch, labels, grpStatus)
-	c.emitHotWaterMetrics(ch, labels, grpHot)
-	c.emitOperationalTimeMetrics(ch, labels, grpTime)
-	c.emitAlertMetrics(ch, labels, activeEvents, allEvents)
+// emitHeatingCurveMetrics emits the heating curve settings, which explain
+// changes of the desired supply line temperature.
+func (c *ThermiaCollector) emitHeatingCurveMetrics(ch chan<- prometheus.Metric, labels []string, items []types.GroupItem) {
+	curve := mapper.ExtractHeatingCurve(items)
+	for _, m := range []struct {
+		desc  *prometheus.Desc
+		value *float64
+	}{
+		{c.metrics.heatingCurve, curve.Curve},
+		{c.metrics.heatingCurveMin, curve.Min},
+		{c.metrics.heatingCurveMax, curve.Max},
+		{c.metrics.heatingCurveOffset, curve.Offset},
+		{c.metrics.roomFactor, curve.RoomFactor},
+	} {
+		if m.value != nil {
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, *m.value, labels...)
+		}
+	}
+}
 
-	return nil
+// emitSystemPressureMetrics emits the hydronic system pressure if a
+// pressure sensor is installed. A falling pressure indicates a leak.
+func (c *ThermiaCollector) emitSystemPressureMetrics(ch chan<- prometheus.Metric, labels []string, items []types.GroupItem) {
+	if bar := mapper.ExtractSystemPressure(items); bar != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.systemPressure, prometheus.GaugeValue, *bar, labels...)
+	}
 }
 
-// emitTemperatureMetrics emits all temperature metrics.
-func (c *ThermiaCollector) emitTemperatureMetrics(ch chan<- prometheus.Metric, labels []string, status *types.InstallationStatus, grpTemps []types.GroupItem) {
-	temps := mapper.ExtractTemperatures(status, grpTemps)
+// emitRegisterValueMetrics emits every numeric register converted to the
+// base unit of its reported unit, one series per register and group. A
+// register repeated within a group is exported once.
+func (c *ThermiaCollector) emitRegisterValueMetrics(ch chan<- prometheus.Metric, labels []string, groups map[string][]types.GroupItem) {
+	for group, items := range groups {
+		seen := make(map[string]bool, len(items))
+		for _, it := range items {
+			if it.RegisterName == "" || it.RegisterValue == nil || seen[it.RegisterName] {
+				continue
+			}
+			seen[it.RegisterName] = true
+			unit, scale := mapper.RegisterUnit(it.Unit)
+			ch <- prometheus.MustNewConstMetric(c.metrics.registerValue, prometheus.GaugeValue, *it.RegisterValue*scale,
+				append(labels, it.RegisterName, group, unit)...)
+		}
+	}
+}
 
-	// Also get outdoor temp from registers
-	if outdoor := mapper.FindValue(grpTemps, mapper.RegOutdoorTemperature); outdoor == nil {
-		temps.Outdoor = mapper.FindValue(grpTemps, mapper.RegOperDataOutdoorTempMaSa)
-	} else {
-		temps.Outdoor = outdoor
+// emitRefrigerantMetrics emits superheat and subcooling estimates, labelled
+// estimated="true" because the pump does not report them itself.
+func (c *ThermiaCollector) emitRefrigerantMetrics(ch chan<- prometheus.Metric, labels []string, items []types.GroupItem) {
+	est := mapper.EstimateRefrigerant(items, c.refrigerant)
+	if est.Superheat != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.superheat, prometheus.GaugeValue, *est.Superheat, labels...)
+	}
+	if est.DischargeSuperheat != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.dischargeSuperheat, prometheus.GaugeValue, *est.DischargeSuperheat, labels...)
+	}
+	if est.Subcooling != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.subcooling, prometheus.GaugeValue, *est.Subcooling, labels...)
 	}
+}
 
-	tempMap := mapper.TemperaturesToMap(temps)
+// emitScheduleMetrics emits the next scheduled operation mode change and
+// conflicting block schedules.
+func (c *ThermiaCollector) emitScheduleMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	now := c.clock.Now()
+	if d.blockSchedules != nil {
+		conflicts := mapper.ScheduleConflicts(d.blockSchedules[mapper.LegionellaFunction],
+			d.blockSchedules[mapper.EVUBlockFunction], d.blockSchedules[mapper.HotWaterBlockFunction], now)
+		for _, conflict := range conflicts {
+			ch <- prometheus.MustNewConstMetric(c.metrics.scheduleConflict, prometheus.GaugeValue, 1,
+				append(labels, fmt.Sprint(conflict.Legionella), fmt.Sprint(conflict.EVUBlock), fmt.Sprint(conflict.HotWaterBlock))...)
+		}
+	}
 
+	mode, at, ok := mapper.NextScheduledMode(d.schedules, d.groups[mapper.RegGroupOperationalOperation], now)
+	if !ok {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.metrics.nextOperationMode, prometheus.GaugeValue, float64(at.Unix()),
+		append(labels, c.labelValue(mode.String()))...)
+}
+
+// emitPriorityMetrics emits the configured and current hot water/heating
+// priority.
+func (c *ThermiaCollector) emitPriorityMetrics(ch chan<- prometheus.Metric, labels []string, items []types.GroupItem) {
+	if priority, ok := mapper.ExtractEnumValue(items, mapper.PrioritySettingCandidates); ok {
+		ch <- prometheus.MustNewConstMetric(c.metrics.prioritySetting, prometheus.GaugeValue, 1, append(labels, c.labelValue(priority))...)
+	}
+	if priority, ok := mapper.ExtractEnumValue(items, mapper.PriorityCurrentCandidates); ok {
+		ch <- prometheus.MustNewConstMetric(c.metrics.priorityCurrent, prometheus.GaugeValue, 1, append(labels, c.labelValue(priority))...)
+	}
+}
+
+// labelValue returns a status, mode or priority name as label value,
+// normalized if configured.
+func (c *ThermiaCollector) labelValue(s string) string {
+	if c.normalizeLabels {
+		return mapper.NormalizeLabelValue(s)
+	}
+	return s
+}
+
+// modelLabel returns the model label for an installation. When the info
+// fetch fails the last known model is reused so series keep their identity.
+func (c *ThermiaCollector) modelLabel(d *installationData) string {
+	if d.info != nil {
+		model := mapper.Safe(d.info.Model, d.info.Profile.Name)
+		c.knownModels[d.inst.ID] = model
+		return model
+	}
+	if model, ok := c.knownModels[d.inst.ID]; ok {
+		return model
+	}
+	return "unknown"
+}
+
+// serial returns the device serial number (or MAC address) of an
+// installation, reusing the last known one when the info fetch fails so the
+// info series keeps its identity. It is empty when anonymizing.
+func (c *ThermiaCollector) serial(d *installationData) string {
+	if c.anonymize {
+		return ""
+	}
+	if d.info != nil {
+		serial := mapper.Safe(d.info.SerialNumber, strings.TrimSpace(d.info.MacAddress))
+		c.knownSerials[d.inst.ID] = serial
+		return serial
+	}
+	return c.knownSerials[d.inst.ID]
+}
+
+// emitTemperatureMetrics emits all temperature metrics.
+func (c *ThermiaCollector) emitTemperatureMetrics(ch chan<- prometheus.Metric, labels []string, tempMap map[string]float64) {
 	tempDescs := map[string]*prometheus.Desc{
 		"indoor":              c.metrics.indoorTemp,
+		"indoor_calibrated":   c.metrics.indoorTempCalibrated,
 		"outdoor":             c.metrics.outdoorTemp,
 		"supply_line":         c.metrics.supplyLineTemp,
 		"desired_supply_line": c.metrics.desiredSupplyTemp,
@@ -387,6 +1747,28 @@ func (c *ThermiaCollector) emitTemperatureMetrics(ch chan<- prometheus.Metric, l
 	}
 }
 
+// emitHeldMetrics flags which temperature readings and registers are held.
+// Nothing is emitted unless holding is enabled.
+func (c *ThermiaCollector) emitHeldMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	if !c.hold.enabled() {
+		return
+	}
+	held := make(map[string]bool, len(d.held))
+	for _, sensor := range d.held {
+		held[sensor] = true
+	}
+	for sensor := range d.temps {
+		value := 0.0
+		if held[sensor] || (sensor == calibratedIndoorKey && held["indoor"]) {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.metrics.valueHeld, prometheus.GaugeValue, value, append(labels, sensor)...)
+	}
+	for _, r := range d.heldRegisters {
+		ch <- prometheus.MustNewConstMetric(c.metrics.registerHeld, prometheus.GaugeValue, 1, append(labels, r.register, r.group)...)
+	}
+}
+
 // emitStatusMetrics emits online status metrics.
 func (c *ThermiaCollector) emitStatusMetrics(ch chan<- prometheus.Metric, labels []string, info *types.InstallationInfo) {
 	onlineValue := 0.0
@@ -402,17 +1784,17 @@ func (c *ThermiaCollector) emitStatusMetrics(ch chan<- prometheus.Metric, labels
 
 // emitModeMetrics emits operation mode metrics.
 func (c *ThermiaCollector) emitModeMetrics(ch chan<- prometheus.Metric, labels []string, grpOperation []types.GroupItem) {
-	modeData := mapper.ExtractOperationMode(grpOperation)
+	modeData := mapper.ExtractOperationMode(grpOperation, mapper.OperationModeCandidates)
 
 	// Available modes
 	for _, mode := range modeData.Available {
-		labelsWithMode := append(labels, mode)
+		labelsWithMode := append(labels, c.labelValue(mode.String()))
 		ch <- prometheus.MustNewConstMetric(c.metrics.operationModeAvail, prometheus.GaugeValue, 1, labelsWithMode...)
 	}
 
 	// Current mode
 	if modeData.Current != "" {
-		labelsWithMode := append(labels, modeData.Current)
+		labelsWithMode := append(labels, c.labelValue(modeData.Current.String()))
 		ch <- prometheus.MustNewConstMetric(c.metrics.operationMode, prometheus.GaugeValue, 1, labelsWithMode...)
 	}
 }
@@ -423,24 +1805,33 @@ func (c *ThermiaCollector) emitOperationalStatusMetrics(ch chan<- prometheus.Met
 
 	// Available statuses
 	for _, status := range statusData.Available {
-		labelsWithStatus := append(labels, status)
+		labelsWithStatus := append(labels, c.labelValue(status.String()))
 		ch <- prometheus.MustNewConstMetric(c.metrics.operationalStatusAvail, prometheus.GaugeValue, 1, labelsWithStatus...)
 	}
 
 	// Running statuses (one-hot encoding - pick primary status)
-	runningSet := make(map[string]bool)
-	for _, s := range statusData.Running {
-		runningSet[strings.ToUpper(s)] = true
+	current := pickCurrentStatus(statusData.Running, statusData.Available, c.statusPriority)
+	for _, status := range statusData.Available {
+		value := 0.0
+		if status.Is(current) {
+			value = 1.0
+		}
+		labelsWithStatus := append(labels, c.labelValue(status.String()))
+		ch <- prometheus.MustNewConstMetric(c.metrics.operationalStatus, prometheus.GaugeValue, value, labelsWithStatus...)
 	}
 
-	current := pickCurrentStatus(statusData.Running, statusData.Available)
+	// Every running status as reported, without picking
+	runningSet := make(map[types.OperationalStatus]bool)
+	for _, s := range statusData.Running {
+		runningSet[s] = true
+	}
 	for _, status := range statusData.Available {
 		value := 0.0
-		if strings.EqualFold(status, current) {
+		if runningSet[status] {
 			value = 1.0
 		}
-		labelsWithStatus := append(labels, status)
-		ch <- prometheus.MustNewConstMetric(c.metrics.operationalStatus, prometheus.GaugeValue, value, labelsWithStatus...)
+		labelsWithStatus := append(labels, c.labelValue(status.String()))
+		ch <- prometheus.MustNewConstMetric(c.metrics.operationalStatusActive, prometheus.GaugeValue, value, labelsWithStatus...)
 	}
 }
 
@@ -450,12 +1841,12 @@ func (c *ThermiaCollector) emitPowerStatusMetrics(ch chan<- prometheus.Metric, l
 
 	// Available power statuses
 	for _, status := range powerData.Available {
-		labelsWithStatus := append(labels, status)
+		labelsWithStatus := append(labels, c.labelValue(status.String()))
 		ch <- prometheus.MustNewConstMetric(c.metrics.powerStatusAvail, prometheus.GaugeValue, 1, labelsWithStatus...)
 	}
 
 	// Running power statuses (can be multiple)
-	runningSet := make(map[string]bool)
+	runningSet := make(map[types.OperationalStatus]bool)
 	for _, s := range powerData.Running {
 		runningSet[s] = true
 	}
@@ -465,7 +1856,7 @@ func (c *ThermiaCollector) emitPowerStatusMetrics(ch chan<- prometheus.Metric, l
 		if runningSet[status] {
 			value = 1.0
 		}
-		labelsWithStatus := append(labels, status)
+		labelsWithStatus := append(labels, c.labelValue(status.String()))
 		ch <- prometheus.MustNewConstMetric(c.metrics.powerStatus, prometheus.GaugeValue, value, labelsWithStatus...)
 	}
 }
@@ -483,11 +1874,20 @@ func (c *ThermiaCollector) emitHotWaterMetrics(ch chan<- prometheus.Metric, labe
 	}
 }
 
-// emitOperationalTimeMetrics emits operational time counter metrics.
+// emitOperationalTimeMetrics emits operational time counter metrics, and
+// the same values as gauges unless those are disabled.
 func (c *ThermiaCollector) emitOperationalTimeMetrics(ch chan<- prometheus.Metric, labels []string, grpTime []types.GroupItem) {
 	opTime := mapper.ExtractOperationalTime(grpTime)
 
-	timeDescs := map[string]*prometheus.Desc{
+	counterDescs := map[string]*prometheus.Desc{
+		mapper.RegOperTimeCompressor: c.metrics.operTimeCompressorTotal,
+		mapper.RegOperTimeHeating:    c.metrics.operTimeHeatingTotal,
+		mapper.RegOperTimeHotWater:   c.metrics.operTimeHotWaterTotal,
+		mapper.RegOperTimeImm1:       c.metrics.operTimeImm1Total,
+		mapper.RegOperTimeImm2:       c.metrics.operTimeImm2Total,
+		mapper.RegOperTimeImm3:       c.metrics.operTimeImm3Total,
+	}
+	gaugeDescs := map[string]*prometheus.Desc{
 		mapper.RegOperTimeCompressor: c.metrics.operTimeCompressor,
 		mapper.RegOperTimeHeating:    c.metrics.operTimeHeating,
 		mapper.RegOperTimeHotWater:   c.metrics.operTimeHotWater,
@@ -497,7 +1897,10 @@ func (c *ThermiaCollector) emitOperationalTimeMetrics(ch chan<- prometheus.Metri
 	}
 
 	for regName, hours := range opTime {
-		if desc, ok := timeDescs[regName]; ok {
+		if desc, ok := counterDescs[regName]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(hours), labels...)
+		}
+		if desc, ok := gaugeDescs[regName]; ok && !c.noOperTimeGauges {
 			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(hours), labels...)
 		}
 	}
@@ -511,17 +1914,18 @@ func (c *ThermiaCollector) emitAlertMetrics(ch chan<- prometheus.Metric, labels
 	ch <- prometheus.MustNewConstMetric(c.metrics.archivedAlerts, prometheus.GaugeValue, float64(len(archived)), labels...)
 }
 
-// pickCurrentStatus chooses the most relevant operational status from running statuses.
-// This matches the logic from the original implementation.
-func pickCurrentStatus(running, available []string) string {
+// pickCurrentStatus chooses the most relevant operational status from running
+// statuses, the first of priority that runs. This matches the logic from the
+// original implementation.
+func pickCurrentStatus(running, available, priority []types.OperationalStatus) types.OperationalStatus {
 	if len(running) == 0 && len(available) == 0 {
 		return ""
 	}
 
 	// Filter out NO_DEMAND if other statuses are present
-	filtered := make([]string, 0, len(running))
+	filtered := make([]types.OperationalStatus, 0, len(running))
 	for _, s := range running {
-		if !strings.EqualFold(s, "STATUS_NO_DEMAND") {
+		if !s.Is(types.StatusNoDemand) {
 			filtered = append(filtered, s)
 		}
 	}
@@ -529,27 +1933,11 @@ func pickCurrentStatus(running, available []string) string {
 		filtered = running
 	}
 
-	// Priority order (highest first)
-	priority := []string{
-		"STATUS_LEGIONELLA",
-		"STATUS_HOTWATER",
-		"STATUS_HEAT",
-		"STATUS_COOL",
-		"STATUS_PASSIVE_COOL",
-		"STATUS_POOL",
-		"STATUS_STANDBY",
-		"STATUS_NO_DEMAND",
-		"OPERATION_MODE_OFF",
-	}
-
-	have := make(map[string]struct{})
-	for _, s := range filtered {
-		have[strings.ToUpper(s)] = struct{}{}
-	}
-
 	for _, p := range priority {
-		if _, ok := have[p]; ok {
-			return p
+		for _, s := range filtered {
+			if s.Is(p) {
+				return p
+			}
 		}
 	}
 
@@ -0,0 +1,532 @@
+package collector
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/relabel"
+	"thermia_exporter/internal/types"
+)
+
+func newTestCollector(clk clock.Clock) *ThermiaCollector {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewThermiaCollector(auth.NewAuthClient(logger), auth.Credentials{}, time.Minute, logger, Options{Clock: clk})
+}
+
+func TestTokenCache_SafetyMargin(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", ExpiresIn: 3600})
+
+	// Valid until 5 minutes before the real expiry
+	clk.Advance(55*time.Minute - time.Second)
+	if !c.tokenValid() {
+		t.Error("token should be valid 1s before the safety margin")
+	}
+	if got := c.tokenExpiresIn(); got != time.Second {
+		t.Errorf("tokenExpiresIn() = %v, want 1s", got)
+	}
+
+	clk.Advance(time.Second)
+	if c.tokenValid() {
+		t.Error("token should be invalid once the safety margin is reached")
+	}
+}
+
+func TestTokenCache_ShortLivedToken(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+
+	// Tokens shorter than the safety margin are used for their full lifetime
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", ExpiresIn: 120})
+
+	clk.Advance(119 * time.Second)
+	if !c.tokenValid() {
+		t.Error("short-lived token should be valid before expiry")
+	}
+
+	clk.Advance(time.Second)
+	if c.tokenValid() {
+		t.Error("short-lived token should be invalid at expiry")
+	}
+}
+
+func TestTokenCache_Invalidate(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", RefreshToken: "r", ExpiresIn: 3600})
+	c.invalidateToken()
+
+	if c.tokenValid() {
+		t.Error("token should be invalid after invalidateToken")
+	}
+	if c.tokenCache == nil || c.tokenCache.RefreshToken != "r" {
+		t.Error("refresh token should survive invalidateToken")
+	}
+}
+
+func TestSetCredentials(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.SetCredentials(auth.Credentials{Username: "a@example.com", Password: "old"})
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", RefreshToken: "r", ExpiresIn: 3600})
+
+	// A rotated password keeps the session of the same account
+	c.SetCredentials(auth.Credentials{Username: "a@example.com", Password: "new"})
+	if !c.tokenValid() {
+		t.Error("password change should keep the cached token")
+	}
+
+	c.SetCredentials(auth.Credentials{Username: "a@example.com", Password: "new", RefreshToken: "provisioned"})
+	if c.tokenValid() || c.tokenCache.RefreshToken != "provisioned" {
+		t.Error("a new refresh token should replace the cached token")
+	}
+
+	c.SetCredentials(auth.Credentials{Username: "b@example.com", Password: "other", RefreshToken: "provisioned"})
+	if c.tokenCache != nil {
+		t.Error("another account should drop the cached token")
+	}
+}
+
+func TestRestartPoller(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		password string
+	}{
+		{"password", "secret"},
+		{"refresh token only", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+			c.creds.Password = tc.password
+			c.restartAfter = 3
+			c.cacheToken(&auth.AuthResult{AccessToken: "a", RefreshToken: "r", ExpiresIn: 3600})
+			oldClient := c.authClient
+
+			c.failed()
+			c.failed()
+			if got := testutil.ToFloat64(c.metrics.pollerRestarts); got != 0 {
+				t.Fatalf("restarted after 2 failures")
+			}
+			c.failed()
+
+			if got := testutil.ToFloat64(c.metrics.pollerRestarts); got != 1 {
+				t.Errorf("poller restarts = %v, want 1", got)
+			}
+			if c.failures != 0 {
+				t.Errorf("failures = %d, want reset to 0", c.failures)
+			}
+			if c.authClient != oldClient {
+				t.Error("auth client was replaced, want it reset in place")
+			}
+			if c.tokenValid() {
+				t.Error("access token should be dropped")
+			}
+			if c.tokenCache == nil || c.tokenCache.RefreshToken != "r" {
+				t.Error("refresh token should be kept to avoid a password login")
+			}
+		})
+	}
+}
+
+func TestMetricRules(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	rules, err := relabel.ParseRules("drop:thermia_oper_time_.*;replace:model:Diplomat.*=Diplomat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.relabel = rules
+
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+	out := string(exposition(t, c))
+
+	if strings.Contains(out, "thermia_oper_time_") {
+		t.Error("dropped metrics are still exposed")
+	}
+	if strings.Contains(out, `model="Diplomat Optimum G3"`) || !strings.Contains(out, `model="Diplomat"`) {
+		t.Error("model label values were not replaced")
+	}
+}
+
+func TestRelabel_FailedRedactionStoresNothing(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	rules, err := relabel.ParseRules("rename:thermia_online=thermia-up;hash:heatpump_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relabel.SaltHashes(rules, "s3cret")
+	c.relabel = rules
+
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+	out := string(exposition(t, c))
+
+	if strings.Contains(out, "heatpump_name=") {
+		t.Errorf("unredacted metrics were stored:\n%s", out)
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.normalizeLabels = true
+
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+	out := string(exposition(t, c))
+
+	for _, want := range []string{`status="hotwater"`, `status="compressor"`, `mode="auto"`, `priority="heating_first"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s", want)
+		}
+	}
+	if strings.Contains(out, `status="STATUS_`) {
+		t.Error("status label values are not normalized")
+	}
+}
+
+func TestAccountLabel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewThermiaCollector(auth.NewAuthClient(logger), auth.Credentials{}, time.Minute, logger, Options{
+		Clock:   clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)),
+		Account: "parents",
+	})
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+
+	for _, line := range strings.Split(string(exposition(t, c)), "\n") {
+		if strings.HasPrefix(line, "thermia_") && !strings.Contains(line, `account="parents"`) {
+			t.Errorf("metric without account label: %s", line)
+		}
+	}
+	if got := c.Installations(); len(got) != 1 || got[0].Account != "parents" {
+		t.Errorf("Installations() = %+v", got)
+	}
+}
+
+func TestRegisterAliases(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	// A firmware reporting the outdoor temperature under another name
+	rename := func(d *installationData) *installationData {
+		for i, it := range d.groups[mapper.RegGroupTemperatures] {
+			if it.RegisterName == mapper.RegOutdoorTemperature {
+				d.groups[mapper.RegGroupTemperatures][i].RegisterName = "REG_UTOMHUSTEMPERATUR"
+			}
+		}
+		return d
+	}
+
+	c.storeInstallation(rename(loadFixture(t, filepath.Join("testdata", "diplomat"))))
+	if strings.Contains(string(exposition(t, c)), "thermia_outdoor_temperature_celsius") {
+		t.Fatal("outdoor temperature exported without an alias")
+	}
+
+	c.SetAliases(mapper.Aliases{"REG_UTOMHUSTEMPERATUR": mapper.RegOutdoorTemperature})
+	c.storeInstallation(rename(loadFixture(t, filepath.Join("testdata", "diplomat"))))
+	if !strings.Contains(string(exposition(t, c)), "thermia_outdoor_temperature_celsius") {
+		t.Error("aliased outdoor temperature not exported")
+	}
+}
+
+func TestRawRegisters(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	fixture := filepath.Join("testdata", "diplomat")
+
+	c.storeInstallation(loadFixture(t, fixture))
+	if strings.Contains(string(exposition(t, c)), "thermia_register_value") {
+		t.Fatal("raw registers exported without the option")
+	}
+
+	c.rawRegisters = true
+	c.storeInstallation(loadFixture(t, fixture))
+	want := `register_name="REG_BRINE_IN",unit="celsius"} 4.6`
+	if !strings.Contains(string(exposition(t, c)), want) {
+		t.Errorf("raw register %s not exported", want)
+	}
+
+	// Registers reported in tenths are converted to the base unit
+	d := loadFixture(t, fixture)
+	for i, it := range d.groups[mapper.RegGroupTemperatures] {
+		if it.RegisterName == "REG_BRINE_IN" {
+			d.groups[mapper.RegGroupTemperatures][i].Unit = "0.1°C"
+			d.groups[mapper.RegGroupTemperatures][i].RegisterValue = ptrFloat(46)
+		}
+	}
+	c.storeInstallation(d)
+	if !strings.Contains(string(exposition(t, c)), want) {
+		t.Errorf("raw register in tenths not exported as %s", want)
+	}
+}
+
+func TestNoOperTimeGauges(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.noOperTimeGauges = true
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+
+	out := string(exposition(t, c))
+	if strings.Contains(out, "thermia_oper_time_compressor_hours{") {
+		t.Error("operating time gauge exported although disabled")
+	}
+	if !strings.Contains(out, "thermia_oper_time_compressor_hours_total{") {
+		t.Error("operating time counter not exported")
+	}
+}
+
+func TestPickCurrentStatus(t *testing.T) {
+	running := []types.OperationalStatus{types.StatusNoDemand, types.StatusHeat, types.StatusHotWater}
+
+	if got := pickCurrentStatus(running, nil, mapper.DefaultStatusPriority); got != types.StatusHotWater {
+		t.Errorf("default priority picked %q, want %q", got, types.StatusHotWater)
+	}
+	custom := []types.OperationalStatus{types.StatusHeat, types.StatusHotWater}
+	if got := pickCurrentStatus(running, nil, custom); got != types.StatusHeat {
+		t.Errorf("custom priority picked %q, want %q", got, types.StatusHeat)
+	}
+	if got := pickCurrentStatus([]types.OperationalStatus{types.StatusNoDemand}, nil, custom); got != types.StatusNoDemand {
+		t.Errorf("picked %q, want %q when nothing else runs", got, types.StatusNoDemand)
+	}
+}
+
+func TestAuthInfo(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	seg := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	c.cacheToken(&auth.AuthResult{AccessToken: "opaque", ExpiresIn: 3600})
+	c.cacheToken(&auth.AuthResult{
+		AccessToken: seg(`{"typ":"JWT"}`) + "." + seg(`{"sub":"user-1","tid":"tenant-1"}`) + ".sig",
+		ExpiresIn:   3600,
+	})
+
+	if n := testutil.CollectAndCount(c.metrics.authInfo); n != 1 {
+		t.Errorf("thermia_auth_info series = %d, want 1 (the previous token's dropped)", n)
+	}
+	if got := testutil.ToFloat64(c.metrics.authInfo.WithLabelValues(subjectHash("user-1"), "tenant-1", "JWT")); got != 1 {
+		t.Errorf("thermia_auth_info = %v, want 1", got)
+	}
+}
+
+func TestClientRejected(t *testing.T) {
+	var logins int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/token") {
+			logins++
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"invalid_scope","error_description":"AADB2C90205"}`)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewThermiaCollector(auth.NewPortalAuthClient(auth.Portal{B2CBaseURL: srv.URL}, logger),
+		auth.Credentials{Username: "a@example.com", Password: "secret"}, time.Minute, logger,
+		Options{Clock: clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))})
+	c.tokenCache = &auth.AuthResult{RefreshToken: "r"}
+
+	if _, err := c.getOrRefreshToken(context.Background()); !errors.Is(err, auth.ErrClientRejected) {
+		t.Fatalf("getOrRefreshToken() error = %v, want ErrClientRejected", err)
+	}
+	if logins != 0 {
+		t.Errorf("password login attempted %d times after the client was rejected", logins)
+	}
+	if got := testutil.ToFloat64(c.metrics.clientRejected.WithLabelValues("invalid_scope")); got != 1 {
+		t.Errorf("thermia_auth_client_rejected = %v, want 1", got)
+	}
+
+	c.clientRejected(nil)
+	if n := testutil.CollectAndCount(c.metrics.clientRejected); n != 0 {
+		t.Errorf("thermia_auth_client_rejected series after a renewal = %d, want 0", n)
+	}
+}
+
+func TestCredentialsRejected(t *testing.T) {
+	var logins int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/authorize"):
+			logins++
+			io.WriteString(w, `<script>var SETTINGS = {"csrf":"c","transId":"StateProperties=abc"};</script>`)
+		case strings.HasSuffix(r.URL.Path, "/SelfAsserted"):
+			io.WriteString(w, `{"status":"400","message":"Your password is incorrect."}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	creds := auth.Credentials{Username: "a@example.com", Password: "wrong"}
+	c := NewThermiaCollector(auth.NewPortalAuthClient(auth.Portal{B2CBaseURL: srv.URL}, logger), creds, time.Minute, logger,
+		Options{Clock: clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))})
+
+	for range 3 {
+		if _, err := c.getOrRefreshToken(context.Background()); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("getOrRefreshToken() error = %v, want ErrInvalidCredentials", err)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("logins = %d, want 1 until the credentials change", logins)
+	}
+
+	c.SetCredentials(auth.Credentials{Username: "a@example.com", Password: "fixed"})
+	c.getOrRefreshToken(context.Background())
+	if logins != 2 {
+		t.Errorf("logins = %d, want a new login after the credentials changed", logins)
+	}
+}
+
+func TestRun_FailingLoginDoesNotSpin(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewThermiaCollector(auth.NewPortalAuthClient(auth.Portal{B2CBaseURL: srv.URL}, logger),
+		auth.Credentials{Username: "a@example.com", Password: "secret"}, time.Second, logger, Options{})
+	// An installation overdue since an earlier collection
+	c.polls.next[7] = time.Now().Add(-time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	c.Run(ctx, 10*time.Millisecond)
+
+	if got := testutil.ToFloat64(c.metrics.consecutiveFailures); got != 1 {
+		t.Errorf("failed collections = %v in 300ms, want 1 (%d login requests)", got, requests.Load())
+	}
+}
+
+func TestRecordGroupShape(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	c.recordGroupShape(7, mapper.RegGroupTemperatures, []types.GroupItem{
+		{RegisterName: mapper.RegOutdoorTemperature},
+		{RegisterName: ""},
+		{RegisterName: mapper.RegIndoorTemperature, Unit: "0.1°C"},
+	})
+
+	if got := testutil.ToFloat64(c.metrics.groupItems.WithLabelValues("7", mapper.RegGroupTemperatures)); got != 3 {
+		t.Errorf("thermia_register_group_items = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(c.metrics.parseFailures.WithLabelValues("7", mapper.RegGroupTemperatures)); got != 1 {
+		t.Errorf("thermia_register_parse_failures_total = %v, want 1 (unnamed item)", got)
+	}
+	if got := testutil.ToFloat64(c.metrics.unitMismatches.WithLabelValues("7", mapper.RegIndoorTemperature)); got != 1 {
+		t.Errorf("thermia_register_unit_mismatches_total = %v, want 1 (tenths)", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+
+	c.backoff(clk.Now().Add(-time.Minute))
+	if !c.backoffUntil.IsZero() {
+		t.Errorf("backoffUntil = %v, want a past throttle ignored", c.backoffUntil)
+	}
+
+	until := clk.Now().Add(10 * time.Minute)
+	c.backoff(until)
+	c.backoff(clk.Now().Add(time.Minute))
+	if !c.backoffUntil.Equal(until) {
+		t.Errorf("backoffUntil = %v, want the longest wait %v", c.backoffUntil, until)
+	}
+}
+
+// TestReadOnlyFilesystem collects every recorded model with the working,
+// home and temp directories on a read-only directory and checks nothing was
+// written: the exporter keeps all state in memory.
+func TestReadOnlyFilesystem(t *testing.T) {
+	root := t.TempDir()
+	for _, env := range []string{"HOME", "TMPDIR", "XDG_CACHE_HOME", "XDG_CONFIG_HOME"} {
+		t.Setenv(env, root)
+	}
+	fixtures := make([]*installationData, 0, len(goldenModels))
+	for _, model := range goldenModels {
+		fixtures = append(fixtures, loadFixture(t, filepath.Join("testdata", model)))
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chmod(root, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(root, 0o755) })
+
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	for _, d := range fixtures {
+		c.storeInstallation(d)
+	}
+	exposition(t, c)
+	c.ModelReports()
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("collection wrote %s", e.Name())
+	}
+}
+
+func TestCollect_NothingCollected(t *testing.T) {
+	var groups atomic.Bool
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/configuration":
+			io.WriteString(w, `{"apiBaseUrl":"`+srv.URL+`"}`)
+		case r.URL.Path == "/api/v1/installationsInfo":
+			io.WriteString(w, `{"items":[{"id":7,"name":"Villa"}]}`)
+		case groups.Load() && strings.Contains(r.URL.Path, mapper.RegGroupTemperatures):
+			io.WriteString(w, `[{"registerName":"REG_OUTDOOR_TEMPERATURE","registerValue":-2.5}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := NewThermiaCollector(auth.NewPortalAuthClient(auth.Portal{ConfigURL: srv.URL + "/api/configuration"}, logger),
+		auth.Credentials{}, time.Minute, logger, Options{})
+	c.tokenCache = &auth.AuthResult{AccessToken: "a"}
+	c.tokenExpiresAt = time.Now().Add(time.Hour)
+
+	if _, err := c.collect(context.Background()); !errors.Is(err, ErrNothingCollected) {
+		t.Fatalf("collect() error = %v with every request failing, want ErrNothingCollected", err)
+	}
+	if _, ok := c.store.Get(7); ok {
+		t.Error("collect() stored a snapshot without any data")
+	}
+
+	groups.Store(true)
+	c.polls.next = map[int64]time.Time{}
+	if n, err := c.collect(context.Background()); err != nil || n != 1 {
+		t.Fatalf("collect() = %d, %v with the temperatures available, want 1, nil", n, err)
+	}
+	if _, ok := c.store.Get(7); !ok {
+		t.Error("collect() stored no snapshot")
+	}
+}
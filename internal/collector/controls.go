@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/mapper"
+)
+
+// buildCapabilities lists the controls of d that are writable for the
+// account. labels are the id, name and model labels returned by
+// installationLabels.
+func buildCapabilities(d *installationData, labels []string) control.Capabilities {
+	caps := control.Capabilities{
+		InstallationID: d.inst.ID,
+		HeatpumpName:   labels[1],
+		HeatpumpModel:  labels[2],
+		Registers:      control.WritableRegisters(d.groups),
+	}
+	if mode := mapper.ExtractOperationMode(d.groups[mapper.RegGroupOperationalOperation], mapper.OperationModeCandidates); mode.Available != nil {
+		caps.OperationMode = &control.OperationModeCapability{ReadOnly: mode.ReadOnly, Modes: mode.Available}
+	}
+	return caps
+}
+
+// ControlCapabilities returns the writable controls of every collected
+// installation, ordered by installation ID.
+func (c *ThermiaCollector) ControlCapabilities() []control.Capabilities {
+	c.reportsMu.RLock()
+	defer c.reportsMu.RUnlock()
+
+	caps := make([]control.Capabilities, 0, len(c.capabilities))
+	for _, cc := range c.capabilities {
+		caps = append(caps, cc)
+	}
+	sort.Slice(caps, func(i, j int) bool { return caps[i].InstallationID < caps[j].InstallationID })
+	return caps
+}
+
+// setCapabilities stores the control capabilities of an installation.
+func (c *ThermiaCollector) setCapabilities(caps control.Capabilities) {
+	c.reportsMu.Lock()
+	defer c.reportsMu.Unlock()
+	c.capabilities[caps.InstallationID] = caps
+}
+
+// ErrNoRegisterID is returned when the API did not report the register ID a
+// write needs.
+var ErrNoRegisterID = errors.New("register has no register ID")
+
+// WriteRegister sets register in group of an installation to value, a
+// number or an enumerated value name. The group is fetched fresh so the
+// write is validated against the heat pump's current metadata, not the last
+// collection's. With dryRun the validated write is returned without being
+// sent.
+func (c *ThermiaCollector) WriteRegister(ctx context.Context, installationID int64, group, register, value string, dryRun bool) (*control.Write, error) {
+	apiClient, err := c.newAPIClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := apiClient.GetRegisterGroup(ctx, installationID, group)
+	if err != nil {
+		return nil, fmt.Errorf("get register group: %w", err)
+	}
+	items = c.registerAliases().Rename(items)
+	if register == mapper.RegOperationMode {
+		register = mapper.OperationModeRegister(items)
+	}
+
+	requested, err := control.ParseValue(group, items, register, value)
+	if err != nil {
+		return nil, err
+	}
+	w, err := control.Prepare(installationID, group, items, register, requested, false)
+	if err != nil {
+		return nil, err
+	}
+	w.DryRun = dryRun
+	if dryRun {
+		return w, nil
+	}
+	if w.RegisterID == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoRegisterID, register)
+	}
+
+	if err := apiClient.SetRegister(ctx, installationID, w.RegisterID, w.Value); err != nil {
+		return nil, fmt.Errorf("set register: %w", err)
+	}
+	c.logger.Info("Register written", "id", installationID, "register", register, "value", w.Value)
+	return w, nil
+}
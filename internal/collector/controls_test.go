@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestControlCapabilities(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+
+	caps := c.ControlCapabilities()
+	if len(caps) != 1 {
+		t.Fatalf("got %d installations, want 1", len(caps))
+	}
+	if caps[0].OperationMode == nil || len(caps[0].OperationMode.Modes) == 0 {
+		t.Errorf("operation mode = %+v, want the available modes", caps[0].OperationMode)
+	}
+
+	writable := make(map[string]bool)
+	for _, rc := range caps[0].Registers {
+		writable[rc.Register] = true
+	}
+	if !writable["REG_HOT_WATER_STATUS"] {
+		t.Errorf("REG_HOT_WATER_STATUS should be writable: %+v", caps[0].Registers)
+	}
+	if writable["REG_CURRENT_PRIORITY"] {
+		t.Error("read-only REG_CURRENT_PRIORITY listed as writable")
+	}
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/mapper"
+)
+
+// minMeteredWatts is the electrical draw below which the pump is considered
+// idle. COP is not meaningful (and explodes numerically) near zero input.
+const minMeteredWatts = 50
+
+// readMeter reads the external energy meter into d.meterWatts. A meter
+// that is not per installation is skipped while the account has several
+// installations, since its one reading would be attributed to all of them.
+func (c *ThermiaCollector) readMeter(ctx context.Context, d *installationData) {
+	if c.meter == nil {
+		return
+	}
+	if !c.meter.PerInstallation() && c.sharedMeter.Load() {
+		c.logger.Warn("Skipping external energy meter with one reading for several installations; add $heatpump_id to its query or topic", "id", d.inst.ID)
+		return
+	}
+	watts, err := c.meter.Power(ctx, d.inst.ID)
+	if err != nil {
+		c.logger.Warn("Failed to read external energy meter", "id", d.inst.ID, "error", err)
+		return
+	}
+	d.meterWatts = &watts
+}
+
+// emitCOPMetrics emits heat output, the electrical power and energy reported
+// by the heat pump, metered electrical power and the measured COP derived
+// from heat output and metered power.
+func (c *ThermiaCollector) emitCOPMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	heat := mapper.ExtractPowerWatts(d.items, c.heatOutputRegisters)
+	if heat != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.heatOutput, prometheus.GaugeValue, *heat, labels...)
+	}
+	if watts := mapper.ExtractPowerWatts(d.items, mapper.PowerConsumptionCandidates); watts != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.powerConsumption, prometheus.GaugeValue, *watts, labels...)
+	}
+	if kwh := mapper.ExtractEnergyKWh(d.items, mapper.EnergyConsumedCandidates); kwh != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.energyConsumed, prometheus.CounterValue, *kwh, labels...)
+	}
+
+	if d.meterWatts == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.metrics.meterPower, prometheus.GaugeValue, *d.meterWatts, labels...)
+
+	if heat != nil && *d.meterWatts >= minMeteredWatts {
+		ch <- prometheus.MustNewConstMetric(c.metrics.measuredCOP, prometheus.GaugeValue, *heat / *d.meterWatts, labels...)
+	}
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+// fakeMeter reports the installation ID as its power.
+type fakeMeter struct {
+	perInstallation bool
+}
+
+func (m fakeMeter) Power(_ context.Context, id int64) (float64, error) {
+	return float64(id), nil
+}
+
+func (m fakeMeter) PerInstallation() bool {
+	return m.perInstallation
+}
+
+func TestReadMeter(t *testing.T) {
+	tests := []struct {
+		name            string
+		perInstallation bool
+		shared          bool
+		want            *float64
+	}{
+		{"single installation", false, false, ptrFloat(42)},
+		{"shared reading, several installations", false, true, nil},
+		{"per installation, several installations", true, true, ptrFloat(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+			c.meter = fakeMeter{perInstallation: tt.perInstallation}
+			c.sharedMeter.Store(tt.shared)
+
+			d := &installationData{inst: types.Installation{ID: 42}}
+			c.readMeter(context.Background(), d)
+
+			switch {
+			case tt.want == nil && d.meterWatts != nil:
+				t.Errorf("meterWatts = %v, want none", *d.meterWatts)
+			case tt.want != nil && (d.meterWatts == nil || *d.meterWatts != *tt.want):
+				t.Errorf("meterWatts = %v, want %v", d.meterWatts, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/mapper"
+)
+
+// discoveryInterval is how often register groups are enumerated for
+// registers the exporter does not map yet.
+const discoveryInterval = 24 * time.Hour
+
+// discoverUnmapped enumerates mapper.DiscoveryGroups at most once per
+// discoveryInterval and reports registers no metric is derived from, so
+// users can include them in issue reports. Groups already fetched by this
+// collection in d are reused. It also records which optionalGroups the
+// installation exposes, so later collections fetch them.
+func (c *ThermiaCollector) discoverUnmapped(ctx context.Context, apiClient *api.APIClient, d *installationData) {
+	id := d.inst.ID
+	now := c.clock.Now()
+	if last, ok := c.lastDiscovery[id]; ok && now.Sub(last) < discoveryInterval {
+		return
+	}
+	c.lastDiscovery[id] = now
+
+	idLabel := fmt.Sprint(id)
+	c.metrics.unmappedRegisters.DeletePartialMatch(prometheus.Labels{mapper.LabelHeatpumpID: idLabel})
+
+	for _, group := range mapper.DiscoveryGroups {
+		items, ok := d.groups[group]
+		if !ok {
+			var err error
+			items, err = apiClient.GetRegisterGroup(ctx, id, group)
+			if err != nil {
+				// Most models don't expose every group
+				c.logger.Debug("Register group not available", "id", id, "group", group, "error", err)
+				c.setGroupFound(id, group, false)
+				continue
+			}
+		}
+		c.setGroupFound(id, group, len(items) > 0)
+
+		unmapped := mapper.UnmappedRegisters(c.registerAliases().Rename(items))
+		c.metrics.unmappedRegisters.WithLabelValues(idLabel, group).Set(float64(len(unmapped)))
+		if len(unmapped) > 0 {
+			c.logger.Info("Unmapped registers found", "id", id, "group", group, "registers", unmapped)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/mapper"
+)
+
+// AccountDump holds the raw API responses of every installation of one
+// account.
+type AccountDump struct {
+	Account       string             `json:"account,omitempty"`
+	Installations []InstallationDump `json:"installations"`
+}
+
+// InstallationDump holds the raw API responses of one installation: its
+// info, every register group of mapper.DiscoveryGroups it returned and all
+// its events. Errors holds the calls that failed, keyed by group name,
+// "info" or "events".
+type InstallationDump struct {
+	ID     int64                      `json:"id"`
+	Name   string                     `json:"name"`
+	Info   json.RawMessage            `json:"info,omitempty"`
+	Groups map[string]json.RawMessage `json:"groups"`
+	Events json.RawMessage            `json:"events,omitempty"`
+	Errors map[string]string          `json:"errors,omitempty"`
+}
+
+// Dump fetches the raw responses of every installation of the account, for
+// debugging registers a model names differently. Unlike a collection it
+// records no metrics, does not touch the snapshot store and applies no
+// redaction. It fails only if the account cannot be read; a failed call
+// for one installation is reported in its Errors.
+func (c *ThermiaCollector) Dump(ctx context.Context) (AccountDump, error) {
+	dump := AccountDump{Account: c.account, Installations: []InstallationDump{}}
+	apiClient, err := c.newAPIClient(ctx)
+	if err != nil {
+		return dump, err
+	}
+	installations, err := apiClient.GetInstallations(ctx)
+	if err != nil {
+		return dump, fmt.Errorf("get installations: %w", err)
+	}
+	if len(installations) == 0 {
+		return dump, errors.New("no installations found")
+	}
+
+	for _, inst := range installations {
+		d := InstallationDump{
+			ID:     inst.ID,
+			Name:   inst.Name,
+			Groups: make(map[string]json.RawMessage),
+			Errors: make(map[string]string),
+		}
+		get := func(key, path string) json.RawMessage {
+			data, err := apiClient.GetRaw(ctx, path)
+			if err != nil {
+				d.Errors[key] = err.Error()
+			}
+			return data
+		}
+		d.Info = get("info", api.InstallationInfoPath(inst.ID))
+		for _, group := range mapper.DiscoveryGroups {
+			if data := get(group, api.RegisterGroupPath(inst.ID, group)); data != nil {
+				d.Groups[group] = data
+			}
+		}
+		d.Events = get("events", api.EventsPath(inst.ID, false))
+		dump.Installations = append(dump.Installations, d)
+	}
+	return dump, nil
+}
+
+// RawRegisterGroup fetches one register group of an installation and
+// returns the API response undecoded, including the fields the exporter
+// ignores. Register aliases and redaction are not applied.
+func (c *ThermiaCollector) RawRegisterGroup(ctx context.Context, installationID int64, group string) (json.RawMessage, error) {
+	apiClient, err := c.newAPIClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return apiClient.GetRaw(ctx, api.RegisterGroupPath(installationID, group))
+}
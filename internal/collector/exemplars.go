@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// traceIDLabel is the exemplar label Grafana uses to link to a trace.
+const traceIDLabel = "trace_id"
+
+// observeCollection records the duration and outcome of a collection. If a
+// trace ID is available the observations carry it as an exemplar, so a slow
+// or failed collection can be followed to its trace.
+func (c *ThermiaCollector) observeCollection(ctx context.Context, duration time.Duration, err error) {
+	var exemplar prometheus.Labels
+	if c.traceID != nil {
+		if id := c.traceID(ctx); id != "" {
+			exemplar = prometheus.Labels{traceIDLabel: id}
+		}
+	}
+
+	if exemplar == nil {
+		c.metrics.scrapeDuration.Observe(duration.Seconds())
+		if err != nil {
+			c.metrics.scrapeErrors.Inc()
+		}
+		return
+	}
+
+	c.metrics.scrapeDuration.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), exemplar)
+	if err != nil {
+		c.metrics.scrapeErrors.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+	}
+}
+
+// Exemplars reports whether the collector attaches exemplars, in which case
+// metrics must be served in the OpenMetrics format to expose them.
+func (c *ThermiaCollector) Exemplars() bool {
+	return c.traceID != nil
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/tracing"
+)
+
+func TestObserveCollection_Exemplar(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+	c.traceID = func(ctx context.Context) string { return "4bf92f3577b34da6a3ce929d0e0e4736" }
+
+	c.observeCollection(context.Background(), 3*time.Second, errors.New("boom"))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c.Internal())
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			if ex := m.GetCounter().GetExemplar(); ex != nil && ex.GetLabel()[0].GetValue() == "4bf92f3577b34da6a3ce929d0e0e4736" {
+				found[mf.GetName()] = true
+			}
+			for _, b := range m.GetHistogram().GetBucket() {
+				if ex := b.GetExemplar(); ex != nil && ex.GetLabel()[0].GetValue() == "4bf92f3577b34da6a3ce929d0e0e4736" {
+					found[mf.GetName()] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{"thermia_scrape_duration_seconds", "thermia_scrape_errors_total"} {
+		if !found[name] {
+			t.Errorf("%s has no trace_id exemplar", name)
+		}
+	}
+}
+
+func TestObserveCollection_NoTraceID(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+	c.traceID = func(ctx context.Context) string { return "" }
+
+	// Must not panic or attach empty exemplars
+	c.observeCollection(context.Background(), time.Second, nil)
+}
+
+func TestTracer_SetsTraceID(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracer := tracing.NewTracer("http://127.0.0.1:1", "thermia_exporter", logger, nil)
+	c := NewThermiaCollector(auth.NewAuthClient(logger), auth.Credentials{}, time.Minute, logger, Options{Tracer: tracer})
+	if !c.Exemplars() {
+		t.Fatal("Exemplars() = false with a tracer")
+	}
+
+	ctx, _ := tracer.Start(context.Background(), "collect")
+	if got := c.traceID(ctx); got == "" || got != tracing.TraceID(ctx) {
+		t.Errorf("traceID() = %q, want the span's trace ID", got)
+	}
+}
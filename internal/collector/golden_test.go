@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from the current output")
+
+// goldenModels are the recorded installations under testdata. Each directory
+// holds one JSON payload per API call; a missing file means that call failed.
+var goldenModels = []string{"diplomat", "atlas", "itec"}
+
+// TestGolden feeds recorded API payloads through the collector and compares
+// the full exposition output with testdata/<model>/metrics.golden, so mapper
+// refactors cannot silently rename or drop series. After an intended change,
+// regenerate with: go test ./internal/collector -run TestGolden -update
+func TestGolden(t *testing.T) {
+	for _, model := range goldenModels {
+		t.Run(model, func(t *testing.T) {
+			dir := filepath.Join("testdata", model)
+
+			c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+			c.storeInstallation(loadFixture(t, dir))
+			got := exposition(t, c)
+
+			golden := filepath.Join(dir, "metrics.golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", golden, firstDiff(string(want), string(got)))
+			}
+		})
+	}
+}
+
+// loadFixture builds installationData from the recorded payloads in dir.
+func loadFixture(t *testing.T, dir string) *installationData {
+	t.Helper()
+
+	d := &installationData{groups: make(map[string][]types.GroupItem)}
+	if !readFixture(t, dir, "installation", &d.inst) {
+		t.Fatalf("%s: installation.json is required", dir)
+	}
+
+	var info types.InstallationInfo
+	if readFixture(t, dir, "info", &info) {
+		d.info = &info
+	}
+	var status types.InstallationStatus
+	if readFixture(t, dir, "status", &status) {
+		d.status = &status
+	}
+	for _, group := range registerGroups {
+		var items []types.GroupItem
+		if readFixture(t, dir, group, &items) {
+			d.groups[group] = items
+		}
+	}
+	okActive := readFixture(t, dir, "events_active", &d.activeEvents)
+	okAll := readFixture(t, dir, "events_all", &d.allEvents)
+	d.eventsOK = okActive && okAll
+
+	return d
+}
+
+// readFixture decodes dir/name.json into v and reports whether it exists.
+func readFixture(t *testing.T, dir, name string, v any) bool {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s/%s.json: %v", dir, name, err)
+	}
+	return true
+}
+
+// exposition renders the collector's metrics in the text exposition format.
+// A pedantic registry also checks that every emitted metric is described.
+func exposition(t *testing.T, c prometheus.Collector) []byte {
+	t.Helper()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	for _, mf := range families {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// firstDiff describes the first line where want and got differ.
+func firstDiff(want, got string) string {
+	wl := strings.Split(want, "\n")
+	gl := strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return "(no line difference)"
+}
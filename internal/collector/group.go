@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"context"
+	"sort"
+
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/snapshot"
+)
+
+// Group is the set of collectors of one exporter, one per Thermia account.
+// It merges what the HTTP endpoints read from them.
+type Group []*ThermiaCollector
+
+// Ready reports whether every collector finished its first collection.
+func (g Group) Ready() bool {
+	for _, c := range g {
+		if !c.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// Collected reports whether every collector has collected its
+// installations at least once.
+func (g Group) Collected() bool {
+	for _, c := range g {
+		if !c.Collected() {
+			return false
+		}
+	}
+	return true
+}
+
+// Health returns the health of every account's collector.
+func (g Group) Health(policy HealthPolicy) []HealthStatus {
+	all := make([]HealthStatus, 0, len(g))
+	for _, c := range g {
+		all = append(all, c.Health(policy))
+	}
+	return all
+}
+
+// Installations returns the installations of all accounts ordered by ID.
+func (g Group) Installations() []Installation {
+	var all []Installation
+	for _, c := range g {
+		all = append(all, c.Installations()...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// ModelReports returns the model reports of all accounts ordered by
+// installation ID.
+func (g Group) ModelReports() []ModelReport {
+	all := []ModelReport{}
+	for _, c := range g {
+		all = append(all, c.ModelReports()...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].HeatpumpID < all[j].HeatpumpID })
+	return all
+}
+
+// ControlCapabilities returns the control capabilities of all accounts
+// ordered by installation ID.
+func (g Group) ControlCapabilities() []control.Capabilities {
+	all := []control.Capabilities{}
+	for _, c := range g {
+		all = append(all, c.ControlCapabilities()...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].InstallationID < all[j].InstallationID })
+	return all
+}
+
+// SelfTest runs the self-test of every account in turn, sharing ctx's
+// budget.
+func (g Group) SelfTest(ctx context.Context) []SelfTestReport {
+	reports := make([]SelfTestReport, 0, len(g))
+	for _, c := range g {
+		reports = append(reports, c.SelfTest(ctx))
+	}
+	return reports
+}
+
+// ForInstallation returns the collector of the account an installation
+// was collected for, or nil if no collection has seen it.
+func (g Group) ForInstallation(id int64) *ThermiaCollector {
+	for _, c := range g {
+		for _, inst := range c.Installations() {
+			if inst.ID == id {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// Snapshots returns the latest snapshot of every installation of all
+// accounts, ordered by installation ID.
+func (g Group) Snapshots() []snapshot.Snapshot {
+	return g.stores().All()
+}
+
+// Changed returns a channel that is closed when the snapshots of any
+// account change next. Callers must close stop once they stop waiting (see
+// snapshot.Set.Changed).
+func (g Group) Changed(stop <-chan struct{}) <-chan struct{} {
+	return g.stores().Changed(stop)
+}
+
+// stores returns the snapshot store of every account.
+func (g Group) stores() snapshot.Set {
+	stores := make(snapshot.Set, 0, len(g))
+	for _, c := range g {
+		stores = append(stores, c.Store())
+	}
+	return stores
+}
+
+// Snapshot returns the latest snapshot of an installation.
+func (g Group) Snapshot(id int64) (snapshot.Snapshot, bool) {
+	for _, c := range g {
+		if snap, ok := c.Store().Get(id); ok {
+			return snap, true
+		}
+	}
+	return snapshot.Snapshot{}, false
+}
@@ -0,0 +1,28 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+func TestGroupSnapshots(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	a, b := newTestCollector(clk), newTestCollector(clk)
+	a.Store().Put(30, clk.Now(), types.ThermiaSummary{HeatpumpID: 30}, nil)
+	b.Store().Put(10, clk.Now(), types.ThermiaSummary{HeatpumpID: 10}, nil)
+	g := Group{a, b}
+
+	snaps := g.Snapshots()
+	if len(snaps) != 2 || snaps[0].InstallationID != 10 || snaps[1].InstallationID != 30 {
+		t.Errorf("Snapshots() = %+v, want installations 10 and 30 in order", snaps)
+	}
+	if snap, ok := g.Snapshot(30); !ok || snap.Summary.HeatpumpID != 30 {
+		t.Errorf("Snapshot(30) = %+v, %v", snap, ok)
+	}
+	if _, ok := g.Snapshot(20); ok {
+		t.Error("Snapshot(20) found an installation that was never collected")
+	}
+}
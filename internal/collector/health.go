@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// HealthPolicy sets when a collector counts as unhealthy. Zero fields are
+// not checked.
+type HealthPolicy struct {
+	// MaxFailures is the number of failed collections in a row after which
+	// the collector is unhealthy.
+	MaxFailures int
+
+	// MaxAge is how old the last successful collection may get.
+	MaxAge time.Duration
+}
+
+// HealthStatus is a collector's connectivity to the Thermia API.
+type HealthStatus struct {
+	Account               string     `json:"account,omitempty"`
+	Healthy               bool       `json:"healthy"`
+	Reason                string     `json:"reason,omitempty"`
+	LastSuccess           *time.Time `json:"last_success,omitempty"`
+	LastSuccessAgeSeconds float64    `json:"last_success_age_seconds,omitempty"`
+	ConsecutiveFailures   int        `json:"consecutive_failures"`
+	TokenValid            bool       `json:"token_valid"`
+	TokenExpiresInSeconds float64    `json:"token_expires_in_seconds,omitempty"`
+
+	// Whether the last request to the identity provider and to the API got
+	// a response, even an error (absent until the first one)
+	IdPReachable *bool `json:"idp_reachable,omitempty"`
+	APIReachable *bool `json:"api_reachable,omitempty"`
+
+	// Time since the last collection started
+	LastPollAgeSeconds float64 `json:"last_poll_age_seconds,omitempty"`
+
+	// Wedged is set when a collection has been running for more than twice
+	// the fetch timeout, which it should never outlive.
+	Wedged bool `json:"wedged,omitempty"`
+}
+
+// Health reports the collector's connectivity against policy. A collector
+// whose first collection is still running is unhealthy. The token state is
+// informational: an expired token is renewed by the next collection.
+func (c *ThermiaCollector) Health(policy HealthPolicy) HealthStatus {
+	now := c.clock.Now()
+	status := HealthStatus{
+		Account:             c.account,
+		Healthy:             true,
+		ConsecutiveFailures: int(c.failedInRow.Load()),
+	}
+
+	c.tokenCacheMu.RLock()
+	if c.tokenValid() {
+		status.TokenValid = true
+		status.TokenExpiresInSeconds = c.tokenExpiresIn().Seconds()
+	}
+	c.tokenCacheMu.RUnlock()
+
+	status.IdPReachable = c.idp.reachable()
+	status.APIReachable = c.apiProbe.reachable()
+	if ns := c.lastPollAt.Load(); ns != 0 {
+		status.LastPollAgeSeconds = now.Sub(time.Unix(0, ns)).Round(time.Second).Seconds()
+	}
+	var running time.Duration
+	if ns := c.collectingSince.Load(); ns != 0 {
+		running = now.Sub(time.Unix(0, ns))
+		status.Wedged = running > 2*c.fetchTimeout
+	}
+
+	var age time.Duration
+	if ns := c.lastSuccessAt.Load(); ns != 0 {
+		last := time.Unix(0, ns).UTC()
+		age = now.Sub(last)
+		status.LastSuccess = &last
+		status.LastSuccessAgeSeconds = age.Round(time.Second).Seconds()
+	}
+
+	switch {
+	case status.Wedged:
+		status.Healthy = false
+		status.Reason = fmt.Sprintf("collection running for %s", running.Round(time.Second))
+	case !c.Ready():
+		status.Healthy, status.Reason = false, "first collection in progress"
+	case c.noInstallations.Load():
+		status.Healthy, status.Reason = false, "account has no installations"
+	case policy.MaxFailures > 0 && status.ConsecutiveFailures >= policy.MaxFailures:
+		status.Healthy = false
+		status.Reason = fmt.Sprintf("last %d collections failed", status.ConsecutiveFailures)
+	case policy.MaxAge > 0 && status.LastSuccess == nil:
+		status.Healthy, status.Reason = false, "no successful collection yet"
+	case policy.MaxAge > 0 && age > policy.MaxAge:
+		status.Healthy = false
+		status.Reason = fmt.Sprintf("last successful collection %s ago", age.Round(time.Second))
+	}
+	return status
+}
+
+// probe records whether the last request to a dependency got a response.
+type probe struct {
+	// 0: no request yet, 1: reachable, 2: unreachable
+	state atomic.Int32
+}
+
+// record notes the outcome of a request. Any response, even an error
+// status, counts as reachable; only transport failures do not.
+func (p *probe) record(err error) {
+	var transport *url.Error
+	if errors.As(err, &transport) {
+		p.state.Store(2)
+	} else {
+		p.state.Store(1)
+	}
+}
+
+// reachable returns the recorded reachability, or nil before any request.
+func (p *probe) reachable() *bool {
+	state := p.state.Load()
+	if state == 0 {
+		return nil
+	}
+	ok := state == 1
+	return &ok
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+)
+
+func TestHealth(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+	policy := HealthPolicy{MaxFailures: 3, MaxAge: time.Hour}
+
+	if s := c.Health(policy); s.Healthy {
+		t.Error("healthy before the first collection")
+	}
+
+	c.ready.Store(true)
+	c.lastSuccessAt.Store(clk.Now().UnixNano())
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", ExpiresIn: 3600})
+	s := c.Health(policy)
+	if !s.Healthy || !s.TokenValid || s.LastSuccess == nil {
+		t.Errorf("Health() = %+v, want healthy with a valid token and last success", s)
+	}
+
+	c.failedInRow.Store(3)
+	if s := c.Health(policy); s.Healthy {
+		t.Error("healthy after 3 failed collections in a row")
+	}
+	if s := c.Health(HealthPolicy{}); !s.Healthy {
+		t.Errorf("unhealthy without a policy: %s", s.Reason)
+	}
+
+	c.failedInRow.Store(0)
+	clk.Advance(61 * time.Minute)
+	s = c.Health(policy)
+	if s.Healthy || s.TokenValid {
+		t.Errorf("Health() = %+v, want unhealthy with stale data and an expired token", s)
+	}
+
+	clk.Advance(-61 * time.Minute)
+	c.noInstallations.Store(true)
+	if s := c.Health(HealthPolicy{}); s.Healthy || s.Reason != "account has no installations" {
+		t.Errorf("Health() = %+v, want unhealthy without installations", s)
+	}
+	c.noInstallations.Store(false)
+
+	c.lastPollAt.Store(clk.Now().UnixNano())
+	c.collectingSince.Store(clk.Now().UnixNano())
+	clk.Advance(c.fetchTimeout)
+	if s := c.Health(HealthPolicy{}); s.Wedged || !s.Healthy || s.LastPollAgeSeconds != c.fetchTimeout.Seconds() {
+		t.Errorf("Health() = %+v, want a running collection within its timeout healthy", s)
+	}
+	clk.Advance(c.fetchTimeout + time.Second)
+	if s := c.Health(HealthPolicy{}); !s.Wedged || s.Healthy {
+		t.Errorf("Health() = %+v, want wedged after twice the fetch timeout", s)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	var p probe
+	if p.reachable() != nil {
+		t.Error("reachable before any request")
+	}
+	p.record(&url.Error{Op: "Get", URL: "https://online.thermia.se", Err: errors.New("connection refused")})
+	if r := p.reachable(); r == nil || *r {
+		t.Error("reachable after a transport failure")
+	}
+	p.record(fmt.Errorf("wrapped: %w", errors.New("status 500")))
+	if r := p.reachable(); r == nil || !*r {
+		t.Error("unreachable after an error response")
+	}
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"sort"
+	"time"
+
+	"thermia_exporter/internal/types"
+)
+
+// valueHold bridges short gaps in readings: a temperature sensor or a
+// register missing from a collection keeps its last reading for up to ttl,
+// so a transient upstream glitch does not leave a hole in graphs.
+//
+// Only accessed from the collection loop.
+type valueHold struct {
+	ttl       time.Duration
+	last      map[spikeKey]heldValue
+	registers map[registerKey]heldItem
+}
+
+type heldValue struct {
+	value float64
+	at    time.Time
+}
+
+// registerKey identifies a register of an installation's register group.
+type registerKey struct {
+	installationID int64
+	group          string
+	register       string
+}
+
+type heldItem struct {
+	item types.GroupItem
+	at   time.Time
+}
+
+// heldRegister names a register whose last reading is held.
+type heldRegister struct {
+	group    string
+	register string
+}
+
+// newValueHold creates a hold. A ttl of 0 disables it.
+func newValueHold(ttl time.Duration) *valueHold {
+	return &valueHold{
+		ttl:       ttl,
+		last:      make(map[spikeKey]heldValue),
+		registers: make(map[registerKey]heldItem),
+	}
+}
+
+// enabled reports whether values are held at all.
+func (h *valueHold) enabled() bool {
+	return h.ttl > 0
+}
+
+// fill records the readings in temps (keyed by sensor) and adds the last
+// reading of sensors missing from it that were seen within ttl. It returns
+// the sorted names of the held sensors. temps must only hold accepted
+// readings, so a rejected spike is never held.
+func (h *valueHold) fill(now time.Time, installationID int64, temps map[string]float64) []string {
+	if !h.enabled() {
+		return nil
+	}
+
+	for sensor, v := range temps {
+		h.last[spikeKey{installationID, sensor}] = heldValue{value: v, at: now}
+	}
+
+	var held []string
+	for key, last := range h.last {
+		if key.installationID != installationID {
+			continue
+		}
+		if _, ok := temps[key.sensor]; ok {
+			continue
+		}
+		if now.Sub(last.at) > h.ttl {
+			delete(h.last, key)
+			continue
+		}
+		temps[key.sensor] = last.value
+		held = append(held, key.sensor)
+	}
+	sort.Strings(held)
+	return held
+}
+
+// fillRegisters records the registers in groups (keyed by register group)
+// and adds the last item of registers missing from them that were seen
+// within ttl, including those of groups that were not fetched at all. It
+// returns the held registers sorted by group and register.
+func (h *valueHold) fillRegisters(now time.Time, installationID int64, groups map[string][]types.GroupItem) []heldRegister {
+	if !h.enabled() {
+		return nil
+	}
+
+	present := make(map[registerKey]bool)
+	for group, items := range groups {
+		for _, item := range items {
+			key := registerKey{installationID, group, item.RegisterName}
+			h.registers[key] = heldItem{item: item, at: now}
+			present[key] = true
+		}
+	}
+
+	var held []heldRegister
+	for key, last := range h.registers {
+		if key.installationID != installationID || present[key] {
+			continue
+		}
+		if now.Sub(last.at) > h.ttl {
+			delete(h.registers, key)
+			continue
+		}
+		held = append(held, heldRegister{group: key.group, register: key.register})
+	}
+	sort.Slice(held, func(i, j int) bool {
+		if held[i].group != held[j].group {
+			return held[i].group < held[j].group
+		}
+		return held[i].register < held[j].register
+	})
+	for _, r := range held {
+		key := registerKey{installationID, r.group, r.register}
+		groups[r.group] = append(groups[r.group], h.registers[key].item)
+	}
+	return held
+}
+
+// forget drops everything held for an installation that is no longer
+// collected.
+func (h *valueHold) forget(installationID int64) {
+	for key := range h.last {
+		if key.installationID == installationID {
+			delete(h.last, key)
+		}
+	}
+	for key := range h.registers {
+		if key.installationID == installationID {
+			delete(h.registers, key)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+func TestValueHold(t *testing.T) {
+	h := newValueHold(30 * time.Minute)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	h.fill(start, 1, map[string]float64{"outdoor": -2.5, "hot_water": 48})
+
+	temps := map[string]float64{"hot_water": 47}
+	if held := h.fill(start.Add(15*time.Minute), 1, temps); !reflect.DeepEqual(held, []string{"outdoor"}) {
+		t.Errorf("held = %v, want [outdoor]", held)
+	}
+	if temps["outdoor"] != -2.5 || temps["hot_water"] != 47 {
+		t.Errorf("temps = %v, want the held outdoor and the fresh hot water reading", temps)
+	}
+
+	// Another installation's readings are not held for this one
+	if held := h.fill(start.Add(15*time.Minute), 2, map[string]float64{}); len(held) != 0 {
+		t.Errorf("installation 2 held = %v, want none", held)
+	}
+
+	temps = map[string]float64{"hot_water": 47}
+	if held := h.fill(start.Add(45*time.Minute), 1, temps); len(held) != 0 {
+		t.Errorf("held past the TTL = %v, want none", held)
+	}
+	if _, ok := temps["outdoor"]; ok {
+		t.Error("outdoor should be dropped once the TTL expired")
+	}
+}
+
+func TestValueHold_Registers(t *testing.T) {
+	h := newValueHold(30 * time.Minute)
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	h.fillRegisters(start, 1, map[string][]types.GroupItem{
+		mapper.RegGroupOperationalTime: {{RegisterName: "REG_OPER_TIME_COMPRESSOR", RegisterValue: ptrFloat(1200)}},
+		mapper.RegGroupHotWater:        {{RegisterName: "REG_HOT_WATER_TEMPERATURE", RegisterValue: ptrFloat(48)}},
+	})
+
+	// The hot water group failed to fetch altogether
+	groups := map[string][]types.GroupItem{
+		mapper.RegGroupOperationalTime: {{RegisterName: "REG_OPER_TIME_COMPRESSOR", RegisterValue: ptrFloat(1201)}},
+	}
+	held := h.fillRegisters(start.Add(15*time.Minute), 1, groups)
+	if want := []heldRegister{{mapper.RegGroupHotWater, "REG_HOT_WATER_TEMPERATURE"}}; !reflect.DeepEqual(held, want) {
+		t.Errorf("held = %v, want %v", held, want)
+	}
+	if items := groups[mapper.RegGroupHotWater]; len(items) != 1 || *items[0].RegisterValue != 48 {
+		t.Errorf("hot water group = %v, want the held register", items)
+	}
+	if v := *groups[mapper.RegGroupOperationalTime][0].RegisterValue; v != 1201 {
+		t.Errorf("fresh register = %v, want 1201", v)
+	}
+
+	groups = map[string][]types.GroupItem{}
+	if held := h.fillRegisters(start.Add(45*time.Minute), 1, groups); len(held) != 1 || held[0].register != "REG_OPER_TIME_COMPRESSOR" {
+		t.Errorf("held = %v, want only the register seen within the TTL", held)
+	}
+}
+
+func TestValueHold_Forget(t *testing.T) {
+	h := newValueHold(30 * time.Minute)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	h.fill(now, 1, map[string]float64{"outdoor": -2.5})
+	h.fillRegisters(now, 1, map[string][]types.GroupItem{mapper.RegGroupHotWater: {{RegisterName: "REG_HOT_WATER_TEMPERATURE"}}})
+	h.fill(now, 2, map[string]float64{"outdoor": 3})
+
+	h.forget(1)
+	if len(h.last) != 1 || len(h.registers) != 0 {
+		t.Errorf("left %d readings and %d registers, want only installation 2's reading", len(h.last), len(h.registers))
+	}
+}
+
+func TestValueHold_Disabled(t *testing.T) {
+	h := newValueHold(0)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	h.fill(now, 1, map[string]float64{"outdoor": -2.5})
+
+	temps := map[string]float64{}
+	if held := h.fill(now.Add(time.Minute), 1, temps); held != nil || len(temps) != 0 {
+		t.Errorf("disabled hold filled %v (held %v)", temps, held)
+	}
+}
+
+func TestStoreInstallation_HoldsAcceptedReadingAfterSpike(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	c := newTestCollector(clk)
+	c.spikes = newTestSpikeFilter(10)
+	c.hold = newValueHold(30 * time.Minute)
+
+	collect := func(outdoor *float64) *installationData {
+		d := &installationData{
+			inst:   types.Installation{ID: 1},
+			status: &types.InstallationStatus{},
+			groups: map[string][]types.GroupItem{},
+		}
+		if outdoor != nil {
+			d.groups[mapper.RegGroupTemperatures] = []types.GroupItem{{RegisterName: mapper.RegOutdoorTemperature, RegisterValue: outdoor}}
+		}
+		c.storeInstallation(d)
+		clk.Advance(5 * time.Minute)
+		return d
+	}
+
+	collect(ptrFloat(5))
+	// A spike, then the sensor is missing once: both times the last
+	// accepted reading is held, and the spike never becomes the baseline
+	for i, outdoor := range []*float64{ptrFloat(85), nil} {
+		d := collect(outdoor)
+		if got, ok := d.temps["outdoor"]; !ok || got != 5 {
+			t.Errorf("collection %d: outdoor = %v (present %v), want the held 5", i+2, got, ok)
+		}
+		if !reflect.DeepEqual(d.held, []string{"outdoor"}) {
+			t.Errorf("collection %d: held = %v, want [outdoor]", i+2, d.held)
+		}
+	}
+
+	d := collect(ptrFloat(5.5))
+	if got := d.temps["outdoor"]; got != 5.5 || len(d.held) != 0 {
+		t.Errorf("outdoor = %v (held %v), want the fresh 5.5", got, d.held)
+	}
+}
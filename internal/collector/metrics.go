@@ -9,28 +9,34 @@ import (
 // MetricSet holds all Prometheus metric descriptors for the Thermia exporter.
 type MetricSet struct {
 	// Temperature metrics
-	indoorTemp          *prometheus.Desc
-	outdoorTemp         *prometheus.Desc
-	supplyLineTemp      *prometheus.Desc
-	desiredSupplyTemp   *prometheus.Desc
-	returnLineTemp      *prometheus.Desc
-	bufferTankTemp      *prometheus.Desc
-	hotWaterTemp        *prometheus.Desc
-	brineOutTemp        *prometheus.Desc
-	brineInTemp         *prometheus.Desc
-	poolTemp            *prometheus.Desc
-	coolingTankTemp     *prometheus.Desc
-	coolingSupplyTemp   *prometheus.Desc
+	indoorTemp           *prometheus.Desc
+	indoorTempCalibrated *prometheus.Desc
+	outdoorTemp          *prometheus.Desc
+	supplyLineTemp       *prometheus.Desc
+	desiredSupplyTemp    *prometheus.Desc
+	returnLineTemp       *prometheus.Desc
+	bufferTankTemp       *prometheus.Desc
+	hotWaterTemp         *prometheus.Desc
+	brineOutTemp         *prometheus.Desc
+	brineInTemp          *prometheus.Desc
+	poolTemp             *prometheus.Desc
+	coolingTankTemp      *prometheus.Desc
+	coolingSupplyTemp    *prometheus.Desc
 
 	// Status metrics
-	online         *prometheus.Desc
-	lastOnlineUnix *prometheus.Desc
+	online           *prometheus.Desc
+	lastOnlineUnix   *prometheus.Desc
+	cloudDataLag     *prometheus.Desc
+	valueHeld        *prometheus.Desc
+	registerHeld     *prometheus.Desc
+	installationInfo *prometheus.Desc
 
 	// Mode/status metrics
 	operationMode      *prometheus.Desc
 	operationModeAvail *prometheus.Desc
 	operationalStatus  *prometheus.Desc
 	operationalStatusAvail *prometheus.Desc
+	operationalStatusActive *prometheus.Desc
 	powerStatus        *prometheus.Desc
 	powerStatusAvail   *prometheus.Desc
 
@@ -46,183 +52,473 @@ type MetricSet struct {
 	operTimeImm2       *prometheus.Desc
 	operTimeImm3       *prometheus.Desc
 
+	// Operational time counters (the gauges above are kept for
+	// compatibility)
+	operTimeCompressorTotal *prometheus.Desc
+	operTimeHeatingTotal    *prometheus.Desc
+	operTimeHotWaterTotal   *prometheus.Desc
+	operTimeImm1Total       *prometheus.Desc
+	operTimeImm2Total       *prometheus.Desc
+	operTimeImm3Total       *prometheus.Desc
+
 	// Alert metrics
 	activeAlerts   *prometheus.Desc
 	archivedAlerts *prometheus.Desc
 
+	// Mixing valve circuit metrics
+	circuitSupplyTemp   *prometheus.Desc
+	mixingValvePosition *prometheus.Desc
+
+	// Efficiency metrics
+	heatOutput       *prometheus.Desc
+	meterPower       *prometheus.Desc
+	measuredCOP      *prometheus.Desc
+	powerConsumption *prometheus.Desc
+	energyConsumed   *prometheus.Desc
+
+	// Compressor metrics
+	compressorStarts *prometheus.Desc
+	shortCycling     *prometheus.Desc
+	auxHeatShare     *prometheus.Desc
+
+	// Heating curve metrics
+	heatingCurve       *prometheus.Desc
+	heatingCurveMin    *prometheus.Desc
+	heatingCurveMax    *prometheus.Desc
+	heatingCurveOffset *prometheus.Desc
+	roomFactor         *prometheus.Desc
+
+	// Hydronic system metrics
+	systemPressure *prometheus.Desc
+
+	// Every numeric register, unmapped (THERMIA_EXPORT_RAW_REGISTERS)
+	registerValue *prometheus.Desc
+
+	// Refrigerant circuit estimates
+	superheat          *prometheus.Desc
+	dischargeSuperheat *prometheus.Desc
+	subcooling         *prometheus.Desc
+
+	// Schedule metrics
+	nextOperationMode *prometheus.Desc
+	scheduleConflict  *prometheus.Desc
+
+	// Priority metrics
+	prioritySetting *prometheus.Desc
+	priorityCurrent *prometheus.Desc
+
 	// Scrape metrics
-	scrapeErrors   prometheus.Counter
-	scrapeDuration prometheus.Histogram
-	lastSuccess    prometheus.Gauge
+	scrapeErrors    prometheus.Counter
+	scrapeDuration  prometheus.Histogram
+	lastSuccess     prometheus.Gauge
+	scrapeTruncated *prometheus.GaugeVec
+	sectionSuccess  *prometheus.GaugeVec
+
+	// Poller health metrics
+	consecutiveFailures prometheus.Gauge
+	pollerRestarts      prometheus.Counter
+	tokenRenewals       *prometheus.CounterVec
+	authInfo            *prometheus.GaugeVec
+	clientRejected      *prometheus.GaugeVec
+	loginPageInfo       *prometheus.GaugeVec
+	breakerState        prometheus.Gauge
+
+	// Data quality metrics
+	rejectedSamples   *prometheus.CounterVec
+	unmappedRegisters *prometheus.GaugeVec
+	registerConflicts *prometheus.CounterVec
+	groupItems        *prometheus.GaugeVec
+	parseFailures     *prometheus.CounterVec
+	unitMismatches    *prometheus.CounterVec
+	counterResets     *prometheus.CounterVec
+	exportedSeries    prometheus.Gauge
+	seriesDropped     prometheus.Counter
+
+	// Startup probe metrics
+	groupSupported   *prometheus.GaugeVec
+	writableRegister *prometheus.GaugeVec
+
+	// Exporter configuration metrics
+	pollInterval prometheus.Gauge
+	scrapeMode   *prometheus.GaugeVec
+	pollPolicy   *prometheus.GaugeVec
 }
 
-// newMetricSet creates all metric descriptors.
-func newMetricSet() *MetricSet {
+// newMetricSet creates all metric descriptors. A non-empty account is added
+// as the account label of every heat pump metric.
+func newMetricSet(account string) *MetricSet {
+	var constLabels prometheus.Labels
+	if account != "" {
+		constLabels = prometheus.Labels{mapper.LabelAccount: account}
+	}
 	labels := []string{mapper.LabelHeatpumpID, mapper.LabelHeatpumpName, mapper.LabelModel}
 	labelsWithMode := append(labels, mapper.LabelMode)
 	labelsWithStatus := append(labels, mapper.LabelStatus)
+	labelsWithCircuit := append(labels, mapper.LabelCircuit)
+	estimatedLabels := prometheus.Labels{mapper.LabelEstimated: "true"}
+	for name, value := range constLabels {
+		estimatedLabels[name] = value
+	}
 
 	return &MetricSet{
 		// Temperature metrics
 		indoorTemp: prometheus.NewDesc(
 			"thermia_indoor_temperature_celsius",
 			"Indoor temperature (°C)",
-			labels, nil,
+			labels, constLabels,
+		),
+		indoorTempCalibrated: prometheus.NewDesc(
+			"thermia_indoor_temperature_calibrated_celsius",
+			"Indoor temperature with the configured sensor offset applied (°C)",
+			labels, constLabels,
 		),
 		outdoorTemp: prometheus.NewDesc(
 			"thermia_outdoor_temperature_celsius",
 			"Outdoor temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		supplyLineTemp: prometheus.NewDesc(
 			"thermia_supply_line_temperature_celsius",
 			"Supply line temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		desiredSupplyTemp: prometheus.NewDesc(
 			"thermia_desired_supply_line_temperature_celsius",
 			"Desired supply line temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		returnLineTemp: prometheus.NewDesc(
 			"thermia_return_line_temperature_celsius",
 			"Return line temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		bufferTankTemp: prometheus.NewDesc(
 			"thermia_buffer_tank_temperature_celsius",
 			"Buffer tank temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		hotWaterTemp: prometheus.NewDesc(
 			"thermia_hot_water_temperature_celsius",
 			"Hot water temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		brineOutTemp: prometheus.NewDesc(
 			"thermia_brine_out_temperature_celsius",
 			"Brine out temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		brineInTemp: prometheus.NewDesc(
 			"thermia_brine_in_temperature_celsius",
 			"Brine in temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		poolTemp: prometheus.NewDesc(
 			"thermia_pool_temperature_celsius",
 			"Pool temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		coolingTankTemp: prometheus.NewDesc(
 			"thermia_cooling_tank_temperature_celsius",
 			"Cooling tank temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 		coolingSupplyTemp: prometheus.NewDesc(
 			"thermia_cooling_supply_temperature_celsius",
 			"Cooling supply line temperature (°C)",
-			labels, nil,
+			labels, constLabels,
 		),
 
 		// Status metrics
+		installationInfo: prometheus.NewDesc(
+			"thermia_installation_info",
+			"Installation metadata (always 1); site and installation_group are set for professional accounts, serial if the portal reports the device serial or MAC address",
+			append(labels, mapper.LabelSite, mapper.LabelGroupName, mapper.LabelSerial), constLabels,
+		),
 		online: prometheus.NewDesc(
 			"thermia_online",
 			"Online (1) / Offline (0)",
-			labels, nil,
+			labels, constLabels,
 		),
 		lastOnlineUnix: prometheus.NewDesc(
 			"thermia_last_online_unix",
 			"Last online timestamp (unix seconds)",
-			labels, nil,
+			labels, constLabels,
+		),
+		cloudDataLag: prometheus.NewDesc(
+			"thermia_cloud_data_lag_seconds",
+			"Age of the newest data the Thermia cloud returned at collection time (newest register timestamp, else last online)",
+			labels, constLabels,
+		),
+		valueHeld: prometheus.NewDesc(
+			"thermia_value_held",
+			"1 if the sensor's last reading is held because it was missing from the latest collection",
+			append(labels, mapper.LabelSensor), constLabels,
+		),
+		registerHeld: prometheus.NewDesc(
+			"thermia_register_held",
+			"Present (1) while the register's last reading is held because it was missing from the latest collection",
+			append(labels, mapper.LabelRegisterName, mapper.LabelGroup), constLabels,
 		),
 
 		// Mode/status metrics
 		operationMode: prometheus.NewDesc(
 			"thermia_operation_mode",
 			"Current operation mode (1 for current)",
-			labelsWithMode, nil,
+			labelsWithMode, constLabels,
 		),
 		operationModeAvail: prometheus.NewDesc(
 			"thermia_operation_mode_available",
 			"Available operation modes (1)",
-			labelsWithMode, nil,
+			labelsWithMode, constLabels,
 		),
 		operationalStatus: prometheus.NewDesc(
 			"thermia_operational_status_running",
 			"Operational status one-hot (1 for current, 0 for others)",
-			labelsWithStatus, nil,
+			labelsWithStatus, constLabels,
 		),
 		operationalStatusAvail: prometheus.NewDesc(
 			"thermia_operational_status_available",
 			"Operational statuses available (1)",
-			labelsWithStatus, nil,
+			labelsWithStatus, constLabels,
+		),
+		operationalStatusActive: prometheus.NewDesc(
+			"thermia_operational_status_active",
+			"Operational statuses the heat pump reports running (1), without picking one",
+			labelsWithStatus, constLabels,
 		),
 		powerStatus: prometheus.NewDesc(
 			"thermia_power_status_running",
 			"Power status bits that are running (1)",
-			labelsWithStatus, nil,
+			labelsWithStatus, constLabels,
 		),
 		powerStatusAvail: prometheus.NewDesc(
 			"thermia_power_status_available",
 			"Power statuses available (1)",
-			labelsWithStatus, nil,
+			labelsWithStatus, constLabels,
 		),
 
 		// Hot water metrics
 		hotWaterSwitch: prometheus.NewDesc(
 			"thermia_hot_water_switch_state",
 			"Hot water switch state (0/1)",
-			labels, nil,
+			labels, constLabels,
 		),
 		hotWaterBoost: prometheus.NewDesc(
 			"thermia_hot_water_boost_state",
 			"Hot water boost state (0/1)",
-			labels, nil,
+			labels, constLabels,
 		),
 
 		// Operational time metrics
 		operTimeCompressor: prometheus.NewDesc(
 			"thermia_oper_time_compressor_hours",
 			"Operational time - compressor (hours)",
-			labels, nil,
+			labels, constLabels,
 		),
 		operTimeHeating: prometheus.NewDesc(
 			"thermia_oper_time_heating_hours",
 			"Operational time - heating (hours)",
-			labels, nil,
+			labels, constLabels,
 		),
 		operTimeHotWater: prometheus.NewDesc(
 			"thermia_oper_time_hot_water_hours",
 			"Operational time - hot water (hours)",
-			labels, nil,
+			labels, constLabels,
 		),
 		operTimeImm1: prometheus.NewDesc(
 			"thermia_oper_time_imm1_hours",
 			"Operational time - aux heater 1 (hours)",
-			labels, nil,
+			labels, constLabels,
 		),
 		operTimeImm2: prometheus.NewDesc(
 			"thermia_oper_time_imm2_hours",
 			"Operational time - aux heater 2 (hours)",
-			labels, nil,
+			labels, constLabels,
 		),
 		operTimeImm3: prometheus.NewDesc(
 			"thermia_oper_time_imm3_hours",
 			"Operational time - aux heater 3 (hours)",
-			labels, nil,
+			labels, constLabels,
+		),
+		operTimeCompressorTotal: prometheus.NewDesc(
+			"thermia_oper_time_compressor_hours_total",
+			"Lifetime operational time - compressor (hours)",
+			labels, constLabels,
+		),
+		operTimeHeatingTotal: prometheus.NewDesc(
+			"thermia_oper_time_heating_hours_total",
+			"Lifetime operational time - heating (hours)",
+			labels, constLabels,
+		),
+		operTimeHotWaterTotal: prometheus.NewDesc(
+			"thermia_oper_time_hot_water_hours_total",
+			"Lifetime operational time - hot water (hours)",
+			labels, constLabels,
+		),
+		operTimeImm1Total: prometheus.NewDesc(
+			"thermia_oper_time_imm1_hours_total",
+			"Lifetime operational time - aux heater 1 (hours)",
+			labels, constLabels,
+		),
+		operTimeImm2Total: prometheus.NewDesc(
+			"thermia_oper_time_imm2_hours_total",
+			"Lifetime operational time - aux heater 2 (hours)",
+			labels, constLabels,
+		),
+		operTimeImm3Total: prometheus.NewDesc(
+			"thermia_oper_time_imm3_hours_total",
+			"Lifetime operational time - aux heater 3 (hours)",
+			labels, constLabels,
 		),
 
 		// Alert metrics
 		activeAlerts: prometheus.NewDesc(
 			"thermia_active_alerts",
 			"Number of active alerts",
-			labels, nil,
+			labels, constLabels,
 		),
 		archivedAlerts: prometheus.NewDesc(
 			"thermia_archived_alerts",
 			"Number of archived alerts (history minus active)",
-			labels, nil,
+			labels, constLabels,
+		),
+
+		// Mixing valve circuit metrics
+		circuitSupplyTemp: prometheus.NewDesc(
+			"thermia_circuit_supply_temperature_celsius",
+			"Mixing valve circuit supply temperature (°C)",
+			labelsWithCircuit, constLabels,
+		),
+		mixingValvePosition: prometheus.NewDesc(
+			"thermia_mixing_valve_position_percent",
+			"Mixing valve position (%)",
+			labelsWithCircuit, constLabels,
+		),
+
+		// Efficiency metrics
+		heatOutput: prometheus.NewDesc(
+			"thermia_heat_output_watts",
+			"Heat output reported by the heat pump (W)",
+			labels, constLabels,
+		),
+		meterPower: prometheus.NewDesc(
+			"thermia_meter_power_watts",
+			"Electrical power from the configured external energy meter (W)",
+			labels, constLabels,
+		),
+		measuredCOP: prometheus.NewDesc(
+			"thermia_measured_cop",
+			"Measured coefficient of performance (heat output / metered electrical power)",
+			labels, constLabels,
+		),
+		powerConsumption: prometheus.NewDesc(
+			"thermia_power_consumption_watts",
+			"Electrical power draw reported by the heat pump (W)",
+			labels, constLabels,
+		),
+		energyConsumed: prometheus.NewDesc(
+			"thermia_energy_consumed_kwh_total",
+			"Electrical energy consumed as counted by the heat pump (kWh)",
+			labels, constLabels,
+		),
+
+		// Compressor metrics
+		compressorStarts: prometheus.NewDesc(
+			"thermia_compressor_starts_total",
+			"Compressor starts, from the starts register or derived from status transitions between collections (source)",
+			append(labels, mapper.LabelSource), constLabels,
+		),
+		shortCycling: prometheus.NewDesc(
+			"thermia_short_cycling_suspected",
+			"1 if compressor starts per hour exceed the configured short-cycling threshold",
+			labels, constLabels,
+		),
+		auxHeatShare: prometheus.NewDesc(
+			"thermia_aux_heat_share_ratio",
+			"Share of auxiliary heater operating time in total heat production time over the configured window",
+			labels, constLabels,
+		),
+
+		// Heating curve metrics
+		heatingCurve: prometheus.NewDesc(
+			"thermia_heating_curve",
+			"Selected heating curve (slope of supply line temperature over outdoor temperature)",
+			labels, constLabels,
+		),
+		heatingCurveMin: prometheus.NewDesc(
+			"thermia_heating_curve_min_celsius",
+			"Lowest supply line temperature the heating curve asks for (°C)",
+			labels, constLabels,
+		),
+		heatingCurveMax: prometheus.NewDesc(
+			"thermia_heating_curve_max_celsius",
+			"Highest supply line temperature the heating curve asks for (°C)",
+			labels, constLabels,
+		),
+		heatingCurveOffset: prometheus.NewDesc(
+			"thermia_heating_curve_offset_celsius",
+			"Heating curve offset set with the comfort wheel (°C)",
+			labels, constLabels,
+		),
+		roomFactor: prometheus.NewDesc(
+			"thermia_room_factor",
+			"Configured influence of the room sensor on the supply line temperature (0: ignored)",
+			labels, constLabels,
+		),
+
+		// Hydronic system metrics
+		systemPressure: prometheus.NewDesc(
+			"thermia_system_pressure_bar",
+			"Heating system (hydronic) pressure from an add-on pressure sensor (bar)",
+			labels, constLabels,
+		),
+
+		// Raw register metrics
+		registerValue: prometheus.NewDesc(
+			"thermia_register_value",
+			"Value of a numeric register as reported by the API, converted to the base unit in the unit label",
+			append(labels, mapper.LabelRegisterName, mapper.LabelGroup, mapper.LabelUnit), constLabels,
+		),
+
+		// Refrigerant circuit estimates
+		superheat: prometheus.NewDesc(
+			"thermia_superheat_kelvin",
+			"Estimated suction gas superheat above the evaporating temperature",
+			labels, estimatedLabels,
+		),
+		dischargeSuperheat: prometheus.NewDesc(
+			"thermia_discharge_superheat_kelvin",
+			"Estimated hot gas (discharge) superheat above the condensing temperature",
+			labels, estimatedLabels,
+		),
+		subcooling: prometheus.NewDesc(
+			"thermia_subcooling_kelvin",
+			"Estimated liquid line subcooling below the condensing temperature",
+			labels, estimatedLabels,
+		),
+
+		// Schedule metrics
+		nextOperationMode: prometheus.NewDesc(
+			"thermia_next_operation_mode_timestamp_seconds",
+			"Start of the next scheduled operation mode change (unix seconds), by scheduled mode",
+			labelsWithMode, constLabels,
+		),
+		scheduleConflict: prometheus.NewDesc(
+			"thermia_schedule_conflict",
+			"1 if an EVU block and a hot water block schedule both overlap a legionella cycle in the coming week",
+			append(labels, mapper.LabelLegionellaSchedule, mapper.LabelEVUBlockSchedule, mapper.LabelHotWaterBlockSchedule), constLabels,
+		),
+
+		// Priority metrics
+		prioritySetting: prometheus.NewDesc(
+			"thermia_priority_setting",
+			"Configured priority between hot water and heating (always 1)",
+			append(labels, mapper.LabelPriority), constLabels,
+		),
+		priorityCurrent: prometheus.NewDesc(
+			"thermia_priority_current",
+			"Current priority decision of the heat pump (always 1)",
+			append(labels, mapper.LabelPriority), constLabels,
 		),
 
 		// Scrape metrics
@@ -239,5 +535,105 @@ func newMetricSet() *MetricSet {
 			Name: "thermia_last_collection_success_timestamp_seconds",
 			Help: "Unix timestamp of the last successful Thermia API collection",
 		}),
+		scrapeTruncated: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_scrape_truncated",
+			Help: "1 if the fetch stage was skipped in the last collection because it would not finish before the deadline",
+		}, []string{mapper.LabelStage}),
+		sectionSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_scrape_section_success",
+			Help: "1 if the data section of the installation was fetched in its last collection, 0 if it failed or was skipped",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelSection}),
+
+		// Poller health metrics
+		consecutiveFailures: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_consecutive_collection_failures",
+			Help: "Number of background collections that failed in a row",
+		}),
+		pollerRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thermia_poller_restarts_total",
+			Help: "Times the authentication client and token cache were rebuilt after consecutive failed collections",
+		}),
+		tokenRenewals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_token_renewals_total",
+			Help: "Access token renewals by grant (refresh_token or password login) and result",
+		}, []string{mapper.LabelGrant, mapper.LabelResult}),
+		authInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_auth_info",
+			Help: "Identity of the current access token: a hash of its subject, its tenant and type (1)",
+		}, []string{mapper.LabelSubjectHash, mapper.LabelTenant, mapper.LabelTokenType}),
+		clientRejected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_auth_client_rejected",
+			Help: "1 if the token endpoint rejected the exporter's client ID or scopes on the last renewal, by OAuth2 error code",
+		}, []string{mapper.LabelError}),
+		loginPageInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_login_page_info",
+			Help: "Version of the B2C login page seen by the last password login: its policy, page template and layout contract (1)",
+		}, []string{mapper.LabelPolicy, mapper.LabelTemplate, mapper.LabelContract}),
+		breakerState: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_circuit_breaker_state",
+			Help: "State of the Thermia API circuit breaker after the last collection (0 closed, 1 open, 2 half-open)",
+		}),
+
+		// Data quality metrics
+		rejectedSamples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_rejected_samples_total",
+			Help: "Temperature samples dropped by spike rejection",
+		}, []string{mapper.LabelSensor}),
+		unmappedRegisters: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_unmapped_registers",
+			Help: "Registers exposed by the heat pump that no metric is derived from, per register group (refreshed daily)",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelGroup}),
+		registerConflicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_register_conflicts_total",
+			Help: "Registers reported in several groups with values that disagree beyond the tolerance",
+		}, []string{mapper.LabelRegister}),
+		groupItems: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_register_group_items",
+			Help: "Items in the last register group payload fetched by a collection",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelGroup}),
+		parseFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_register_parse_failures_total",
+			Help: "Register group payloads that could not be decoded and items without a register name",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelGroup}),
+		unitMismatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_register_unit_mismatches_total",
+			Help: "Collections in which a hand-mapped register reported a different unit than its metric assumes",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelRegister}),
+		counterResets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "thermia_oper_time_counter_resets_total",
+			Help: "Operating time counters found lower than in the previous collection, e.g. after a compressor replacement",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelRegister}),
+		exportedSeries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_exported_series",
+			Help: "Heat pump series the last scrape emitted, after the series cap",
+		}),
+		seriesDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "thermia_series_dropped_total",
+			Help: "Heat pump series dropped by the series cap, summed over scrapes (a series dropped on every scrape counts every time)",
+		}),
+
+		// Startup probe metrics
+		groupSupported: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_register_group_supported",
+			Help: "1 if the heat pump returned data for the register group in the startup probe",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelGroup}),
+		writableRegister: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_writable_register_info",
+			Help: "Writable registers found by the startup probe (always 1)",
+		}, []string{mapper.LabelHeatpumpID, mapper.LabelGroup, mapper.LabelRegister}),
+
+		// Exporter configuration metrics
+		pollInterval: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "thermia_poll_interval_seconds",
+			Help: "Configured interval between Thermia API collections",
+		}),
+		scrapeMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_scrape_mode",
+			Help: "How the Thermia API is collected (1 for the active mode)",
+		}, []string{mapper.LabelMode}),
+		pollPolicy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "thermia_poll_policy",
+			Help: "Active polling policy: normal, or quiet during quiet hours (1 for the active policy)",
+		}, []string{mapper.LabelPolicy}),
 	}
 }
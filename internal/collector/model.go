@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/mapper"
+)
+
+// ModelReport describes what the exporter derives from one installation:
+// the metrics it emits and which registers were found, used or missing. It
+// is served on /debug/model for support requests and new model profiles.
+type ModelReport struct {
+	HeatpumpID    int64     `json:"heatpump_id"`
+	HeatpumpName  string    `json:"heatpump_name"`
+	HeatpumpModel string    `json:"heatpump_model"`
+	CollectedAt   time.Time `json:"collected_at"`
+
+	// Metrics are the names of the metrics emitted in the last collection.
+	Metrics []string `json:"metrics"`
+
+	// Groups reports the registers of every fetched register group.
+	Groups map[string]GroupReport `json:"groups"`
+
+	// RegistersNotFound are registers the exporter maps that the
+	// installation does not expose (many are alternatives for other models).
+	RegistersNotFound []string `json:"registers_not_found"`
+}
+
+// GroupReport lists the registers of one register group.
+type GroupReport struct {
+	Mapped   []string `json:"mapped"`
+	Unmapped []string `json:"unmapped"`
+}
+
+// buildModelReport builds the report for d from the metrics emitted for it.
+// labels are the id, name and model labels returned by installationLabels.
+func buildModelReport(d *installationData, labels []string, metrics []prometheus.Metric, at time.Time) ModelReport {
+	r := ModelReport{
+		HeatpumpID:        d.inst.ID,
+		HeatpumpName:      labels[1],
+		HeatpumpModel:     labels[2],
+		CollectedAt:       at,
+		Metrics:           metricNames(metrics),
+		Groups:            make(map[string]GroupReport, len(d.groups)),
+		RegistersNotFound: mapper.MissingRegisters(d.groups),
+	}
+	for group, items := range d.groups {
+		r.Groups[group] = GroupReport{
+			Mapped:   mapper.MappedRegisters(items),
+			Unmapped: mapper.UnmappedRegisters(items),
+		}
+	}
+	return r
+}
+
+// ModelReports returns the report of every collected installation, ordered
+// by installation ID.
+func (c *ThermiaCollector) ModelReports() []ModelReport {
+	c.reportsMu.RLock()
+	defer c.reportsMu.RUnlock()
+
+	reports := make([]ModelReport, 0, len(c.reports))
+	for _, r := range c.reports {
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].HeatpumpID < reports[j].HeatpumpID })
+	return reports
+}
+
+// setModelReport stores the report for an installation.
+func (c *ThermiaCollector) setModelReport(r ModelReport) {
+	c.reportsMu.Lock()
+	defer c.reportsMu.Unlock()
+	c.reports[r.HeatpumpID] = r
+}
+
+// metricNames returns the sorted, de-duplicated names of metrics.
+func metricNames(metrics []prometheus.Metric) []string {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(metricList(metrics)); err != nil {
+		return nil
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(families))
+	for _, mf := range families {
+		names = append(names, mf.GetName())
+	}
+	return names
+}
+
+// metricList is an unchecked collector emitting a fixed set of metrics.
+type metricList []prometheus.Metric
+
+func (metricList) Describe(chan<- *prometheus.Desc) {}
+
+func (ml metricList) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range ml {
+		ch <- m
+	}
+}
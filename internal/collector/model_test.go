@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+)
+
+func TestModelReport(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+
+	reports := c.ModelReports()
+	if len(reports) != 1 {
+		t.Fatalf("ModelReports() returned %d reports, want 1", len(reports))
+	}
+	r := reports[0]
+
+	if !slices.Contains(r.Metrics, "thermia_outdoor_temperature_celsius") {
+		t.Errorf("Metrics = %v, want thermia_outdoor_temperature_celsius", r.Metrics)
+	}
+	if !slices.IsSorted(r.Metrics) {
+		t.Errorf("Metrics = %v, want sorted", r.Metrics)
+	}
+
+	temps, ok := r.Groups[mapper.RegGroupTemperatures]
+	if !ok || !slices.Contains(temps.Mapped, mapper.RegOutdoorTemperature) {
+		t.Errorf("temperatures group = %+v, want %s mapped", temps, mapper.RegOutdoorTemperature)
+	}
+	if slices.Contains(r.RegistersNotFound, mapper.RegOutdoorTemperature) {
+		t.Errorf("RegistersNotFound contains found register %s", mapper.RegOutdoorTemperature)
+	}
+}
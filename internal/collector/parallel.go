@@ -0,0 +1,31 @@
+package collector
+
+import "sync"
+
+// parallel runs fetches concurrently, at most c.fetchConcurrency at a time,
+// and returns when all have finished. Fetches must guard any state they
+// share.
+func (c *ThermiaCollector) parallel(fetches ...func()) {
+	limit := c.fetchConcurrency
+	if limit <= 1 || len(fetches) == 1 {
+		for _, fetch := range fetches {
+			fetch()
+		}
+		return
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, fetch := range fetches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(fetch func()) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fetch()
+		}(fetch)
+	}
+	wg.Wait()
+}
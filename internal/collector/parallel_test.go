@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestParallel(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	for _, limit := range []int{0, 1, 3} {
+		c.fetchConcurrency = limit
+		var (
+			mu            sync.Mutex
+			running, peak int
+			done          int
+		)
+		fetch := func() {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running--
+			done++
+			mu.Unlock()
+		}
+		c.parallel(fetch, fetch, fetch, fetch, fetch, fetch)
+
+		if done != 6 {
+			t.Errorf("limit %d: %d fetches finished, want 6", limit, done)
+		}
+		if want := max(limit, 1); peak > want {
+			t.Errorf("limit %d: %d fetches ran at once, want at most %d", limit, peak, want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package collector
+
+import (
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+// pollPlan decides which installations are due for collection. Each
+// installation is polled at its own interval (the default unless
+// overridden); installations are spread evenly over their interval after
+// their first collection so their polls do not hit the API at once. During
+// quiet hours no installation is polled more often than quietInterval.
+// Every poll is delayed by a random duration up to jitter, so exporters
+// started together drift apart instead of polling in lockstep.
+//
+// Only accessed from the collection loop.
+type pollPlan struct {
+	interval  time.Duration
+	intervals map[int64]time.Duration
+	next      map[int64]time.Time
+
+	quiet         clock.DailyWindow
+	quietInterval time.Duration
+
+	jitter time.Duration
+	random func(n int64) int64
+}
+
+// newPollPlan creates a plan polling at interval, with per-installation
+// overrides.
+func newPollPlan(interval time.Duration, intervals map[int64]time.Duration) *pollPlan {
+	return &pollPlan{
+		interval:  interval,
+		intervals: intervals,
+		next:      make(map[int64]time.Time),
+		random:    rand.Int64N,
+	}
+}
+
+// randomDelay returns a random duration in [0, limit), or 0 if limit is
+// not positive.
+func (p *pollPlan) randomDelay(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(p.random(int64(limit)))
+}
+
+// intervalFor returns the poll interval of an installation outside quiet
+// hours.
+func (p *pollPlan) intervalFor(id int64) time.Duration {
+	if d, ok := p.intervals[id]; ok {
+		return d
+	}
+	return p.interval
+}
+
+// isQuiet reports whether quiet hours apply at now.
+func (p *pollPlan) isQuiet(now time.Time) bool {
+	return p.quietInterval > 0 && p.quiet.Contains(now)
+}
+
+// nextPoll returns when an installation polled at now is due again,
+// including jitter.
+func (p *pollPlan) nextPoll(id int64, now time.Time) time.Time {
+	return p.unjitteredPoll(id, now).Add(p.randomDelay(p.jitter))
+}
+
+// unjitteredPoll returns when an installation polled at now is due again. A
+// poll slowed down by quiet hours happens no later than their end.
+func (p *pollPlan) unjitteredPoll(id int64, now time.Time) time.Time {
+	interval := p.intervalFor(id)
+	next := now.Add(interval)
+	if !p.isQuiet(now) || p.quietInterval <= interval {
+		return next
+	}
+
+	next = now.Add(p.quietInterval)
+	if end := p.quiet.EndAfter(now); end.Before(next) {
+		next = end
+	}
+	if earliest := now.Add(interval); next.Before(earliest) {
+		next = earliest
+	}
+	return next
+}
+
+// due returns the installations to collect at now and schedules their next
+// poll. Installations seen for the first time are always due; the k-th of n
+// new installations is then delayed by k/n of its interval once.
+func (p *pollPlan) due(now time.Time, installations []types.Installation) []types.Installation {
+	var due, fresh []types.Installation
+	for _, inst := range installations {
+		next, known := p.next[inst.ID]
+		switch {
+		case !known:
+			fresh = append(fresh, inst)
+		case !now.Before(next):
+			p.next[inst.ID] = p.nextPoll(inst.ID, now)
+			due = append(due, inst)
+		}
+	}
+	for k, inst := range fresh {
+		interval := p.intervalFor(inst.ID)
+		p.next[inst.ID] = p.nextPoll(inst.ID, now).Add(interval * time.Duration(k) / time.Duration(len(fresh)))
+	}
+
+	due = append(due, fresh...)
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+
+	// Forget installations that left the account
+	if len(p.next) > len(installations) {
+		listed := make(map[int64]bool, len(installations))
+		for _, inst := range installations {
+			listed[inst.ID] = true
+		}
+		for id := range p.next {
+			if !listed[id] {
+				delete(p.next, id)
+			}
+		}
+	}
+	return due
+}
+
+// postpone reschedules every overdue installation one interval from now,
+// after a collection round failed before any installation was collected.
+func (p *pollPlan) postpone(now time.Time) {
+	for id, next := range p.next {
+		if !now.Before(next) {
+			p.next[id] = p.nextPoll(id, now)
+		}
+	}
+}
+
+// wait returns how long to wait until the next installation is due, or the
+// default interval if no installation is known yet.
+func (p *pollPlan) wait(now time.Time) time.Duration {
+	if len(p.next) == 0 {
+		return p.interval
+	}
+	var earliest time.Time
+	for _, next := range p.next {
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	return max(earliest.Sub(now), 0)
+}
+
+// Values of the thermia_poll_policy policy label.
+const (
+	pollPolicyNormal = "normal"
+	pollPolicyQuiet  = "quiet"
+)
+
+// recordPollPolicy exports whether quiet hours apply at now.
+func (c *ThermiaCollector) recordPollPolicy(now time.Time) {
+	quiet := 0.0
+	if c.polls.isQuiet(now) {
+		quiet = 1
+	}
+	c.metrics.pollPolicy.WithLabelValues(pollPolicyNormal).Set(1 - quiet)
+	c.metrics.pollPolicy.WithLabelValues(pollPolicyQuiet).Set(quiet)
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/types"
+)
+
+func dueIDs(insts []types.Installation) []int64 {
+	ids := make([]int64, 0, len(insts))
+	for _, inst := range insts {
+		ids = append(ids, inst.ID)
+	}
+	return ids
+}
+
+func TestPollPlan(t *testing.T) {
+	p := newPollPlan(10*time.Minute, map[int64]time.Duration{1: time.Minute})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	installations := []types.Installation{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	// Everything is collected on the first round
+	if got := dueIDs(p.due(now, installations)); len(got) != 3 {
+		t.Fatalf("first round due = %v, want all", got)
+	}
+	if got := p.wait(now); got != time.Minute {
+		t.Errorf("wait = %v, want 1m (installation 1)", got)
+	}
+
+	// Installation 1 is polled every minute; 2 and 3 are spread over
+	// their 10 minute interval (second and third of three new ones)
+	var polls []int64
+	for m := 1; m <= 25; m++ {
+		for _, id := range dueIDs(p.due(now.Add(time.Duration(m)*time.Minute), installations)) {
+			if id != 1 {
+				polls = append(polls, int64(m)*100+id)
+			}
+		}
+	}
+	want := []int64{1402, 1703, 2402}
+	if len(polls) != len(want) {
+		t.Fatalf("polls of 2 and 3 (minute*100+id) = %v, want %v", polls, want)
+	}
+	for i := range want {
+		if polls[i] != want[i] {
+			t.Errorf("polls of 2 and 3 (minute*100+id) = %v, want %v", polls, want)
+			break
+		}
+	}
+}
+
+func TestPollPlan_Postpone(t *testing.T) {
+	p := newPollPlan(10*time.Minute, nil)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	if got := p.wait(now); got != 10*time.Minute {
+		t.Errorf("wait without installations = %v, want the default interval", got)
+	}
+
+	p.due(now, []types.Installation{{ID: 1}})
+	later := now.Add(15 * time.Minute)
+	p.postpone(later)
+	if got := p.wait(later); got != 10*time.Minute {
+		t.Errorf("wait after a failed round = %v, want 10m", got)
+	}
+
+	// Installations that left the account are forgotten
+	p.due(later.Add(10*time.Minute), []types.Installation{{ID: 2}})
+	if _, ok := p.next[1]; ok {
+		t.Error("installation 1 is still scheduled")
+	}
+}
+
+func TestPollPlan_QuietHours(t *testing.T) {
+	p := newPollPlan(5*time.Minute, map[int64]time.Duration{2: time.Hour})
+	p.quiet = clock.DailyWindow{Start: 2 * time.Hour, End: 5 * time.Hour}
+	p.quietInterval = 30 * time.Minute
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)
+
+	if p.isQuiet(day.Add(time.Hour)) || !p.isQuiet(day.Add(3*time.Hour)) {
+		t.Fatal("quiet hours not applied to 02:00-05:00")
+	}
+	for _, tc := range []struct {
+		id   int64
+		now  time.Duration
+		want time.Duration
+	}{
+		{1, time.Hour, time.Hour + 5*time.Minute},                      // before quiet hours
+		{1, 3 * time.Hour, 3*time.Hour + 30*time.Minute},               // slowed down
+		{1, 4*time.Hour + 50*time.Minute, 5 * time.Hour},               // no later than the end
+		{1, 4*time.Hour + 58*time.Minute, 5*time.Hour + 3*time.Minute}, // but not faster than usual
+		{2, 3 * time.Hour, 4 * time.Hour},                              // already slower than quiet
+	} {
+		if got := p.nextPoll(tc.id, day.Add(tc.now)); !got.Equal(day.Add(tc.want)) {
+			t.Errorf("nextPoll(%d, %v) = %v, want %v", tc.id, tc.now, got.Sub(day), tc.want)
+		}
+	}
+}
+
+func TestPollPlan_Jitter(t *testing.T) {
+	p := newPollPlan(10*time.Minute, nil)
+	p.jitter = time.Minute
+	var limits []int64
+	p.random = func(n int64) int64 {
+		limits = append(limits, n)
+		return n / 2
+	}
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	p.due(now, []types.Installation{{ID: 1}})
+	if got := p.wait(now); got != 10*time.Minute+30*time.Second {
+		t.Errorf("wait = %v, want 10m30s (interval plus jitter)", got)
+	}
+	if len(limits) != 1 || limits[0] != int64(time.Minute) {
+		t.Errorf("random called with %v, want one call bounded by 1m", limits)
+	}
+
+	if got := p.randomDelay(0); got != 0 {
+		t.Errorf("randomDelay(0) = %v, want 0", got)
+	}
+}
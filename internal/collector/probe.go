@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+// Capability is what the startup probe found for one installation.
+type Capability struct {
+	InstallationID int64
+	Name           string
+	Model          string
+
+	// Groups are the register groups the heat pump returned data for.
+	Groups []string
+
+	// Writable are the writable registers per supported group.
+	Writable map[string][]string
+}
+
+// Probe fetches every installation of the account once and reports which
+// register groups and writable registers each one exposes. The result is
+// logged and exported as thermia_register_group_supported and
+// thermia_writable_register_info. It fails if the account cannot be read or
+// an installation returns no register group at all, so a broken deployment
+// is noticed at startup rather than on the first dashboard.
+//
+// Probe must be called before Run.
+func (c *ThermiaCollector) Probe(ctx context.Context) ([]Capability, error) {
+	apiClient, err := c.newAPIClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	installations, err := apiClient.GetInstallations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get installations: %w", err)
+	}
+	if len(installations) == 0 {
+		return nil, errors.New("no installations found")
+	}
+
+	var caps []Capability
+	var errs []error
+	for _, inst := range installations {
+		capability := c.probeInstallation(ctx, apiClient, inst)
+		c.recordCapability(capability)
+		c.logger.Info("Installation capabilities", "id", capability.InstallationID,
+			"name", capability.Name, "model", capability.Model,
+			"groups", capability.Groups, "writable_registers", capability.writableCount())
+		for _, group := range capability.Groups {
+			if registers := capability.Writable[group]; len(registers) > 0 {
+				c.logger.Debug("Writable registers", "id", capability.InstallationID, "group", group, "registers", registers)
+			}
+		}
+
+		if len(capability.Groups) == 0 {
+			errs = append(errs, fmt.Errorf("installation %d: no register group available", inst.ID))
+		}
+		caps = append(caps, capability)
+	}
+
+	return caps, errors.Join(errs...)
+}
+
+// probeInstallation fetches the info and every discovery group of inst.
+func (c *ThermiaCollector) probeInstallation(ctx context.Context, apiClient *api.APIClient, inst types.Installation) Capability {
+	d := &installationData{inst: inst, groups: make(map[string][]types.GroupItem)}
+
+	info, err := apiClient.GetInstallationInfo(ctx, inst.ID)
+	if err != nil {
+		c.logger.Warn("Failed to get installation info", "id", inst.ID, "error", err)
+	} else {
+		d.info = info
+	}
+
+	for _, group := range mapper.DiscoveryGroups {
+		items, err := apiClient.GetRegisterGroup(ctx, inst.ID, group)
+		if err != nil {
+			c.logger.Debug("Register group not available", "id", inst.ID, "group", group, "error", err)
+			continue
+		}
+		d.groups[group] = items
+		c.setGroupFound(inst.ID, group, len(items) > 0)
+	}
+
+	c.redact(d)
+	return buildCapability(d, c.installationLabels(d))
+}
+
+// buildCapability summarizes the groups and writable registers in d. labels
+// are the id, name and model labels returned by installationLabels.
+func buildCapability(d *installationData, labels []string) Capability {
+	capability := Capability{
+		InstallationID: d.inst.ID,
+		Name:           labels[1],
+		Model:          labels[2],
+		Writable:       make(map[string][]string),
+	}
+
+	for group, items := range d.groups {
+		if len(items) == 0 {
+			continue
+		}
+		capability.Groups = append(capability.Groups, group)
+
+		for _, item := range items {
+			if !item.IsReadOnly {
+				capability.Writable[group] = append(capability.Writable[group], item.RegisterName)
+			}
+		}
+		sort.Strings(capability.Writable[group])
+	}
+	sort.Strings(capability.Groups)
+
+	return capability
+}
+
+// recordCapability exports capability as info metrics.
+func (c *ThermiaCollector) recordCapability(capability Capability) {
+	idLabel := fmt.Sprint(capability.InstallationID)
+	match := prometheus.Labels{mapper.LabelHeatpumpID: idLabel}
+	c.metrics.groupSupported.DeletePartialMatch(match)
+	c.metrics.writableRegister.DeletePartialMatch(match)
+
+	supported := make(map[string]bool, len(capability.Groups))
+	for _, group := range capability.Groups {
+		supported[group] = true
+	}
+	for _, group := range mapper.DiscoveryGroups {
+		value := 0.0
+		if supported[group] {
+			value = 1
+		}
+		c.metrics.groupSupported.WithLabelValues(idLabel, group).Set(value)
+
+		for _, register := range capability.Writable[group] {
+			c.metrics.writableRegister.WithLabelValues(idLabel, group, register).Set(1)
+		}
+	}
+}
+
+func (capability Capability) writableCount() int {
+	n := 0
+	for _, registers := range capability.Writable {
+		n += len(registers)
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+func TestBuildCapability(t *testing.T) {
+	d := &installationData{
+		inst: types.Installation{ID: 7},
+		groups: map[string][]types.GroupItem{
+			mapper.RegGroupHotWater: {
+				{RegisterName: "REG_HOT_WATER_STATUS", IsReadOnly: false},
+				{RegisterName: "REG_HOT_WATER_TEMPERATURE", IsReadOnly: true},
+			},
+			mapper.RegGroupTemperatures: {
+				{RegisterName: "REG_OUTDOOR_TEMPERATURE", IsReadOnly: true},
+			},
+			mapper.RegGroupOperationalTime: {},
+		},
+	}
+
+	got := buildCapability(d, []string{"7", "Villa", "Diplomat"})
+
+	wantGroups := []string{mapper.RegGroupHotWater, mapper.RegGroupTemperatures}
+	if !reflect.DeepEqual(got.Groups, wantGroups) {
+		t.Errorf("Groups = %v, want %v", got.Groups, wantGroups)
+	}
+	wantWritable := map[string][]string{mapper.RegGroupHotWater: {"REG_HOT_WATER_STATUS"}}
+	if !reflect.DeepEqual(got.Writable, wantWritable) {
+		t.Errorf("Writable = %v, want %v", got.Writable, wantWritable)
+	}
+	if got.Name != "Villa" || got.Model != "Diplomat" {
+		t.Errorf("Name, Model = %q, %q, want Villa, Diplomat", got.Name, got.Model)
+	}
+}
+
+func TestRecordCapability(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	c.recordCapability(Capability{
+		InstallationID: 7,
+		Groups:         []string{mapper.RegGroupHotWater},
+		Writable:       map[string][]string{mapper.RegGroupHotWater: {"REG_HOT_WATER_STATUS"}},
+	})
+
+	want := `
+# HELP thermia_writable_register_info Writable registers found by the startup probe (always 1)
+# TYPE thermia_writable_register_info gauge
+thermia_writable_register_info{group="REG_GROUP_HOT_WATER",heatpump_id="7",register="REG_HOT_WATER_STATUS"} 1
+`
+	if err := testutil.CollectAndCompare(c.metrics.writableRegister, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(c.metrics.groupSupported.WithLabelValues("7", mapper.RegGroupHotWater)); got != 1 {
+		t.Errorf("hot water group supported = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(c.metrics.groupSupported.WithLabelValues("7", mapper.RegGroupTemperatures)); got != 0 {
+		t.Errorf("temperatures group supported = %v, want 0", got)
+	}
+}
+
+func TestStatusGroups_OptionalGroupsFound(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	if got := c.statusGroups(7); !reflect.DeepEqual(got, statusGroups) {
+		t.Errorf("statusGroups() before a probe = %v, want %v", got, statusGroups)
+	}
+
+	c.setGroupFound(7, mapper.RegGroupEnergy, true)
+	c.setGroupFound(7, mapper.RegGroupHotWater, false)
+	want := append(append([]string{}, statusGroups...), mapper.RegGroupEnergy)
+	if got := c.statusGroups(7); !reflect.DeepEqual(got, want) {
+		t.Errorf("statusGroups() with the energy group found = %v, want %v", got, want)
+	}
+	if got := c.statusGroups(8); !reflect.DeepEqual(got, statusGroups) {
+		t.Errorf("statusGroups() of another installation = %v, want %v", got, statusGroups)
+	}
+
+	c.setGroupFound(7, mapper.RegGroupEnergy, false)
+	if got := c.statusGroups(7); !reflect.DeepEqual(got, statusGroups) {
+		t.Errorf("statusGroups() after the energy group went away = %v, want %v", got, statusGroups)
+	}
+}
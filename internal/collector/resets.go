@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterResets detects operating time counters that went backwards, as
+// happens when a compressor is replaced or the controller is reset. Such a
+// reset silently breaks increase() over the thermia_oper_time_*_hours
+// gauges, so it is counted and logged for dashboards to be corrected.
+//
+// Only accessed from the collection loop.
+type counterResets struct {
+	resets *prometheus.CounterVec
+	last   map[spikeKey]int
+}
+
+func newCounterResets(resets *prometheus.CounterVec) *counterResets {
+	return &counterResets{resets: resets, last: make(map[spikeKey]int)}
+}
+
+// observe records the counters in hours (keyed by register) and returns the
+// sorted names of those lower than in the previous collection.
+func (r *counterResets) observe(installationID int64, hours map[string]int) []string {
+	var reset []string
+	for register, v := range hours {
+		key := spikeKey{installationID, register}
+		if last, ok := r.last[key]; ok && v < last {
+			r.resets.WithLabelValues(fmt.Sprint(installationID), register).Inc()
+			reset = append(reset, register)
+		}
+		r.last[key] = v
+	}
+	sort.Strings(reset)
+	return reset
+}
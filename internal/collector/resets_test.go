@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/mapper"
+)
+
+func TestCounterResets(t *testing.T) {
+	resets := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "resets"}, []string{"heatpump_id", "register"})
+	r := newCounterResets(resets)
+
+	if reset := r.observe(1, map[string]int{mapper.RegOperTimeCompressor: 1200, mapper.RegOperTimeHeating: 900}); len(reset) != 0 {
+		t.Errorf("first collection reset = %v, want none", reset)
+	}
+	// A replaced compressor starts from zero; other counters keep counting
+	reset := r.observe(1, map[string]int{mapper.RegOperTimeCompressor: 3, mapper.RegOperTimeHeating: 901})
+	if !reflect.DeepEqual(reset, []string{mapper.RegOperTimeCompressor}) {
+		t.Errorf("reset = %v, want [%s]", reset, mapper.RegOperTimeCompressor)
+	}
+	// Another installation's counters are tracked separately
+	if reset := r.observe(2, map[string]int{mapper.RegOperTimeCompressor: 1}); len(reset) != 0 {
+		t.Errorf("installation 2 reset = %v, want none", reset)
+	}
+	if reset := r.observe(1, map[string]int{mapper.RegOperTimeCompressor: 4}); len(reset) != 0 {
+		t.Errorf("after the reset = %v, want none", reset)
+	}
+
+	if got := testutil.ToFloat64(resets.WithLabelValues("1", mapper.RegOperTimeCompressor)); got != 1 {
+		t.Errorf("resets = %v, want 1", got)
+	}
+}
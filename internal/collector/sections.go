@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Data sections of an installation besides its register groups, reported
+// in thermia_scrape_section_success{section}.
+const (
+	sectionInfo      = "info"
+	sectionStatus    = "status"
+	sectionEvents    = "events"
+	sectionSchedules = "schedules"
+)
+
+// groupSection returns the section name of a register group, e.g.
+// "hot_water" for REG_GROUP_HOT_WATER.
+func groupSection(group string) string {
+	return strings.ToLower(strings.TrimPrefix(group, "REG_GROUP_"))
+}
+
+// recordSections exports which data sections of d were fetched. Every
+// section is fetched independently, so a failed section only removes the
+// metrics derived from it; this tells dashboards whether a missing series
+// failed this time or is simply not reported by the model. Register groups
+// the installation has never returned registers for are not reported.
+func (c *ThermiaCollector) recordSections(d *installationData) {
+	id := fmt.Sprint(d.inst.ID)
+	set := func(section string, ok bool) {
+		v := 0.0
+		if ok {
+			v = 1
+		}
+		c.metrics.sectionSuccess.WithLabelValues(id, section).Set(v)
+	}
+
+	set(sectionInfo, d.info != nil)
+	set(sectionStatus, d.status != nil)
+	for _, group := range registerGroups {
+		items, ok := d.groups[group]
+		if len(items) > 0 {
+			c.setGroupFound(d.inst.ID, group, true)
+		}
+		if !c.foundGroups[d.inst.ID][group] {
+			c.metrics.sectionSuccess.DeleteLabelValues(id, groupSection(group))
+			continue
+		}
+		set(groupSection(group), ok)
+	}
+	set(sectionEvents, d.eventsOK)
+	if c.schedules {
+		set(sectionSchedules, d.schedules != nil)
+	}
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+)
+
+func TestRecordSections(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	d := loadFixture(t, "testdata/atlas")
+	d.info = nil
+	d.eventsOK = false
+
+	c.storeInstallation(d)
+
+	// The heating curve group is not reported by this model
+	want := `
+# HELP thermia_scrape_section_success 1 if the data section of the installation was fetched in its last collection, 0 if it failed or was skipped
+# TYPE thermia_scrape_section_success gauge
+thermia_scrape_section_success{heatpump_id="2200002",section="energy"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="events"} 0
+thermia_scrape_section_success{heatpump_id="2200002",section="hot_water"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="info"} 0
+thermia_scrape_section_success{heatpump_id="2200002",section="operational_operation"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="operational_status"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="operational_time"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="status"} 1
+thermia_scrape_section_success{heatpump_id="2200002",section="temperatures"} 1
+`
+	if err := testutil.CollectAndCompare(c.metrics.sectionSuccess, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// The temperatures are still exported without installation info
+	if snap, ok := c.store.Get(d.inst.ID); !ok || len(snap.Summary.Temperatures) == 0 {
+		t.Error("temperatures missing from the snapshot after the info fetch failed")
+	}
+
+	// A group the installation returned before fails
+	d = loadFixture(t, "testdata/atlas")
+	delete(d.groups, mapper.RegGroupHotWater)
+	c.storeInstallation(d)
+	if got := testutil.ToFloat64(c.metrics.sectionSuccess.WithLabelValues("2200002", "hot_water")); got != 0 {
+		t.Errorf("thermia_scrape_section_success{section=\"hot_water\"} = %v after a failed fetch, want 0", got)
+	}
+}
+
+func TestForgetInstallations(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	d := loadFixture(t, "testdata/atlas")
+	c.storeInstallation(d)
+	c.recordGroupShape(d.inst.ID, mapper.RegGroupTemperatures, d.groups[mapper.RegGroupTemperatures])
+
+	c.forgetInstallations([]int64{d.inst.ID})
+	if testutil.CollectAndCount(c.metrics.sectionSuccess) == 0 {
+		t.Fatal("sections of a listed installation deleted")
+	}
+
+	c.forgetInstallations([]int64{42})
+	if n := testutil.CollectAndCount(c.metrics.sectionSuccess) + testutil.CollectAndCount(c.metrics.groupItems); n != 0 {
+		t.Errorf("%d series left of an installation no longer listed", n)
+	}
+	if _, ok := c.foundGroups[d.inst.ID]; ok {
+		t.Error("found groups kept for an installation no longer listed")
+	}
+}
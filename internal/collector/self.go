@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// selfCollector exposes the exporter's own operational metrics (collection
+// errors, durations, last success). It is kept separate from
+// ThermiaCollector so heat pump data and exporter internals can be served
+// from different endpoints.
+type selfCollector struct {
+	metrics *MetricSet
+}
+
+// Internal returns a collector for the exporter's self-metrics.
+func (c *ThermiaCollector) Internal() prometheus.Collector {
+	return &selfCollector{metrics: c.metrics}
+}
+
+// Describe implements prometheus.Collector.
+func (s *selfCollector) Describe(ch chan<- *prometheus.Desc) {
+	s.metrics.scrapeErrors.Describe(ch)
+	s.metrics.scrapeDuration.Describe(ch)
+	s.metrics.lastSuccess.Describe(ch)
+	s.metrics.scrapeTruncated.Describe(ch)
+	s.metrics.sectionSuccess.Describe(ch)
+	s.metrics.consecutiveFailures.Describe(ch)
+	s.metrics.pollerRestarts.Describe(ch)
+	s.metrics.tokenRenewals.Describe(ch)
+	s.metrics.authInfo.Describe(ch)
+	s.metrics.clientRejected.Describe(ch)
+	s.metrics.loginPageInfo.Describe(ch)
+	s.metrics.breakerState.Describe(ch)
+	s.metrics.rejectedSamples.Describe(ch)
+	s.metrics.unmappedRegisters.Describe(ch)
+	s.metrics.registerConflicts.Describe(ch)
+	s.metrics.groupItems.Describe(ch)
+	s.metrics.parseFailures.Describe(ch)
+	s.metrics.unitMismatches.Describe(ch)
+	s.metrics.counterResets.Describe(ch)
+	s.metrics.exportedSeries.Describe(ch)
+	s.metrics.seriesDropped.Describe(ch)
+	s.metrics.groupSupported.Describe(ch)
+	s.metrics.writableRegister.Describe(ch)
+	s.metrics.pollInterval.Describe(ch)
+	s.metrics.scrapeMode.Describe(ch)
+	s.metrics.pollPolicy.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (s *selfCollector) Collect(ch chan<- prometheus.Metric) {
+	s.metrics.scrapeErrors.Collect(ch)
+	s.metrics.scrapeDuration.Collect(ch)
+	s.metrics.lastSuccess.Collect(ch)
+	s.metrics.scrapeTruncated.Collect(ch)
+	s.metrics.sectionSuccess.Collect(ch)
+	s.metrics.consecutiveFailures.Collect(ch)
+	s.metrics.pollerRestarts.Collect(ch)
+	s.metrics.tokenRenewals.Collect(ch)
+	s.metrics.authInfo.Collect(ch)
+	s.metrics.clientRejected.Collect(ch)
+	s.metrics.loginPageInfo.Collect(ch)
+	s.metrics.breakerState.Collect(ch)
+	s.metrics.rejectedSamples.Collect(ch)
+	s.metrics.unmappedRegisters.Collect(ch)
+	s.metrics.registerConflicts.Collect(ch)
+	s.metrics.groupItems.Collect(ch)
+	s.metrics.parseFailures.Collect(ch)
+	s.metrics.unitMismatches.Collect(ch)
+	s.metrics.counterResets.Collect(ch)
+	s.metrics.exportedSeries.Collect(ch)
+	s.metrics.seriesDropped.Collect(ch)
+	s.metrics.groupSupported.Collect(ch)
+	s.metrics.writableRegister.Collect(ch)
+	s.metrics.pollInterval.Collect(ch)
+	s.metrics.scrapeMode.Collect(ch)
+	s.metrics.pollPolicy.Collect(ch)
+}
@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+// Self-test stage outcomes.
+const (
+	StagePass = "pass"
+	StageFail = "fail"
+	StageSkip = "skip"
+)
+
+// SelfTestReport is the result of a self-test of one account.
+type SelfTestReport struct {
+	Account string          `json:"account,omitempty"`
+	Passed  bool            `json:"passed"`
+	Stages  []SelfTestStage `json:"stages"`
+}
+
+// SelfTestStage is the outcome of one step of the self-test. Stages after
+// a failed one are skipped.
+type SelfTestStage struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Detail          string  `json:"detail,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// selfTestStep is one stage of the self-test; run returns a short
+// human-readable detail on success.
+type selfTestStep struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// SelfTest checks the whole upstream pipeline once: authentication, API
+// configuration discovery, the installation list, one installation's info
+// and one register group. ctx bounds the whole run; a stage that runs out
+// of time fails and the rest are skipped. Unlike a collection it records
+// no metrics and does not touch the snapshot store.
+func (c *ThermiaCollector) SelfTest(ctx context.Context) SelfTestReport {
+	var (
+		apiClient *api.APIClient
+		inst      types.Installation
+	)
+	steps := []selfTestStep{
+		{"auth", func(ctx context.Context) (string, error) {
+			if _, err := c.getOrRefreshToken(ctx); err != nil {
+				return "", err
+			}
+			c.tokenCacheMu.RLock()
+			defer c.tokenCacheMu.RUnlock()
+			return fmt.Sprintf("token valid for %s", c.tokenExpiresIn()), nil
+		}},
+		{"config", func(ctx context.Context) (string, error) {
+			// The same client as collections, so the self-test is rate
+			// limited and stopped by an open circuit breaker
+			var err error
+			apiClient, err = c.newAPIClient(ctx)
+			return "", err
+		}},
+		{"installations", func(ctx context.Context) (string, error) {
+			installations, err := apiClient.GetInstallations(ctx)
+			if err != nil {
+				return "", err
+			}
+			if len(installations) == 0 {
+				return "", errors.New("no installations found")
+			}
+			inst = installations[0]
+			return fmt.Sprintf("%d installations", len(installations)), nil
+		}},
+		{"installation_info", func(ctx context.Context) (string, error) {
+			info, err := apiClient.GetInstallationInfo(ctx, inst.ID)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("installation %d (%s)", inst.ID, info.Model), nil
+		}},
+		{"register_group", func(ctx context.Context) (string, error) {
+			items, err := apiClient.GetRegisterGroup(ctx, inst.ID, mapper.RegGroupTemperatures)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s: %d registers", mapper.RegGroupTemperatures, len(items)), nil
+		}},
+	}
+
+	report := runSelfTest(ctx, c.clock, steps)
+	report.Account = c.account
+	return report
+}
+
+// runSelfTest runs steps in order until one fails and skips the rest.
+func runSelfTest(ctx context.Context, clk clock.Clock, steps []selfTestStep) SelfTestReport {
+	report := SelfTestReport{Passed: true, Stages: make([]SelfTestStage, 0, len(steps))}
+	for _, step := range steps {
+		stage := SelfTestStage{Name: step.name, Status: StageSkip}
+		if report.Passed {
+			start := clk.Now()
+			detail, err := step.run(ctx)
+			if err == nil {
+				err = ctx.Err()
+			}
+			stage.DurationSeconds = clk.Now().Sub(start).Seconds()
+			if err != nil {
+				stage.Status, stage.Error = StageFail, err.Error()
+				report.Passed = false
+			} else {
+				stage.Status, stage.Detail = StagePass, detail
+			}
+		}
+		report.Stages = append(report.Stages, stage)
+	}
+	return report
+}
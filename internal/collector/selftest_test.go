@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	ran := 0
+	step := func(name string, err error) selfTestStep {
+		return selfTestStep{name, func(ctx context.Context) (string, error) {
+			ran++
+			clk.Advance(time.Second)
+			return name + " ok", err
+		}}
+	}
+
+	report := runSelfTest(context.Background(), clk, []selfTestStep{
+		step("auth", nil),
+		step("config", errors.New("configuration endpoint returned 500")),
+		step("installations", nil),
+	})
+
+	if report.Passed || ran != 2 {
+		t.Fatalf("Passed = %v, ran = %d, want false and 2", report.Passed, ran)
+	}
+	want := []SelfTestStage{
+		{Name: "auth", Status: StagePass, DurationSeconds: 1, Detail: "auth ok"},
+		{Name: "config", Status: StageFail, DurationSeconds: 1, Error: "configuration endpoint returned 500"},
+		{Name: "installations", Status: StageSkip},
+	}
+	for i, stage := range report.Stages {
+		if stage != want[i] {
+			t.Errorf("stage %d = %+v, want %+v", i, stage, want[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := runSelfTest(ctx, clk, []selfTestStep{step("auth", nil)}); report.Passed {
+		t.Error("a self-test past its budget should fail")
+	}
+}
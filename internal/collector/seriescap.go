@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// capSeries returns at most c.maxSeries of metrics and counts the rest in
+// thermia_series_dropped_total on every scrape. Series are dropped from the
+// metric families with the most series first, so a runaway family such as
+// raw registers is trimmed before the few series of the core metrics. In a
+// trimmed family the first series by labels are kept, so the same series
+// are dropped on every scrape. The first scrape over the cap logs a
+// warning; it is logged again after a scrape under it.
+func (c *ThermiaCollector) capSeries(metrics []prometheus.Metric) []prometheus.Metric {
+	if c.maxSeries <= 0 || len(metrics) <= c.maxSeries {
+		c.seriesCapped.Store(false)
+		return metrics
+	}
+
+	keys := make(map[prometheus.Metric]string, len(metrics))
+	families := make(map[string][]prometheus.Metric)
+	var names []string
+	for _, m := range metrics {
+		keys[m] = metricKey(m)
+		name := m.Desc().String()
+		if _, ok := families[name]; !ok {
+			names = append(names, name)
+		}
+		families[name] = append(families[name], m)
+	}
+	sort.Strings(names)
+
+	// Lower the per-family limit until the families fit, then hand the
+	// series still free to the largest trimmed families
+	limit := 0
+	for _, fam := range families {
+		limit = max(limit, len(fam))
+	}
+	kept := len(metrics)
+	for kept > c.maxSeries {
+		limit--
+		kept = 0
+		for _, fam := range families {
+			kept += min(len(fam), limit)
+		}
+	}
+	extra := c.maxSeries - kept
+	bySize := slices.Clone(names)
+	sort.SliceStable(bySize, func(i, j int) bool { return len(families[bySize[i]]) > len(families[bySize[j]]) })
+	bonus := make(map[string]bool, extra)
+	for _, name := range bySize[:extra] {
+		bonus[name] = true
+	}
+
+	capped := make([]prometheus.Metric, 0, c.maxSeries)
+	for _, name := range names {
+		fam := families[name]
+		n := min(len(fam), limit)
+		if bonus[name] {
+			n++
+		}
+		if n < len(fam) {
+			sort.SliceStable(fam, func(i, j int) bool { return keys[fam[i]] < keys[fam[j]] })
+		}
+		capped = append(capped, fam[:n]...)
+	}
+
+	dropped := len(metrics) - len(capped)
+	c.metrics.seriesDropped.Add(float64(dropped))
+	if !c.seriesCapped.Swap(true) {
+		c.logger.Warn("Too many series, dropping the excess; raise THERMIA_MAX_SERIES or drop metrics with THERMIA_METRIC_RULES",
+			"series", len(metrics), "max_series", c.maxSeries, "dropped", dropped)
+	}
+	return capped
+}
+
+// metricKey identifies a metric by its descriptor and label values.
+func metricKey(m prometheus.Metric) string {
+	var b strings.Builder
+	b.WriteString(m.Desc().String())
+	var pb dto.Metric
+	if err := m.Write(&pb); err == nil {
+		for _, lp := range pb.GetLabel() {
+			b.WriteString("\xff" + lp.GetName() + "=" + lp.GetValue())
+		}
+	}
+	return b.String()
+}
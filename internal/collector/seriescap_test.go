@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestCapSeries(t *testing.T) {
+	c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+	c.storeInstallation(loadFixture(t, filepath.Join("testdata", "diplomat")))
+
+	all := seriesCount(exposition(t, c))
+	if got := testutil.ToFloat64(c.metrics.exportedSeries); got != float64(all) {
+		t.Errorf("thermia_exported_series = %v, want %d", got, all)
+	}
+
+	c.maxSeries = 20
+	first := exposition(t, c)
+	if n := seriesCount(first); n != 20 {
+		t.Fatalf("series = %d, want the cap of 20", n)
+	}
+	if !bytes.Equal(first, exposition(t, c)) {
+		t.Error("a different set of series was dropped on the second scrape")
+	}
+	if got := testutil.ToFloat64(c.metrics.seriesDropped); got != float64(2*(all-20)) {
+		t.Errorf("thermia_series_dropped_total = %v, want %d", got, 2*(all-20))
+	}
+	if got := testutil.ToFloat64(c.metrics.exportedSeries); got != 20 {
+		t.Errorf("thermia_exported_series = %v, want 20", got)
+	}
+}
+
+func TestCapSeries_LargestFamiliesFirst(t *testing.T) {
+	names := make(map[*prometheus.Desc]string)
+	family := func(name string, n int) []prometheus.Metric {
+		desc := prometheus.NewDesc(name, name, []string{"i"}, nil)
+		names[desc] = name
+		ms := make([]prometheus.Metric, n)
+		for i := range ms {
+			ms[i] = prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, fmt.Sprintf("%02d", i))
+		}
+		return ms
+	}
+	metrics := slices.Concat(family("a", 10), family("b", 3), family("c", 1))
+	// Series left over go to the larger family, not the first by name
+	leftover := slices.Concat(family("x", 4), family("y", 10))
+
+	tests := []struct {
+		metrics []prometheus.Metric
+		max     int
+		want    map[string]int
+	}{
+		{metrics, 8, map[string]int{"a": 4, "b": 3, "c": 1}},
+		{metrics, 7, map[string]int{"a": 3, "b": 3, "c": 1}},
+		{metrics, 6, map[string]int{"a": 3, "b": 2, "c": 1}},
+		{leftover, 7, map[string]int{"x": 3, "y": 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.max), func(t *testing.T) {
+			c := newTestCollector(clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+			c.maxSeries = tt.max
+
+			got := make(map[string]int)
+			for _, m := range c.capSeries(tt.metrics) {
+				got[names[m.Desc()]]++
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("series per family = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// seriesCount counts the samples of a text exposition.
+func seriesCount(exp []byte) int {
+	n := 0
+	for _, line := range strings.Split(string(exp), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			n++
+		}
+	}
+	return n
+}
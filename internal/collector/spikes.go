@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// spikeFilter drops single-sample temperature spikes. A reading that moved
+// more than maxDelta since the last accepted reading of the same sensor is
+// rejected; if the next reading confirms the new level it is accepted, so
+// genuine step changes only lose one sample.
+//
+// Only accessed from the collection loop.
+type spikeFilter struct {
+	maxDelta float64
+	rejected *prometheus.CounterVec
+
+	last    map[spikeKey]float64
+	pending map[spikeKey]float64
+}
+
+type spikeKey struct {
+	installationID int64
+	sensor         string
+}
+
+// newSpikeFilter creates a filter. A maxDelta of 0 disables rejection.
+func newSpikeFilter(maxDelta float64, rejected *prometheus.CounterVec) *spikeFilter {
+	return &spikeFilter{
+		maxDelta: maxDelta,
+		rejected: rejected,
+		last:     make(map[spikeKey]float64),
+		pending:  make(map[spikeKey]float64),
+	}
+}
+
+// filter removes rejected readings from temps (keyed by sensor) in place and
+// returns the names of the rejected sensors.
+func (f *spikeFilter) filter(installationID int64, temps map[string]float64) []string {
+	if f.maxDelta <= 0 {
+		return nil
+	}
+
+	var rejected []string
+	for sensor, v := range temps {
+		key := spikeKey{installationID, sensor}
+
+		last, ok := f.last[key]
+		if !ok || math.Abs(v-last) <= f.maxDelta {
+			f.accept(key, v)
+			continue
+		}
+		if p, ok := f.pending[key]; ok && math.Abs(v-p) <= f.maxDelta {
+			// Second reading at the new level: a real change, not a spike
+			f.accept(key, v)
+			continue
+		}
+
+		f.pending[key] = v
+		f.rejected.WithLabelValues(sensor).Inc()
+		delete(temps, sensor)
+		rejected = append(rejected, sensor)
+	}
+	return rejected
+}
+
+func (f *spikeFilter) accept(key spikeKey, v float64) {
+	f.last[key] = v
+	delete(f.pending, key)
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestSpikeFilter(maxDelta float64) *spikeFilter {
+	rejected := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "rejected_total"}, []string{"sensor"})
+	return newSpikeFilter(maxDelta, rejected)
+}
+
+func TestSpikeFilter_RejectsSingleSpike(t *testing.T) {
+	f := newTestSpikeFilter(10)
+
+	readings := []float64{5.1, 85.0, 5.3}
+	var kept []float64
+	for _, v := range readings {
+		temps := map[string]float64{"brine_in": v}
+		f.filter(1, temps)
+		if got, ok := temps["brine_in"]; ok {
+			kept = append(kept, got)
+		}
+	}
+
+	if len(kept) != 2 || kept[0] != 5.1 || kept[1] != 5.3 {
+		t.Errorf("kept = %v, want [5.1 5.3]", kept)
+	}
+	if got := testutil.ToFloat64(f.rejected.WithLabelValues("brine_in")); got != 1 {
+		t.Errorf("rejected = %v, want 1", got)
+	}
+}
+
+func TestSpikeFilter_AcceptsConfirmedStep(t *testing.T) {
+	f := newTestSpikeFilter(10)
+
+	f.filter(1, map[string]float64{"hot_water": 20})
+
+	temps := map[string]float64{"hot_water": 45}
+	if rejected := f.filter(1, temps); len(rejected) != 1 {
+		t.Fatalf("first reading at new level should be rejected, got %v", rejected)
+	}
+
+	temps = map[string]float64{"hot_water": 47}
+	if rejected := f.filter(1, temps); len(rejected) != 0 {
+		t.Errorf("confirmed step should be accepted, got rejected %v", rejected)
+	}
+	if temps["hot_water"] != 47 {
+		t.Errorf("hot_water = %v, want 47", temps["hot_water"])
+	}
+}
+
+func TestSpikeFilter_PerInstallation(t *testing.T) {
+	f := newTestSpikeFilter(10)
+
+	f.filter(1, map[string]float64{"outdoor": -5})
+	temps := map[string]float64{"outdoor": 20}
+	if rejected := f.filter(2, temps); len(rejected) != 0 {
+		t.Errorf("first reading of another installation rejected: %v", rejected)
+	}
+}
+
+func TestSpikeFilter_Disabled(t *testing.T) {
+	f := newTestSpikeFilter(0)
+
+	f.filter(1, map[string]float64{"indoor": 21})
+	temps := map[string]float64{"indoor": 90}
+	if rejected := f.filter(1, temps); len(rejected) != 0 {
+		t.Errorf("disabled filter rejected %v", rejected)
+	}
+}
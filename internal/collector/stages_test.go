@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestStageFits(t *testing.T) {
+	if !stageFits(context.Background(), time.Now(), time.Hour) {
+		t.Error("a stage should always fit without a deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if !stageFits(ctx, time.Now(), 0) {
+		t.Error("a stage without history should be attempted")
+	}
+	if !stageFits(ctx, time.Now(), 10*time.Second) {
+		t.Error("a 10s stage should fit into a minute")
+	}
+	if stageFits(ctx, time.Now(), 2*time.Minute) {
+		t.Error("a 2m stage should not fit into a minute")
+	}
+
+	cancel()
+	if stageFits(ctx, time.Now(), 0) {
+		t.Error("no stage should fit once the context is done")
+	}
+}
+
+func TestRunStage_SkipsAndRecords(t *testing.T) {
+	// Context deadlines are wall clock times
+	c := newTestCollector(clock.NewFake(time.Now()))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ran := false
+	c.runStage(ctx, stageEvents, func() { ran = true })
+	if !ran {
+		t.Fatal("stage without history should run")
+	}
+	if got := testutil.ToFloat64(c.metrics.scrapeTruncated.WithLabelValues(stageEvents)); got != 0 {
+		t.Errorf("thermia_scrape_truncated{stage=events} = %v, want 0", got)
+	}
+
+	// The last run took longer than the time left
+	c.stageDurations[stageEvents] = 5 * time.Minute
+	ran = false
+	c.runStage(ctx, stageEvents, func() { ran = true })
+	if ran {
+		t.Error("stage expected to overrun the deadline should be skipped")
+	}
+	if got := testutil.ToFloat64(c.metrics.scrapeTruncated.WithLabelValues(stageEvents)); got != 1 {
+		t.Errorf("thermia_scrape_truncated{stage=events} = %v, want 1", got)
+	}
+
+	// Skipping halves the estimate (2m30s, 1m15s, 37s) so the stage is
+	// eventually retried
+	for i := 0; i < 3 && !ran; i++ {
+		c.runStage(ctx, stageEvents, func() { ran = true })
+	}
+	if !ran {
+		t.Error("skipped stage should be retried once its estimate fits again")
+	}
+}
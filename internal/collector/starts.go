@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/mapper"
+)
+
+// shortCycleWindow is the period compressor starts per hour are measured over.
+const shortCycleWindow = time.Hour
+
+// Values of the thermia_compressor_starts_total source label.
+const (
+	startsSourceRegister = "register"
+	startsSourceDerived  = "derived"
+)
+
+type startsSample struct {
+	at     time.Time
+	starts float64
+}
+
+// startsTracker provides compressor start counts and the short-cycling
+// heuristic. Models without a starts register get starts derived from
+// off-to-on transitions between collections, which undercounts when the
+// compressor cycles faster than the collection interval.
+//
+// Only accessed from the collection loop.
+type startsTracker struct {
+	threshold float64
+
+	derived map[int64]float64
+	running map[int64]bool
+	history map[int64][]startsSample
+}
+
+// newStartsTracker creates a tracker. A threshold (starts per hour) of 0
+// disables the short-cycling heuristic.
+func newStartsTracker(threshold float64) *startsTracker {
+	return &startsTracker{
+		threshold: threshold,
+		derived:   make(map[int64]float64),
+		running:   make(map[int64]bool),
+		history:   make(map[int64][]startsSample),
+	}
+}
+
+// observe records the starts for an installation at now and sets
+// d.compressorStarts, d.startsSource and d.shortCycling.
+func (t *startsTracker) observe(now time.Time, d *installationData) {
+	id := d.inst.ID
+
+	starts := mapper.ExtractCompressorStarts(d.items)
+	source := startsSourceRegister
+	if starts == nil {
+		running, known := mapper.CompressorRunning(d.items)
+		if !known {
+			return
+		}
+		count := t.derived[id]
+		if was, seen := t.running[id]; seen && running && !was {
+			count++
+		}
+		t.running[id] = running
+		t.derived[id] = count
+		starts, source = &count, startsSourceDerived
+	}
+	d.compressorStarts = starts
+	d.startsSource = source
+
+	if t.threshold <= 0 {
+		return
+	}
+	perHour, ok := t.rate(id, now, *starts)
+	suspected := ok && perHour > t.threshold
+	d.shortCycling = &suspected
+}
+
+// rate returns the starts per hour over at least shortCycleWindow, or false
+// if not enough history has been collected yet.
+func (t *startsTracker) rate(id int64, now time.Time, starts float64) (float64, bool) {
+	hist := append(t.history[id], startsSample{at: now, starts: starts})
+
+	// Counter reset (e.g. controller replaced): start over
+	if n := len(hist); n > 1 && starts < hist[n-2].starts {
+		hist = hist[n-1:]
+	}
+
+	// Keep the newest sample that is at least a window old as the baseline
+	for len(hist) > 1 && now.Sub(hist[1].at) >= shortCycleWindow {
+		hist = hist[1:]
+	}
+	t.history[id] = hist
+
+	elapsed := now.Sub(hist[0].at)
+	if elapsed < shortCycleWindow {
+		return 0, false
+	}
+	return (starts - hist[0].starts) / elapsed.Hours(), true
+}
+
+// emitCompressorMetrics emits the compressor start counter and, if enabled,
+// the short-cycling flag.
+func (c *ThermiaCollector) emitCompressorMetrics(ch chan<- prometheus.Metric, labels []string, d *installationData) {
+	if d.compressorStarts != nil {
+		ch <- prometheus.MustNewConstMetric(c.metrics.compressorStarts, prometheus.CounterValue, *d.compressorStarts,
+			append(labels, d.startsSource)...)
+	}
+
+	if d.shortCycling != nil {
+		value := 0.0
+		if *d.shortCycling {
+			value = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.metrics.shortCycling, prometheus.GaugeValue, value, labels...)
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+func ptrFloat(v float64) *float64 { return &v }
+
+func powerStatus(compressor bool) []types.GroupItem {
+	value := 0.0
+	if compressor {
+		value = 1
+	}
+	return []types.GroupItem{{
+		RegisterName:  mapper.CompPowerStatus,
+		RegisterValue: ptrFloat(value),
+		ValueNames: []types.ValueEntry{
+			{Name: "COMP_VALUE_POWER_COMPRESSOR", Value: 1, Visible: true},
+		},
+	}}
+}
+
+func TestStartsTracker_Derived(t *testing.T) {
+	tr := newStartsTracker(0)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// on (unknown prior state, not a start), off, on, on, off, on
+	want := []float64{0, 0, 1, 1, 1, 2}
+	for i, running := range []bool{true, false, true, true, false, true} {
+		d := &installationData{inst: types.Installation{ID: 1}, items: powerStatus(running)}
+		tr.observe(now.Add(time.Duration(i)*time.Minute), d)
+
+		if d.compressorStarts == nil || *d.compressorStarts != want[i] {
+			t.Fatalf("step %d: starts = %v, want %v", i, d.compressorStarts, want[i])
+		}
+		if d.startsSource != startsSourceDerived {
+			t.Errorf("source = %q, want %q", d.startsSource, startsSourceDerived)
+		}
+		if d.shortCycling != nil {
+			t.Error("short cycling flag should be absent when disabled")
+		}
+	}
+}
+
+func TestStartsTracker_ShortCycling(t *testing.T) {
+	tr := newStartsTracker(3)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	observe := func(at time.Duration, starts float64) *installationData {
+		d := &installationData{
+			inst:  types.Installation{ID: 1},
+			items: []types.GroupItem{{RegisterName: mapper.CompressorStartsCandidates[0], RegisterValue: ptrFloat(starts)}},
+		}
+		tr.observe(now.Add(at), d)
+		return d
+	}
+
+	d := observe(0, 100)
+	if d.startsSource != startsSourceRegister || *d.shortCycling {
+		t.Fatalf("first sample: source=%q suspected=%v", d.startsSource, *d.shortCycling)
+	}
+
+	// 2 starts per hour: below the threshold
+	observe(30*time.Minute, 101)
+	if d := observe(time.Hour, 102); *d.shortCycling {
+		t.Error("2 starts/h flagged as short cycling")
+	}
+
+	// 6 starts in the last hour
+	observe(90*time.Minute, 105)
+	if d := observe(2*time.Hour, 108); !*d.shortCycling {
+		t.Error("6 starts/h not flagged as short cycling")
+	}
+
+	// Counter reset restarts the measurement instead of going negative
+	if d := observe(150*time.Minute, 2); *d.shortCycling {
+		t.Error("counter reset flagged as short cycling")
+	}
+}
@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/types"
+)
+
+// buildSummary builds the structured summary for an installation from the
+// same data its metrics were emitted from. labels are the id, name and model
+// labels returned by installationLabels.
+func buildSummary(d *installationData, labels []string) types.ThermiaSummary {
+	s := types.ThermiaSummary{
+		HeatpumpID:    d.inst.ID,
+		HeatpumpName:  labels[1],
+		HeatpumpModel: labels[2],
+		Site:          d.inst.Site,
+		Group:         d.inst.Group,
+		Serial:        d.serial,
+		Temperatures:  d.temps,
+	}
+
+	if d.info != nil {
+		s.Online = d.info.IsOnline
+		s.LastOnline = d.info.LastOnline
+		s.LastOnlineUnix = mapper.ParseTimeToUnix(d.info.LastOnline)
+	}
+
+	modeData := mapper.ExtractOperationMode(d.groups[mapper.RegGroupOperationalOperation], mapper.OperationModeCandidates)
+	s.OperationModesAvailable = modeData.Available
+	s.OperationMode = modeData.Current
+
+	grpStatus := d.groups[mapper.RegGroupOperationalStatus]
+	statusData := mapper.ExtractBitmaskStatuses(grpStatus, mapper.OperationalStatusCandidates)
+	s.OperationalStatusAvailable = statusData.Available
+	s.OperationalStatusRunning = statusData.Running
+	powerData := mapper.ExtractBitmaskStatuses(grpStatus, mapper.PowerStatusCandidates)
+	s.PowerStatusAvailable = powerData.Available
+	s.PowerStatusRunning = powerData.Running
+
+	s.HotWaterSwitch, s.HotWaterBoost = mapper.ExtractHotWaterSwitches(d.groups[mapper.RegGroupHotWater])
+	s.OperationalTimeHours = mapper.ExtractOperationalTime(d.groups[mapper.RegGroupOperationalTime])
+
+	if d.eventsOK {
+		s.ActiveAlerts, s.ArchivedAlerts = mapper.ExtractAlerts(d.activeEvents, d.allEvents)
+	}
+
+	return s
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"thermia_exporter/internal/api"
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/statefile"
+)
+
+// persistedToken is the token cache file's content.
+type persistedToken struct {
+	Username     string    `json:"username"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// tokenCachePath returns the token cache file of an account: path itself
+// for the default account, else path with the account name before the
+// extension (token.json becomes token.home.json).
+func tokenCachePath(path, account string) string {
+	if path == "" || account == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + account + ext
+}
+
+// CheckTokenCache verifies that the token cache file, if one is configured,
+// can be written and is private to the exporter's user.
+func (c *ThermiaCollector) CheckTokenCache() error {
+	return statefile.Check(c.tokenCacheFile)
+}
+
+// persistToken writes the cached token to the token cache file, if one is
+// configured. Failures are logged: the token stays usable in memory.
+// Caller must hold tokenCacheMu.
+func (c *ThermiaCollector) persistToken() {
+	if c.tokenCacheFile == "" || c.tokenCache == nil {
+		return
+	}
+	data, err := json.Marshal(persistedToken{
+		Username:     c.creds.Username,
+		AccessToken:  c.tokenCache.AccessToken,
+		RefreshToken: c.tokenCache.RefreshToken,
+		ExpiresAt:    c.tokenExpiresAt,
+	})
+	if err == nil {
+		err = statefile.WriteFile(c.tokenCacheFile, data)
+	}
+	if err != nil {
+		c.logger.Warn("Failed to persist token", "file", c.tokenCacheFile, "error", err)
+	}
+}
+
+// restoreToken loads a persisted token and checks it with a cheap API call
+// (configuration discovery) instead of logging in again after a restart.
+// A token the portal rejects is dropped but its refresh token is kept, so
+// the next renewal uses the lightweight grant. Any other failure, such as
+// throttling or a network error, says nothing about the token, which is
+// then kept.
+func (c *ThermiaCollector) restoreToken(ctx context.Context) {
+	if c.tokenCacheFile == "" {
+		return
+	}
+	data, err := os.ReadFile(c.tokenCacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var saved persistedToken
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		c.logger.Warn("Ignoring unreadable token cache", "file", c.tokenCacheFile, "error", err)
+		return
+	}
+
+	c.tokenCacheMu.RLock()
+	username := c.creds.Username
+	c.tokenCacheMu.RUnlock()
+	if saved.Username != username {
+		c.logger.Info("Ignoring token cache of other credentials", "file", c.tokenCacheFile)
+		return
+	}
+
+	expiresAt := saved.ExpiresAt
+	if !c.clock.Now().Before(expiresAt) {
+		c.logger.Info("Persisted token expired, renewing with its refresh token")
+	} else {
+		_, err := api.NewAPIClient(ctx, c.authClient.Portal().ConfigURL, saved.AccessToken, c.logger, c.clock, c.apiGuard)
+		switch {
+		case errors.Is(err, api.ErrTokenNotAccepted):
+			c.logger.Info("Persisted token not accepted, renewing with its refresh token")
+			expiresAt = time.Time{}
+		case err != nil:
+			c.logger.Warn("Could not check persisted token, using it anyway", "error", err)
+		default:
+			c.logger.Info("Restored persisted token", "expires_in", expiresAt.Sub(c.clock.Now()).Round(time.Second))
+		}
+	}
+
+	c.tokenCacheMu.Lock()
+	defer c.tokenCacheMu.Unlock()
+	c.tokenCache = &auth.AuthResult{AccessToken: saved.AccessToken, RefreshToken: saved.RefreshToken}
+	c.tokenExpiresAt = expiresAt
+	if !expiresAt.IsZero() {
+		c.observeClaims(saved.AccessToken)
+	}
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+)
+
+func newTokenCacheCollector(clk clock.Clock, file, username string) *ThermiaCollector {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewThermiaCollector(auth.NewAuthClient(logger), auth.Credentials{Username: username}, time.Minute, logger,
+		Options{Clock: clk, TokenCacheFile: file})
+}
+
+func TestTokenCachePath(t *testing.T) {
+	if got := tokenCachePath("/data/token.json", ""); got != "/data/token.json" {
+		t.Errorf("default account path = %q", got)
+	}
+	if got := tokenCachePath("/data/token.json", "home"); got != "/data/token.home.json" {
+		t.Errorf("account path = %q, want /data/token.home.json", got)
+	}
+}
+
+func TestTokenCache_Restore(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	file := filepath.Join(t.TempDir(), "token.json")
+
+	c := newTokenCacheCollector(clk, file, "user@example.com")
+	c.tokenCacheMu.Lock()
+	c.cacheToken(&auth.AuthResult{AccessToken: "a", RefreshToken: "r", ExpiresIn: 3600})
+	c.tokenCacheMu.Unlock()
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("token cache file = %v, %v, want mode 0600", info, err)
+	}
+
+	// Past its expiry the access token is not checked, but its refresh
+	// token is kept for the lightweight grant
+	clk.Advance(2 * time.Hour)
+	restarted := newTokenCacheCollector(clk, file, "user@example.com")
+	restarted.restoreToken(context.Background())
+	if restarted.tokenValid() {
+		t.Error("expired persisted token should not be valid")
+	}
+	if restarted.tokenCache == nil || restarted.tokenCache.RefreshToken != "r" {
+		t.Errorf("tokenCache = %+v, want the persisted refresh token", restarted.tokenCache)
+	}
+
+	other := newTokenCacheCollector(clk, file, "someone@example.com")
+	other.restoreToken(context.Background())
+	if other.tokenCache != nil {
+		t.Error("a token persisted for other credentials should be ignored")
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Account is one Thermia account to collect, with its own credentials.
+type Account struct {
+	// Name is the value of the account label on the account's metrics.
+	Name         string `json:"name"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// parseAccounts parses a JSON array of accounts:
+//
+//	[{"name": "home", "username": "me@example.com", "password": "..."},
+//	 {"name": "parents", "refresh_token": "..."}]
+func parseAccounts(s string) ([]Account, error) {
+	var accounts []Account
+	if err := json.Unmarshal([]byte(s), &accounts); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts")
+	}
+
+	seen := make(map[string]bool)
+	for i, a := range accounts {
+		switch {
+		case a.Name == "":
+			return nil, fmt.Errorf("account %d: name is required", i+1)
+		case seen[a.Name]:
+			return nil, fmt.Errorf("account %q: duplicate name", a.Name)
+		case a.RefreshToken == "" && (a.Username == "" || a.Password == ""):
+			return nil, fmt.Errorf("account %q: username and password or refresh_token is required", a.Name)
+		}
+		seen[a.Name] = true
+	}
+	return accounts, nil
+}
+
+// AccountList returns the accounts to collect: the configured accounts, or
+// a single unnamed account with the top-level credentials.
+func (c *Config) AccountList() []Account {
+	if len(c.Accounts) > 0 {
+		return c.Accounts
+	}
+	return []Account{{Username: c.Username, Password: c.Password, RefreshToken: c.RefreshToken}}
+}
+
+// formatAccounts lists the account names; credentials are never shown.
+func formatAccounts(accounts []Account) string {
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -3,16 +3,73 @@ package config
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
+
+	"thermia_exporter/internal/auth"
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/mapper"
+	"thermia_exporter/internal/meter"
+	"thermia_exporter/internal/relabel"
+	"thermia_exporter/internal/sink"
+	"thermia_exporter/internal/types"
+)
+
+// Run modes
+const (
+	// ModeServer serves /metrics and the other HTTP endpoints (default).
+	ModeServer = "server"
+
+	// ModeAgent runs only the collector and push sinks, without opening
+	// any port.
+	ModeAgent = "agent"
+)
+
+// Health check modes of /health
+const (
+	// HealthLiveness answers 200 unless a collection is wedged (default).
+	HealthLiveness = "liveness"
+
+	// HealthStrict applies the /ready checks.
+	HealthStrict = "strict"
+)
+
+// Push protocols of THERMIA_PUSH_URL
+const (
+	// PushRemoteWrite sends Prometheus remote write requests (default).
+	PushRemoteWrite = "remote_write"
+
+	// PushPushgateway pushes to a Prometheus Pushgateway.
+	PushPushgateway = "pushgateway"
 )
 
 // Config holds all configuration for the thermia exporter.
 type Config struct {
-	// Authentication credentials
-	Username string
-	Password string
+	// Authentication credentials. RefreshToken may replace the password:
+	// the exporter then only ever uses the refresh-token grant.
+	Username     string
+	Password     string
+	RefreshToken string
+
+	// BundleKey decrypts RefreshToken when it is a token bundle produced by
+	// the login command.
+	BundleKey string
+
+	// Portal is the B2C tenant, app registration and API configuration
+	// endpoint to log in to (default: Thermia Online). Other
+	// Thermia-branded portals run the same software under their own tenant.
+	Portal auth.Portal
+
+	// Accounts replace the credentials above to collect several Thermia
+	// accounts, each under its own account label.
+	Accounts []Account
+
+	// Mode is ModeServer or ModeAgent
+	Mode string
 
 	// Server configuration
 	ListenAddr     string
@@ -21,9 +78,224 @@ type Config struct {
 	// Background collection interval (how often the Thermia API is polled)
 	CollectInterval time.Duration
 
+	// InstallationIntervals override CollectInterval per installation ID.
+	InstallationIntervals map[int64]time.Duration
+
+	// During QuietHours (local time) no installation is polled more often
+	// than QuietInterval.
+	QuietHours    clock.DailyWindow
+	QuietInterval time.Duration
+
+	// SplitMetrics serves only heat pump metrics on /metrics. Exporter
+	// self-metrics are always available on /metrics/internal.
+	SplitMetrics bool
+
+	// External energy meter for measured COP: a Prometheus-compatible API URL
+	// and an instant query returning the heat pump's electrical power in W,
+	// or an MQTT broker URL and a topic publishing it. The query or topic may
+	// contain $heatpump_id to meter each installation separately.
+	MeterURL     string
+	MeterQuery   string
+	MeterMQTTURL string
+	MeterTopic   string
+
+	// OTLPEndpoint is the OTLP/HTTP endpoint collections are traced to;
+	// their metrics then carry the trace ID as an exemplar.
+	OTLPEndpoint string
+
+	// HeatOutputRegister overrides the register used as heat output (W or kW).
+	HeatOutputRegister string
+
+	// IndoorOffsets calibrate the indoor temperature per installation ID;
+	// key 0 applies to all other installations.
+	IndoorOffsets map[int64]float64
+
+	// PushURL is the endpoint every collection is pushed to, speaking
+	// PushProtocol (PushRemoteWrite or PushPushgateway). Pushgateway pushes
+	// are grouped under PushJob.
+	PushURL      string
+	PushProtocol string
+	PushJob      string
+
+	// PushInterval pushes on a fixed schedule instead of after every
+	// collection (0).
+	PushInterval time.Duration
+
+	// PushQueueSize bounds the samples held in memory while the push
+	// endpoint is unreachable (0 disables the queue).
+	PushQueueSize int
+
+	// PushQueueDrop selects what a full push queue discards.
+	PushQueueDrop sink.DropPolicy
+
+	// PushDeadband holds the change below which remote write skips a
+	// sample, by metric name suffix ("" for all metrics; nil: send every
+	// sample). PushFullInterval resends unchanged series.
+	PushDeadband     map[string]float64
+	PushFullInterval time.Duration
+
+	// ConsulAddr is the Consul agent the exporter registers itself with
+	// ("" disables registration). The service is advertised under
+	// ConsulService at ConsulAdvertise (host:port; default: the hostname
+	// and the listen port).
+	ConsulAddr      string
+	ConsulService   string
+	ConsulToken     string
+	ConsulAdvertise string
+
+	// ShortCycleStartsPerHour is the compressor starts per hour above which
+	// short cycling is flagged (0 disables the heuristic).
+	ShortCycleStartsPerHour float64
+
+	// StatusPriority orders operational statuses by relevance, highest
+	// first, to pick the current one when several run at once.
+	StatusPriority []types.OperationalStatus
+
+	// AuxShareWindow is the rolling window the auxiliary heat share is
+	// computed over (0 disables it).
+	AuxShareWindow time.Duration
+
+	// Refrigerant selects the saturation table for superheat and subcooling
+	// estimates ("" disables them).
+	Refrigerant mapper.Refrigerant
+
+	// SpikeMaxDelta rejects temperature readings that moved more than this
+	// many degrees between collections (0 disables spike rejection).
+	SpikeMaxDelta float64
+
+	// ValueHoldTTL keeps the last reading of a temperature sensor or
+	// register missing from a collection for this long (0 disables holding).
+	ValueHoldTTL time.Duration
+
+	// EventsSince limits the event history to events that occurred within
+	// this window (0: all events the portal returns).
+	EventsSince time.Duration
+
+	// StartupProbe fetches every installation once at startup, logs a
+	// capability report and exits if the account is not usable.
+	StartupProbe bool
+
+	// Schedules fetches operation mode schedules and exports the next
+	// scheduled mode change.
+	Schedules bool
+
+	// ExportRawRegisters exports every numeric register as
+	// thermia_register_value.
+	ExportRawRegisters bool
+
+	// MaxSeries caps the heat pump series each account's scrape emits (0:
+	// unlimited).
+	MaxSeries int
+
+	// ReadyMaxFailures and ReadyMaxAge make /ready fail after this many
+	// failed collections in a row or once the last successful collection
+	// is older (0 disables each check). HealthMode is HealthLiveness or
+	// HealthStrict, which applies the same checks to /health.
+	ReadyMaxFailures int
+	ReadyMaxAge      time.Duration
+	HealthMode       string
+
+	// Expvar serves the latest summaries and the exporter's counters on
+	// /debug/vars.
+	Expvar bool
+
+	// OperTimeGauges keeps exporting operating times as the
+	// thermia_oper_time_*_hours gauges next to the counters.
+	OperTimeGauges bool
+
+	// PrewarmTimeout bounds authentication and the installation list fetch
+	// before the first collection (0 disables the pre-warm).
+	PrewarmTimeout time.Duration
+
+	// FetchConcurrency is the number of API requests an installation's
+	// fetch runs concurrently (1 fetches sequentially).
+	FetchConcurrency int
+
+	// HedgeDelay re-sends API GET requests without a response after this
+	// long, at most HedgeMax times per collection (0 disables hedging).
+	HedgeDelay time.Duration
+	HedgeMax   int
+
+	// APIRateLimit bounds the API requests per minute of each account (0:
+	// unlimited). After BreakerFailures consecutive failed responses
+	// (0 disables the breaker) requests fail without being sent for
+	// BreakerCooldown.
+	APIRateLimit    int
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	// StartupJitter and PollJitter delay the first collection and every
+	// later poll by a random duration up to this long (0 disables them).
+	StartupJitter time.Duration
+	PollJitter    time.Duration
+
+	// AliasesFile is a YAML file mapping register names from localized or
+	// older firmwares onto canonical ones; RegisterAliases holds its
+	// contents.
+	AliasesFile     string
+	RegisterAliases mapper.Aliases
+
+	// MetricRules drop and rename heat pump metrics and label values at
+	// emission time.
+	MetricRules []relabel.Rule
+
+	// RedactLabels hash or drop label values of heat pump metrics at
+	// emission time, applied after MetricRules.
+	RedactLabels []relabel.Rule
+
+	// RedactSalt keys the hashes of hash rules in MetricRules and
+	// RedactLabels and the anonymized heat pump names; required when
+	// anything is hashed.
+	RedactSalt string
+
+	// Anonymize hashes heat pump names and omits identifying details so
+	// dashboards can be shared publicly.
+	Anonymize bool
+
+	// TokenCacheFile persists tokens across restarts ("" keeps them in
+	// memory only).
+	TokenCacheFile string
+
+	// InstanceIDFile persists the exporter instance ID across restarts
+	// ("" generates a new ID on every start).
+	InstanceIDFile string
+
+	// EnableWrite serves the control write endpoints, which change heat
+	// pump settings through the Thermia API.
+	EnableWrite bool
+
+	// WriteToken is the bearer token the control write endpoints require.
+	WriteToken string
+
+	// OverrideFile persists pending operation mode overrides, so they
+	// still expire after a restart ("" keeps them in memory only; requires
+	// EnableWrite).
+	OverrideFile string
+
+	// DebugToken is the bearer token the raw register endpoint requires;
+	// the endpoint is only served when it is set.
+	DebugToken string
+
+	// AlertMitigations are the writes performed when the alert webhook
+	// receives a firing alert (requires EnableWrite).
+	AlertMitigations []control.Mitigation
+
+	// NormalizeLabels lowercases status, mode and priority label values and
+	// strips their prefixes.
+	NormalizeLabels bool
+
+	// RestartAfterFailures resets the authentication client and drops the
+	// access token after this many consecutive failed collections (0
+	// disables).
+	RestartAfterFailures int
+
 	// Logging configuration
 	LogLevel  string // debug, info, warn, error
 	LogFormat string // text, json
+
+	// sources records where each setting (by environment variable name) was
+	// set from; settings missing here use their default.
+	sources map[string]string
 }
 
 // LoadConfig loads configuration from environment variables and Kubernetes secrets.
@@ -31,59 +303,488 @@ type Config struct {
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
 		// Set defaults
-		ListenAddr:      ":9808",
-		RequestTimeout:  2 * time.Minute,
-		CollectInterval: 15 * time.Minute,
-		LogLevel:        "info",
-		LogFormat:       "text",
+		Mode:                 ModeServer,
+		HealthMode:           HealthLiveness,
+		Portal:               auth.ThermiaPortal,
+		ListenAddr:           ":9808",
+		RequestTimeout:       2 * time.Minute,
+		CollectInterval:      15 * time.Minute,
+		PrewarmTimeout:       30 * time.Second,
+		FetchConcurrency:     4,
+		HedgeMax:             5,
+		MaxSeries:            10000,
+		BreakerFailures:      5,
+		BreakerCooldown:      5 * time.Minute,
+		OperTimeGauges:       true,
+		AuxShareWindow:       24 * time.Hour,
+		StatusPriority:       mapper.DefaultStatusPriority,
+		PushProtocol:         PushRemoteWrite,
+		PushJob:              "thermia_exporter",
+		PushQueueSize:        10000,
+		PushQueueDrop:        sink.DropOldest,
+		PushFullInterval:     4 * time.Minute,
+		ConsulService:        "thermia-exporter",
+		QuietInterval:        30 * time.Minute,
+		RestartAfterFailures: 0,
+		LogLevel:             "info",
+		LogFormat:            "text",
+		sources:              make(map[string]string),
 	}
 
 	// Try to load from Kubernetes secrets first
-	username, password, err := tryLoadFromSecrets()
-	if err == nil && username != "" && password != "" {
-		cfg.Username = username
-		cfg.Password = password
+	secrets, err := tryLoadFromSecrets()
+	if err == nil && secrets.usable() {
+		cfg.Username = secrets.username
+		cfg.Password = secrets.password
+		cfg.RefreshToken = secrets.refreshToken
+		cfg.setSource("THERMIA_USERNAME", cfg.Username, SourceSecret)
+		cfg.setSource("THERMIA_PASSWORD", cfg.Password, SourceSecret)
+		cfg.setSource("THERMIA_REFRESH_TOKEN", cfg.RefreshToken, SourceSecret)
 	} else {
 		// Fallback to environment variables
-		cfg.Username = os.Getenv("THERMIA_USERNAME")
-		cfg.Password = os.Getenv("THERMIA_PASSWORD")
+		cfg.Username = cfg.getenv("THERMIA_USERNAME")
+		cfg.Password = cfg.getenv("THERMIA_PASSWORD")
+		cfg.RefreshToken = cfg.getenv("THERMIA_REFRESH_TOKEN")
+	}
+
+	accounts := secrets.accounts
+	cfg.setSource("THERMIA_ACCOUNTS", accounts, SourceSecret)
+	if accounts == "" {
+		accounts = cfg.getenv("THERMIA_ACCOUNTS")
+	}
+	if accounts != "" {
+		parsed, err := parseAccounts(accounts)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ACCOUNTS: %w", err)
+		}
+		cfg.Accounts = parsed
+	}
+
+	cfg.BundleKey = secrets.bundleKey
+	cfg.setSource("THERMIA_BUNDLE_KEY", cfg.BundleKey, SourceSecret)
+	if cfg.BundleKey == "" {
+		cfg.BundleKey = cfg.getenv("THERMIA_BUNDLE_KEY")
+	}
+
+	if err := cfg.loadPortal(); err != nil {
+		return nil, err
+	}
+
+	cfg.WriteToken = secrets.writeToken
+	cfg.setSource("THERMIA_WRITE_TOKEN", cfg.WriteToken, SourceSecret)
+	if cfg.WriteToken == "" {
+		cfg.WriteToken = cfg.getenv("THERMIA_WRITE_TOKEN")
+	}
+	cfg.DebugToken = secrets.debugToken
+	cfg.setSource("THERMIA_DEBUG_TOKEN", cfg.DebugToken, SourceSecret)
+	if cfg.DebugToken == "" {
+		cfg.DebugToken = cfg.getenv("THERMIA_DEBUG_TOKEN")
 	}
 
 	// Override defaults from environment variables
-	if addr := os.Getenv("THERMIA_ADDR"); addr != "" {
+	if mode := cfg.getenv("THERMIA_MODE"); mode != "" {
+		cfg.Mode = strings.ToLower(mode)
+	}
+
+	if addr := cfg.getenv("THERMIA_ADDR"); addr != "" {
 		cfg.ListenAddr = addr
 	}
 
-	if level := os.Getenv("THERMIA_LOG_LEVEL"); level != "" {
+	if level := cfg.getenv("THERMIA_LOG_LEVEL"); level != "" {
 		cfg.LogLevel = level
 	}
 
-	if format := os.Getenv("THERMIA_LOG_FORMAT"); format != "" {
+	if format := cfg.getenv("THERMIA_LOG_FORMAT"); format != "" {
 		cfg.LogFormat = format
 	}
 
-	if timeout := os.Getenv("THERMIA_REQUEST_TIMEOUT"); timeout != "" {
+	if timeout := cfg.getenv("THERMIA_REQUEST_TIMEOUT"); timeout != "" {
 		if seconds, err := strconv.Atoi(timeout); err == nil && seconds > 0 {
 			cfg.RequestTimeout = time.Duration(seconds) * time.Second
 		}
 	}
 
-	if interval := os.Getenv("THERMIA_SCRAPE_INTERVAL"); interval != "" {
+	if interval := cfg.getenv("THERMIA_SCRAPE_INTERVAL"); interval != "" {
 		if seconds, err := strconv.Atoi(interval); err == nil && seconds > 0 {
 			cfg.CollectInterval = time.Duration(seconds) * time.Second
 		}
 	}
 
+	if intervals := cfg.getenv("THERMIA_INSTALLATION_INTERVALS"); intervals != "" {
+		parsed, err := ParseIntervals(intervals)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_INSTALLATION_INTERVALS: %w", err)
+		}
+		cfg.InstallationIntervals = parsed
+	}
+
+	if quiet := cfg.getenv("THERMIA_QUIET_HOURS"); quiet != "" {
+		window, err := clock.ParseDailyWindow(quiet)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_QUIET_HOURS: %w", err)
+		}
+		cfg.QuietHours = window
+	}
+
+	if interval := cfg.getenv("THERMIA_QUIET_INTERVAL"); interval != "" {
+		d, err := ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("THERMIA_QUIET_INTERVAL: invalid duration %q", interval)
+		}
+		cfg.QuietInterval = d
+	}
+
+	if split := cfg.getenv("THERMIA_SPLIT_METRICS"); split != "" {
+		if v, err := strconv.ParseBool(split); err == nil {
+			cfg.SplitMetrics = v
+		}
+	}
+
+	cfg.MeterURL = cfg.getenv("THERMIA_METER_PROMETHEUS_URL")
+	cfg.MeterQuery = cfg.getenv("THERMIA_METER_QUERY")
+	cfg.MeterMQTTURL = cfg.getenv("THERMIA_METER_MQTT_URL")
+	cfg.MeterTopic = cfg.getenv("THERMIA_METER_MQTT_TOPIC")
+	cfg.OTLPEndpoint = cfg.getenv("THERMIA_OTLP_ENDPOINT")
+	cfg.HeatOutputRegister = cfg.getenv("THERMIA_HEAT_OUTPUT_REGISTER")
+	cfg.PushURL = cfg.getenv("THERMIA_PUSH_URL")
+	if protocol := cfg.getenv("THERMIA_PUSH_PROTOCOL"); protocol != "" {
+		cfg.PushProtocol = strings.ToLower(protocol)
+	}
+	if job := cfg.getenv("THERMIA_PUSH_JOB"); job != "" {
+		cfg.PushJob = job
+	}
+	if interval := cfg.getenv("THERMIA_PUSH_INTERVAL"); interval != "" {
+		d, err := ParseDuration(interval)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_PUSH_INTERVAL: invalid duration %q", interval)
+		}
+		cfg.PushInterval = d
+	}
+	cfg.ConsulAddr = cfg.getenv("THERMIA_CONSUL_ADDR")
+	cfg.ConsulToken = cfg.getenv("THERMIA_CONSUL_TOKEN")
+	cfg.ConsulAdvertise = cfg.getenv("THERMIA_CONSUL_ADVERTISE_ADDR")
+	if service := cfg.getenv("THERMIA_CONSUL_SERVICE"); service != "" {
+		cfg.ConsulService = service
+	}
+
+	if size := cfg.getenv("THERMIA_PUSH_QUEUE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_PUSH_QUEUE_SIZE: invalid sample count %q", size)
+		}
+		cfg.PushQueueSize = n
+	}
+
+	if policy := cfg.getenv("THERMIA_PUSH_QUEUE_DROP"); policy != "" {
+		p, err := sink.ParseDropPolicy(policy)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_PUSH_QUEUE_DROP: %w", err)
+		}
+		cfg.PushQueueDrop = p
+	}
+
+	if deadband := cfg.getenv("THERMIA_PUSH_DEADBAND"); deadband != "" {
+		parsed, err := ParseDeadband(deadband)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_PUSH_DEADBAND: %w", err)
+		}
+		cfg.PushDeadband = parsed
+	}
+	if interval := cfg.getenv("THERMIA_PUSH_FULL_INTERVAL"); interval != "" {
+		d, err := ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("THERMIA_PUSH_FULL_INTERVAL: invalid duration %q", interval)
+		}
+		cfg.PushFullInterval = d
+	}
+
+	if offsets := cfg.getenv("THERMIA_INDOOR_OFFSET"); offsets != "" {
+		parsed, err := ParseOffsets(offsets)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_INDOOR_OFFSET: %w", err)
+		}
+		cfg.IndoorOffsets = parsed
+	}
+
+	if starts := cfg.getenv("THERMIA_SHORT_CYCLE_STARTS_PER_HOUR"); starts != "" {
+		if v, err := strconv.ParseFloat(starts, 64); err == nil && v > 0 {
+			cfg.ShortCycleStartsPerHour = v
+		}
+	}
+
+	if window := cfg.getenv("THERMIA_AUX_SHARE_WINDOW"); window != "" {
+		d, err := ParseDuration(window)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_AUX_SHARE_WINDOW: invalid duration %q", window)
+		}
+		cfg.AuxShareWindow = d
+	}
+
+	if name := cfg.getenv("THERMIA_REFRIGERANT"); name != "" {
+		r, err := mapper.ParseRefrigerant(name)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_REFRIGERANT: %w", err)
+		}
+		cfg.Refrigerant = r
+	}
+
+	if delta := cfg.getenv("THERMIA_SPIKE_MAX_DELTA"); delta != "" {
+		v, err := strconv.ParseFloat(delta, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("THERMIA_SPIKE_MAX_DELTA: invalid delta %q", delta)
+		}
+		cfg.SpikeMaxDelta = v
+	}
+
+	if ttl := cfg.getenv("THERMIA_VALUE_HOLD_TTL"); ttl != "" {
+		d, err := ParseDuration(ttl)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_VALUE_HOLD_TTL: invalid duration %q", ttl)
+		}
+		cfg.ValueHoldTTL = d
+	}
+
+	if probe := cfg.getenv("THERMIA_STARTUP_PROBE"); probe != "" {
+		v, err := strconv.ParseBool(probe)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_STARTUP_PROBE: invalid boolean %q", probe)
+		}
+		cfg.StartupProbe = v
+	}
+
+	if schedules := cfg.getenv("THERMIA_SCHEDULES"); schedules != "" {
+		v, err := strconv.ParseBool(schedules)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_SCHEDULES: invalid boolean %q", schedules)
+		}
+		cfg.Schedules = v
+	}
+
+	if series := cfg.getenv("THERMIA_MAX_SERIES"); series != "" {
+		n, err := strconv.Atoi(series)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_MAX_SERIES: invalid series count %q", series)
+		}
+		cfg.MaxSeries = n
+	}
+
+	if raw := cfg.getenv("THERMIA_EXPORT_RAW_REGISTERS"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_EXPORT_RAW_REGISTERS: invalid boolean %q", raw)
+		}
+		cfg.ExportRawRegisters = v
+	}
+
+	if failures := cfg.getenv("THERMIA_READY_MAX_FAILURES"); failures != "" {
+		n, err := strconv.Atoi(failures)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_READY_MAX_FAILURES: invalid count %q", failures)
+		}
+		cfg.ReadyMaxFailures = n
+	}
+
+	if age := cfg.getenv("THERMIA_READY_MAX_AGE"); age != "" {
+		d, err := ParseDuration(age)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_READY_MAX_AGE: invalid duration %q", age)
+		}
+		cfg.ReadyMaxAge = d
+	}
+
+	if mode := cfg.getenv("THERMIA_HEALTH_MODE"); mode != "" {
+		cfg.HealthMode = strings.ToLower(mode)
+	}
+
+	if expvars := cfg.getenv("THERMIA_EXPVAR"); expvars != "" {
+		v, err := strconv.ParseBool(expvars)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_EXPVAR: invalid boolean %q", expvars)
+		}
+		cfg.Expvar = v
+	}
+
+	if priority := cfg.getenv("THERMIA_STATUS_PRIORITY"); priority != "" {
+		parsed, err := ParseStatusPriority(priority)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_STATUS_PRIORITY: %w", err)
+		}
+		cfg.StatusPriority = parsed
+	}
+
+	if gauges := cfg.getenv("THERMIA_OPER_TIME_GAUGES"); gauges != "" {
+		v, err := strconv.ParseBool(gauges)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_OPER_TIME_GAUGES: invalid boolean %q", gauges)
+		}
+		cfg.OperTimeGauges = v
+	}
+
+	if anonymize := cfg.getenv("THERMIA_ANONYMIZE"); anonymize != "" {
+		v, err := strconv.ParseBool(anonymize)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ANONYMIZE: invalid boolean %q", anonymize)
+		}
+		cfg.Anonymize = v
+	}
+
+	cfg.TokenCacheFile = cfg.getenv("THERMIA_TOKEN_CACHE_FILE")
+	cfg.InstanceIDFile = cfg.getenv("THERMIA_INSTANCE_ID_FILE")
+
+	if write := cfg.getenv("THERMIA_ENABLE_WRITE"); write != "" {
+		v, err := strconv.ParseBool(write)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ENABLE_WRITE: invalid boolean %q", write)
+		}
+		cfg.EnableWrite = v
+	}
+
+	cfg.OverrideFile = cfg.getenv("THERMIA_OVERRIDE_FILE")
+
+	if actions := cfg.getenv("THERMIA_ALERT_ACTIONS"); actions != "" {
+		parsed, err := control.ParseMitigations(actions)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ALERT_ACTIONS: %w", err)
+		}
+		cfg.AlertMitigations = parsed
+	}
+
+	if restart := cfg.getenv("THERMIA_RESTART_AFTER_FAILURES"); restart != "" {
+		n, err := strconv.Atoi(restart)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_RESTART_AFTER_FAILURES: invalid count %q", restart)
+		}
+		cfg.RestartAfterFailures = n
+	}
+
+	if normalize := cfg.getenv("THERMIA_NORMALIZE_LABELS"); normalize != "" {
+		v, err := strconv.ParseBool(normalize)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_NORMALIZE_LABELS: invalid boolean %q", normalize)
+		}
+		cfg.NormalizeLabels = v
+	}
+
+	if timeout := cfg.getenv("THERMIA_PREWARM_TIMEOUT"); timeout != "" {
+		d, err := ParseDuration(timeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_PREWARM_TIMEOUT: invalid duration %q", timeout)
+		}
+		cfg.PrewarmTimeout = d
+	}
+
+	if concurrency := cfg.getenv("THERMIA_FETCH_CONCURRENCY"); concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("THERMIA_FETCH_CONCURRENCY: invalid request count %q", concurrency)
+		}
+		cfg.FetchConcurrency = n
+	}
+
+	if delay := cfg.getenv("THERMIA_HEDGE_DELAY"); delay != "" {
+		d, err := ParseDuration(delay)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("THERMIA_HEDGE_DELAY: invalid duration %q", delay)
+		}
+		cfg.HedgeDelay = d
+	}
+
+	for _, jitter := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"THERMIA_STARTUP_JITTER", &cfg.StartupJitter},
+		{"THERMIA_POLL_JITTER", &cfg.PollJitter},
+	} {
+		if v := cfg.getenv(jitter.name); v != "" {
+			d, err := ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("%s: invalid duration %q", jitter.name, v)
+			}
+			*jitter.dst = d
+		}
+	}
+
+	if hedges := cfg.getenv("THERMIA_HEDGE_MAX"); hedges != "" {
+		n, err := strconv.Atoi(hedges)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_HEDGE_MAX: invalid request count %q", hedges)
+		}
+		cfg.HedgeMax = n
+	}
+
+	if limit := cfg.getenv("THERMIA_API_RATE_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_API_RATE_LIMIT: invalid requests per minute %q", limit)
+		}
+		cfg.APIRateLimit = n
+	}
+
+	if failures := cfg.getenv("THERMIA_CIRCUIT_BREAKER_FAILURES"); failures != "" {
+		n, err := strconv.Atoi(failures)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("THERMIA_CIRCUIT_BREAKER_FAILURES: invalid failure count %q", failures)
+		}
+		cfg.BreakerFailures = n
+	}
+
+	if cooldown := cfg.getenv("THERMIA_CIRCUIT_BREAKER_COOLDOWN"); cooldown != "" {
+		d, err := ParseDuration(cooldown)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("THERMIA_CIRCUIT_BREAKER_COOLDOWN: invalid duration %q", cooldown)
+		}
+		cfg.BreakerCooldown = d
+	}
+
+	if rules := cfg.getenv("THERMIA_METRIC_RULES"); rules != "" {
+		parsed, err := relabel.ParseRules(rules)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_METRIC_RULES: %w", err)
+		}
+		cfg.MetricRules = parsed
+	}
+
+	if labels := cfg.getenv("THERMIA_REDACT_LABELS"); labels != "" {
+		parsed, err := relabel.ParseRedactLabels(labels)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_REDACT_LABELS: %w", err)
+		}
+		cfg.RedactLabels = parsed
+	}
+	cfg.RedactSalt = cfg.getenv("THERMIA_REDACT_SALT")
+	relabel.SaltHashes(cfg.MetricRules, cfg.RedactSalt)
+	relabel.SaltHashes(cfg.RedactLabels, cfg.RedactSalt)
+
+	if path := cfg.getenv("THERMIA_ALIASES_FILE"); path != "" {
+		aliases, err := mapper.LoadAliases(path)
+		if err != nil {
+			return nil, fmt.Errorf("THERMIA_ALIASES_FILE: %w", err)
+		}
+		cfg.AliasesFile = path
+		cfg.RegisterAliases = aliases
+	}
+
+	if since := cfg.getenv("THERMIA_EVENTS_SINCE"); since != "" {
+		d, err := ParseDuration(since)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("THERMIA_EVENTS_SINCE: invalid duration %q", since)
+		}
+		cfg.EventsSince = d
+	}
+
 	return cfg, nil
 }
 
 // Validate checks that all required configuration fields are set.
 func (c *Config) Validate() error {
-	if c.Username == "" {
-		return errors.New("username is required (set THERMIA_USERNAME or mount K8s secret)")
-	}
-	if c.Password == "" {
-		return errors.New("password is required (set THERMIA_PASSWORD or mount K8s secret)")
+	if c.RefreshToken == "" && len(c.Accounts) == 0 {
+		if c.Username == "" {
+			return errors.New("username is required (set THERMIA_USERNAME or mount K8s secret)")
+		}
+		if c.Password == "" {
+			return errors.New("password or refresh token is required (set THERMIA_PASSWORD, THERMIA_REFRESH_TOKEN or mount K8s secret)")
+		}
 	}
 	if c.RequestTimeout < 10*time.Second {
 		return errors.New("request timeout must be at least 10 seconds")
@@ -91,5 +792,234 @@ func (c *Config) Validate() error {
 	if c.CollectInterval < time.Minute {
 		return errors.New("scrape interval must be at least 60 seconds")
 	}
+	for id, interval := range c.InstallationIntervals {
+		if interval < time.Minute {
+			return fmt.Errorf("interval of installation %d must be at least 60 seconds", id)
+		}
+	}
+	if c.EnableWrite && c.WriteToken == "" {
+		return errors.New("THERMIA_ENABLE_WRITE requires THERMIA_WRITE_TOKEN")
+	}
+	if c.RedactSalt == "" && (relabel.Hashes(c.MetricRules) || relabel.Hashes(c.RedactLabels)) {
+		return errors.New("hashing labels requires THERMIA_REDACT_SALT")
+	}
+	if c.Anonymize && c.RedactSalt == "" {
+		return errors.New("THERMIA_ANONYMIZE requires THERMIA_REDACT_SALT")
+	}
+	if c.OverrideFile != "" && !c.EnableWrite {
+		return errors.New("THERMIA_OVERRIDE_FILE requires THERMIA_ENABLE_WRITE")
+	}
+	if len(c.AlertMitigations) > 0 && !c.EnableWrite {
+		return errors.New("THERMIA_ALERT_ACTIONS requires THERMIA_ENABLE_WRITE")
+	}
+	if (c.MeterURL == "") != (c.MeterQuery == "") {
+		return errors.New("THERMIA_METER_PROMETHEUS_URL and THERMIA_METER_QUERY must be set together")
+	}
+	if (c.MeterMQTTURL == "") != (c.MeterTopic == "") {
+		return errors.New("THERMIA_METER_MQTT_URL and THERMIA_METER_MQTT_TOPIC must be set together")
+	}
+	if c.MeterURL != "" && c.MeterMQTTURL != "" {
+		return errors.New("THERMIA_METER_PROMETHEUS_URL and THERMIA_METER_MQTT_URL are mutually exclusive")
+	}
+	if len(c.Accounts) > 1 && (c.MeterURL != "" && !meter.Templated(c.MeterQuery) || c.MeterMQTTURL != "" && !meter.Templated(c.MeterTopic)) {
+		return fmt.Errorf("the energy meter query or topic must contain %s with several accounts", meter.IDPlaceholder)
+	}
+	switch c.Mode {
+	case "", ModeServer:
+	case ModeAgent:
+		if c.SinkCount() == 0 {
+			return errors.New("agent mode requires at least one push sink (set THERMIA_PUSH_URL)")
+		}
+		if c.ConsulAddr != "" {
+			return errors.New("THERMIA_CONSUL_ADDR requires server mode: agent mode has no port to register")
+		}
+	default:
+		return fmt.Errorf("unknown mode %q (use %q or %q)", c.Mode, ModeServer, ModeAgent)
+	}
+	if len(c.PushDeadband) > 0 && c.PushProtocol == PushPushgateway {
+		return errors.New("THERMIA_PUSH_DEADBAND only applies to remote write, not to a Pushgateway")
+	}
+	switch c.PushProtocol {
+	case "", PushRemoteWrite, PushPushgateway:
+	default:
+		return fmt.Errorf("THERMIA_PUSH_PROTOCOL: unknown protocol %q (use %q or %q)", c.PushProtocol, PushRemoteWrite, PushPushgateway)
+	}
+	switch c.HealthMode {
+	case "", HealthLiveness, HealthStrict:
+	default:
+		return fmt.Errorf("THERMIA_HEALTH_MODE: unknown mode %q (use %q or %q)", c.HealthMode, HealthLiveness, HealthStrict)
+	}
+	return nil
+}
+
+// SinkCount returns the number of configured push sinks.
+func (c *Config) SinkCount() int {
+	n := 0
+	if c.PushURL != "" {
+		n++
+	}
+	return n
+}
+
+// ParseDuration parses a Go duration string, additionally accepting a whole
+// number of days with a "d" suffix (e.g. "30d").
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseIntervals parses per-installation collection intervals of the form
+// "1234567=10m,7654321=1m". Durations accept a "d" suffix (see
+// ParseDuration).
+func ParseIntervals(s string) (map[int64]time.Duration, error) {
+	intervals := make(map[int64]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		idStr, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("missing installation ID in %q", part)
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid installation ID %q", idStr)
+		}
+		d, err := ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", value)
+		}
+		intervals[id] = d
+	}
+	return intervals, nil
+}
+
+// ParseOffsets parses per-installation offsets of the form
+// "1234567=-0.7,7654321=0.3". A bare number ("-0.7") applies to every
+// installation and is stored under key 0.
+func ParseOffsets(s string) (map[int64]float64, error) {
+	offsets := make(map[int64]float64)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var id int64
+		value := part
+		if idStr, v, ok := strings.Cut(part, "="); ok {
+			n, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid installation ID %q", idStr)
+			}
+			id, value = n, v
+		}
+
+		offset, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %q", value)
+		}
+		offsets[id] = offset
+	}
+	return offsets, nil
+}
+
+// ParseDeadband parses remote write deadbands of the form
+// "0.5,_celsius=0.1,_watts=50": a change below the deadband of the longest
+// matching metric name suffix is not sent. A bare number applies to metrics
+// without a matching suffix and is stored under key "".
+func ParseDeadband(s string) (map[string]float64, error) {
+	deadband := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		suffix, value := "", part
+		if sfx, v, ok := strings.Cut(part, "="); ok {
+			suffix, value = strings.TrimSpace(sfx), v
+			if suffix == "" {
+				return nil, fmt.Errorf("empty metric suffix in %q", part)
+			}
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid deadband %q", value)
+		}
+		deadband[suffix] = threshold
+	}
+	return deadband, nil
+}
+
+// ParseStatusPriority parses a comma-separated list of operational statuses,
+// highest priority first, such as "STATUS_HOTWATER,STATUS_HEAT". Register
+// value prefixes (REG_VALUE_) are accepted and removed.
+func ParseStatusPriority(s string) ([]types.OperationalStatus, error) {
+	var priority []types.OperationalStatus
+	seen := make(map[types.OperationalStatus]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		status := types.ParseOperationalStatus(part)
+		if seen[status] {
+			return nil, fmt.Errorf("status %q listed twice", status)
+		}
+		seen[status] = true
+		priority = append(priority, status)
+	}
+	if len(priority) == 0 {
+		return nil, errors.New("no statuses listed")
+	}
+	return priority, nil
+}
+
+// LoadPortal returns the portal configured by the THERMIA_PORTAL_*
+// variables, for commands that do not need the rest of the configuration.
+func LoadPortal() (auth.Portal, error) {
+	c := &Config{Portal: auth.ThermiaPortal, sources: make(map[string]string)}
+	err := c.loadPortal()
+	return c.Portal, err
+}
+
+// loadPortal overrides the Thermia Online portal settings from the
+// THERMIA_PORTAL_* variables.
+func (c *Config) loadPortal() error {
+	for _, s := range []struct {
+		name  string
+		field *string
+		isURL bool
+	}{
+		{"THERMIA_PORTAL_CLIENT_ID", &c.Portal.ClientID, false},
+		{"THERMIA_PORTAL_POLICY", &c.Portal.Policy, false},
+		{"THERMIA_PORTAL_TENANT", &c.Portal.TenantDomain, false},
+		{"THERMIA_PORTAL_REDIRECT_URI", &c.Portal.RedirectURI, true},
+		{"THERMIA_PORTAL_B2C_URL", &c.Portal.B2CBaseURL, true},
+		{"THERMIA_PORTAL_CONFIG_URL", &c.Portal.ConfigURL, true},
+	} {
+		v := c.getenv(s.name)
+		if v == "" {
+			continue
+		}
+		if s.isURL {
+			u, err := url.Parse(v)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("%s: invalid URL %q", s.name, v)
+			}
+		}
+		*s.field = v
+	}
 	return nil
 }
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"thermia_exporter/internal/control"
+	"thermia_exporter/internal/relabel"
+	"thermia_exporter/internal/types"
+)
+
+// Sources a setting can come from
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceSecret  = "secret"
+)
+
+// redacted replaces credential values in the effective configuration.
+const redacted = "<redacted>"
+
+// Setting is one effective configuration value and where it came from.
+type Setting struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// getenv returns the environment variable name and records it as the
+// setting's source if it is set.
+func (c *Config) getenv(name string) string {
+	v := os.Getenv(name)
+	c.setSource(name, v, SourceEnv)
+	return v
+}
+
+// setSource records source for the setting name if value is set.
+func (c *Config) setSource(name, value, source string) {
+	if value != "" {
+		c.sources[name] = source
+	}
+}
+
+// Effective returns the effective configuration keyed by environment
+// variable name, including defaults. Credentials are redacted and URLs are
+// stripped of user info, so the result is safe to serve.
+func (c *Config) Effective() map[string]Setting {
+	values := map[string]string{
+		"THERMIA_USERNAME":                    secret(c.Username),
+		"THERMIA_PASSWORD":                    secret(c.Password),
+		"THERMIA_REFRESH_TOKEN":               secret(c.RefreshToken),
+		"THERMIA_BUNDLE_KEY":                  secret(c.BundleKey),
+		"THERMIA_ACCOUNTS":                    formatAccounts(c.Accounts),
+		"THERMIA_PORTAL_CLIENT_ID":            c.Portal.ClientID,
+		"THERMIA_PORTAL_POLICY":               c.Portal.Policy,
+		"THERMIA_PORTAL_TENANT":               c.Portal.TenantDomain,
+		"THERMIA_PORTAL_REDIRECT_URI":         redactURL(c.Portal.RedirectURI),
+		"THERMIA_PORTAL_B2C_URL":              redactURL(c.Portal.B2CBaseURL),
+		"THERMIA_PORTAL_CONFIG_URL":           redactURL(c.Portal.ConfigURL),
+		"THERMIA_MODE":                        c.Mode,
+		"THERMIA_ADDR":                        c.ListenAddr,
+		"THERMIA_REQUEST_TIMEOUT":             c.RequestTimeout.String(),
+		"THERMIA_SCRAPE_INTERVAL":             c.CollectInterval.String(),
+		"THERMIA_PREWARM_TIMEOUT":             c.PrewarmTimeout.String(),
+		"THERMIA_FETCH_CONCURRENCY":           strconv.Itoa(c.FetchConcurrency),
+		"THERMIA_HEDGE_DELAY":                 formatDuration(c.HedgeDelay),
+		"THERMIA_HEDGE_MAX":                   strconv.Itoa(c.HedgeMax),
+		"THERMIA_API_RATE_LIMIT":              strconv.Itoa(c.APIRateLimit),
+		"THERMIA_CIRCUIT_BREAKER_FAILURES":    strconv.Itoa(c.BreakerFailures),
+		"THERMIA_CIRCUIT_BREAKER_COOLDOWN":    c.BreakerCooldown.String(),
+		"THERMIA_STARTUP_JITTER":              formatDuration(c.StartupJitter),
+		"THERMIA_POLL_JITTER":                 formatDuration(c.PollJitter),
+		"THERMIA_SPLIT_METRICS":               strconv.FormatBool(c.SplitMetrics),
+		"THERMIA_METER_PROMETHEUS_URL":        redactURL(c.MeterURL),
+		"THERMIA_METER_QUERY":                 c.MeterQuery,
+		"THERMIA_METER_MQTT_URL":              redactURL(c.MeterMQTTURL),
+		"THERMIA_METER_MQTT_TOPIC":            c.MeterTopic,
+		"THERMIA_OTLP_ENDPOINT":               redactURL(c.OTLPEndpoint),
+		"THERMIA_HEAT_OUTPUT_REGISTER":        c.HeatOutputRegister,
+		"THERMIA_PUSH_URL":                    redactURL(c.PushURL),
+		"THERMIA_PUSH_PROTOCOL":               c.PushProtocol,
+		"THERMIA_PUSH_JOB":                    c.PushJob,
+		"THERMIA_PUSH_INTERVAL":               formatDuration(c.PushInterval),
+		"THERMIA_PUSH_QUEUE_SIZE":             strconv.Itoa(c.PushQueueSize),
+		"THERMIA_PUSH_QUEUE_DROP":             string(c.PushQueueDrop),
+		"THERMIA_PUSH_DEADBAND":               formatDeadband(c.PushDeadband),
+		"THERMIA_PUSH_FULL_INTERVAL":          formatDuration(c.PushFullInterval),
+		"THERMIA_CONSUL_ADDR":                 redactURL(c.ConsulAddr),
+		"THERMIA_CONSUL_SERVICE":              c.ConsulService,
+		"THERMIA_CONSUL_TOKEN":                secret(c.ConsulToken),
+		"THERMIA_CONSUL_ADVERTISE_ADDR":       c.ConsulAdvertise,
+		"THERMIA_INSTALLATION_INTERVALS":      formatIntervals(c.InstallationIntervals),
+		"THERMIA_QUIET_HOURS":                 c.QuietHours.String(),
+		"THERMIA_QUIET_INTERVAL":              c.QuietInterval.String(),
+		"THERMIA_INDOOR_OFFSET":               formatOffsets(c.IndoorOffsets),
+		"THERMIA_SHORT_CYCLE_STARTS_PER_HOUR": formatFloat(c.ShortCycleStartsPerHour),
+		"THERMIA_STATUS_PRIORITY":             formatStatuses(c.StatusPriority),
+		"THERMIA_AUX_SHARE_WINDOW":            formatDuration(c.AuxShareWindow),
+		"THERMIA_REFRIGERANT":                 string(c.Refrigerant),
+		"THERMIA_SPIKE_MAX_DELTA":             formatFloat(c.SpikeMaxDelta),
+		"THERMIA_VALUE_HOLD_TTL":              formatDuration(c.ValueHoldTTL),
+		"THERMIA_STARTUP_PROBE":               strconv.FormatBool(c.StartupProbe),
+		"THERMIA_SCHEDULES":                   strconv.FormatBool(c.Schedules),
+		"THERMIA_EXPORT_RAW_REGISTERS":        strconv.FormatBool(c.ExportRawRegisters),
+		"THERMIA_MAX_SERIES":                  strconv.Itoa(c.MaxSeries),
+		"THERMIA_OPER_TIME_GAUGES":            strconv.FormatBool(c.OperTimeGauges),
+		"THERMIA_EXPVAR":                      strconv.FormatBool(c.Expvar),
+		"THERMIA_READY_MAX_FAILURES":          strconv.Itoa(c.ReadyMaxFailures),
+		"THERMIA_READY_MAX_AGE":               formatDuration(c.ReadyMaxAge),
+		"THERMIA_HEALTH_MODE":                 c.HealthMode,
+		"THERMIA_ANONYMIZE":                   strconv.FormatBool(c.Anonymize),
+		"THERMIA_TOKEN_CACHE_FILE":            c.TokenCacheFile,
+		"THERMIA_INSTANCE_ID_FILE":            c.InstanceIDFile,
+		"THERMIA_ENABLE_WRITE":                strconv.FormatBool(c.EnableWrite),
+		"THERMIA_WRITE_TOKEN":                 secret(c.WriteToken),
+		"THERMIA_OVERRIDE_FILE":               c.OverrideFile,
+		"THERMIA_DEBUG_TOKEN":                 secret(c.DebugToken),
+		"THERMIA_ALERT_ACTIONS":               formatMitigations(c.AlertMitigations),
+		"THERMIA_NORMALIZE_LABELS":            strconv.FormatBool(c.NormalizeLabels),
+		"THERMIA_RESTART_AFTER_FAILURES":      strconv.Itoa(c.RestartAfterFailures),
+		"THERMIA_ALIASES_FILE":                c.AliasesFile,
+		"THERMIA_METRIC_RULES":                formatRules(c.MetricRules),
+		"THERMIA_REDACT_LABELS":               formatRedactLabels(c.RedactLabels),
+		"THERMIA_REDACT_SALT":                 secret(c.RedactSalt),
+		"THERMIA_EVENTS_SINCE":                formatDuration(c.EventsSince),
+		"THERMIA_LOG_LEVEL":                   c.LogLevel,
+		"THERMIA_LOG_FORMAT":                  c.LogFormat,
+	}
+
+	settings := make(map[string]Setting, len(values))
+	for name, value := range values {
+		source, ok := c.sources[name]
+		if !ok {
+			source = SourceDefault
+		}
+		settings[name] = Setting{Value: value, Source: source}
+	}
+	return settings
+}
+
+// secret redacts a credential, keeping whether it is set.
+func secret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL removes the password from a URL, or the whole value if it
+// cannot be parsed.
+func redactURL(v string) string {
+	if v == "" {
+		return ""
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// formatMitigations formats mitigations in the THERMIA_ALERT_ACTIONS syntax.
+func formatMitigations(mitigations []control.Mitigation) string {
+	parts := make([]string, len(mitigations))
+	for i, m := range mitigations {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatStatuses formats statuses in the THERMIA_STATUS_PRIORITY syntax.
+func formatStatuses(statuses []types.OperationalStatus) string {
+	names := make([]string, len(statuses))
+	for i, s := range statuses {
+		names[i] = s.String()
+	}
+	return strings.Join(names, ",")
+}
+
+// formatRules formats rules in the THERMIA_METRIC_RULES syntax.
+func formatRules(rules []relabel.Rule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// formatRedactLabels formats rules in the THERMIA_REDACT_LABELS syntax.
+func formatRedactLabels(rules []relabel.Rule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		mode := "hash"
+		if r.Action == relabel.ActionDropLabel {
+			mode = "drop"
+		}
+		parts = append(parts, r.Label+":"+mode)
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatDeadband formats deadbands in the THERMIA_PUSH_DEADBAND syntax.
+func formatDeadband(deadband map[string]float64) string {
+	suffixes := make([]string, 0, len(deadband))
+	for suffix := range deadband {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+
+	parts := make([]string, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		if suffix == "" {
+			parts = append(parts, formatFloat(deadband[suffix]))
+			continue
+		}
+		parts = append(parts, suffix+"="+formatFloat(deadband[suffix]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatOffsets formats offsets in the THERMIA_INDOOR_OFFSET syntax.
+func formatOffsets(offsets map[int64]float64) string {
+	ids := make([]int64, 0, len(offsets))
+	for id := range offsets {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 {
+			parts = append(parts, formatFloat(offsets[id]))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d=%s", id, formatFloat(offsets[id])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatIntervals formats intervals in the THERMIA_INSTALLATION_INTERVALS
+// syntax.
+func formatIntervals(intervals map[int64]time.Duration) string {
+	ids := make([]int64, 0, len(intervals))
+	for id := range intervals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%d=%s", id, intervals[id]))
+	}
+	return strings.Join(parts, ",")
+}
@@ -10,11 +10,29 @@ const (
 	defaultSecretsPath = "/var/run/secrets/thermia"
 	usernameFile       = "username"
 	passwordFile       = "password"
+	refreshTokenFile   = "refresh_token"
+	bundleKeyFile      = "bundle_key"
+	accountsFile       = "accounts"
+	writeTokenFile     = "write_token"
+	debugTokenFile     = "debug_token"
 )
 
+// secretValues holds credentials read from mounted secret files.
+type secretValues struct {
+	username     string
+	password     string
+	refreshToken string
+	bundleKey    string
+	accounts     string
+	writeToken   string
+	debugToken   string
+}
+
 // tryLoadFromSecrets attempts to read credentials from mounted Kubernetes secret files.
-// Returns empty strings if the secrets path doesn't exist (not an error - allows fallback to env vars).
-func tryLoadFromSecrets() (username, password string, err error) {
+// Missing files yield empty values (not an error - allows fallback to env vars).
+func tryLoadFromSecrets() (secretValues, error) {
+	var v secretValues
+
 	secretsPath := os.Getenv("THERMIA_SECRETS_PATH")
 	if secretsPath == "" {
 		secretsPath = defaultSecretsPath
@@ -22,30 +40,49 @@ func tryLoadFromSecrets() (username, password string, err error) {
 
 	// Check if secrets directory exists
 	if _, err := os.Stat(secretsPath); os.IsNotExist(err) {
-		return "", "", nil // Not an error, just not using secrets
+		return v, nil // Not an error, just not using secrets
 	}
 
-	// Read username
-	usernameData, err := os.ReadFile(filepath.Join(secretsPath, usernameFile))
-	if err != nil {
-		// Don't fail if file doesn't exist, just return empty
-		if os.IsNotExist(err) {
-			return "", "", nil
-		}
-		return "", "", err
+	var err error
+	if v.username, err = readSecretFile(secretsPath, usernameFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.password, err = readSecretFile(secretsPath, passwordFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.refreshToken, err = readSecretFile(secretsPath, refreshTokenFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.bundleKey, err = readSecretFile(secretsPath, bundleKeyFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.accounts, err = readSecretFile(secretsPath, accountsFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.writeToken, err = readSecretFile(secretsPath, writeTokenFile); err != nil {
+		return secretValues{}, err
+	}
+	if v.debugToken, err = readSecretFile(secretsPath, debugTokenFile); err != nil {
+		return secretValues{}, err
 	}
-	username = strings.TrimSpace(string(usernameData))
 
-	// Read password
-	passwordData, err := os.ReadFile(filepath.Join(secretsPath, passwordFile))
+	return v, nil
+}
+
+// readSecretFile reads and trims a single secret file.
+// A missing file is not an error and returns an empty string.
+func readSecretFile(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
 	if err != nil {
-		// Don't fail if file doesn't exist, just return empty
 		if os.IsNotExist(err) {
-			return username, "", nil
+			return "", nil
 		}
-		return "", "", err
+		return "", err
 	}
-	password = strings.TrimSpace(string(passwordData))
+	return strings.TrimSpace(string(data)), nil
+}
 
-	return username, password, nil
+// usable reports whether the secrets contain a complete credential set.
+func (v secretValues) usable() bool {
+	return (v.username != "" && v.password != "") || v.refreshToken != ""
 }
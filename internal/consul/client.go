@@ -0,0 +1,117 @@
+// Package consul implements the minimal Consul agent API needed to register
+// the exporter as a service for Consul service discovery.
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Service is a service registration with an HTTP health check.
+type Service struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+
+	// CheckURL is polled by the agent every CheckInterval; a service
+	// failing it for an hour is deregistered by Consul.
+	CheckURL      string
+	CheckInterval time.Duration
+}
+
+// Client talks to a local Consul agent.
+type Client struct {
+	addr       string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the agent at addr (e.g.
+// http://localhost:8500). token is an ACL token, "" for none.
+func NewClient(addr, token string, timeout time.Duration) *Client {
+	return &Client{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// agentService is the agent API's service registration body.
+type agentService struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address,omitempty"`
+	Port    int               `json:"Port,omitempty"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *agentCheck       `json:"Check,omitempty"`
+}
+
+type agentCheck struct {
+	HTTP                           string `json:"HTTP"`
+	Interval                       string `json:"Interval"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+// Register registers svc with the agent, replacing an earlier registration
+// with the same ID.
+func (c *Client) Register(ctx context.Context, svc Service) error {
+	body := agentService{
+		ID:      svc.ID,
+		Name:    svc.Name,
+		Address: svc.Address,
+		Port:    svc.Port,
+		Tags:    svc.Tags,
+		Meta:    svc.Meta,
+	}
+	if svc.CheckURL != "" {
+		body.Check = &agentCheck{
+			HTTP:                           svc.CheckURL,
+			Interval:                       svc.CheckInterval.String(),
+			DeregisterCriticalServiceAfter: "1h",
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode registration: %w", err)
+	}
+	return c.put(ctx, "/v1/agent/service/register", data)
+}
+
+// Deregister removes the service with the given ID from the agent.
+func (c *Client) Deregister(ctx context.Context, id string) error {
+	return c.put(ctx, "/v1/agent/service/deregister/"+url.PathEscape(id), nil)
+}
+
+func (c *Client) put(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.addr+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("consul returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegisterDeregister(t *testing.T) {
+	type request struct {
+		method, path, token string
+		body                map[string]any
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path, token: r.Header.Get("X-Consul-Token")}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &req.body); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+		}
+		got = append(got, req)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/", "secret", time.Second)
+	err := c.Register(context.Background(), Service{
+		ID:            "thermia-exporter-host",
+		Name:          "thermia-exporter",
+		Address:       "10.0.0.5",
+		Port:          9808,
+		Tags:          []string{"installation-2200002"},
+		CheckURL:      "http://10.0.0.5:9808/ready",
+		CheckInterval: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := c.Deregister(context.Background(), "thermia-exporter-host"); err != nil {
+		t.Fatalf("Deregister() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2", len(got))
+	}
+	reg := got[0]
+	if reg.method != http.MethodPut || reg.path != "/v1/agent/service/register" || reg.token != "secret" {
+		t.Errorf("register request = %s %s (token %q)", reg.method, reg.path, reg.token)
+	}
+	if reg.body["Name"] != "thermia-exporter" || reg.body["Port"] != float64(9808) {
+		t.Errorf("register body = %v", reg.body)
+	}
+	if !reflect.DeepEqual(reg.body["Tags"], []any{"installation-2200002"}) {
+		t.Errorf("tags = %v", reg.body["Tags"])
+	}
+	check, _ := reg.body["Check"].(map[string]any)
+	if check["HTTP"] != "http://10.0.0.5:9808/ready" || check["Interval"] != "30s" {
+		t.Errorf("check = %v", check)
+	}
+	if got[1].path != "/v1/agent/service/deregister/thermia-exporter-host" {
+		t.Errorf("deregister path = %s", got[1].path)
+	}
+}
+
+func TestRegister_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := NewClient(srv.URL, "", time.Second).Register(context.Background(), Service{ID: "x", Name: "x"})
+	if err == nil {
+		t.Fatal("Register() error = nil, want the agent's rejection")
+	}
+}
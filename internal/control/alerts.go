@@ -0,0 +1,129 @@
+package control
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"thermia_exporter/internal/mapper"
+)
+
+// Mitigation actions, named like the write endpoints they correspond to.
+const (
+	ActionOperationMode = "operation_mode"
+	ActionHotWaterBoost = "hot_water_boost"
+)
+
+// Mitigation is a write performed when an alert fires: Action set to Value
+// on the installation the alert is about.
+type Mitigation struct {
+	Alert  string
+	Action string
+	Value  string
+}
+
+// ParseMitigations parses mitigations of the form
+// "ThermiaHotWaterLow=hot_water_boost:ON,ThermiaFrostRisk=operation_mode:AUTO".
+// An alert may be listed more than once to trigger several actions.
+func ParseMitigations(s string) ([]Mitigation, error) {
+	var mitigations []Mitigation
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		alert, action, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(alert) == "" {
+			return nil, fmt.Errorf("invalid mitigation %q (use alert=action:value)", part)
+		}
+		action, value, ok := strings.Cut(action, ":")
+		if !ok || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("mitigation %q has no value", part)
+		}
+		action = strings.ToLower(strings.TrimSpace(action))
+		if _, ok := Target(action); !ok {
+			return nil, fmt.Errorf("unknown action %q (use %q or %q)", action, ActionOperationMode, ActionHotWaterBoost)
+		}
+		mitigations = append(mitigations, Mitigation{
+			Alert:  strings.TrimSpace(alert),
+			Action: action,
+			Value:  strings.TrimSpace(value),
+		})
+	}
+	return mitigations, nil
+}
+
+// String formats m in the ParseMitigations syntax.
+func (m Mitigation) String() string {
+	return m.Alert + "=" + m.Action + ":" + m.Value
+}
+
+// RegisterTarget is the register group and register an action writes.
+type RegisterTarget struct {
+	Group    string
+	Register string
+}
+
+// Target returns the register an action writes.
+func Target(action string) (RegisterTarget, bool) {
+	switch action {
+	case ActionOperationMode:
+		return RegisterTarget{mapper.RegGroupOperationalOperation, mapper.RegOperationMode}, true
+	case ActionHotWaterBoost:
+		return RegisterTarget{mapper.RegGroupHotWater, mapper.RegHotWaterBoost}, true
+	}
+	return RegisterTarget{}, false
+}
+
+// Notification is the body of an Alertmanager webhook notification. Grafana
+// alerting sends the same format from its webhook contact point.
+type Notification struct {
+	Status string  `json:"status"`
+	Alerts []Alert `json:"alerts"`
+}
+
+// Alert is one alert of a Notification.
+type Alert struct {
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Name returns the alert's alertname label.
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
+
+// Action is a mitigation to perform for a firing alert. Err is set when the
+// alert does not name the installation to act on.
+type Action struct {
+	Mitigation
+	InstallationID int64
+	Err            error
+}
+
+// Plan returns the actions mitigations call for on the firing alerts of n.
+// Resolved alerts trigger nothing: mitigations are not undone. The
+// installation is taken from the alert's heatpump_id label, which every
+// thermia_* heat pump metric carries.
+func Plan(n Notification, mitigations []Mitigation) []Action {
+	var actions []Action
+	for _, alert := range n.Alerts {
+		if alert.Status != "firing" {
+			continue
+		}
+		for _, m := range mitigations {
+			if m.Alert != alert.Name() {
+				continue
+			}
+			action := Action{Mitigation: m}
+			id, err := strconv.ParseInt(alert.Labels[mapper.LabelHeatpumpID], 10, 64)
+			if err != nil || id <= 0 {
+				action.Err = fmt.Errorf("alert %s has no valid %s label", m.Alert, mapper.LabelHeatpumpID)
+			}
+			action.InstallationID = id
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
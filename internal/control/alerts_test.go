@@ -0,0 +1,50 @@
+package control
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMitigations(t *testing.T) {
+	got, err := ParseMitigations("ThermiaHotWaterLow=hot_water_boost:ON, ThermiaFrostRisk=Operation_Mode:AUTO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mitigation{
+		{Alert: "ThermiaHotWaterLow", Action: ActionHotWaterBoost, Value: "ON"},
+		{Alert: "ThermiaFrostRisk", Action: ActionOperationMode, Value: "AUTO"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMitigations() = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"ThermiaHotWaterLow", "=hot_water_boost:ON", "A=hot_water_boost", "A=defrost:ON"} {
+		if _, err := ParseMitigations(bad); err == nil {
+			t.Errorf("ParseMitigations(%q) expected error", bad)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	mitigations := []Mitigation{{Alert: "ThermiaHotWaterLow", Action: ActionHotWaterBoost, Value: "ON"}}
+	n := Notification{
+		Status: "firing",
+		Alerts: []Alert{
+			{Status: "firing", Labels: map[string]string{"alertname": "ThermiaHotWaterLow", "heatpump_id": "1100001"}},
+			{Status: "resolved", Labels: map[string]string{"alertname": "ThermiaHotWaterLow", "heatpump_id": "1100002"}},
+			{Status: "firing", Labels: map[string]string{"alertname": "ThermiaOffline", "heatpump_id": "1100001"}},
+			{Status: "firing", Labels: map[string]string{"alertname": "ThermiaHotWaterLow"}},
+		},
+	}
+
+	actions := Plan(n, mitigations)
+	if len(actions) != 2 {
+		t.Fatalf("actions = %+v, want 2 (resolved and unmatched alerts trigger nothing)", actions)
+	}
+	if actions[0].InstallationID != 1100001 || actions[0].Err != nil {
+		t.Errorf("actions[0] = %+v, want installation 1100001", actions[0])
+	}
+	if actions[1].Err == nil {
+		t.Error("an alert without heatpump_id should not be acted on")
+	}
+}
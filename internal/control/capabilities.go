@@ -0,0 +1,88 @@
+package control
+
+import (
+	"sort"
+
+	"thermia_exporter/internal/types"
+)
+
+// Capabilities lists what an account may change on one installation, so
+// automations can discover writable controls instead of trying writes.
+type Capabilities struct {
+	InstallationID int64  `json:"installation_id"`
+	HeatpumpName   string `json:"heatpump_name"`
+	HeatpumpModel  string `json:"heatpump_model"`
+
+	// OperationMode is nil if the installation has no operation mode
+	// register.
+	OperationMode *OperationModeCapability `json:"operation_mode"`
+
+	// Registers are the writable registers, ordered by group and name.
+	Registers []RegisterCapability `json:"registers"`
+}
+
+// OperationModeCapability describes the operation mode control.
+type OperationModeCapability struct {
+	ReadOnly bool                  `json:"read_only"`
+	Modes    []types.OperationMode `json:"modes"`
+}
+
+// RegisterCapability describes the values a register accepts: Values for
+// enumerated registers, otherwise Min, Max and Step.
+type RegisterCapability struct {
+	Group    string         `json:"group"`
+	Register string         `json:"register"`
+	Min      *float64       `json:"min,omitempty"`
+	Max      *float64       `json:"max,omitempty"`
+	Step     *float64       `json:"step,omitempty"`
+	Values   []AllowedValue `json:"values,omitempty"`
+}
+
+// AllowedValue is a value an enumerated register may be set to.
+type AllowedValue struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// WritableRegisters returns the registers in groups that ValidateWrite can
+// accept some value for: registers not flagged read-only with either a
+// visible, writable value entry or min/max metadata.
+func WritableRegisters(groups map[string][]types.GroupItem) []RegisterCapability {
+	var registers []RegisterCapability
+	for group, items := range groups {
+		for _, item := range items {
+			if rc, ok := registerCapability(group, item); ok {
+				registers = append(registers, rc)
+			}
+		}
+	}
+	sort.Slice(registers, func(i, j int) bool {
+		if registers[i].Group != registers[j].Group {
+			return registers[i].Group < registers[j].Group
+		}
+		return registers[i].Register < registers[j].Register
+	})
+	return registers
+}
+
+func registerCapability(group string, item types.GroupItem) (RegisterCapability, bool) {
+	rc := RegisterCapability{Group: group, Register: item.RegisterName}
+	if item.IsReadOnly {
+		return rc, false
+	}
+
+	if len(item.ValueNames) > 0 {
+		for _, vn := range item.ValueNames {
+			if vn.Visible && !vn.Readonly {
+				rc.Values = append(rc.Values, AllowedValue{Name: trimValuePrefix(vn.Name), Value: vn.Value})
+			}
+		}
+		return rc, len(rc.Values) > 0
+	}
+
+	if item.MinValue == nil || item.MaxValue == nil {
+		return rc, false
+	}
+	rc.Min, rc.Max, rc.Step = item.MinValue, item.MaxValue, item.Step
+	return rc, true
+}
@@ -0,0 +1,146 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/statefile"
+)
+
+// Override is a temporary operation mode of an installation: Restore is
+// written back once Until has passed.
+type Override struct {
+	InstallationID int64     `json:"installation_id"`
+	Value          float64   `json:"value"`
+	Restore        float64   `json:"restore_value"`
+	Until          time.Time `json:"until"`
+}
+
+// Overrides holds the pending operation mode overrides. With a path they
+// are persisted, so an override set before a restart still expires after
+// it. Safe for concurrent use.
+type Overrides struct {
+	mu      sync.Mutex
+	path    string
+	pending map[int64]Override
+}
+
+// LoadOverrides returns the overrides persisted in path. A missing file
+// holds none, and an empty path keeps them in memory only.
+func LoadOverrides(path string) (*Overrides, error) {
+	o := &Overrides{path: path, pending: make(map[int64]Override)}
+	if path == "" {
+		return o, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []Override
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, ov := range saved {
+		o.pending[ov.InstallationID] = ov
+	}
+	return o, nil
+}
+
+// Get returns the pending override of an installation.
+func (o *Overrides) Get(installationID int64) (Override, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ov, ok := o.pending[installationID]
+	return ov, ok
+}
+
+// Set stores ov and returns it with a function that reverts the change,
+// for when writing the override fails. Replacing an override keeps its
+// restore value: the mode to go back to is the one from before the first
+// override, not the one it set. If ov cannot be persisted nothing changes.
+func (o *Overrides) Set(ov Override) (Override, func() error, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	replaced, ok := o.pending[ov.InstallationID]
+	if ok {
+		ov.Restore = replaced.Restore
+	}
+	o.pending[ov.InstallationID] = ov
+	revert := func() error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.pending[ov.InstallationID] != ov {
+			return nil
+		}
+		o.undo(ov.InstallationID, replaced, ok)
+		return o.save()
+	}
+	if err := o.save(); err != nil {
+		o.undo(ov.InstallationID, replaced, ok)
+		return Override{}, nil, err
+	}
+	return ov, revert, nil
+}
+
+// Remove drops ov once its restore value has been written. An override
+// replaced in the meantime is kept.
+func (o *Overrides) Remove(ov Override) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.pending[ov.InstallationID] != ov {
+		return nil
+	}
+	delete(o.pending, ov.InstallationID)
+	return o.save()
+}
+
+// Due returns the overrides that expired at now, ordered by installation ID.
+func (o *Overrides) Due(now time.Time) []Override {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var due []Override
+	for _, ov := range o.pending {
+		if !now.Before(ov.Until) {
+			due = append(due, ov)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].InstallationID < due[j].InstallationID })
+	return due
+}
+
+// undo puts replaced back for an installation, or removes its override.
+// Caller must hold mu.
+func (o *Overrides) undo(installationID int64, replaced Override, ok bool) {
+	if ok {
+		o.pending[installationID] = replaced
+	} else {
+		delete(o.pending, installationID)
+	}
+}
+
+// save persists the pending overrides, if a path is configured. Caller
+// must hold mu.
+func (o *Overrides) save() error {
+	if o.path == "" {
+		return nil
+	}
+	saved := make([]Override, 0, len(o.pending))
+	for _, ov := range o.pending {
+		saved = append(saved, ov)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].InstallationID < saved[j].InstallationID })
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return statefile.WriteFile(o.path, data)
+}
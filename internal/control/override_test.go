@@ -0,0 +1,92 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOverrides_PersistAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	o, err := LoadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := o.Set(Override{InstallationID: 1, Value: 5, Restore: 1, Until: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	// Extending the override keeps the mode from before the first one
+	ov, _, err := o.Set(Override{InstallationID: 1, Value: 6, Restore: 5, Until: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ov.Restore != 1 {
+		t.Errorf("restore value = %v, want 1 from before the first override", ov.Restore)
+	}
+
+	restarted, err := LoadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due := restarted.Due(now.Add(time.Hour)); len(due) != 0 {
+		t.Errorf("due before expiry = %v, want none", due)
+	}
+	due := restarted.Due(now.Add(2 * time.Hour))
+	if len(due) != 1 || due[0].Value != 6 || due[0].Restore != 1 {
+		t.Fatalf("due after restart = %+v, want the extended override", due)
+	}
+
+	if err := restarted.Remove(due[0]); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := LoadOverrides(path); err != nil || len(reloaded.Due(now.Add(3*time.Hour))) != 0 {
+		t.Errorf("restored override still persisted (error %v)", err)
+	}
+}
+
+func TestOverrides_Revert(t *testing.T) {
+	o, _ := LoadOverrides("")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	first := Override{InstallationID: 1, Value: 5, Restore: 1, Until: now.Add(time.Hour)}
+	o.Set(first)
+	_, revert, _ := o.Set(Override{InstallationID: 1, Value: 6, Until: now.Add(2 * time.Hour)})
+	if err := revert(); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := o.Get(1); !ok || got != first {
+		t.Errorf("after revert = %+v, want the replaced %+v", got, first)
+	}
+
+	_, revert, _ = o.Set(Override{InstallationID: 2, Value: 5, Restore: 1, Until: now.Add(time.Hour)})
+	revert()
+	if _, ok := o.Get(2); ok {
+		t.Error("reverting a new override should remove it")
+	}
+}
+
+func TestOverrides_Remove_KeepsReplaced(t *testing.T) {
+	o, _ := LoadOverrides("")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	expired, _, _ := o.Set(Override{InstallationID: 1, Value: 5, Restore: 1, Until: now})
+	// Extended while the expired one was being restored
+	o.Set(Override{InstallationID: 1, Value: 5, Until: now.Add(time.Hour)})
+	o.Remove(expired)
+	if _, ok := o.Get(1); !ok {
+		t.Error("an override replaced during the restore should be kept")
+	}
+}
+
+func TestLoadOverrides_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOverrides(path); err == nil {
+		t.Error("LoadOverrides() expected error for an unreadable file")
+	}
+}
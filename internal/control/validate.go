@@ -0,0 +1,102 @@
+// Package control validates and prepares register writes to the Thermia API.
+// Every write must pass ValidateWrite so a typo in an automation cannot push
+// a register outside the range the heat pump itself advertises.
+package control
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// Validation errors. Returned errors wrap one of these with details.
+var (
+	ErrReadOnly   = errors.New("register is read-only")
+	ErrNotAllowed = errors.New("value not allowed")
+	ErrOutOfRange = errors.New("value out of range")
+	ErrNoRange    = errors.New("register has no range metadata")
+)
+
+// ValidateWrite checks value against the register's metadata and returns the
+// value to send.
+//
+// Enumerated registers (with ValueNames) only accept a visible, writable
+// entry. Numeric registers require min/max metadata; writes to registers
+// without it are refused rather than guessed. With clamp, numeric values
+// outside the range are clamped to the nearest bound instead of rejected.
+// Enumerated values are never clamped.
+func ValidateWrite(item types.GroupItem, value float64, clamp bool) (float64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%w: %v for %s", ErrNotAllowed, value, item.RegisterName)
+	}
+	if item.IsReadOnly {
+		return 0, fmt.Errorf("%w: %s", ErrReadOnly, item.RegisterName)
+	}
+
+	if len(item.ValueNames) > 0 {
+		return validateEnum(item, value)
+	}
+
+	if item.MinValue == nil || item.MaxValue == nil {
+		return 0, fmt.Errorf("%w: %s", ErrNoRange, item.RegisterName)
+	}
+	lo, hi := *item.MinValue, *item.MaxValue
+
+	if value < lo || value > hi {
+		if !clamp {
+			return 0, fmt.Errorf("%w: %v outside [%v, %v] for %s", ErrOutOfRange, value, lo, hi, item.RegisterName)
+		}
+		value = math.Max(lo, math.Min(hi, value))
+	}
+
+	if item.Step != nil && *item.Step > 0 {
+		steps := (value - lo) / *item.Step
+		if math.Abs(steps-math.Round(steps)) > 1e-6 {
+			return 0, fmt.Errorf("%w: %v is not a multiple of step %v from %v for %s",
+				ErrNotAllowed, value, *item.Step, lo, item.RegisterName)
+		}
+	}
+
+	return value, nil
+}
+
+// validateEnum checks value against the register's value list.
+func validateEnum(item types.GroupItem, value float64) (float64, error) {
+	allowed := make([]string, 0, len(item.ValueNames))
+	for _, vn := range item.ValueNames {
+		if !vn.Visible || vn.Readonly {
+			continue
+		}
+		if float64(vn.Value) == value {
+			return value, nil
+		}
+		allowed = append(allowed, fmt.Sprintf("%d (%s)", vn.Value, vn.Name))
+	}
+
+	return 0, fmt.Errorf("%w: %v for %s, allowed: %s",
+		ErrNotAllowed, value, item.RegisterName, strings.Join(allowed, ", "))
+}
+
+// LookupValue resolves an enumerated value name (with or without the
+// REG_VALUE_ prefix, case-insensitive) to its numeric value.
+func LookupValue(item types.GroupItem, name string) (float64, bool) {
+	for _, vn := range item.ValueNames {
+		if strings.EqualFold(vn.Name, name) || strings.EqualFold(trimValuePrefix(vn.Name), name) {
+			return float64(vn.Value), true
+		}
+	}
+	return 0, false
+}
+
+// trimValuePrefix strips the REG_VALUE_ style prefixes used in value names.
+func trimValuePrefix(s string) string {
+	for _, p := range []string{"REG_VALUE_OPERATION_MODE_", "COMP_VALUE_OPERATION_MODE_", "REG_VALUE_", "COMP_VALUE_"} {
+		if strings.HasPrefix(s, p) {
+			return strings.TrimPrefix(s, p)
+		}
+	}
+	return s
+}
@@ -0,0 +1,208 @@
+package control
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"thermia_exporter/internal/types"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func hotWaterTarget() types.GroupItem {
+	return types.GroupItem{
+		RegisterID:    105,
+		RegisterName:  "REG_HOT_WATER_TEMPERATURE",
+		RegisterValue: ptr(50),
+		MinValue:      ptr(20),
+		MaxValue:      ptr(60),
+		Step:          ptr(1),
+	}
+}
+
+func operationMode() types.GroupItem {
+	return types.GroupItem{
+		RegisterName:  "REG_OPERATIONMODE",
+		RegisterValue: ptr(0),
+		ValueNames: []types.ValueEntry{
+			{Name: "REG_VALUE_OPERATION_MODE_AUTO", Value: 0, Visible: true},
+			{Name: "REG_VALUE_OPERATION_MODE_MANUAL", Value: 1, Visible: true},
+			{Name: "REG_VALUE_OPERATION_MODE_SERVICE", Value: 2, Visible: true, Readonly: true},
+			{Name: "REG_VALUE_HIDDEN", Value: 3, Visible: false},
+		},
+	}
+}
+
+func TestValidateWrite_Range(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		clamp   bool
+		want    float64
+		wantErr error
+	}{
+		{"in range", 55, false, 55, nil},
+		{"at max", 60, false, 60, nil},
+		{"typo rejected", 90, false, 0, ErrOutOfRange},
+		{"typo clamped", 90, true, 60, nil},
+		{"below min clamped", 5, true, 20, nil},
+		{"off step", 55.5, false, 0, ErrNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateWrite(hotWaterTarget(), tt.value, tt.clamp)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateWrite(%v) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateWrite(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWrite_Enum(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   float64
+		wantErr error
+	}{
+		{"visible", 1, nil},
+		{"readonly entry", 2, ErrNotAllowed},
+		{"hidden entry", 3, ErrNotAllowed},
+		{"unknown", 7, ErrNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// clamp must never apply to enumerated values
+			_, err := ValidateWrite(operationMode(), tt.value, true)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateWrite(%v) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWrite_Refusals(t *testing.T) {
+	readOnly := hotWaterTarget()
+	readOnly.IsReadOnly = true
+	if _, err := ValidateWrite(readOnly, 50, false); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only register error = %v, want ErrReadOnly", err)
+	}
+
+	noRange := types.GroupItem{RegisterName: "REG_UNKNOWN", RegisterValue: ptr(1)}
+	if _, err := ValidateWrite(noRange, 1, true); !errors.Is(err, ErrNoRange) {
+		t.Errorf("register without metadata error = %v, want ErrNoRange", err)
+	}
+}
+
+func TestLookupValue(t *testing.T) {
+	item := operationMode()
+
+	if v, ok := LookupValue(item, "manual"); !ok || v != 1 {
+		t.Errorf("LookupValue(manual) = %v, %v, want 1, true", v, ok)
+	}
+	if v, ok := LookupValue(item, "REG_VALUE_OPERATION_MODE_AUTO"); !ok || v != 0 {
+		t.Errorf("LookupValue(full name) = %v, %v, want 0, true", v, ok)
+	}
+	if _, ok := LookupValue(item, "turbo"); ok {
+		t.Error("LookupValue(turbo) should not be found")
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	items := []types.GroupItem{operationMode(), hotWaterTarget()}
+
+	w, err := Prepare(42, "REG_GROUP_HOT_WATER", items, "REG_HOT_WATER_TEMPERATURE", 75, true)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if w.Value != 60 || w.RequestedValue != 75 || !w.Clamped {
+		t.Errorf("Prepare() = %+v, want value 60 clamped from 75", w)
+	}
+	if w.RegisterID != 105 {
+		t.Errorf("RegisterID = %d, want 105", w.RegisterID)
+	}
+	if w.CurrentValue == nil || *w.CurrentValue != 50 {
+		t.Errorf("CurrentValue = %v, want 50", w.CurrentValue)
+	}
+
+	if _, err := Prepare(42, "REG_GROUP_HOT_WATER", items, "REG_MISSING", 1, false); !errors.Is(err, ErrUnknownRegister) {
+		t.Errorf("Prepare(missing) error = %v, want ErrUnknownRegister", err)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	items := []types.GroupItem{operationMode(), hotWaterTarget()}
+
+	tests := []struct {
+		register string
+		value    string
+		want     float64
+		wantErr  error
+	}{
+		{"REG_OPERATIONMODE", "manual", 1, nil},
+		{"REG_OPERATIONMODE", "2", 2, nil},
+		{"REG_OPERATIONMODE", "turbo", 0, ErrNotAllowed},
+		{"REG_HOT_WATER_TEMPERATURE", "52.5", 52.5, nil},
+		{"REG_MISSING", "1", 0, ErrUnknownRegister},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue("REG_GROUP", items, tt.register, tt.value)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ParseValue(%s, %q) = %v, %v, want %v, %v", tt.register, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIsDryRun(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", false},
+		{"dry_run=false", false},
+		{"dry_run=true", true},
+		{"dry_run=1", true},
+		{"dry_run=yes-please", true},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		if got := IsDryRun(q); got != tt.want {
+			t.Errorf("IsDryRun(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWritableRegisters(t *testing.T) {
+	readOnly := hotWaterTarget()
+	readOnly.RegisterName = "REG_SUPPLY_LINE"
+	readOnly.IsReadOnly = true
+	noRange := types.GroupItem{RegisterName: "REG_NO_RANGE", RegisterValue: ptr(1)}
+	allReadonlyValues := types.GroupItem{
+		RegisterName: "REG_SERVICE_ONLY",
+		ValueNames:   []types.ValueEntry{{Name: "REG_VALUE_ON", Value: 1, Visible: true, Readonly: true}},
+	}
+
+	got := WritableRegisters(map[string][]types.GroupItem{
+		"REG_GROUP_HOT_WATER":             {hotWaterTarget(), readOnly, noRange},
+		"REG_GROUP_OPERATIONAL_OPERATION": {operationMode(), allReadonlyValues},
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("got %d writable registers, want 2: %+v", len(got), got)
+	}
+	if got[0].Register != "REG_HOT_WATER_TEMPERATURE" || *got[0].Min != 20 || *got[0].Max != 60 || got[0].Values != nil {
+		t.Errorf("numeric register = %+v", got[0])
+	}
+	want := []AllowedValue{{Name: "AUTO", Value: 0}, {Name: "MANUAL", Value: 1}}
+	if got[1].Register != "REG_OPERATIONMODE" || len(got[1].Values) != 2 || got[1].Values[0] != want[0] || got[1].Values[1] != want[1] {
+		t.Errorf("enumerated register = %+v, want values %v", got[1], want)
+	}
+}
@@ -0,0 +1,92 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"thermia_exporter/internal/types"
+)
+
+// ErrUnknownRegister is returned when the register is not present in the
+// installation's register group.
+var ErrUnknownRegister = errors.New("unknown register")
+
+// Write is a fully validated register write. Control endpoints send it to the
+// API, or return it unchanged when the request is a dry run, so automations
+// can see exactly what would be written without touching the heat pump.
+type Write struct {
+	InstallationID int64    `json:"installation_id"`
+	Group          string   `json:"group"`
+	Register       string   `json:"register"`
+	RegisterID     int64    `json:"register_id"`
+	CurrentValue   *float64 `json:"current_value"`
+	RequestedValue float64  `json:"requested_value"`
+	Value          float64  `json:"value"`
+	Clamped        bool     `json:"clamped"`
+	DryRun         bool     `json:"dry_run"`
+}
+
+// Prepare looks up register in the group items, validates value against its
+// metadata and returns the write that would be sent.
+func Prepare(installationID int64, group string, items []types.GroupItem, register string, value float64, clamp bool) (*Write, error) {
+	item, err := findItem(group, items, register)
+	if err != nil {
+		return nil, err
+	}
+
+	validated, err := ValidateWrite(*item, value, clamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Write{
+		InstallationID: installationID,
+		Group:          group,
+		Register:       register,
+		RegisterID:     item.RegisterID,
+		CurrentValue:   item.RegisterValue,
+		RequestedValue: value,
+		Value:          validated,
+		Clamped:        validated != value,
+	}, nil
+}
+
+// ParseValue resolves a requested value for register: a number, or the name
+// of one of its enumerated values (see LookupValue).
+func ParseValue(group string, items []types.GroupItem, register, value string) (float64, error) {
+	item, err := findItem(group, items, register)
+	if err != nil {
+		return 0, err
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v, nil
+	}
+	if v, ok := LookupValue(*item, value); ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("%w: %q for %s", ErrNotAllowed, value, register)
+}
+
+// findItem returns register from the group items.
+func findItem(group string, items []types.GroupItem, register string) (*types.GroupItem, error) {
+	for i := range items {
+		if items[i].RegisterName == register {
+			return &items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s in %s", ErrUnknownRegister, register, group)
+}
+
+// IsDryRun reports whether a control request asked for a dry run
+// (?dry_run=true). Unparseable values are treated as a dry run so a typo
+// never results in a real write.
+func IsDryRun(q url.Values) bool {
+	raw := q.Get("dry_run")
+	if raw == "" {
+		return false
+	}
+	v, err := strconv.ParseBool(raw)
+	return err != nil || v
+}
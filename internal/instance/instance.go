@@ -0,0 +1,55 @@
+// Package instance identifies a running exporter, so logs, metrics and
+// pushed data from the same instance can be correlated across restarts and
+// told apart between replicas.
+package instance
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"thermia_exporter/internal/statefile"
+)
+
+// idPattern matches the version 4 UUIDs NewID generates.
+var idPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// NewID returns a random version 4 UUID.
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// LoadID returns the instance ID stored in path, generating and storing a
+// new one if the file does not exist yet. With an empty path the ID is
+// generated and not persisted, so it changes on every restart. A file that
+// does not hold an ID is an error rather than overwritten: it is probably
+// not ours.
+func LoadID(path string) (string, error) {
+	if path == "" {
+		return NewID(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(data))
+		if !idPattern.MatchString(id) {
+			return "", fmt.Errorf("%s does not hold an instance ID", path)
+		}
+		return id, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	id := NewID()
+	if err := statefile.WriteFile(path, []byte(id+"\n")); err != nil {
+		return "", err
+	}
+	return id, nil
+}
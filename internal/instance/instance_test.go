@@ -0,0 +1,34 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instance_id")
+
+	first, err := LoadID(path)
+	if err != nil {
+		t.Fatalf("LoadID() error = %v", err)
+	}
+	if !idPattern.MatchString(first) {
+		t.Errorf("LoadID() = %q, want a version 4 UUID", first)
+	}
+	second, err := LoadID(path)
+	if err != nil || second != first {
+		t.Errorf("LoadID() = %q, %v after a restart, want the persisted %q", second, err, first)
+	}
+
+	if id, _ := LoadID(""); id == first {
+		t.Error("LoadID(\"\") returned the persisted ID, want a new one")
+	}
+
+	if err := os.WriteFile(path, []byte("not an id\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadID(path); err == nil {
+		t.Error("LoadID() error = nil for a file without an ID, want an error")
+	}
+}
@@ -0,0 +1,99 @@
+package mapper
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// Aliases map register names reported by localized or older firmwares onto
+// the canonical names the mapper understands.
+type Aliases map[string]string
+
+// LoadAliases reads an alias file (see ParseAliases).
+func LoadAliases(path string) (Aliases, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAliases(data)
+}
+
+// ParseAliases parses a flat YAML mapping of reported register names to
+// canonical ones:
+//
+//	# Swedish firmware
+//	REG_UTOMHUSTEMPERATUR: REG_OUTDOOR_TEMPERATURE
+//	"REG_INNETEMPERATUR": REG_INDOOR_TEMPERATURE
+//
+// Only this subset of YAML is accepted: one mapping per line, optionally
+// quoted, with blank lines and # comments.
+func ParseAliases(data []byte) (Aliases, error) {
+	aliases := make(Aliases)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' || line[0] == '-' {
+			return nil, fmt.Errorf("line %d: expected a flat \"REPORTED: CANONICAL\" mapping", n)
+		}
+
+		from, to, ok := strings.Cut(line, ":")
+		from, to = unquote(from), unquote(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("line %d: expected \"REPORTED: CANONICAL\", got %q", n, sc.Text())
+		}
+		if from == to {
+			return nil, fmt.Errorf("line %d: %s is aliased to itself", n, from)
+		}
+		if _, dup := aliases[from]; dup {
+			return nil, fmt.Errorf("line %d: duplicate alias for %s", n, from)
+		}
+		aliases[from] = to
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// unquote trims whitespace and one pair of surrounding quotes.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Rename returns items with aliased register names replaced by their
+// canonical names. items is not modified.
+func (a Aliases) Rename(items []types.GroupItem) []types.GroupItem {
+	if len(a) == 0 {
+		return items
+	}
+	renamed := make([]types.GroupItem, len(items))
+	for i, it := range items {
+		if to, ok := a[it.RegisterName]; ok {
+			it.RegisterName = to
+		}
+		renamed[i] = it
+	}
+	return renamed
+}
+
+// RenameGroups renames the registers of every group (see Rename).
+func (a Aliases) RenameGroups(groups map[string][]types.GroupItem) {
+	for group, items := range groups {
+		groups[group] = a.Rename(items)
+	}
+}
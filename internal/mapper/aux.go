@@ -0,0 +1,24 @@
+package mapper
+
+import "thermia_exporter/internal/types"
+
+// auxHeaterTimeRegisters are the operating hour counters of the auxiliary
+// (immersion) heater steps.
+var auxHeaterTimeRegisters = []string{RegOperTimeImm1, RegOperTimeImm2, RegOperTimeImm3}
+
+// ExtractRunHours returns the total auxiliary heater and compressor operating
+// hours. ok is false unless the compressor counter and at least one
+// auxiliary heater counter are present.
+func ExtractRunHours(items []types.GroupItem) (aux, compressor float64, ok bool) {
+	comp := findValue(items, RegOperTimeCompressor)
+	if comp == nil {
+		return 0, 0, false
+	}
+	for _, name := range auxHeaterTimeRegisters {
+		if v := findValue(items, name); v != nil {
+			aux += *v
+			ok = true
+		}
+	}
+	return aux, *comp, ok
+}
@@ -0,0 +1,57 @@
+package mapper
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+
+	"thermia_exporter/internal/types"
+)
+
+// circuitRegisterPattern matches per-circuit mixing valve registers such as
+// REG_MIX_VALVE_1_SUPPLY_LINE_TEMP or REG_MIXING_VALVE_2_POSITION.
+var circuitRegisterPattern = regexp.MustCompile(`^REG_MIX(?:ING)?_VALVE_?(\d+)_(SUPPLY_LINE(?:_TEMP)?|POSITION)$`)
+
+// ExtractCircuits enumerates mixing valve distribution circuits from register
+// items. Circuits are returned sorted by number; installations without mixing
+// valves return an empty slice.
+func ExtractCircuits(items []types.GroupItem) []types.CircuitData {
+	byNum := make(map[int]*types.CircuitData)
+
+	for _, it := range items {
+		m := circuitRegisterPattern.FindStringSubmatch(it.RegisterName)
+		if m == nil || it.RegisterValue == nil {
+			continue
+		}
+		num, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+
+		c, ok := byNum[num]
+		if !ok {
+			c = &types.CircuitData{Circuit: m[1]}
+			byNum[num] = c
+		}
+
+		if m[2] == "POSITION" {
+			if c.ValvePosition == nil {
+				c.ValvePosition = it.RegisterValue
+			}
+		} else if c.SupplyTemperature == nil {
+			c.SupplyTemperature = it.RegisterValue
+		}
+	}
+
+	nums := make([]int, 0, len(byNum))
+	for n := range byNum {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	result := make([]types.CircuitData, 0, len(nums))
+	for _, n := range nums {
+		result = append(result, *byNum[n])
+	}
+	return result
+}
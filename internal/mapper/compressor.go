@@ -0,0 +1,64 @@
+package mapper
+
+import (
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// CompressorStartsCandidates lists registers counting compressor starts,
+// checked in order. Not every model exposes one.
+var CompressorStartsCandidates = []string{
+	"REG_OPER_DATA_COMPRESSOR_STARTS",
+	"REG_COMPRESSOR_STARTS",
+	"REG_OPER_NUMBER_OF_STARTS_COMPRESSOR",
+}
+
+// compressorDemandStatuses are operational statuses that imply a running
+// compressor when no power status register is available.
+var compressorDemandStatuses = []types.OperationalStatus{
+	types.StatusHeat,
+	types.StatusHotWater,
+	types.StatusCool,
+	types.StatusPool,
+	types.StatusLegionella,
+}
+
+// ExtractCompressorStarts returns the compressor start counter, or nil if
+// none of the candidate registers is present.
+func ExtractCompressorStarts(items []types.GroupItem) *float64 {
+	for _, name := range CompressorStartsCandidates {
+		if v := findValue(items, name); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// CompressorRunning reports whether the compressor is running. The power
+// status bitmask is used when present, otherwise an active heating, hot
+// water, cooling or pool status. known is false if neither is available.
+func CompressorRunning(items []types.GroupItem) (running, known bool) {
+	power := ExtractBitmaskStatuses(items, PowerStatusCandidates)
+	if power.Available != nil {
+		for _, s := range power.Running {
+			if strings.Contains(strings.ToUpper(s.String()), "COMPRESSOR") {
+				return true, true
+			}
+		}
+		return false, true
+	}
+
+	status := ExtractBitmaskStatuses(items, OperationalStatusCandidates)
+	if status.Available == nil {
+		return false, false
+	}
+	for _, s := range status.Running {
+		for _, demand := range compressorDemandStatuses {
+			if s.Is(demand) {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
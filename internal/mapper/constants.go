@@ -8,6 +8,8 @@ const (
 	RegGroupOperationalTime      = "REG_GROUP_OPERATIONAL_TIME"
 	RegGroupOperationalOperation = "REG_GROUP_OPERATIONAL_OPERATION"
 	RegGroupHotWater             = "REG_GROUP_HOT_WATER"
+	RegGroupHeatingCurve         = "REG_GROUP_HEATING_CURVE"
+	RegGroupEnergy               = "REG_GROUP_ENERGY"
 )
 
 // Temperature register names
@@ -38,9 +40,14 @@ const (
 	CompPowerStatus                     = "COMP_POWER_STATUS"
 )
 
-// Operation mode register name
+// Operation mode register names. Atlas, Calibra and ITEC firmwares report
+// the mode under a COMP_ register instead of REG_OPERATIONMODE.
 const (
-	RegOperationMode = "REG_OPERATIONMODE"
+	RegOperationMode      = "REG_OPERATIONMODE"
+	RegOperationModeAlt   = "REG_OPERATION_MODE"
+	CompOperationMode     = "COMP_OPERATION_MODE"
+	CompOperationModeAtec = "COMP_OPERATION_MODE_ATEC"
+	CompOperationModeItec = "COMP_OPERATION_MODE_ITEC"
 )
 
 // Hot water register names
@@ -59,6 +66,20 @@ const (
 	RegOperTimeImm3       = "REG_OPER_TIME_IMM3"
 )
 
+// Power register names
+const (
+	RegHeatOutputPower      = "REG_HEAT_OUTPUT_POWER"
+	RegOperDataHeatingPower = "REG_OPER_DATA_HEATING_POWER"
+
+	RegPowerConsumption         = "REG_POWER_CONSUMPTION"
+	RegOperDataPowerConsumption = "REG_OPER_DATA_POWER_CONSUMPTION"
+	RegCompressorPower          = "REG_COMPRESSOR_POWER"
+
+	RegEnergyConsumption         = "REG_ENERGY_CONSUMPTION"
+	RegOperDataEnergyConsumption = "REG_OPER_DATA_ENERGY_CONSUMPTION"
+	RegAccumulatedEnergy         = "REG_ACCUMULATED_ENERGY"
+)
+
 // Prometheus metric label names
 const (
 	LabelHeatpumpID   = "heatpump_id"
@@ -66,15 +87,39 @@ const (
 	LabelModel        = "model"
 	LabelMode         = "mode"
 	LabelStatus       = "status"
-)
+	LabelCircuit      = "circuit"
+	LabelSensor       = "sensor"
+	LabelGroup        = "group"
+	LabelRegister     = "register"
+	LabelRegisterName = "register_name"
+	LabelUnit         = "unit"
+	LabelSite         = "site"
+	LabelSource       = "source"
+	LabelGroupName    = "installation_group"
+	LabelStage        = "stage"
+	LabelSerial       = "serial"
+	LabelPriority     = "priority"
+	LabelPolicy       = "policy"
+	LabelEstimated    = "estimated"
+	LabelGrant        = "grant"
+	LabelResult       = "result"
+	LabelAccount      = "account"
+	LabelSubjectHash  = "subject_hash"
+	LabelTenant       = "tenant"
+	LabelTokenType    = "token_type"
+	LabelSection      = "section"
+	LabelError        = "error"
+	LabelTemplate     = "template"
+	LabelContract     = "contract"
 
-// String trimming prefixes
-const (
-	StatusPrefixRegValue  = "REG_VALUE_"
-	StatusPrefixCompValue = "COMP_VALUE_"
-	ModePrefixRegValue    = "REG_VALUE_OPERATION_MODE_"
+	LabelLegionellaSchedule    = "legionella_schedule"
+	LabelEVUBlockSchedule      = "evu_block_schedule"
+	LabelHotWaterBlockSchedule = "hot_water_block_schedule"
 )
 
+// normalizedPrefixes are stripped from label values by NormalizeLabelValue.
+var normalizedPrefixes = []string{"STATUS_", "POWER_", "OPERATION_MODE_"}
+
 // OperationalStatusCandidates lists the register names to check for operational status bitmasks.
 var OperationalStatusCandidates = []string{
 	RegOperationalStatusPriorityBitmask,
@@ -83,6 +128,16 @@ var OperationalStatusCandidates = []string{
 	CompStatusItec,
 }
 
+// OperationModeCandidates lists the register names to check for the
+// operation mode, in order of preference.
+var OperationModeCandidates = []string{
+	RegOperationMode,
+	RegOperationModeAlt,
+	CompOperationMode,
+	CompOperationModeAtec,
+	CompOperationModeItec,
+}
+
 // PowerStatusCandidates lists the register names to check for power status bitmasks.
 var PowerStatusCandidates = []string{
 	CompPowerStatus,
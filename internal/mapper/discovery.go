@@ -0,0 +1,120 @@
+package mapper
+
+import (
+	"sort"
+
+	"thermia_exporter/internal/types"
+)
+
+// DiscoveryGroups lists the register groups probed when looking for
+// registers the exporter does not map yet. Besides the collected groups it
+// includes groups seen in the Thermia web UI; not every model exposes all of
+// them.
+var DiscoveryGroups = []string{
+	RegGroupTemperatures,
+	RegGroupOperationalStatus,
+	RegGroupOperationalTime,
+	RegGroupOperationalOperation,
+	RegGroupHotWater,
+	RegGroupHeatingCurve,
+	RegGroupEnergy,
+	"REG_GROUP_HEATING",
+	"REG_GROUP_COOLING",
+	"REG_GROUP_POOL",
+	"REG_GROUP_SYSTEM",
+}
+
+// mappedRegisters holds every register name some metric is derived from.
+var mappedRegisters = func() map[string]bool {
+	m := make(map[string]bool)
+	for name := range TemperatureRegisterKeys {
+		m[name] = true
+	}
+	for _, list := range [][]string{
+		OperationalStatusCandidates,
+		PowerStatusCandidates,
+		HeatOutputCandidates,
+		PowerConsumptionCandidates,
+		EnergyConsumedCandidates,
+		CompressorStartsCandidates,
+		PrioritySettingCandidates,
+		PriorityCurrentCandidates,
+		DischargeTempCandidates,
+		SuctionTempCandidates,
+		LiquidTempCandidates,
+		HighPressureCandidates,
+		LowPressureCandidates,
+		HeatingCurveCandidates,
+		HeatingCurveMinCandidates,
+		HeatingCurveMaxCandidates,
+		HeatingCurveOffsetCandidates,
+		RoomFactorCandidates,
+		SystemPressureCandidates,
+		OperationModeCandidates,
+		{RegHotWaterBoost, RegHotWaterStatus},
+		{RegOperTimeCompressor, RegOperTimeHeating, RegOperTimeHotWater, RegOperTimeImm1, RegOperTimeImm2, RegOperTimeImm3},
+	} {
+		for _, name := range list {
+			m[name] = true
+		}
+	}
+	return m
+}()
+
+// IsMapped reports whether a register is exported as (part of) a metric.
+func IsMapped(registerName string) bool {
+	return mappedRegisters[registerName] || circuitRegisterPattern.MatchString(registerName)
+}
+
+// MappedRegisters returns the sorted, de-duplicated names of registers in
+// items that some metric is derived from.
+func MappedRegisters(items []types.GroupItem) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, it := range items {
+		if seen[it.RegisterName] || !IsMapped(it.RegisterName) {
+			continue
+		}
+		seen[it.RegisterName] = true
+		names = append(names, it.RegisterName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MissingRegisters returns the sorted names of registers the exporter maps
+// that none of groups contains. Many are alternatives for other models, so
+// a missing register is not necessarily a problem.
+func MissingRegisters(groups map[string][]types.GroupItem) []string {
+	found := make(map[string]bool)
+	for _, items := range groups {
+		for _, it := range items {
+			found[it.RegisterName] = true
+		}
+	}
+
+	var names []string
+	for name := range mappedRegisters {
+		if !found[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnmappedRegisters returns the sorted, de-duplicated names of registers in
+// items that no metric is derived from.
+func UnmappedRegisters(items []types.GroupItem) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, it := range items {
+		if it.RegisterName == "" || seen[it.RegisterName] || IsMapped(it.RegisterName) {
+			continue
+		}
+		seen[it.RegisterName] = true
+		names = append(names, it.RegisterName)
+	}
+	sort.Strings(names)
+	return names
+}
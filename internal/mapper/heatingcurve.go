@@ -0,0 +1,40 @@
+package mapper
+
+import "thermia_exporter/internal/types"
+
+// Heating curve register candidates (REG_GROUP_HEATING_CURVE), checked in
+// order. The offset is the parallel shift set with the comfort wheel; the
+// room factor is how strongly the indoor sensor corrects the curve.
+var (
+	HeatingCurveCandidates       = []string{"REG_HEATING_HEAT_CURVE", "REG_HEAT_CURVE"}
+	HeatingCurveMinCandidates    = []string{"REG_HEATING_HEAT_CURVE_MIN"}
+	HeatingCurveMaxCandidates    = []string{"REG_HEATING_HEAT_CURVE_MAX"}
+	HeatingCurveOffsetCandidates = []string{"REG_HEATING_HEAT_CURVE_OFFSET", "REG_HEATING_CURVE_OFFSET", "REG_HEATING_ROOM_TEMP_OFFSET"}
+	RoomFactorCandidates         = []string{"REG_HEATING_ROOM_FACTOR", "REG_ROOM_FACTOR", "REG_ROOM_SENSOR_INFLUENCE"}
+)
+
+// HeatingCurve holds the heating curve settings. Fields are nil when the
+// heat pump does not expose them.
+type HeatingCurve struct {
+	// Curve is the selected heating curve (slope).
+	Curve *float64
+	// Min and Max bound the supply line temperature the curve asks for (°C).
+	Min *float64
+	Max *float64
+	// Offset is the comfort wheel shift of the curve (°C).
+	Offset *float64
+	// RoomFactor is the influence of the room sensor on the supply line
+	// temperature (0: the sensor is ignored).
+	RoomFactor *float64
+}
+
+// ExtractHeatingCurve returns the heating curve settings from items.
+func ExtractHeatingCurve(items []types.GroupItem) HeatingCurve {
+	return HeatingCurve{
+		Curve:      findFirst(items, HeatingCurveCandidates),
+		Min:        findFirst(items, HeatingCurveMinCandidates),
+		Max:        findFirst(items, HeatingCurveMaxCandidates),
+		Offset:     findFirst(items, HeatingCurveOffsetCandidates),
+		RoomFactor: findFirst(items, RoomFactorCandidates),
+	}
+}
@@ -1,7 +1,10 @@
 package mapper
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"thermia_exporter/internal/types"
 )
@@ -40,6 +43,93 @@ func TestExtractTemperatures(t *testing.T) {
 	}
 }
 
+func TestExtractTemperatures_NilStatus(t *testing.T) {
+	grp := []types.GroupItem{
+		{
+			RegisterName:  RegIndoorTemperature,
+			RegisterValue: ptr(21.0),
+		},
+	}
+
+	temps := ExtractTemperatures(nil, grp)
+
+	if temps.Indoor == nil || *temps.Indoor != 21.0 {
+		t.Errorf("Indoor = %v, want 21.0 from register fallback", temps.Indoor)
+	}
+	if temps.HotWater != nil {
+		t.Errorf("HotWater = %v, want nil", temps.HotWater)
+	}
+}
+
+func TestTemperatureSources(t *testing.T) {
+	if n := reflect.TypeOf(types.TemperatureData{}).NumField(); len(TemperatureSources) != n {
+		t.Fatalf("TemperatureSources has %d sensors, TemperatureData %d fields", len(TemperatureSources), n)
+	}
+	keys := make(map[string]bool)
+	for _, src := range TemperatureSources {
+		if keys[src.Key] {
+			t.Errorf("sensor %s listed twice", src.Key)
+		}
+		keys[src.Key] = true
+		if src.Status == nil && len(src.Registers) == 0 {
+			t.Errorf("sensor %s has no source", src.Key)
+		}
+	}
+
+	for _, src := range TemperatureSources {
+		t.Run(src.Key, func(t *testing.T) {
+			// Every candidate alone yields the sensor
+			for _, name := range src.Registers {
+				got := TemperaturesToMap(ExtractTemperatures(nil, []types.GroupItem{{RegisterName: name, RegisterValue: ptr(12.5)}}))
+				if len(got) != 1 || got[src.Key] != 12.5 {
+					t.Errorf("%s alone = %v, want %s=12.5", name, got, src.Key)
+				}
+				if key := TemperatureRegisterKeys[name]; key != src.Key {
+					t.Errorf("TemperatureRegisterKeys[%s] = %q, want %q", name, key, src.Key)
+				}
+			}
+
+			// Earlier candidates win, in reverse order in the group too
+			var grp []types.GroupItem
+			for i := len(src.Registers) - 1; i >= 0; i-- {
+				grp = append(grp, types.GroupItem{RegisterName: src.Registers[i], RegisterValue: ptr(float64(i))})
+			}
+			if len(grp) > 0 {
+				if got := TemperaturesToMap(ExtractTemperatures(nil, grp))[src.Key]; got != 0 {
+					t.Errorf("with all candidates %s = %v, want %s's 0", src.Key, got, src.Registers[0])
+				}
+			}
+
+			// The status wins over registers
+			if src.Status != nil {
+				var status types.InstallationStatus
+				setStatusField(t, &status, src, 42)
+				got := TemperaturesToMap(ExtractTemperatures(&status, grp))
+				if got[src.Key] != 42 {
+					t.Errorf("with status %s = %v, want 42", src.Key, got[src.Key])
+				}
+			}
+		})
+	}
+}
+
+// setStatusField sets the status field src reads to v.
+func setStatusField(t *testing.T, status *types.InstallationStatus, src TemperatureSource, v float64) {
+	t.Helper()
+	fields := reflect.ValueOf(status).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		f := fields.Field(i)
+		f.Set(reflect.ValueOf(ptr(v)))
+		hit := src.Status(status) != nil
+		f.Set(reflect.Zero(f.Type()))
+		if hit {
+			f.Set(reflect.ValueOf(ptr(v)))
+			return
+		}
+	}
+	t.Fatalf("sensor %s reads no status field", src.Key)
+}
+
 func TestTemperaturesToMap(t *testing.T) {
 	temps := types.TemperatureData{
 		Indoor:     ptr(22.5),
@@ -87,7 +177,7 @@ func TestExtractOperationMode(t *testing.T) {
 		},
 	}
 
-	modeData := ExtractOperationMode(items)
+	modeData := ExtractOperationMode(items, OperationModeCandidates)
 
 	if modeData.Current != "AUTO" {
 		t.Errorf("Current = %v, want AUTO", modeData.Current)
@@ -100,6 +190,42 @@ func TestExtractOperationMode(t *testing.T) {
 	}
 }
 
+func TestExtractOperationMode_Fallback(t *testing.T) {
+	items := []types.GroupItem{
+		{
+			RegisterName:  CompOperationModeAtec,
+			RegisterValue: ptr(1),
+			IsReadOnly:    true,
+			ValueNames: []types.ValueEntry{
+				{Name: "COMP_VALUE_OPERATION_MODE_AUTO", Value: 0, Visible: true},
+				{Name: "COMP_VALUE_OPERATION_MODE_HEAT", Value: 1, Visible: true},
+			},
+		},
+	}
+
+	modeData := ExtractOperationMode(items, OperationModeCandidates)
+
+	if modeData.Register != CompOperationModeAtec || modeData.Current != "HEAT" {
+		t.Errorf("ExtractOperationMode() = %+v, want HEAT from %s", modeData, CompOperationModeAtec)
+	}
+	if len(modeData.Available) != 2 || modeData.Available[0] != "AUTO" {
+		t.Errorf("Available = %v, want [AUTO HEAT]", modeData.Available)
+	}
+	if got := OperationModeRegister(items); got != CompOperationModeAtec {
+		t.Errorf("OperationModeRegister() = %q, want %q", got, CompOperationModeAtec)
+	}
+	if got := OperationModeRegister(nil); got != RegOperationMode {
+		t.Errorf("OperationModeRegister(nil) = %q, want %q", got, RegOperationMode)
+	}
+
+	// REG_OPERATIONMODE wins when a firmware reports both
+	items = append(items, types.GroupItem{RegisterName: RegOperationMode, RegisterValue: ptr(0),
+		ValueNames: []types.ValueEntry{{Name: "REG_VALUE_OPERATION_MODE_AUTO", Value: 0, Visible: true}}})
+	if modeData := ExtractOperationMode(items, OperationModeCandidates); modeData.Register != RegOperationMode || modeData.Current != "AUTO" {
+		t.Errorf("ExtractOperationMode() = %+v, want AUTO from %s", modeData, RegOperationMode)
+	}
+}
+
 func TestExtractBitmaskStatuses(t *testing.T) {
 	items := []types.GroupItem{
 		{
@@ -124,7 +250,7 @@ func TestExtractBitmaskStatuses(t *testing.T) {
 	}
 
 	// Check that STATUS_A and STATUS_C are running (bits 0 and 2)
-	found := make(map[string]bool)
+	found := make(map[types.OperationalStatus]bool)
 	for _, s := range statusData.Running {
 		found[s] = true
 	}
@@ -183,6 +309,302 @@ func TestExtractOperationalTime(t *testing.T) {
 	}
 }
 
+func TestExtractCircuits(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: "REG_MIX_VALVE_2_SUPPLY_LINE_TEMP", RegisterValue: ptr(31.5)},
+		{RegisterName: "REG_MIX_VALVE_1_SUPPLY_LINE_TEMP", RegisterValue: ptr(28.0)},
+		{RegisterName: "REG_MIX_VALVE_1_POSITION", RegisterValue: ptr(45)},
+		{RegisterName: "REG_MIX_VALVE_3_POSITION", RegisterValue: nil},
+		{RegisterName: RegSupplyLine, RegisterValue: ptr(35)},
+	}
+
+	circuits := ExtractCircuits(items)
+
+	if len(circuits) != 2 {
+		t.Fatalf("circuits = %d, want 2", len(circuits))
+	}
+	if circuits[0].Circuit != "1" || circuits[1].Circuit != "2" {
+		t.Errorf("circuit order = %s,%s, want 1,2", circuits[0].Circuit, circuits[1].Circuit)
+	}
+	if circuits[0].SupplyTemperature == nil || *circuits[0].SupplyTemperature != 28.0 {
+		t.Errorf("circuit 1 supply = %v, want 28.0", circuits[0].SupplyTemperature)
+	}
+	if circuits[0].ValvePosition == nil || *circuits[0].ValvePosition != 45 {
+		t.Errorf("circuit 1 valve = %v, want 45", circuits[0].ValvePosition)
+	}
+	if circuits[1].ValvePosition != nil {
+		t.Errorf("circuit 2 valve = %v, want nil", circuits[1].ValvePosition)
+	}
+}
+
+func TestExtractCircuits_None(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegSupplyLine, RegisterValue: ptr(35)},
+	}
+
+	if circuits := ExtractCircuits(items); len(circuits) != 0 {
+		t.Errorf("circuits = %d, want 0", len(circuits))
+	}
+}
+
+func TestExtractPowerWatts(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegOperDataHeatingPower, RegisterValue: ptr(4.2), Unit: "kW"},
+	}
+
+	got := ExtractPowerWatts(items, HeatOutputCandidates)
+	if got == nil || *got != 4200 {
+		t.Errorf("ExtractPowerWatts() = %v, want 4200", got)
+	}
+
+	items = append(items, types.GroupItem{RegisterName: RegHeatOutputPower, RegisterValue: ptr(3900), Unit: "W"})
+	got = ExtractPowerWatts(items, HeatOutputCandidates)
+	if got == nil || *got != 3900 {
+		t.Errorf("ExtractPowerWatts() = %v, want 3900 from the first candidate", got)
+	}
+
+	if got := ExtractPowerWatts(nil, HeatOutputCandidates); got != nil {
+		t.Errorf("ExtractPowerWatts(nil) = %v, want nil", *got)
+	}
+}
+
+func TestExtractEnergyKWh(t *testing.T) {
+	tests := []struct {
+		unit string
+		want float64
+	}{
+		{"kWh", 1834},
+		{"", 1834},
+		{"MWh", 1834000},
+		{"Wh", 1.834},
+	}
+	for _, tt := range tests {
+		items := []types.GroupItem{{RegisterName: RegEnergyConsumption, RegisterValue: ptr(1834), Unit: tt.unit}}
+		got := ExtractEnergyKWh(items, EnergyConsumedCandidates)
+		if got == nil || *got != tt.want {
+			t.Errorf("ExtractEnergyKWh(unit %q) = %v, want %v", tt.unit, got, tt.want)
+		}
+	}
+
+	if got := ExtractEnergyKWh(nil, EnergyConsumedCandidates); got != nil {
+		t.Errorf("ExtractEnergyKWh(nil) = %v, want nil", *got)
+	}
+}
+
+func TestUnmappedRegisters(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegSupplyLine, RegisterValue: ptr(35)},
+		{RegisterName: "REG_MIX_VALVE_1_POSITION", RegisterValue: ptr(45)},
+		{RegisterName: "REG_HEATING_CURVE_MAX", RegisterValue: ptr(55)},
+		{RegisterName: "REG_COMPRESSOR_SPEED", RegisterValue: ptr(60)},
+		{RegisterName: "REG_HEATING_CURVE_MAX", RegisterValue: ptr(55)},
+		{RegisterName: CompPowerStatus, RegisterValue: ptr(1)},
+	}
+
+	got := UnmappedRegisters(items)
+	want := []string{"REG_COMPRESSOR_SPEED", "REG_HEATING_CURVE_MAX"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("UnmappedRegisters() = %v, want %v", got, want)
+	}
+}
+
+func TestMappedAndMissingRegisters(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegSupplyLine, RegisterValue: ptr(35)},
+		{RegisterName: "REG_COMPRESSOR_SPEED", RegisterValue: ptr(60)},
+		{RegisterName: RegSupplyLine, RegisterValue: ptr(35)},
+		{RegisterName: RegOperationMode, RegisterValue: ptr(3)},
+	}
+
+	got := MappedRegisters(items)
+	want := []string{RegOperationMode, RegSupplyLine}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("MappedRegisters() = %v, want %v", got, want)
+	}
+
+	missing := MissingRegisters(map[string][]types.GroupItem{RegGroupTemperatures: items})
+	for _, name := range missing {
+		if name == RegSupplyLine || name == RegOperationMode {
+			t.Errorf("MissingRegisters() contains found register %s", name)
+		}
+	}
+	if len(missing) != len(mappedRegisters)-2 {
+		t.Errorf("MissingRegisters() has %d entries, want %d", len(missing), len(mappedRegisters)-2)
+	}
+}
+
+func TestLatestRegisterTime(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: "REG_A", Timestamp: "2026-10-16T08:55:00Z"},
+		{RegisterName: "REG_B", Timestamp: "2026-10-16T08:58:30.000Z"},
+		{RegisterName: "REG_C", Timestamp: "not a time"},
+		{RegisterName: "REG_D"},
+	}
+	if got, want := LatestRegisterTime(items), time.Date(2026, 10, 16, 8, 58, 30, 0, time.UTC).Unix(); got != want {
+		t.Errorf("LatestRegisterTime() = %d, want %d", got, want)
+	}
+	if got := LatestRegisterTime(items[2:]); got != 0 {
+		t.Errorf("LatestRegisterTime(no timestamps) = %d, want 0", got)
+	}
+}
+
+func TestExtractRunHours(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegOperTimeCompressor, RegisterValue: ptr(1200)},
+		{RegisterName: RegOperTimeImm1, RegisterValue: ptr(40)},
+		{RegisterName: RegOperTimeImm2, RegisterValue: ptr(5)},
+	}
+	aux, compressor, ok := ExtractRunHours(items)
+	if !ok || aux != 45 || compressor != 1200 {
+		t.Errorf("ExtractRunHours() = %v, %v, %v, want 45, 1200, true", aux, compressor, ok)
+	}
+
+	if _, _, ok := ExtractRunHours(items[:1]); ok {
+		t.Error("expected ok = false without auxiliary heater counters")
+	}
+}
+
+func TestNormalizeLabelValue(t *testing.T) {
+	tests := map[string]string{
+		"STATUS_HOTWATER":      "hotwater",
+		"POWER_IMM_HEATER_3KW": "imm_heater_3kw",
+		"OPERATION_MODE_AUTO":  "auto",
+		"HOT_WATER_ONLY":       "hot_water_only",
+		"STATUS_":              "status_",
+	}
+	for in, want := range tests {
+		if got := NormalizeLabelValue(in); got != want {
+			t.Errorf("NormalizeLabelValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtractEnumValue(t *testing.T) {
+	items := []types.GroupItem{
+		{
+			RegisterName:  "REG_HOT_WATER_PRIORITY",
+			RegisterValue: ptr(1),
+			ValueNames: []types.ValueEntry{
+				{Name: "REG_VALUE_HEATING_FIRST", Value: 0, Visible: true},
+				{Name: "REG_VALUE_HOT_WATER_FIRST", Value: 1, Visible: true},
+			},
+		},
+		{RegisterName: "REG_OPER_DATA_PRIORITY", RegisterValue: ptr(2)},
+	}
+
+	if got, ok := ExtractEnumValue(items, PrioritySettingCandidates); !ok || got != "HOT_WATER_FIRST" {
+		t.Errorf("priority setting = %q, %v, want HOT_WATER_FIRST", got, ok)
+	}
+	if got, ok := ExtractEnumValue(items, PriorityCurrentCandidates); !ok || got != "2" {
+		t.Errorf("current priority = %q, %v, want 2 (unnamed value)", got, ok)
+	}
+	if _, ok := ExtractEnumValue(items, []string{"REG_MISSING"}); ok {
+		t.Error("missing register should not be found")
+	}
+}
+
+func TestMergeGroups_Precedence(t *testing.T) {
+	groups := map[string][]types.GroupItem{
+		"REG_GROUP_ZZZ": {
+			{RegisterName: "REG_EXTRA", RegisterValue: ptr(7)},
+		},
+		RegGroupOperationalStatus: {
+			{RegisterName: RegHeatOutputPower, RegisterValue: ptr(4.2)},
+			{RegisterName: RegSupplyLine, RegisterValue: ptr(38.0)},
+		},
+		RegGroupTemperatures: {
+			{RegisterName: RegSupplyLine, RegisterValue: ptr(35.1)},
+			{RegisterName: RegBrineIn, RegisterValue: ptr(4.0)},
+		},
+		RegGroupHotWater: {
+			{RegisterName: RegBrineIn, RegisterValue: ptr(4.3)},
+		},
+	}
+
+	items, conflicts := MergeGroups(groups, ConflictTolerance)
+
+	want := map[string]float64{
+		RegSupplyLine:      35.1, // temperatures beat operational status
+		RegBrineIn:         4.0,
+		RegHeatOutputPower: 4.2,
+		"REG_EXTRA":        7,
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %d, want %d", len(items), len(want))
+	}
+	for name, v := range want {
+		got := FindValue(items, name)
+		if got == nil || *got != v {
+			t.Errorf("%s = %v, want %v", name, got, v)
+		}
+	}
+
+	// Brine in differs by 0.3 (within tolerance); supply line by 2.9
+	if len(conflicts) != 1 {
+		t.Fatalf("conflicts = %+v, want 1", conflicts)
+	}
+	c := conflicts[0]
+	if c.RegisterName != RegSupplyLine || c.Group != RegGroupTemperatures || c.OtherGroup != RegGroupOperationalStatus {
+		t.Errorf("conflict = %+v", c)
+	}
+}
+
+func TestCompressorRunning(t *testing.T) {
+	power := types.GroupItem{
+		RegisterName:  CompPowerStatus,
+		RegisterValue: ptr(1),
+		ValueNames: []types.ValueEntry{
+			{Name: "COMP_VALUE_POWER_COMPRESSOR", Value: 1, Visible: true},
+			{Name: "COMP_VALUE_POWER_IMM_HEATER_3KW", Value: 2, Visible: true},
+		},
+	}
+	if running, known := CompressorRunning([]types.GroupItem{power}); !running || !known {
+		t.Errorf("power status compressor bit: running=%v known=%v, want true/true", running, known)
+	}
+
+	power.RegisterValue = ptr(2)
+	if running, known := CompressorRunning([]types.GroupItem{power}); running || !known {
+		t.Errorf("immersion heater only: running=%v known=%v, want false/true", running, known)
+	}
+
+	status := types.GroupItem{
+		RegisterName:  CompStatusItec,
+		RegisterValue: ptr(2),
+		ValueNames: []types.ValueEntry{
+			{Name: "COMP_VALUE_STATUS_DEFROST", Value: 1, Visible: true},
+			{Name: "COMP_VALUE_STATUS_HEAT", Value: 2, Visible: true},
+		},
+	}
+	if running, known := CompressorRunning([]types.GroupItem{status}); !running || !known {
+		t.Errorf("heating status: running=%v known=%v, want true/true", running, known)
+	}
+
+	if _, known := CompressorRunning(nil); known {
+		t.Error("no status registers should be unknown")
+	}
+}
+
+func TestEventsSince(t *testing.T) {
+	active := true
+	events := []types.Event{
+		{EventTitle: "old", OccurredWhen: "2019-01-01T00:00:00Z"},
+		{EventTitle: "old but active", OccurredWhen: "2019-01-01T00:00:00Z", IsActive: &active},
+		{EventTitle: "recent", OccurredWhen: "2026-09-30T12:00:00Z"},
+		{EventTitle: "unparseable", OccurredWhen: "yesterday"},
+	}
+
+	got := EventsSince(events, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	var titles []string
+	for _, e := range got {
+		titles = append(titles, e.EventTitle)
+	}
+	want := []string{"old but active", "recent", "unparseable"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Errorf("EventsSince() = %v, want %v", titles, want)
+	}
+}
+
 func TestParseTimeToUnix(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -223,3 +645,164 @@ func TestSafe(t *testing.T) {
 		}
 	}
 }
+
+func TestEstimateRefrigerant(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: "REG_SUCTION_GAS_TEMPERATURE", RegisterValue: ptr(5.0)},
+		{RegisterName: "REG_HOT_GAS_TEMPERATURE", RegisterValue: ptr(70.0)},
+		{RegisterName: "REG_LIQUID_LINE_TEMPERATURE", RegisterValue: ptr(35.0)},
+		{RegisterName: "REG_LOW_PRESSURE", RegisterValue: ptr(7.99 - atmosphericBar)},
+		{RegisterName: "REG_HIGH_PRESSURE", RegisterValue: ptr(24.19 - atmosphericBar)},
+	}
+	r, err := ParseRefrigerant("r410a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	est := EstimateRefrigerant(items, r)
+	for name, tc := range map[string]struct {
+		got  *float64
+		want float64
+	}{
+		"superheat":           {est.Superheat, 5},
+		"discharge superheat": {est.DischargeSuperheat, 30},
+		"subcooling":          {est.Subcooling, 5},
+	} {
+		if tc.got == nil || *tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+
+	// Without a refrigerant, or without pressures, nothing is estimated
+	if est := EstimateRefrigerant(items, ""); est.Superheat != nil {
+		t.Errorf("estimate without refrigerant: %+v", est)
+	}
+	if est := EstimateRefrigerant(items[:3], r); est.Superheat != nil || est.Subcooling != nil {
+		t.Errorf("estimate without pressures: %+v", est)
+	}
+	if _, err := ParseRefrigerant("R22"); err == nil {
+		t.Error("ParseRefrigerant(R22) should fail")
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases([]byte(`# Swedish firmware
+REG_UTOMHUSTEMPERATUR: REG_OUTDOOR_TEMPERATURE  # outdoor
+
+"REG_INNETEMPERATUR": 'REG_INDOOR_TEMPERATURE'
+`))
+	if err != nil {
+		t.Fatalf("ParseAliases() error = %v", err)
+	}
+	want := Aliases{
+		"REG_UTOMHUSTEMPERATUR": RegOutdoorTemperature,
+		"REG_INNETEMPERATUR":    RegIndoorTemperature,
+	}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("ParseAliases() = %v, want %v", aliases, want)
+	}
+
+	items := []types.GroupItem{{RegisterName: "REG_INNETEMPERATUR"}, {RegisterName: RegSupplyLine}}
+	renamed := aliases.Rename(items)
+	if renamed[0].RegisterName != RegIndoorTemperature || renamed[1].RegisterName != RegSupplyLine {
+		t.Errorf("Rename() = %+v", renamed)
+	}
+	if items[0].RegisterName != "REG_INNETEMPERATUR" {
+		t.Error("Rename() modified its input")
+	}
+
+	for _, bad := range []string{
+		"aliases:\n  REG_A: REG_B\n",
+		"- REG_A\n",
+		"REG_A\n",
+		"REG_A:\n",
+		"REG_A: REG_A\n",
+		"REG_A: REG_B\nREG_A: REG_C\n",
+	} {
+		if _, err := ParseAliases([]byte(bad)); err == nil {
+			t.Errorf("ParseAliases(%q) should fail", bad)
+		}
+	}
+}
+
+func TestExtractSystemPressure(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []types.GroupItem
+		want  *float64
+	}{
+		{"bar", []types.GroupItem{{RegisterName: "REG_SYSTEM_PRESSURE", RegisterValue: ptr(1.4), Unit: "bar"}}, ptr(1.4)},
+		{"kPa", []types.GroupItem{{RegisterName: "REG_HEATING_SYSTEM_PRESSURE", RegisterValue: ptr(150), Unit: "kPa"}}, ptr(1.5)},
+		{"leak", []types.GroupItem{{RegisterName: "REG_SYSTEM_PRESSURE", RegisterValue: ptr(0)}}, ptr(0)},
+		{"no sensor connected", []types.GroupItem{{RegisterName: "REG_SYSTEM_PRESSURE", RegisterValue: ptr(-1)}}, nil},
+		{"no register", []types.GroupItem{{RegisterName: "REG_OUTDOOR_TEMPERATURE", RegisterValue: ptr(3)}}, nil},
+	}
+	for _, tt := range tests {
+		got := ExtractSystemPressure(tt.items)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("%s: ExtractSystemPressure() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Unit
+		wantOK bool
+	}{
+		{"°C", Unit{"_celsius", 1}, true},
+		{" %", Unit{"_percent", 1}, true},
+		{"kWh", Unit{"_kwh", 1}, true},
+		{"kW", Unit{"_watts", 1000}, true},
+		{"min", Unit{"_seconds", 60}, true},
+		{"0.1°C", Unit{"_celsius", 0.1}, true},
+		{"", Unit{}, false},
+		{"furlongs", Unit{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseUnit(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseUnit(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRegisterUnit(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantUnit  string
+		wantScale float64
+	}{
+		{"°C", "celsius", 1},
+		{"0.1°C", "celsius", 0.1},
+		{"kW", "watts", 1000},
+		{"Wh", "kwh", 0.001},
+		{" furlongs ", "furlongs", 1},
+		{"", "", 1},
+	}
+	for _, tt := range tests {
+		unit, scale := RegisterUnit(tt.in)
+		if unit != tt.wantUnit || scale != tt.wantScale {
+			t.Errorf("RegisterUnit(%q) = %q, %v, want %q, %v", tt.in, unit, scale, tt.wantUnit, tt.wantScale)
+		}
+	}
+}
+
+func TestUnitMismatches(t *testing.T) {
+	items := []types.GroupItem{
+		{RegisterName: RegOutdoorTemperature, Unit: "°C"},
+		{RegisterName: RegSupplyLine, Unit: "0.1°C"},
+		{RegisterName: RegReturnLine, Unit: ""},
+		{RegisterName: RegOperTimeCompressor, Unit: "min"},
+		{RegisterName: RegOperTimeHeating, Unit: "h"},
+		{RegisterName: "REG_UNMAPPED", Unit: "bogus"},
+	}
+	var got []string
+	for _, it := range UnitMismatches(items) {
+		got = append(got, it.RegisterName)
+	}
+	want := []string{RegSupplyLine, RegOperTimeCompressor}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnitMismatches() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package mapper
+
+import (
+	"math"
+	"sort"
+
+	"thermia_exporter/internal/types"
+)
+
+// ConflictTolerance is the largest difference between two values of the same
+// register that is not reported as a conflict. Groups are read a few seconds
+// apart, so slowly moving values legitimately differ slightly.
+const ConflictTolerance = 0.5
+
+// GroupPrecedence decides which group's value wins when a register appears
+// in several register groups. A register's dedicated group comes first;
+// groups not listed here follow in name order.
+var GroupPrecedence = []string{
+	RegGroupTemperatures,
+	RegGroupOperationalOperation,
+	RegGroupOperationalStatus,
+	RegGroupHotWater,
+	RegGroupOperationalTime,
+	RegGroupHeatingCurve,
+	RegGroupEnergy,
+}
+
+// RegisterConflict describes a register whose values in two groups disagree
+// by more than the tolerance. Group holds the value that was kept.
+type RegisterConflict struct {
+	RegisterName string
+	Group        string
+	Value        float64
+	OtherGroup   string
+	OtherValue   float64
+}
+
+// MergeGroups flattens register groups into one item per register name,
+// keeping the item from the group with the highest precedence (see
+// GroupPrecedence). Within a group the first item wins. Duplicates whose
+// values differ by more than tolerance are returned as conflicts.
+func MergeGroups(groups map[string][]types.GroupItem, tolerance float64) ([]types.GroupItem, []RegisterConflict) {
+	var items []types.GroupItem
+	var conflicts []RegisterConflict
+	index := make(map[string]int)
+	source := make(map[string]string)
+
+	for _, group := range groupOrder(groups) {
+		for _, it := range groups[group] {
+			i, dup := index[it.RegisterName]
+			if !dup {
+				index[it.RegisterName] = len(items)
+				source[it.RegisterName] = group
+				items = append(items, it)
+				continue
+			}
+
+			kept := items[i]
+			if kept.RegisterValue != nil && it.RegisterValue != nil &&
+				math.Abs(*kept.RegisterValue-*it.RegisterValue) > tolerance {
+				conflicts = append(conflicts, RegisterConflict{
+					RegisterName: it.RegisterName,
+					Group:        source[it.RegisterName],
+					Value:        *kept.RegisterValue,
+					OtherGroup:   group,
+					OtherValue:   *it.RegisterValue,
+				})
+			}
+		}
+	}
+
+	return items, conflicts
+}
+
+// groupOrder returns the names of groups ordered by GroupPrecedence.
+func groupOrder(groups map[string][]types.GroupItem) []string {
+	order := make([]string, 0, len(groups))
+	listed := make(map[string]bool, len(GroupPrecedence))
+	for _, g := range GroupPrecedence {
+		listed[g] = true
+		if _, ok := groups[g]; ok {
+			order = append(order, g)
+		}
+	}
+
+	var rest []string
+	for g := range groups {
+		if !listed[g] {
+			rest = append(rest, g)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
@@ -1,44 +1,55 @@
 package mapper
 
-import (
-	"strings"
+import "thermia_exporter/internal/types"
 
-	"thermia_exporter/internal/types"
-)
-
-// ExtractOperationMode extracts the current and available operation modes from register items.
-func ExtractOperationMode(items []types.GroupItem) types.OperationModeData {
+// ExtractOperationMode extracts the current and available operation modes
+// from the first of registerNames present in items.
+func ExtractOperationMode(items []types.GroupItem, registerNames []string) types.OperationModeData {
 	var result types.OperationModeData
 
-	for _, it := range items {
-		if it.RegisterName == RegOperationMode {
-			result.Available = make([]string, 0, len(it.ValueNames))
-			for _, vn := range it.ValueNames {
-				if vn.Visible {
-					result.Available = append(result.Available, trimMode(vn.Name))
-				}
-			}
-			result.ReadOnly = it.IsReadOnly
+	it := findOperationMode(items, registerNames)
+	if it == nil {
+		return result
+	}
+	result.Register = it.RegisterName
+	result.Available = make([]types.OperationMode, 0, len(it.ValueNames))
+	for _, vn := range it.ValueNames {
+		if vn.Visible {
+			result.Available = append(result.Available, types.ParseOperationMode(vn.Name))
+		}
+	}
+	result.ReadOnly = it.IsReadOnly
 
-			if it.RegisterValue != nil {
-				val := int(*it.RegisterValue + 0.00001)
-				for _, vn := range it.ValueNames {
-					if vn.Value == val {
-						result.Current = trimMode(vn.Name)
-						break
-					}
-				}
+	if it.RegisterValue != nil {
+		val := int(*it.RegisterValue + 0.00001)
+		for _, vn := range it.ValueNames {
+			if vn.Value == val {
+				result.Current = types.ParseOperationMode(vn.Name)
+				break
 			}
-			break
 		}
 	}
 
 	return result
 }
 
-// trimMode removes common prefixes from operation mode names.
-func trimMode(s string) string {
-	s = strings.TrimPrefix(s, ModePrefixRegValue)
-	s = strings.TrimPrefix(s, "REG_VALUE_")
-	return s
+// OperationModeRegister returns the name of the register items report the
+// operation mode in, or RegOperationMode if none of the candidates is present.
+func OperationModeRegister(items []types.GroupItem) string {
+	if it := findOperationMode(items, OperationModeCandidates); it != nil {
+		return it.RegisterName
+	}
+	return RegOperationMode
+}
+
+// findOperationMode returns the first of registerNames present in items.
+func findOperationMode(items []types.GroupItem, registerNames []string) *types.GroupItem {
+	for _, rn := range registerNames {
+		for i := range items {
+			if items[i].RegisterName == rn {
+				return &items[i]
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,68 @@
+package mapper
+
+import (
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// HeatOutputCandidates lists registers reporting the current heat output,
+// checked in order.
+var HeatOutputCandidates = []string{
+	RegHeatOutputPower,
+	RegOperDataHeatingPower,
+}
+
+// PowerConsumptionCandidates lists registers reporting the heat pump's
+// instantaneous electrical power draw, checked in order.
+var PowerConsumptionCandidates = []string{
+	RegPowerConsumption,
+	RegOperDataPowerConsumption,
+	RegCompressorPower,
+}
+
+// EnergyConsumedCandidates lists registers reporting the heat pump's
+// cumulative electrical energy consumption, checked in order.
+var EnergyConsumedCandidates = []string{
+	RegEnergyConsumption,
+	RegOperDataEnergyConsumption,
+	RegAccumulatedEnergy,
+}
+
+// ExtractPowerWatts returns the value of the first matching register converted
+// to watts based on its unit ("kW" is scaled, anything else is taken as W).
+// Returns nil if none of the registers is present.
+func ExtractPowerWatts(items []types.GroupItem, registerNames []string) *float64 {
+	for _, rn := range registerNames {
+		for _, it := range items {
+			if it.RegisterName != rn || it.RegisterValue == nil {
+				continue
+			}
+			watts := *it.RegisterValue
+			if strings.EqualFold(strings.TrimSpace(it.Unit), "kW") {
+				watts *= 1000
+			}
+			return &watts
+		}
+	}
+	return nil
+}
+
+// ExtractEnergyKWh returns the value of the first matching register converted
+// to kWh based on its unit ("Wh" and "MWh" are scaled; an empty or unknown
+// unit is taken as kWh). Returns nil if none of the registers is present.
+func ExtractEnergyKWh(items []types.GroupItem, registerNames []string) *float64 {
+	for _, rn := range registerNames {
+		for _, it := range items {
+			if it.RegisterName != rn || it.RegisterValue == nil {
+				continue
+			}
+			kwh := *it.RegisterValue
+			if u, ok := ParseUnit(it.Unit); ok && u.Suffix == "_kwh" {
+				kwh *= u.Scale
+			}
+			return &kwh
+		}
+	}
+	return nil
+}
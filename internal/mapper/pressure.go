@@ -0,0 +1,34 @@
+package mapper
+
+import (
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// SystemPressureCandidates are the registers of the optional hydronic
+// system pressure sensor, checked in order.
+var SystemPressureCandidates = []string{"REG_SYSTEM_PRESSURE", "REG_HEATING_SYSTEM_PRESSURE", "REG_OPER_DATA_SYSTEM_PRESSURE"}
+
+// ExtractSystemPressure returns the heating system pressure in bar, or nil
+// if no pressure sensor is installed. Readings in kPa are converted;
+// negative readings are what an unconnected sensor input reports and are
+// treated as no sensor.
+func ExtractSystemPressure(items []types.GroupItem) *float64 {
+	for _, name := range SystemPressureCandidates {
+		for _, it := range items {
+			if it.RegisterName != name || it.RegisterValue == nil {
+				continue
+			}
+			bar := *it.RegisterValue
+			if strings.EqualFold(strings.TrimSpace(it.Unit), "kPa") {
+				bar /= 100
+			}
+			if bar < 0 {
+				return nil
+			}
+			return &bar
+		}
+	}
+	return nil
+}
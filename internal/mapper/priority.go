@@ -0,0 +1,55 @@
+package mapper
+
+import (
+	"strconv"
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// PrioritySettingCandidates lists registers holding the configured priority
+// between hot water and heating, checked in order.
+var PrioritySettingCandidates = []string{
+	"REG_HOT_WATER_PRIORITY",
+	"REG_PRIORITY_HOT_WATER",
+	"REG_OPER_PRIORITY",
+}
+
+// PriorityCurrentCandidates lists registers holding the pump's current
+// priority decision, checked in order.
+var PriorityCurrentCandidates = []string{
+	"REG_CURRENT_PRIORITY",
+	"REG_OPER_DATA_PRIORITY",
+	"REG_PRIORITY_DECISION",
+}
+
+// ExtractEnumValue returns the value of the first register in candidates
+// that has one, named after its value list entry (prefix trimmed), or the
+// number itself if the value has no name.
+func ExtractEnumValue(items []types.GroupItem, candidates []string) (string, bool) {
+	for _, rn := range candidates {
+		for _, it := range items {
+			if it.RegisterName != rn || it.RegisterValue == nil {
+				continue
+			}
+			val := int(*it.RegisterValue + 0.00001)
+			for _, vn := range it.ValueNames {
+				if vn.Value == val {
+					return trimValueName(vn.Name), true
+				}
+			}
+			return strconv.Itoa(val), true
+		}
+	}
+	return "", false
+}
+
+// trimValueName removes the REG_VALUE_ or COMP_VALUE_ prefix of a value name.
+func trimValueName(s string) string {
+	for _, p := range []string{"REG_VALUE_", "COMP_VALUE_"} {
+		if trimmed, ok := strings.CutPrefix(s, p); ok {
+			return trimmed
+		}
+	}
+	return s
+}
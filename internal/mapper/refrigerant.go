@@ -0,0 +1,140 @@
+package mapper
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// Refrigerant circuit register candidates, checked in order. Pressures are
+// gauge pressures in bar, as shown on the pump's service display.
+var (
+	DischargeTempCandidates = []string{"REG_DISCHARGE_PIPE_TEMPERATURE", "REG_HOT_GAS_TEMPERATURE", "REG_OPER_DATA_HOT_GAS"}
+	SuctionTempCandidates   = []string{"REG_SUCTION_GAS_TEMPERATURE", "REG_SUCTION_TEMPERATURE", "REG_OPER_DATA_SUCTION_GAS"}
+	LiquidTempCandidates    = []string{"REG_LIQUID_LINE_TEMPERATURE", "REG_OPER_DATA_LIQUID_LINE"}
+	HighPressureCandidates  = []string{"REG_HIGH_PRESSURE", "REG_CONDENSER_PRESSURE", "REG_OPER_DATA_HIGH_PRESSURE"}
+	LowPressureCandidates   = []string{"REG_LOW_PRESSURE", "REG_EVAPORATOR_PRESSURE", "REG_OPER_DATA_LOW_PRESSURE"}
+)
+
+// atmosphericBar converts gauge to absolute pressure.
+const atmosphericBar = 1.013
+
+// saturationPoint is one row of a pressure/temperature table: the bubble
+// (liquid) and dew (vapour) pressures in bar absolute at a temperature.
+// They differ for zeotropic blends such as R407C.
+type saturationPoint struct {
+	celsius float64
+	bubble  float64
+	dew     float64
+}
+
+// saturationTables hold approximate saturation pressures for the
+// refrigerants used in Thermia heat pumps, by ascending temperature.
+var saturationTables = map[string][]saturationPoint{
+	"R407C": {
+		{-30, 1.92, 1.40}, {-20, 2.93, 2.20}, {-10, 4.28, 3.31}, {0, 6.05, 4.80},
+		{10, 8.31, 6.74}, {20, 11.16, 9.21}, {30, 14.67, 12.28}, {40, 18.94, 16.05},
+		{50, 24.06, 20.62}, {60, 30.14, 26.10},
+	},
+	"R410A": {
+		{-30, 2.70, 2.70}, {-20, 3.99, 3.99}, {-10, 5.73, 5.73}, {0, 7.99, 7.99},
+		{10, 10.86, 10.86}, {20, 14.44, 14.44}, {30, 18.85, 18.85}, {40, 24.19, 24.19},
+		{50, 30.60, 30.60}, {60, 38.20, 38.20},
+	},
+	"R134A": {
+		{-30, 0.84, 0.84}, {-20, 1.33, 1.33}, {-10, 2.01, 2.01}, {0, 2.93, 2.93},
+		{10, 4.15, 4.15}, {20, 5.72, 5.72}, {30, 7.70, 7.70}, {40, 10.17, 10.17},
+		{50, 13.18, 13.18}, {60, 16.82, 16.82},
+	},
+}
+
+// Refrigerant identifies a saturation table.
+type Refrigerant string
+
+// ParseRefrigerant validates a refrigerant name (case-insensitive).
+func ParseRefrigerant(s string) (Refrigerant, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if _, ok := saturationTables[name]; !ok {
+		return "", fmt.Errorf("unknown refrigerant %q (supported: R407C, R410A, R134a)", s)
+	}
+	return Refrigerant(name), nil
+}
+
+// saturationTemp interpolates the saturation temperature at an absolute
+// pressure from the bubble or dew column. ok is false outside the table.
+func (r Refrigerant) saturationTemp(bar float64, dew bool) (float64, bool) {
+	table := saturationTables[string(r)]
+	pressure := func(p saturationPoint) float64 {
+		if dew {
+			return p.dew
+		}
+		return p.bubble
+	}
+	for i := 1; i < len(table); i++ {
+		lo, hi := table[i-1], table[i]
+		plo, phi := pressure(lo), pressure(hi)
+		if bar < plo || bar > phi {
+			continue
+		}
+		return lo.celsius + (bar-plo)/(phi-plo)*(hi.celsius-lo.celsius), true
+	}
+	return 0, false
+}
+
+// RefrigerantEstimates are superheat and subcooling figures derived from
+// refrigerant circuit temperatures and pressures. Fields are nil when the
+// registers they need are missing or a pressure is outside the table.
+type RefrigerantEstimates struct {
+	// Superheat is the suction gas temperature above the evaporating
+	// (dew) temperature.
+	Superheat *float64
+	// DischargeSuperheat is the hot gas temperature above the condensing
+	// (dew) temperature.
+	DischargeSuperheat *float64
+	// Subcooling is how far the liquid line is below the condensing
+	// (bubble) temperature.
+	Subcooling *float64
+}
+
+// EstimateRefrigerant computes superheat and subcooling for r from items.
+// The results are estimates: the saturation tables are approximate and the
+// pump's sensors are not service-grade instruments.
+func EstimateRefrigerant(items []types.GroupItem, r Refrigerant) RefrigerantEstimates {
+	var est RefrigerantEstimates
+	if r == "" {
+		return est
+	}
+	above := func(temp *float64, pressure []string, dew bool, invert bool) *float64 {
+		p := findFirst(items, pressure)
+		if temp == nil || p == nil {
+			return nil
+		}
+		sat, ok := r.saturationTemp(*p+atmosphericBar, dew)
+		if !ok {
+			return nil
+		}
+		k := *temp - sat
+		if invert {
+			k = -k
+		}
+		k = math.Round(k*10) / 10
+		return &k
+	}
+	est.Superheat = above(findFirst(items, SuctionTempCandidates), LowPressureCandidates, true, false)
+	est.DischargeSuperheat = above(findFirst(items, DischargeTempCandidates), HighPressureCandidates, true, false)
+	est.Subcooling = above(findFirst(items, LiquidTempCandidates), HighPressureCandidates, false, true)
+	return est
+}
+
+// findFirst returns the value of the first register in candidates that has
+// one.
+func findFirst(items []types.GroupItem, candidates []string) *float64 {
+	for _, name := range candidates {
+		if v := findValue(items, name); v != nil {
+			return v
+		}
+	}
+	return nil
+}
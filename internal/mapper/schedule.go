@@ -0,0 +1,192 @@
+package mapper
+
+import (
+	"strings"
+	"time"
+
+	"thermia_exporter/internal/types"
+)
+
+// Calendar function name fragments, matched ignoring case and underscores
+const (
+	OperationModeFunction = "OPERATIONMODE"
+	EVUBlockFunction      = "EVU"
+	HotWaterBlockFunction = "HOTWATERBLOCK"
+	LegionellaFunction    = "LEGIONELLA"
+)
+
+// FindOperationModeFunction returns the calendar function scheduling the
+// operation mode, if the installation has one.
+func FindOperationModeFunction(functions []types.CalendarFunction) (types.CalendarFunction, bool) {
+	return FindCalendarFunction(functions, OperationModeFunction)
+}
+
+// FindCalendarFunction returns the calendar function whose name contains
+// fragment, if the installation has one.
+func FindCalendarFunction(functions []types.CalendarFunction, fragment string) (types.CalendarFunction, bool) {
+	for _, f := range functions {
+		name := strings.ToUpper(strings.ReplaceAll(f.Name, "_", ""))
+		if strings.Contains(name, fragment) {
+			return f, true
+		}
+	}
+	return types.CalendarFunction{}, false
+}
+
+// ScheduleConflict identifies a legionella schedule that both an EVU block
+// and a hot water block schedule overlap.
+type ScheduleConflict struct {
+	Legionella    int64
+	EVUBlock      int64
+	HotWaterBlock int64
+}
+
+// ScheduleConflicts returns the combinations of schedules where an EVU
+// block and a hot water block both overlap the same legionella cycle in the
+// week after now. The heat pump can then neither run the compressor nor
+// heat hot water during the cycle; the portal accepts such schedules
+// without a warning.
+func ScheduleConflicts(legionella, evuBlock, hotWaterBlock []types.CalendarSchedule, now time.Time) []ScheduleConflict {
+	const week = 7 * 24 * time.Hour
+	from, to := now, now.Add(week)
+
+	var out []ScheduleConflict
+	for _, l := range legionella {
+		for _, cycle := range occurrences(l, from, to) {
+			for _, e := range evuBlock {
+				if !overlapsAny(e, cycle, from, to) {
+					continue
+				}
+				for _, h := range hotWaterBlock {
+					if overlapsAny(h, cycle, from, to) {
+						out = append(out, ScheduleConflict{l.ScheduleID, e.ScheduleID, h.ScheduleID})
+					}
+				}
+			}
+		}
+	}
+	return dedupeConflicts(out)
+}
+
+// interval is a half-open time range [start, end).
+type interval struct{ start, end time.Time }
+
+func (i interval) overlaps(o interval) bool {
+	return i.start.Before(o.end) && o.start.Before(i.end)
+}
+
+// occurrences returns the intervals of s overlapping [from, to). Recurring
+// schedules repeat weekly from their start.
+func occurrences(s types.CalendarSchedule, from, to time.Time) []interval {
+	startUnix, endUnix := ParseTimeToUnix(s.Start), ParseTimeToUnix(s.End)
+	if startUnix == 0 || endUnix <= startUnix {
+		return nil
+	}
+	first := interval{time.Unix(startUnix, 0), time.Unix(endUnix, 0)}
+	window := interval{from, to}
+	if !s.Recurring {
+		if first.overlaps(window) {
+			return []interval{first}
+		}
+		return nil
+	}
+
+	const week = 7 * 24 * time.Hour
+	var k time.Duration
+	if from.After(first.end) {
+		k = from.Sub(first.end) / week
+	}
+	var out []interval
+	for ; ; k++ {
+		occ := interval{first.start.Add(k * week), first.end.Add(k * week)}
+		if !occ.start.Before(to) {
+			return out
+		}
+		if occ.overlaps(window) {
+			out = append(out, occ)
+		}
+	}
+}
+
+// overlapsAny reports whether any occurrence of s in [from, to) overlaps i.
+func overlapsAny(s types.CalendarSchedule, i interval, from, to time.Time) bool {
+	for _, occ := range occurrences(s, from, to) {
+		if occ.overlaps(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeConflicts drops repeated conflicts, keeping the first occurrence.
+func dedupeConflicts(conflicts []ScheduleConflict) []ScheduleConflict {
+	seen := make(map[ScheduleConflict]bool, len(conflicts))
+	out := conflicts[:0]
+	for _, c := range conflicts {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// NextScheduledMode returns the operation mode and start time of the next
+// schedule starting after now. Recurring schedules repeat weekly. Mode
+// values are named using the operation mode register in grpOperation; ok is
+// false if nothing is scheduled or the value has no name.
+func NextScheduledMode(schedules []types.CalendarSchedule, grpOperation []types.GroupItem, now time.Time) (mode types.OperationMode, at time.Time, ok bool) {
+	var next *types.CalendarSchedule
+	for i, s := range schedules {
+		if s.Value == nil {
+			continue
+		}
+		start, found := nextStart(s, now)
+		if !found {
+			continue
+		}
+		if next == nil || start.Before(at) {
+			next, at = &schedules[i], start
+		}
+	}
+	if next == nil {
+		return "", time.Time{}, false
+	}
+
+	mode = modeName(grpOperation, *next.Value)
+	return mode, at, mode != ""
+}
+
+// nextStart returns the first start of s after now.
+func nextStart(s types.CalendarSchedule, now time.Time) (time.Time, bool) {
+	unix := ParseTimeToUnix(s.Start)
+	if unix == 0 {
+		return time.Time{}, false
+	}
+	start := time.Unix(unix, 0)
+	if start.After(now) {
+		return start, true
+	}
+	if !s.Recurring {
+		return time.Time{}, false
+	}
+
+	const week = 7 * 24 * time.Hour
+	weeks := now.Sub(start)/week + 1
+	return start.Add(weeks * week), true
+}
+
+// modeName returns the trimmed operation mode name for value.
+func modeName(grpOperation []types.GroupItem, value float64) types.OperationMode {
+	it := findOperationMode(grpOperation, OperationModeCandidates)
+	if it == nil {
+		return ""
+	}
+	val := int(value + 0.00001)
+	for _, vn := range it.ValueNames {
+		if vn.Value == val {
+			return types.ParseOperationMode(vn.Name)
+		}
+	}
+	return ""
+}
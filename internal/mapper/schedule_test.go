@@ -0,0 +1,101 @@
+package mapper
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/types"
+)
+
+func TestNextScheduledMode(t *testing.T) {
+	grpOperation := []types.GroupItem{{
+		RegisterName: RegOperationMode,
+		ValueNames: []types.ValueEntry{
+			{Name: "REG_VALUE_OPERATION_MODE_AUTO", Value: 3, Visible: true},
+			{Name: "REG_VALUE_OPERATION_MODE_HOT_WATER", Value: 4, Visible: true},
+		},
+	}}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // Wednesday
+
+	schedules := []types.CalendarSchedule{
+		// Weekly on Mondays 06:00: next is 2026-10-19
+		{Start: "2026-09-07T06:00:00Z", Value: ptr(3), Recurring: true},
+		// One-off in two days
+		{Start: "2026-10-16T08:00:00Z", Value: ptr(4)},
+		// One-off in the past
+		{Start: "2026-10-01T08:00:00Z", Value: ptr(4)},
+	}
+
+	mode, at, ok := NextScheduledMode(schedules, grpOperation, now)
+	if !ok || mode != "HOT_WATER" || !at.Equal(time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextScheduledMode() = %q, %v, %v; want HOT_WATER at 2026-10-16 08:00", mode, at, ok)
+	}
+
+	mode, at, ok = NextScheduledMode(schedules[:1], grpOperation, now)
+	if !ok || mode != "AUTO" || !at.Equal(time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextScheduledMode(weekly) = %q, %v, %v; want AUTO at 2026-10-19 06:00", mode, at, ok)
+	}
+
+	if _, _, ok := NextScheduledMode(schedules[2:], grpOperation, now); ok {
+		t.Error("past one-off schedule should not be reported")
+	}
+}
+
+func TestFindOperationModeFunction(t *testing.T) {
+	functions := []types.CalendarFunction{
+		{FunctionID: 1, Name: "REG_HOT_WATER_BOOST"},
+		{FunctionID: 2, Name: "REG_OPERATION_MODE"},
+	}
+	f, ok := FindOperationModeFunction(functions)
+	if !ok || f.FunctionID != 2 {
+		t.Errorf("FindOperationModeFunction() = %+v, %v; want function 2", f, ok)
+	}
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // Wednesday
+
+	// Weekly on Saturdays 02:00-04:00
+	legionella := []types.CalendarSchedule{
+		{ScheduleID: 1, Start: "2026-09-05T02:00:00Z", End: "2026-09-05T04:00:00Z", Recurring: true},
+	}
+	evuBlock := []types.CalendarSchedule{
+		// Weekly on Saturdays 03:00-05:00: overlaps
+		{ScheduleID: 10, Start: "2026-09-12T03:00:00Z", End: "2026-09-12T05:00:00Z", Recurring: true},
+		// Weekly on Sundays: does not overlap
+		{ScheduleID: 11, Start: "2026-09-13T03:00:00Z", End: "2026-09-13T05:00:00Z", Recurring: true},
+	}
+	hotWaterBlock := []types.CalendarSchedule{
+		// One-off covering the coming Saturday night: overlaps
+		{ScheduleID: 20, Start: "2026-10-16T22:00:00Z", End: "2026-10-17T06:00:00Z"},
+		// One-off in the past
+		{ScheduleID: 21, Start: "2026-10-10T01:00:00Z", End: "2026-10-10T05:00:00Z"},
+	}
+
+	got := ScheduleConflicts(legionella, evuBlock, hotWaterBlock, now)
+	want := []ScheduleConflict{{Legionella: 1, EVUBlock: 10, HotWaterBlock: 20}}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("ScheduleConflicts() = %+v, want %+v", got, want)
+	}
+
+	if got := ScheduleConflicts(legionella, evuBlock, hotWaterBlock[1:], now); len(got) != 0 {
+		t.Errorf("ScheduleConflicts() without a hot water block = %+v, want none", got)
+	}
+}
+
+func TestFindCalendarFunction(t *testing.T) {
+	functions := []types.CalendarFunction{
+		{FunctionID: 1, Name: "REG_HOT_WATER_BLOCK"},
+		{FunctionID: 2, Name: "REG_EVU_BLOCK"},
+		{FunctionID: 3, Name: "REG_LEGIONELLA"},
+	}
+	for fragment, id := range map[string]int64{
+		HotWaterBlockFunction: 1,
+		EVUBlockFunction:      2,
+		LegionellaFunction:    3,
+	} {
+		if f, ok := FindCalendarFunction(functions, fragment); !ok || f.FunctionID != id {
+			t.Errorf("FindCalendarFunction(%q) = %+v, %v; want function %d", fragment, f, ok, id)
+		}
+	}
+}
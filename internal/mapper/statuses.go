@@ -7,6 +7,21 @@ import (
 	"thermia_exporter/internal/types"
 )
 
+// DefaultStatusPriority orders operational statuses by relevance, highest
+// first, for picking the single current status of an installation whose
+// status register reports several at once.
+var DefaultStatusPriority = []types.OperationalStatus{
+	types.StatusLegionella,
+	types.StatusHotWater,
+	types.StatusHeat,
+	types.StatusCool,
+	types.StatusPassiveCool,
+	types.StatusPool,
+	types.StatusStandby,
+	types.StatusNoDemand,
+	types.StatusOperationModeOff,
+}
+
 // ExtractBitmaskStatuses extracts bitmask status flags from register items.
 // It searches for the first matching register from the provided register names.
 func ExtractBitmaskStatuses(items []types.GroupItem, registerNames []string) types.StatusData {
@@ -31,24 +46,24 @@ func ExtractBitmaskStatuses(items []types.GroupItem, registerNames []string) typ
 	}
 
 	// Extract available statuses
-	result.Available = make([]string, 0, len(match.ValueNames))
+	result.Available = make([]types.OperationalStatus, 0, len(match.ValueNames))
 	for _, vn := range match.ValueNames {
 		if vn.Visible {
-			result.Available = append(result.Available, trimStatus(vn.Name))
+			result.Available = append(result.Available, types.ParseOperationalStatus(vn.Name))
 		}
 	}
 
 	// Extract running statuses (bitmask)
 	if match.RegisterValue == nil {
-		result.Running = []string{}
+		result.Running = []types.OperationalStatus{}
 		return result
 	}
 
 	val := int(*match.RegisterValue + 0.00001)
-	result.Running = make([]string, 0)
+	result.Running = make([]types.OperationalStatus, 0)
 	for _, vn := range match.ValueNames {
 		if vn.Visible && (val&vn.Value) != 0 {
-			result.Running = append(result.Running, trimStatus(vn.Name))
+			result.Running = append(result.Running, types.ParseOperationalStatus(vn.Name))
 		}
 	}
 
@@ -107,6 +122,20 @@ func ExtractAlerts(activeEvents, allEvents []types.Event) (active, archived []st
 	return activeTitles, archived
 }
 
+// EventsSince returns the events that occurred at or after cutoff. Events
+// that are still active or whose time cannot be parsed are always kept.
+func EventsSince(events []types.Event, cutoff time.Time) []types.Event {
+	result := make([]types.Event, 0, len(events))
+	for _, e := range events {
+		occurred := ParseTimeToUnix(e.OccurredWhen)
+		active := e.IsActive != nil && *e.IsActive
+		if active || occurred == 0 || occurred >= cutoff.Unix() {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
 // ParseTimeToUnix converts a time string to Unix timestamp (seconds).
 // Supports multiple common time formats. Returns 0 if parsing fails.
 func ParseTimeToUnix(s string) int64 {
@@ -132,14 +161,28 @@ func ParseTimeToUnix(s string) int64 {
 	return 0
 }
 
-// trimStatus removes common prefixes from status names.
-func trimStatus(s string) string {
-	for _, p := range []string{StatusPrefixRegValue, StatusPrefixCompValue} {
-		if strings.HasPrefix(s, p) {
-			return strings.TrimPrefix(s, p)
+// LatestRegisterTime returns the newest register timestamp in items as Unix
+// seconds, or 0 if no register carries a parseable timestamp.
+func LatestRegisterTime(items []types.GroupItem) int64 {
+	var latest int64
+	for _, it := range items {
+		if ts := ParseTimeToUnix(it.Timestamp); ts > latest {
+			latest = ts
+		}
+	}
+	return latest
+}
+
+// NormalizeLabelValue lowercases a status or mode name and strips its
+// STATUS_, POWER_ or OPERATION_MODE_ prefix (STATUS_HOTWATER: hotwater).
+func NormalizeLabelValue(s string) string {
+	for _, p := range normalizedPrefixes {
+		if trimmed, ok := strings.CutPrefix(s, p); ok && trimmed != "" {
+			s = trimmed
+			break
 		}
 	}
-	return s
+	return strings.ToLower(s)
 }
 
 // uniqueTitles extracts unique non-empty event titles.
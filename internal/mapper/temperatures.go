@@ -4,58 +4,113 @@ import (
 	"thermia_exporter/internal/types"
 )
 
-// ExtractTemperatures extracts temperature data from installation status and register groups.
-// It tries multiple fallback register names for each temperature to handle different heat pump models.
+// TemperatureSource says where a temperature sensor's reading comes from:
+// the installation status field, if the status reports the sensor, then
+// register candidates in order of preference.
+type TemperatureSource struct {
+	Key       string
+	Status    func(*types.InstallationStatus) *float64
+	Registers []string
+	field     func(*types.TemperatureData) **float64
+}
+
+// TemperatureSources lists every temperature sensor, keyed as in
+// TemperaturesToMap. Supporting a model that reports a sensor under another
+// register name is a matter of adding it to Registers here, or mapping it
+// onto one of the candidates in a register alias file.
+var TemperatureSources = []TemperatureSource{
+	{
+		Key:       "indoor",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.IndoorTemperature },
+		Registers: []string{RegIndoorTemperature},
+		field:     func(t *types.TemperatureData) **float64 { return &t.Indoor },
+	},
+	{
+		Key:       "outdoor",
+		Registers: []string{RegOutdoorTemperature, RegOperDataOutdoorTempMaSa},
+		field:     func(t *types.TemperatureData) **float64 { return &t.Outdoor },
+	},
+	{
+		Key:       "supply_line",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.SupplyLine },
+		Registers: []string{RegSupplyLine},
+		field:     func(t *types.TemperatureData) **float64 { return &t.SupplyLine },
+	},
+	{
+		Key:       "desired_supply_line",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.DesiredSupplyLineTemperature },
+		Registers: []string{RegDesiredSupplyLineTemp, RegDesiredSupplyLine, RegDesiredSysSupplyLineTemp},
+		field:     func(t *types.TemperatureData) **float64 { return &t.DesiredSupplyLine },
+	},
+	{
+		Key:       "return_line",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.ReturnLineTemperature },
+		Registers: []string{RegReturnLine, RegOperDataReturn},
+		field:     func(t *types.TemperatureData) **float64 { return &t.ReturnLine },
+	},
+	{
+		Key:       "buffer_tank",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.BufferTankTemperature },
+		Registers: []string{RegOperDataBufferTank},
+		field:     func(t *types.TemperatureData) **float64 { return &t.BufferTank },
+	},
+	{
+		Key:    "hot_water",
+		Status: func(s *types.InstallationStatus) *float64 { return s.HotWaterTemperature },
+		field:  func(t *types.TemperatureData) **float64 { return &t.HotWater },
+	},
+	{
+		Key:       "brine_out",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.BrineOutTemperature },
+		Registers: []string{RegBrineOut},
+		field:     func(t *types.TemperatureData) **float64 { return &t.BrineOut },
+	},
+	{
+		Key:       "brine_in",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.BrineInTemperature },
+		Registers: []string{RegBrineIn},
+		field:     func(t *types.TemperatureData) **float64 { return &t.BrineIn },
+	},
+	{
+		Key:       "pool",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.PoolTemperature },
+		Registers: []string{RegActualPoolTemp},
+		field:     func(t *types.TemperatureData) **float64 { return &t.Pool },
+	},
+	{
+		Key:       "cooling_tank",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.CoolingTankTemperature },
+		Registers: []string{RegCoolSensorTank},
+		field:     func(t *types.TemperatureData) **float64 { return &t.CoolingTank },
+	},
+	{
+		Key:       "cooling_supply",
+		Status:    func(s *types.InstallationStatus) *float64 { return s.CoolingSupplyLineTemperature },
+		Registers: []string{RegCoolSensorSupply},
+		field:     func(t *types.TemperatureData) **float64 { return &t.CoolingSupply },
+	},
+}
+
+// ExtractTemperatures extracts temperature data from installation status and
+// register groups, taking each sensor from the first source in
+// TemperatureSources that reports it. A nil status (failed status fetch)
+// falls back to the register group alone.
 func ExtractTemperatures(status *types.InstallationStatus, grp []types.GroupItem) types.TemperatureData {
-	data := types.TemperatureData{
-		Indoor:     status.IndoorTemperature,
-		HotWater:   status.HotWaterTemperature,
-		SupplyLine: status.SupplyLine,
-		DesiredSupplyLine: firstNonNil(
-			status.DesiredSupplyLineTemperature,
-			findValue(grp, RegDesiredSupplyLineTemp),
-			findValue(grp, RegDesiredSupplyLine),
-			findValue(grp, RegDesiredSysSupplyLineTemp),
-		),
-		BufferTank: status.BufferTankTemperature,
-		ReturnLine: firstNonNil(
-			status.ReturnLineTemperature,
-			findValue(grp, RegReturnLine),
-			findValue(grp, RegOperDataReturn),
-		),
-		BrineOut:      status.BrineOutTemperature,
-		BrineIn:       status.BrineInTemperature,
-		Pool:          status.PoolTemperature,
-		CoolingTank:   status.CoolingTankTemperature,
-		CoolingSupply: status.CoolingSupplyLineTemperature,
+	if status == nil {
+		status = &types.InstallationStatus{}
 	}
 
-	// Fallback to register groups if status doesn't have the value
-	if data.Indoor == nil {
-		data.Indoor = findValue(grp, RegIndoorTemperature)
-	}
-	if data.SupplyLine == nil {
-		data.SupplyLine = findValue(grp, RegSupplyLine)
-	}
-	if data.BufferTank == nil {
-		data.BufferTank = findValue(grp, RegOperDataBufferTank)
-	}
-	if data.BrineOut == nil {
-		data.BrineOut = findValue(grp, RegBrineOut)
-	}
-	if data.BrineIn == nil {
-		data.BrineIn = findValue(grp, RegBrineIn)
-	}
-	if data.Pool == nil {
-		data.Pool = findValue(grp, RegActualPoolTemp)
-	}
-	if data.CoolingTank == nil {
-		data.CoolingTank = findValue(grp, RegCoolSensorTank)
-	}
-	if data.CoolingSupply == nil {
-		data.CoolingSupply = findValue(grp, RegCoolSensorSupply)
+	var data types.TemperatureData
+	for _, src := range TemperatureSources {
+		var v *float64
+		if src.Status != nil {
+			v = src.Status(status)
+		}
+		for i := 0; v == nil && i < len(src.Registers); i++ {
+			v = findValue(grp, src.Registers[i])
+		}
+		*src.field(&data) = v
 	}
-
 	return data
 }
 
@@ -105,6 +160,40 @@ func TemperaturesToMap(t types.TemperatureData) map[string]float64 {
 	return result
 }
 
+// TemperatureRegisterKeys maps temperature register names to the keys used
+// by TemperaturesToMap (and thus the thermia_<key>_temperature_celsius metrics).
+var TemperatureRegisterKeys = func() map[string]string {
+	m := make(map[string]string)
+	for _, src := range TemperatureSources {
+		for _, name := range src.Registers {
+			m[name] = src.Key
+		}
+	}
+	return m
+}()
+
+// PreferredTemperatureRegisters returns, of the given register names, the
+// one register per temperature key that ExtractTemperatures reads first,
+// mapped to its key. Other candidates for the same key are left out, so
+// each thermia_<key>_temperature_celsius has one source.
+func PreferredTemperatureRegisters(names []string) map[string]string {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	preferred := make(map[string]string)
+	for _, src := range TemperatureSources {
+		for _, name := range src.Registers {
+			if present[name] {
+				preferred[name] = src.Key
+				break
+			}
+		}
+	}
+	return preferred
+}
+
 // findValue searches for a register by name and returns its value if found.
 func findValue(items []types.GroupItem, registerName string) *float64 {
 	for _, it := range items {
@@ -0,0 +1,116 @@
+package mapper
+
+import (
+	"strconv"
+	"strings"
+
+	"thermia_exporter/internal/types"
+)
+
+// Unit is a register unit resolved to a Prometheus metric name suffix and
+// the factor that converts register values to that base unit.
+type Unit struct {
+	Suffix string
+	Scale  float64
+}
+
+// units maps normalized register units (lower case, degree sign dropped)
+// to their metric suffix and conversion.
+var units = map[string]Unit{
+	"c":     {"_celsius", 1},
+	"k":     {"_kelvin", 1},
+	"%":     {"_percent", 1},
+	"h":     {"_hours", 1},
+	"min":   {"_seconds", 60},
+	"s":     {"_seconds", 1},
+	"w":     {"_watts", 1},
+	"kw":    {"_watts", 1000},
+	"wh":    {"_kwh", 0.001},
+	"kwh":   {"_kwh", 1},
+	"mwh":   {"_kwh", 1000},
+	"bar":   {"_bar", 1},
+	"kpa":   {"_bar", 0.01},
+	"hz":    {"_hertz", 1},
+	"rpm":   {"_rpm", 1},
+	"l/min": {"_liters_per_minute", 1},
+	"l/h":   {"_liters_per_minute", 1.0 / 60},
+}
+
+// ParseUnit resolves a register unit such as "°C", "kWh" or "0.1°C".
+// A leading number is a multiplier on the raw value, which is how some
+// firmware reports registers in tenths. It returns false for an empty or
+// unknown unit.
+func ParseUnit(s string) (Unit, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, "°", "")
+	scale := 1.0
+	if i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i > 0 {
+		f, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil || f == 0 {
+			return Unit{}, false
+		}
+		scale, s = f, strings.TrimSpace(s[i:])
+	}
+	u, ok := units[s]
+	if !ok {
+		return Unit{}, false
+	}
+	u.Scale *= scale
+	return u, true
+}
+
+// RegisterUnit returns the unit label a raw register reported in unit is
+// exported with and the factor converting its values to that unit: the
+// base unit of the metric suffix, such as "celsius" with 0.1 for "0.1°C".
+// Units ParseUnit does not know are kept as reported, with factor 1.
+func RegisterUnit(unit string) (string, float64) {
+	if u, ok := ParseUnit(unit); ok {
+		return strings.TrimPrefix(u.Suffix, "_"), u.Scale
+	}
+	return strings.TrimSpace(unit), 1
+}
+
+// expectedUnits lists the unit suffix each hand-mapped register is
+// exported with. Power and pressure registers are converted by their
+// extractors and are not listed.
+var expectedUnits = map[string]string{
+	RegIndoorTemperature:        "_celsius",
+	RegOutdoorTemperature:       "_celsius",
+	RegOperDataOutdoorTempMaSa:  "_celsius",
+	RegSupplyLine:               "_celsius",
+	RegDesiredSupplyLineTemp:    "_celsius",
+	RegDesiredSupplyLine:        "_celsius",
+	RegDesiredSysSupplyLineTemp: "_celsius",
+	RegReturnLine:               "_celsius",
+	RegOperDataReturn:           "_celsius",
+	RegOperDataBufferTank:       "_celsius",
+	RegBrineOut:                 "_celsius",
+	RegBrineIn:                  "_celsius",
+	RegActualPoolTemp:           "_celsius",
+	RegCoolSensorTank:           "_celsius",
+	RegCoolSensorSupply:         "_celsius",
+	RegOperTimeCompressor:       "_hours",
+	RegOperTimeHeating:          "_hours",
+	RegOperTimeHotWater:         "_hours",
+	RegOperTimeImm1:             "_hours",
+	RegOperTimeImm2:             "_hours",
+	RegOperTimeImm3:             "_hours",
+}
+
+// UnitMismatches returns the hand-mapped registers in items whose reported
+// unit differs from the one their metric assumes, for example a
+// temperature register that starts reporting tenths. Registers without a
+// unit are not checked: many models leave it empty.
+func UnitMismatches(items []types.GroupItem) []types.GroupItem {
+	var out []types.GroupItem
+	for _, it := range items {
+		want, ok := expectedUnits[it.RegisterName]
+		if !ok || strings.TrimSpace(it.Unit) == "" {
+			continue
+		}
+		if u, ok := ParseUnit(it.Unit); !ok || u.Suffix != want || u.Scale != 1 {
+			out = append(out, it)
+		}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+// Package meter reads electrical power from an external energy meter (for
+// example a P1/HAN reader on the heat pump's circuit) so the exporter can
+// compute a measured COP.
+package meter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IDPlaceholder in a query or topic is replaced with the installation ID,
+// so one exporter can meter several heat pumps.
+const IDPlaceholder = "$heatpump_id"
+
+// Source reports the current electrical power draw in watts.
+type Source interface {
+	// Power returns the power draw of the installation with the given ID.
+	Power(ctx context.Context, id int64) (float64, error)
+
+	// PerInstallation reports whether the readings depend on the
+	// installation; otherwise the one reading only fits an account with a
+	// single installation.
+	PerInstallation() bool
+}
+
+// Templated reports whether s contains IDPlaceholder.
+func Templated(s string) bool {
+	return strings.Contains(s, IDPlaceholder)
+}
+
+// expand replaces IDPlaceholder in s with id.
+func expand(s string, id int64) string {
+	return strings.ReplaceAll(s, IDPlaceholder, strconv.FormatInt(id, 10))
+}
+
+// PrometheusSource evaluates an instant PromQL query returning watts.
+type PrometheusSource struct {
+	baseURL    string
+	query      string
+	httpClient *http.Client
+}
+
+// NewPrometheusSource creates a source querying the Prometheus-compatible API
+// at baseURL (e.g. http://prometheus:9090) with query, which may contain
+// IDPlaceholder.
+func NewPrometheusSource(baseURL, query string) *PrometheusSource {
+	return &PrometheusSource{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		query:      query,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// PerInstallation reports whether the query contains IDPlaceholder.
+func (p *PrometheusSource) PerInstallation() bool {
+	return Templated(p.query)
+}
+
+// Power runs the query for installation id and returns its single scalar
+// or vector value.
+func (p *PrometheusSource) Power(ctx context.Context, id int64) (float64, error) {
+	u := p.baseURL + "/api/v1/query?" + url.Values{"query": {expand(p.query, id)}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d: %s", resp.StatusCode, string(data))
+	}
+
+	return parseQueryResult(data)
+}
+
+// parseQueryResult extracts one value from a Prometheus query API response.
+func parseQueryResult(data []byte) (float64, error) {
+	var r struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return 0, fmt.Errorf("unmarshal query result: %w", err)
+	}
+	if r.Status != "success" {
+		return 0, fmt.Errorf("query status %q", r.Status)
+	}
+
+	var sample [2]interface{}
+	switch r.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(r.Data.Result, &sample); err != nil {
+			return 0, fmt.Errorf("unmarshal scalar: %w", err)
+		}
+	case "vector":
+		var vec []struct {
+			Value [2]interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(r.Data.Result, &vec); err != nil {
+			return 0, fmt.Errorf("unmarshal vector: %w", err)
+		}
+		if len(vec) != 1 {
+			return 0, fmt.Errorf("query returned %d series, want exactly 1", len(vec))
+		}
+		sample = vec[0].Value
+	default:
+		return 0, fmt.Errorf("unsupported result type %q", r.Data.ResultType)
+	}
+
+	raw, ok := sample[1].(string)
+	if !ok {
+		return 0, errors.New("malformed sample value")
+	}
+	return strconv.ParseFloat(raw, 64)
+}
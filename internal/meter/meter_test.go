@@ -0,0 +1,56 @@
+package meter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrometheusSource_Vector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.URL.Query().Get("query") != `p1_power_watts{heatpump="42"}` {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"1234.5"]}]}}`))
+	}))
+	defer srv.Close()
+
+	src := NewPrometheusSource(srv.URL+"/", `p1_power_watts{heatpump="$heatpump_id"}`)
+	if !src.PerInstallation() {
+		t.Error("PerInstallation() = false for a templated query")
+	}
+	got, err := src.Power(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("Power() error = %v", err)
+	}
+	if got != 1234.5 {
+		t.Errorf("Power() = %v, want 1234.5", got)
+	}
+}
+
+func TestParseQueryResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{"scalar", `{"status":"success","data":{"resultType":"scalar","result":[1,"800"]}}`, 800, false},
+		{"empty vector", `{"status":"success","data":{"resultType":"vector","result":[]}}`, 0, true},
+		{"two series", `{"status":"success","data":{"resultType":"vector","result":[{"value":[1,"1"]},{"value":[1,"2"]}]}}`, 0, true},
+		{"error", `{"status":"error","data":{}}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQueryResult([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQueryResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseQueryResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package meter
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestMQTTSource_PerInstallationTopic(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	filters := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		if header, _, err := readMQTTPacket(r); err != nil || header>>4 != mqttConnect {
+			t.Errorf("expected connect, got %d (%v)", header>>4, err)
+			return
+		}
+		conn.Write(mqttPacket(mqttConnAck<<4, []byte{0, 0}))
+
+		header, body, err := readMQTTPacket(r)
+		if err != nil || header>>4 != mqttSubscribe {
+			t.Errorf("expected subscribe, got %d (%v)", header>>4, err)
+			return
+		}
+		filter, _, _ := cutMQTTString(body[2:])
+		filters <- filter
+		conn.Write(mqttPacket(mqttSubAck<<4, []byte{0, 1, 0}))
+		conn.Write(mqttPacket(mqttPublish<<4, append(appendMQTTString(nil, "p1/42/power"), " 1234.5\n"...)))
+		conn.Write(mqttPacket(mqttPublish<<4, append(appendMQTTString(nil, "p1/7/power"), "n/a"...)))
+
+		// Hold the connection until the client disconnects
+		io.Copy(io.Discard, r)
+	}()
+
+	clk := clock.NewFake(time.Now())
+	src, err := NewMQTTSource("mqtt://"+ln.Addr().String(), "p1/$heatpump_id/power", slog.New(slog.NewTextHandler(io.Discard, nil)), clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !src.PerInstallation() {
+		t.Error("PerInstallation() = false for a templated topic")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Run(ctx)
+
+	if got := <-filters; got != "p1/+/power" {
+		t.Errorf("subscribed to %q, want p1/+/power", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := src.Power(ctx, 42)
+		if err == nil {
+			if got != 1234.5 {
+				t.Errorf("Power(42) = %v, want 1234.5", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Power(42) error = %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := src.Power(ctx, 7); err == nil {
+		t.Error("Power(7) should fail for a non-numeric payload")
+	}
+
+	clk.Advance(mqttMaxAge + time.Minute)
+	if _, err := src.Power(ctx, 42); err == nil {
+		t.Error("Power(42) should reject a stale reading")
+	}
+}
+
+func TestNewMQTTSource_InvalidTopic(t *testing.T) {
+	for _, topic := range []string{"", "p1/#", "p1/+/power", "p1/hp$heatpump_id/power"} {
+		if _, err := NewMQTTSource("mqtt://broker", topic, slog.Default(), nil); err == nil {
+			t.Errorf("NewMQTTSource(%q) should fail", topic)
+		}
+	}
+	if _, err := NewMQTTSource("http://broker", "p1/power", slog.Default(), nil); err == nil {
+		t.Error("NewMQTTSource() should reject an http URL")
+	}
+}
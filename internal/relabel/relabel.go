@@ -0,0 +1,350 @@
+// Package relabel drops and renames metrics and label values at emission
+// time, so users billed per series can shape what the exporter produces
+// without server-side relabeling.
+package relabel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// Rule actions
+const (
+	// ActionDrop drops metrics whose name matches.
+	ActionDrop = "drop"
+
+	// ActionRename renames metrics whose name matches.
+	ActionRename = "rename"
+
+	// ActionReplace replaces matching values of one label.
+	ActionReplace = "replace"
+
+	// ActionHash replaces every value of one label with a short hash keyed
+	// by the rule's Salt.
+	ActionHash = "hash"
+
+	// ActionDropLabel removes one label.
+	ActionDropLabel = "droplabel"
+)
+
+// Rule is one relabel rule. Regex is anchored at both ends; Replacement may
+// refer to capture groups as $1. Hash and droplabel rules have no Regex.
+// Salt keys the hash of hash rules, so hashes of short names cannot be
+// reversed by hashing candidates.
+type Rule struct {
+	Action      string
+	Label       string
+	Regex       *regexp.Regexp
+	Replacement string
+	Salt        string
+}
+
+// SaltHashes sets the salt of every hash rule in rules.
+func SaltHashes(rules []Rule, salt string) {
+	for i := range rules {
+		if rules[i].Action == ActionHash {
+			rules[i].Salt = salt
+		}
+	}
+}
+
+// Hashes reports whether rules hash any label.
+func Hashes(rules []Rule) bool {
+	for _, r := range rules {
+		if r.Action == ActionHash {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacts reports whether rules hash or drop any label, so metrics they
+// failed on must not be exported unchanged.
+func Redacts(rules []Rule) bool {
+	for _, r := range rules {
+		if r.Action == ActionHash || r.Action == ActionDropLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRules parses rules separated by ";":
+//
+//	drop:<regex>                          drop metrics by name
+//	rename:<regex>=<replacement>          rename metrics
+//	replace:<label>:<regex>=<replacement> replace label values
+//	hash:<label>                          hash label values
+//	droplabel:<label>                     remove a label
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		rule, err := parseRule(part)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", part, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// String formats the rule in the ParseRules syntax.
+func (r Rule) String() string {
+	if r.Regex == nil {
+		return r.Action + ":" + r.Label
+	}
+	pattern := strings.TrimSuffix(strings.TrimPrefix(r.Regex.String(), "^(?:"), ")$")
+	switch r.Action {
+	case ActionRename:
+		return r.Action + ":" + pattern + "=" + r.Replacement
+	case ActionReplace:
+		return r.Action + ":" + r.Label + ":" + pattern + "=" + r.Replacement
+	default:
+		return r.Action + ":" + pattern
+	}
+}
+
+func parseRule(s string) (Rule, error) {
+	action, args, ok := strings.Cut(s, ":")
+	if !ok {
+		return Rule{}, errors.New("missing action")
+	}
+
+	rule := Rule{Action: action}
+	var pattern string
+	switch action {
+	case ActionHash, ActionDropLabel:
+		if !model.LabelName(args).IsValid() {
+			return Rule{}, fmt.Errorf("invalid label %q", args)
+		}
+		rule.Label = args
+		return rule, nil
+	case ActionDrop:
+		pattern = args
+	case ActionRename:
+		if pattern, rule.Replacement, ok = strings.Cut(args, "="); !ok {
+			return Rule{}, errors.New("missing =<replacement>")
+		}
+	case ActionReplace:
+		var rest string
+		if rule.Label, rest, ok = strings.Cut(args, ":"); !ok || rule.Label == "" {
+			return Rule{}, errors.New("missing label")
+		}
+		if pattern, rule.Replacement, ok = strings.Cut(rest, "="); !ok {
+			return Rule{}, errors.New("missing =<replacement>")
+		}
+	default:
+		return Rule{}, fmt.Errorf("unknown action %q (use %s, %s, %s, %s or %s)",
+			action, ActionDrop, ActionRename, ActionReplace, ActionHash, ActionDropLabel)
+	}
+
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return Rule{}, err
+	}
+	rule.Regex = re
+	return rule, nil
+}
+
+// ParseRedactLabels parses a comma-separated list of label names whose
+// values are hashed, or dropped with a ":drop" suffix (":hash" is the
+// default), into hash and droplabel rules.
+func ParseRedactLabels(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		label, mode, _ := strings.Cut(part, ":")
+		action := ActionHash
+		switch mode {
+		case "", "hash":
+		case "drop":
+			action = ActionDropLabel
+		default:
+			return nil, fmt.Errorf("label %q: unknown mode %q (use hash or drop)", label, mode)
+		}
+		rule, err := parseRule(action + ":" + label)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Apply applies rules to metrics in order and returns the resulting
+// metrics. Only gauge, counter and untyped metrics are supported; metrics
+// of other types are dropped when rules are set. Series that rules map
+// onto the same name and labels (for example by dropping the label that
+// told them apart) are merged, keeping the first.
+func Apply(rules []Rule, metrics []prometheus.Metric) ([]prometheus.Metric, error) {
+	if len(rules) == 0 {
+		return metrics, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(metricList(metrics)); err != nil {
+		return nil, err
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []prometheus.Metric
+	seen := make(map[string]bool)
+	for _, mf := range families {
+		for _, pb := range mf.GetMetric() {
+			labels := make(map[string]string, len(pb.GetLabel()))
+			for _, lp := range pb.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+
+			name, keep := applyRules(rules, mf.GetName(), labels)
+			if !keep {
+				continue
+			}
+			if !model.IsValidMetricName(model.LabelValue(name)) {
+				return nil, fmt.Errorf("invalid metric name %q after relabeling %s", name, mf.GetName())
+			}
+			key := seriesKey(name, labels)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m, ok, err := rebuild(name, mf.GetHelp(), labels, pb)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result = append(result, m)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ApplyLabels applies rules to a series given by name and labels, for
+// writers that do not go through prometheus.Metric such as backfill. labels
+// is modified in place; it returns the new name and whether the series is
+// kept.
+func ApplyLabels(rules []Rule, name string, labels map[string]string) (string, bool) {
+	return applyRules(rules, name, labels)
+}
+
+// applyRules applies rules to one metric, modifying labels in place. It
+// returns the new name and whether the metric is kept.
+func applyRules(rules []Rule, name string, labels map[string]string) (string, bool) {
+	for _, r := range rules {
+		switch r.Action {
+		case ActionDrop:
+			if r.Regex.MatchString(name) {
+				return name, false
+			}
+		case ActionRename:
+			if r.Regex.MatchString(name) {
+				name = r.Regex.ReplaceAllString(name, r.Replacement)
+			}
+		case ActionReplace:
+			if v, ok := labels[r.Label]; ok && r.Regex.MatchString(v) {
+				labels[r.Label] = r.Regex.ReplaceAllString(v, r.Replacement)
+			}
+		case ActionHash:
+			if v, ok := labels[r.Label]; ok && v != "" {
+				labels[r.Label] = HashValue(v, r.Salt)
+			}
+		case ActionDropLabel:
+			delete(labels, r.Label)
+		}
+	}
+	return name, true
+}
+
+// HashValue returns a short stable hash of a label value keyed by salt.
+// Anonymized heat pump names are hashed the same way.
+func HashValue(v, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil)[:6])
+}
+
+// seriesKey identifies a series by name and labels.
+func seriesKey(name string, labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(name)
+	for _, n := range names {
+		b.WriteString("\xff" + n + "=" + labels[n])
+	}
+	return b.String()
+}
+
+// rebuild creates a const metric with the relabeled name and labels and the
+// value and timestamp of pb. ok is false for unsupported metric types.
+func rebuild(name, help string, labels map[string]string, pb *dto.Metric) (m prometheus.Metric, ok bool, err error) {
+	var valueType prometheus.ValueType
+	var value float64
+	switch {
+	case pb.Gauge != nil:
+		valueType, value = prometheus.GaugeValue, pb.GetGauge().GetValue()
+	case pb.Counter != nil:
+		valueType, value = prometheus.CounterValue, pb.GetCounter().GetValue()
+	case pb.Untyped != nil:
+		valueType, value = prometheus.UntypedValue, pb.GetUntyped().GetValue()
+	default:
+		return nil, false, nil
+	}
+
+	m, err = prometheus.NewConstMetric(prometheus.NewDesc(name, help, nil, labels), valueType, value)
+	if err != nil {
+		return nil, false, fmt.Errorf("rebuild %s: %w", name, err)
+	}
+	if ex := pb.GetCounter().GetExemplar(); ex != nil {
+		// Keep the link to the trace
+		exLabels := make(prometheus.Labels, len(ex.GetLabel()))
+		for _, lp := range ex.GetLabel() {
+			exLabels[lp.GetName()] = lp.GetValue()
+		}
+		m, err = prometheus.NewMetricWithExemplars(m, prometheus.Exemplar{Value: ex.GetValue(), Labels: exLabels, Timestamp: ex.GetTimestamp().AsTime()})
+		if err != nil {
+			return nil, false, fmt.Errorf("rebuild %s: %w", name, err)
+		}
+	}
+	if pb.TimestampMs != nil {
+		m = prometheus.NewMetricWithTimestamp(time.UnixMilli(pb.GetTimestampMs()), m)
+	}
+	return m, true, nil
+}
+
+// metricList is an unchecked collector emitting a fixed set of metrics.
+type metricList []prometheus.Metric
+
+func (metricList) Describe(chan<- *prometheus.Desc) {}
+
+func (ml metricList) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range ml {
+		ch <- m
+	}
+}
@@ -0,0 +1,187 @@
+package relabel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("drop:thermia_pool_.*; rename:thermia_(.*)_celsius=heatpump_${1}_celsius;replace:model:Diplomat.*=Diplomat")
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	want := []string{
+		"drop:thermia_pool_.*",
+		"rename:thermia_(.*)_celsius=heatpump_${1}_celsius",
+		"replace:model:Diplomat.*=Diplomat",
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules() returned %d rules, want %d", len(rules), len(want))
+	}
+	for i, r := range rules {
+		if r.String() != want[i] {
+			t.Errorf("rule %d = %q, want %q", i, r.String(), want[i])
+		}
+	}
+
+	for _, bad := range []string{"drop", "keep:x", "rename:x", "replace:x=y", "drop:("} {
+		if _, err := ParseRules(bad); err == nil {
+			t.Errorf("ParseRules(%q) should fail", bad)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	labels := []string{"heatpump_id", "model"}
+	pool := prometheus.NewDesc("thermia_pool_temperature_celsius", "Pool temperature", labels, nil)
+	outdoor := prometheus.NewDesc("thermia_outdoor_temperature_celsius", "Outdoor temperature", labels, nil)
+	starts := prometheus.NewDesc("thermia_compressor_starts_total", "Compressor starts", labels, nil)
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(pool, prometheus.GaugeValue, 27, "1", "Diplomat Optimum G3"),
+		prometheus.MustNewConstMetric(outdoor, prometheus.GaugeValue, 4.5, "1", "Diplomat Optimum G3"),
+		prometheus.MustNewConstMetric(starts, prometheus.CounterValue, 1200, "1", "Diplomat Optimum G3"),
+	}
+	rules, err := ParseRules("drop:thermia_pool_.*;rename:thermia_outdoor_(.*)=thermia_outside_$1;replace:model:Diplomat.*=Diplomat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Apply(rules, metrics)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(collector(got))
+	want := `
+# HELP thermia_compressor_starts_total Compressor starts
+# TYPE thermia_compressor_starts_total counter
+thermia_compressor_starts_total{heatpump_id="1",model="Diplomat"} 1200
+# HELP thermia_outside_temperature_celsius Outdoor temperature
+# TYPE thermia_outside_temperature_celsius gauge
+thermia_outside_temperature_celsius{heatpump_id="1",model="Diplomat"} 4.5
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseRedactLabels(t *testing.T) {
+	rules, err := ParseRedactLabels("heatpump_name, alert_title:drop,site:hash")
+	if err != nil {
+		t.Fatalf("ParseRedactLabels() error = %v", err)
+	}
+	want := []string{"hash:heatpump_name", "droplabel:alert_title", "hash:site"}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRedactLabels() returned %d rules, want %d", len(rules), len(want))
+	}
+	for i, r := range rules {
+		if r.String() != want[i] {
+			t.Errorf("rule %d = %q, want %q", i, r.String(), want[i])
+		}
+	}
+
+	for _, bad := range []string{"heatpump_name:mask", "heatpump-name"} {
+		if _, err := ParseRedactLabels(bad); err == nil {
+			t.Errorf("ParseRedactLabels(%q) should fail", bad)
+		}
+	}
+}
+
+func TestApply_RedactLabels(t *testing.T) {
+	labels := []string{"heatpump_id", "heatpump_name", "sensor"}
+	temp := prometheus.NewDesc("thermia_temperature_celsius", "Temperature", labels, nil)
+
+	metrics := []prometheus.Metric{
+		prometheus.MustNewConstMetric(temp, prometheus.GaugeValue, 21, "1", "Home", "indoor"),
+		prometheus.MustNewConstMetric(temp, prometheus.GaugeValue, 4, "1", "Home", "outdoor"),
+	}
+	rules, err := ParseRedactLabels("heatpump_name,sensor:drop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SaltHashes(rules, "s3cret")
+
+	got, err := Apply(rules, metrics)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// Without the sensor label both series collapse into the first
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(collector(got))
+	want := `
+# HELP thermia_temperature_celsius Temperature
+# TYPE thermia_temperature_celsius gauge
+thermia_temperature_celsius{heatpump_id="1",heatpump_name="` + HashValue("Home", "s3cret") + `"} 21
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHashValue_Salted(t *testing.T) {
+	if HashValue("Home", "a") == HashValue("Home", "b") {
+		t.Error("hashes with different salts are equal")
+	}
+	if HashValue("Home", "a") != HashValue("Home", "a") {
+		t.Error("hashes with the same salt differ")
+	}
+}
+
+func TestApply_KeepsExemplars(t *testing.T) {
+	desc := prometheus.NewDesc("thermia_scrape_errors_total", "Errors", nil, nil)
+	m, err := prometheus.NewMetricWithExemplars(prometheus.MustNewConstMetric(desc, prometheus.CounterValue, 1),
+		prometheus.Exemplar{Value: 1, Labels: prometheus.Labels{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRules("rename:thermia_(.*)=heatpump_${1}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Apply(rules, []prometheus.Metric{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pb dto.Metric
+	if err := out[0].Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+	if ex := pb.GetCounter().GetExemplar(); ex == nil || ex.GetLabel()[0].GetValue() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("exemplar = %v, want the trace ID", ex)
+	}
+}
+
+func TestApply_InvalidName(t *testing.T) {
+	desc := prometheus.NewDesc("thermia_online", "Online", nil, nil)
+	metrics := []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)}
+	rules, err := ParseRules("rename:thermia_online=thermia-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(rules, metrics); err == nil {
+		t.Error("Apply() should reject an invalid metric name")
+	}
+}
+
+// collector is a checked collector for the relabeled metrics.
+type collector []prometheus.Metric
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c {
+		ch <- m.Desc()
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
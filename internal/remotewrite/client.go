@@ -0,0 +1,95 @@
+// Package remotewrite implements a minimal Prometheus remote write 1.0 client.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sample is a single timestamped value.
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// TimeSeries is a labelled series of samples. Labels must include __name__.
+type TimeSeries struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// Client pushes samples to a remote write endpoint.
+type Client struct {
+	url        string
+	httpClient *http.Client
+	headers    map[string]string
+}
+
+// NewClient creates a remote write client for url.
+func NewClient(url string, timeout time.Duration) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+		headers:    make(map[string]string),
+	}
+}
+
+// SetHeader adds a header sent with every request (e.g. X-Scope-OrgID).
+func (c *Client) SetHeader(key, value string) {
+	c.headers[key] = value
+}
+
+// Write sends series in a single remote write request.
+func (c *Client) Write(ctx context.Context, series []TimeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	body := snappyEncode(encodeWriteRequest(series))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	return nil
+}
+
+// StatusError is returned by Write when the endpoint answers with a non-2xx
+// status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("remote write returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether resending the same request may succeed: server
+// errors and rate limiting are, other client errors mean the data itself was
+// rejected.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode/100 == 5 || e.StatusCode == http.StatusTooManyRequests
+}
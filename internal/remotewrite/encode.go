@@ -0,0 +1,96 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protobuf field numbers from prometheus/prompb (remote write 1.0).
+const (
+	fieldWriteRequestTimeseries = 1
+	fieldTimeSeriesLabels       = 1
+	fieldTimeSeriesSamples      = 2
+	fieldLabelName              = 1
+	fieldLabelValue             = 2
+	fieldSampleValue            = 1
+	fieldSampleTimestamp        = 2
+)
+
+// encodeWriteRequest marshals series as a prompb.WriteRequest.
+func encodeWriteRequest(series []TimeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = protowire.AppendTag(b, fieldWriteRequestTimeseries, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeTimeSeries(ts))
+	}
+	return b
+}
+
+// encodeTimeSeries marshals one prompb.TimeSeries. Labels are sorted by name
+// as required by the remote write spec.
+func encodeTimeSeries(ts TimeSeries) []byte {
+	names := make([]string, 0, len(ts.Labels))
+	for name := range ts.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b []byte
+	for _, name := range names {
+		var l []byte
+		l = protowire.AppendTag(l, fieldLabelName, protowire.BytesType)
+		l = protowire.AppendString(l, name)
+		l = protowire.AppendTag(l, fieldLabelValue, protowire.BytesType)
+		l = protowire.AppendString(l, ts.Labels[name])
+
+		b = protowire.AppendTag(b, fieldTimeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, l)
+	}
+
+	for _, s := range ts.Samples {
+		var sb []byte
+		sb = protowire.AppendTag(sb, fieldSampleValue, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(s.Value))
+		sb = protowire.AppendTag(sb, fieldSampleTimestamp, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(s.Timestamp.UnixMilli()))
+
+		b = protowire.AppendTag(b, fieldTimeSeriesSamples, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+
+	return b
+}
+
+// maxLiteral is the largest literal chunk emitted by snappyEncode.
+const maxLiteral = 1 << 16
+
+// snappyEncode frames src in the snappy block format using literals only.
+// The output is valid snappy that any decoder accepts; it trades compression
+// for not pulling in a compression dependency. Backfill volumes are small
+// enough that this does not matter.
+func snappyEncode(src []byte) []byte {
+	dst := protowire.AppendVarint(make([]byte, 0, len(src)+len(src)/maxLiteral*3+16), uint64(len(src)))
+
+	for len(src) > 0 {
+		n := len(src)
+		if n > maxLiteral {
+			n = maxLiteral
+		}
+
+		switch m := n - 1; {
+		case m < 60:
+			dst = append(dst, byte(m)<<2)
+		case m < 1<<8:
+			dst = append(dst, 60<<2, byte(m))
+		default:
+			dst = append(dst, 61<<2, byte(m), byte(m>>8))
+		}
+
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+
+	return dst
+}
@@ -0,0 +1,83 @@
+package remotewrite
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// snappyDecodeLiterals decodes the literal-only subset of snappy produced by
+// snappyEncode.
+func snappyDecodeLiterals(t *testing.T, src []byte) []byte {
+	t.Helper()
+
+	n, l := protowire.ConsumeVarint(src)
+	if l < 0 {
+		t.Fatal("bad length varint")
+	}
+	src = src[l:]
+
+	var out []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			t.Fatalf("unexpected non-literal tag %#x", tag)
+		}
+		m := int(tag >> 2)
+		src = src[1:]
+		switch m {
+		case 60:
+			m = int(src[0])
+			src = src[1:]
+		case 61:
+			m = int(src[0]) | int(src[1])<<8
+			src = src[2:]
+		}
+		out = append(out, src[:m+1]...)
+		src = src[m+1:]
+	}
+
+	if uint64(len(out)) != n {
+		t.Fatalf("decoded %d bytes, header says %d", len(out), n)
+	}
+	return out
+}
+
+func TestSnappyEncode_RoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 60, 61, 256, 257, maxLiteral, maxLiteral + 1, 3*maxLiteral + 7} {
+		src := bytes.Repeat([]byte{'x'}, size)
+		if got := snappyDecodeLiterals(t, snappyEncode(src)); !bytes.Equal(got, src) {
+			t.Errorf("round trip of %d bytes failed", size)
+		}
+	}
+}
+
+func TestSnappyEncode_SmallLiteral(t *testing.T) {
+	want := []byte{0x03, 0x08, 'a', 'b', 'c'}
+	if got := snappyEncode([]byte("abc")); !bytes.Equal(got, want) {
+		t.Errorf("snappyEncode(abc) = %x, want %x", got, want)
+	}
+}
+
+func TestEncodeTimeSeries_SortsLabels(t *testing.T) {
+	ts := TimeSeries{
+		Labels:  map[string]string{"heatpump_id": "1", "__name__": "m"},
+		Samples: []Sample{{Timestamp: time.UnixMilli(1000), Value: 1.5}},
+	}
+
+	b := encodeTimeSeries(ts)
+
+	// First label must be __name__
+	num, typ, n := protowire.ConsumeTag(b)
+	if num != fieldTimeSeriesLabels || typ != protowire.BytesType {
+		t.Fatalf("first field = %d/%d, want label", num, typ)
+	}
+	label, _ := protowire.ConsumeBytes(b[n:])
+	_, _, n = protowire.ConsumeTag(label)
+	name, _ := protowire.ConsumeString(label[n:])
+	if name != "__name__" {
+		t.Errorf("first label = %q, want __name__", name)
+	}
+}
@@ -0,0 +1,130 @@
+package sink
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+// Deadband drops samples whose value has not moved beyond a threshold since
+// it was last published, which keeps mostly static statuses off the wire.
+// A series is published again after fullInterval regardless, so consumers
+// that joined late (or lost retained state) converge and queries looking
+// back a few minutes keep finding it. Series not seen for longer than
+// fullInterval (removed installations, renamed rules) are forgotten.
+type Deadband struct {
+	mu           sync.Mutex
+	clock        clock.Clock
+	fallback     float64
+	thresholds   map[string]float64
+	fullInterval time.Duration
+	last         map[string]published
+	swept        time.Time
+}
+
+// published is the last published value of a series and when the series
+// was last selected.
+type published struct {
+	value float64
+	at    time.Time
+	seen  time.Time
+}
+
+// NewDeadband creates a filter with a default threshold applied to every
+// metric without a more specific one. A threshold of 0 publishes any change.
+func NewDeadband(threshold float64, fullInterval time.Duration, clk clock.Clock) *Deadband {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Deadband{
+		clock:        clk,
+		fallback:     threshold,
+		thresholds:   make(map[string]float64),
+		fullInterval: fullInterval,
+		last:         make(map[string]published),
+	}
+}
+
+// SetThreshold sets the threshold for metrics whose name ends with suffix
+// (for example "_celsius"). The longest matching suffix wins.
+func (d *Deadband) SetThreshold(suffix string, threshold float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.thresholds[suffix] = threshold
+}
+
+// Select returns the samples that should be published now. They are only
+// compared against later samples once Commit records them as published,
+// so samples that never reach the receiver are selected again.
+func (d *Deadband) Select(samples []Sample) []Sample {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	out := make([]Sample, 0, len(samples))
+	for _, s := range samples {
+		key := s.Key()
+		prev, seen := d.last[key]
+		if seen {
+			prev.seen = now
+			d.last[key] = prev
+		}
+		due := d.fullInterval > 0 && now.Sub(prev.at) >= d.fullInterval
+		if !seen || due || math.Abs(s.Value-prev.value) > d.threshold(s.Name) {
+			out = append(out, s)
+		}
+	}
+	d.sweep(now)
+	return out
+}
+
+// Commit records samples returned by Select as published.
+func (d *Deadband) Commit(samples []Sample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	for _, s := range samples {
+		d.last[s.Key()] = published{value: s.Value, at: now, seen: now}
+	}
+}
+
+// Forget drops what was published for the series of samples, so they are
+// selected again whatever their value, for example after their publication
+// was lost.
+func (d *Deadband) Forget(samples []Sample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, s := range samples {
+		delete(d.last, s.Key())
+	}
+}
+
+// sweep forgets series not selected for longer than fullInterval, at most once
+// per fullInterval. Caller must hold mu.
+func (d *Deadband) sweep(now time.Time) {
+	if d.fullInterval <= 0 || now.Sub(d.swept) < d.fullInterval {
+		return
+	}
+	for key, p := range d.last {
+		if now.Sub(p.seen) > d.fullInterval {
+			delete(d.last, key)
+		}
+	}
+	d.swept = now
+}
+
+// threshold returns the threshold for a metric name. Caller must hold mu.
+func (d *Deadband) threshold(name string) float64 {
+	best, bestLen := d.fallback, -1
+	for suffix, t := range d.thresholds {
+		if strings.HasSuffix(name, suffix) && len(suffix) > bestLen {
+			best, bestLen = t, len(suffix)
+		}
+	}
+	return best
+}
@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func temp(v float64) Sample {
+	return Sample{
+		Name:   "thermia_indoor_temperature_celsius",
+		Labels: map[string]string{"heatpump_id": "1"},
+		Value:  v,
+	}
+}
+
+func status(v float64) Sample {
+	return Sample{
+		Name:   "thermia_online",
+		Labels: map[string]string{"heatpump_id": "1"},
+		Value:  v,
+	}
+}
+
+// publish selects samples and records them as published, as a successful
+// write does.
+func publish(d *Deadband, samples []Sample) []Sample {
+	selected := d.Select(samples)
+	d.Commit(selected)
+	return selected
+}
+
+func TestDeadband_SuppressesSmallChanges(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeadband(0, 10*time.Minute, clk)
+	d.SetThreshold("_celsius", 0.1)
+
+	if got := publish(d, []Sample{temp(21.0), status(1)}); len(got) != 2 {
+		t.Fatalf("first publish = %d samples, want 2", len(got))
+	}
+
+	clk.Advance(time.Minute)
+	if got := publish(d, []Sample{temp(21.05), status(1)}); len(got) != 0 {
+		t.Errorf("unchanged publish = %d samples, want 0", len(got))
+	}
+
+	clk.Advance(time.Minute)
+	got := publish(d, []Sample{temp(21.2), status(0)})
+	if len(got) != 2 {
+		t.Errorf("changed publish = %d samples, want 2", len(got))
+	}
+}
+
+func TestDeadband_ComparesAgainstLastPublished(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeadband(0.1, time.Hour, clk)
+
+	publish(d, []Sample{temp(21.0)})
+	// Slow drift must still be published once it exceeds the deadband
+	publish(d, []Sample{temp(21.06)})
+	if got := publish(d, []Sample{temp(21.12)}); len(got) != 1 {
+		t.Errorf("drift beyond deadband = %d samples, want 1", len(got))
+	}
+}
+
+func TestDeadband_FullPublish(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeadband(0, 10*time.Minute, clk)
+
+	publish(d, []Sample{status(1)})
+
+	clk.Advance(9 * time.Minute)
+	if got := publish(d, []Sample{status(1)}); len(got) != 0 {
+		t.Errorf("before full interval = %d samples, want 0", len(got))
+	}
+
+	clk.Advance(time.Minute)
+	if got := publish(d, []Sample{status(1)}); len(got) != 1 {
+		t.Errorf("at full interval = %d samples, want 1", len(got))
+	}
+}
+
+func TestDeadband_FullPublishPerSeries(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeadband(0, 10*time.Minute, clk)
+
+	// Publishes filter one installation's samples at a time
+	publish(d, []Sample{temp(21)})
+	clk.Advance(5 * time.Minute)
+	publish(d, []Sample{status(1)})
+
+	clk.Advance(5 * time.Minute)
+	if got := publish(d, []Sample{temp(21)}); len(got) != 1 {
+		t.Errorf("series due for a full publish = %d samples, want 1", len(got))
+	}
+	if got := publish(d, []Sample{status(1)}); len(got) != 0 {
+		t.Errorf("series published 5m ago = %d samples, want 0", len(got))
+	}
+}
+
+func TestDeadband_ForgetsUnseenSeries(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeadband(0, 10*time.Minute, clk)
+
+	publish(d, []Sample{temp(21), status(1)})
+
+	// The temperature series keeps being filtered (and suppressed), the
+	// status series disappears
+	for i := 0; i < 4; i++ {
+		clk.Advance(3 * time.Minute)
+		publish(d, []Sample{temp(21)})
+	}
+	if _, ok := d.last[status(1).Key()]; ok {
+		t.Error("series unseen for longer than the full interval should be forgotten")
+	}
+	if _, ok := d.last[temp(21).Key()]; !ok {
+		t.Error("series still filtered should be kept")
+	}
+}
+
+func TestSample_KeyIsLabelOrderIndependent(t *testing.T) {
+	a := Sample{Name: "m", Labels: map[string]string{"a": "1", "b": "2"}}
+	b := Sample{Name: "m", Labels: map[string]string{"b": "2", "a": "1"}}
+	if a.Key() != b.Key() {
+		t.Errorf("Key() differs: %q vs %q", a.Key(), b.Key())
+	}
+}
@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"thermia_exporter/internal/snapshot"
+)
+
+// Pushgateway publishes snapshots to a Prometheus Pushgateway. Each publish
+// replaces the metrics of the job's group, so the gateway always holds the
+// latest collection of every installation.
+type Pushgateway struct {
+	url     string
+	job     string
+	timeout time.Duration
+}
+
+// NewPushgateway creates a Pushgateway sink pushing to the gateway at url
+// (e.g. http://pushgateway:9091) under job.
+func NewPushgateway(url, job string, timeout time.Duration) *Pushgateway {
+	return &Pushgateway{url: url, job: job, timeout: timeout}
+}
+
+// Name implements Sink.
+func (p *Pushgateway) Name() string {
+	return "pushgateway"
+}
+
+// Publish implements Sink. The gateway rejects samples with timestamps and
+// stamps pushes itself (push_time_seconds), so collection times are not
+// sent.
+func (p *Pushgateway) Publish(ctx context.Context, snaps []snapshot.Snapshot) error {
+	var metrics metricsCollector
+	for _, snap := range snaps {
+		metrics = append(metrics, snap.Metrics...)
+	}
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(metrics); err != nil {
+		return err
+	}
+	return push.New(p.url, p.job).
+		Gatherer(reg).
+		Client(&http.Client{Timeout: p.timeout}).
+		PushContext(ctx)
+}
+
+// Close implements Sink. Pushes are synchronous and the gateway keeps the
+// last push, so there is nothing to flush.
+func (p *Pushgateway) Close(ctx context.Context) error {
+	return nil
+}
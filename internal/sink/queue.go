@@ -0,0 +1,146 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/remotewrite"
+)
+
+var (
+	droppedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "thermia_sink_dropped_samples_total",
+		Help: "Samples a push sink gave up on: evicted from a full offline queue or rejected by the endpoint",
+	}, []string{"sink"})
+	queuedSamples = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thermia_sink_queued_samples",
+		Help: "Samples held in a push sink's offline queue awaiting delivery",
+	}, []string{"sink"})
+)
+
+// Metrics returns the sinks' self-metrics.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{droppedSamples, queuedSamples}
+}
+
+// DropPolicy selects what a full queue discards.
+type DropPolicy string
+
+const (
+	// DropOldest evicts the oldest queued samples to make room.
+	DropOldest DropPolicy = "oldest"
+	// DropNewest keeps the queued samples and discards the incoming ones.
+	DropNewest DropPolicy = "newest"
+)
+
+// ParseDropPolicy validates a drop policy name (case-insensitive).
+func ParseDropPolicy(s string) (DropPolicy, error) {
+	switch p := DropPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case DropOldest, DropNewest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown drop policy %q (use %q or %q)", s, DropOldest, DropNewest)
+}
+
+// Queue buffers remote write batches in memory while the endpoint is
+// unreachable and replays them in order once it is back. It is bounded by
+// a number of samples; there is no disk spool, so queued samples are lost
+// on restart. A Queue is not safe for concurrent use; the Dispatcher
+// serializes publishes.
+type Queue struct {
+	max     int
+	policy  DropPolicy
+	batches [][]remotewrite.TimeSeries
+	samples int
+
+	dropped prometheus.Counter
+	queued  prometheus.Gauge
+}
+
+// NewQueue creates a queue holding at most max samples for the named sink.
+func NewQueue(name string, max int, policy DropPolicy) *Queue {
+	return &Queue{
+		max:     max,
+		policy:  policy,
+		dropped: droppedSamples.WithLabelValues(name),
+		queued:  queuedSamples.WithLabelValues(name),
+	}
+}
+
+// Len returns the number of queued samples.
+func (q *Queue) Len() int {
+	return q.samples
+}
+
+// push appends batch, applying the drop policy if the queue overflows. It
+// reports whether batch was queued and returns the older batches evicted
+// to make room for it.
+func (q *Queue) push(batch []remotewrite.TimeSeries) (bool, [][]remotewrite.TimeSeries) {
+	n := countSamples(batch)
+	if n == 0 {
+		return true, nil
+	}
+	if q.policy == DropNewest && len(q.batches) > 0 && q.samples+n > q.max {
+		q.dropped.Add(float64(n))
+		return false, nil
+	}
+	q.batches = append(q.batches, batch)
+	q.samples += n
+	// Under DropOldest a single oversized batch is still kept, so the
+	// latest collection is always attempted.
+	var evicted [][]remotewrite.TimeSeries
+	for q.samples > q.max && len(q.batches) > 1 {
+		evicted = append(evicted, q.batches[0])
+		q.drop()
+	}
+	q.queued.Set(float64(q.samples))
+	return true, evicted
+}
+
+// drop removes the oldest batch and counts its samples as dropped.
+func (q *Queue) drop() {
+	n := countSamples(q.batches[0])
+	q.dropped.Add(float64(n))
+	q.pop()
+}
+
+// pop removes the oldest batch.
+func (q *Queue) pop() {
+	q.samples -= countSamples(q.batches[0])
+	q.batches[0] = nil
+	q.batches = q.batches[1:]
+	q.queued.Set(float64(q.samples))
+}
+
+// flush writes queued batches oldest first, stopping at the first
+// retryable failure so samples reach the endpoint in order. Batches the
+// endpoint rejects outright are dropped and reported.
+func (q *Queue) flush(ctx context.Context, client *remotewrite.Client) error {
+	var rejected error
+	for len(q.batches) > 0 {
+		err := client.Write(ctx, q.batches[0])
+		var status *remotewrite.StatusError
+		switch {
+		case err == nil:
+			q.pop()
+		case errors.As(err, &status) && !status.Retryable():
+			q.drop()
+			rejected = err
+		default:
+			return err
+		}
+	}
+	return rejected
+}
+
+func countSamples(batch []remotewrite.TimeSeries) int {
+	n := 0
+	for _, ts := range batch {
+		n += len(ts.Samples)
+	}
+	return n
+}
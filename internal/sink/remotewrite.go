@@ -0,0 +1,114 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"thermia_exporter/internal/remotewrite"
+	"thermia_exporter/internal/snapshot"
+)
+
+// RemoteWrite publishes snapshots to a Prometheus remote write endpoint.
+type RemoteWrite struct {
+	client   *remotewrite.Client
+	deadband *Deadband
+	queue    *Queue
+}
+
+// NewRemoteWrite creates a remote write sink. deadband is optional; without
+// it every sample is published. queue is optional; without it samples that
+// fail to send are lost.
+func NewRemoteWrite(client *remotewrite.Client, deadband *Deadband, queue *Queue) *RemoteWrite {
+	return &RemoteWrite{client: client, deadband: deadband, queue: queue}
+}
+
+// Name implements Sink.
+func (r *RemoteWrite) Name() string {
+	return "remote_write"
+}
+
+// Publish implements Sink. Samples are timestamped with their snapshot's
+// collection time. With a queue, earlier undelivered batches are replayed
+// first and a failed batch is queued for the next publish. The deadband
+// only records samples as published once they were written or queued, and
+// forgets the series of batches evicted from the queue, so lost samples
+// are sent again on the next publish.
+func (r *RemoteWrite) Publish(ctx context.Context, snaps []snapshot.Snapshot) error {
+	var series []remotewrite.TimeSeries
+	var selected []Sample
+	for _, snap := range snaps {
+		samples, err := SnapshotSamples(snap)
+		if err != nil {
+			return err
+		}
+		if r.deadband != nil {
+			samples = r.deadband.Select(samples)
+			selected = append(selected, samples...)
+		}
+
+		for _, s := range samples {
+			labels := make(map[string]string, len(s.Labels)+1)
+			for k, v := range s.Labels {
+				labels[k] = v
+			}
+			labels["__name__"] = s.Name
+			series = append(series, remotewrite.TimeSeries{
+				Labels:  labels,
+				Samples: []remotewrite.Sample{{Timestamp: snap.CollectedAt, Value: s.Value}},
+			})
+		}
+	}
+	if r.queue == nil {
+		if err := r.client.Write(ctx, series); err != nil {
+			return err
+		}
+		r.commit(selected)
+		return nil
+	}
+	queued, evicted := r.queue.push(series)
+	if queued {
+		r.commit(selected)
+	}
+	if r.deadband != nil {
+		for _, batch := range evicted {
+			r.deadband.Forget(batchSamples(batch))
+		}
+	}
+	return r.queue.flush(ctx, r.client)
+}
+
+// commit records samples as published in the deadband, if there is one.
+func (r *RemoteWrite) commit(samples []Sample) {
+	if r.deadband != nil {
+		r.deadband.Commit(samples)
+	}
+}
+
+// batchSamples returns the samples of a remote write batch.
+func batchSamples(batch []remotewrite.TimeSeries) []Sample {
+	samples := make([]Sample, 0, len(batch))
+	for _, ts := range batch {
+		labels := make(map[string]string, len(ts.Labels))
+		for k, v := range ts.Labels {
+			if k != "__name__" {
+				labels[k] = v
+			}
+		}
+		for _, s := range ts.Samples {
+			samples = append(samples, Sample{Name: ts.Labels["__name__"], Labels: labels, Value: s.Value})
+		}
+	}
+	return samples
+}
+
+// Close implements Sink. Writes are synchronous; only queued batches are
+// pending, and they get one last delivery attempt.
+func (r *RemoteWrite) Close(ctx context.Context) error {
+	if r.queue == nil || r.queue.Len() == 0 {
+		return nil
+	}
+	if err := r.queue.flush(ctx, r.client); err != nil {
+		return fmt.Errorf("%d queued samples not delivered: %w", r.queue.Len(), err)
+	}
+	return nil
+}
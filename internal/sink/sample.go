@@ -0,0 +1,87 @@
+// Package sink contains push destinations for collected heat pump data and
+// the helpers they share.
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/snapshot"
+)
+
+// Sample is a single metric value published to a push sink.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Key returns a stable identity for the sample's series (name plus sorted labels).
+func (s Sample) Key() string {
+	names := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(s.Name)
+	for _, k := range names {
+		b.WriteByte('|')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(s.Labels[k])
+	}
+	return b.String()
+}
+
+// SnapshotSamples converts the metrics of a snapshot to samples. Only gauge,
+// counter and untyped metrics are converted; the collector emits no others.
+func SnapshotSamples(snap snapshot.Snapshot) ([]Sample, error) {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(metricsCollector(snap.Metrics)); err != nil {
+		return nil, err
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("gather snapshot %d: %w", snap.InstallationID, err)
+	}
+
+	var samples []Sample
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var value float64
+			switch {
+			case m.GetGauge() != nil:
+				value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				value = m.GetCounter().GetValue()
+			case m.GetUntyped() != nil:
+				value = m.GetUntyped().GetValue()
+			default:
+				continue
+			}
+
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			samples = append(samples, Sample{Name: mf.GetName(), Labels: labels, Value: value})
+		}
+	}
+	return samples, nil
+}
+
+// metricsCollector is an unchecked collector emitting a fixed set of metrics.
+type metricsCollector []prometheus.Metric
+
+func (metricsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (mc metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range mc {
+		ch <- m
+	}
+}
@@ -0,0 +1,123 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/snapshot"
+)
+
+// Sink is a push destination for collected heat pump data (MQTT, remote
+// write, InfluxDB, ...).
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+
+	// Publish sends the latest snapshots of all installations.
+	Publish(ctx context.Context, snaps []snapshot.Snapshot) error
+
+	// Close flushes pending publishes, marks the device unavailable where
+	// the protocol supports it (e.g. an MQTT availability topic) and releases
+	// resources. It must return once ctx is done.
+	Close(ctx context.Context) error
+}
+
+// Source provides the snapshots to publish: a *snapshot.Store or a
+// snapshot.Set.
+type Source interface {
+	All() []snapshot.Snapshot
+	Version() uint64
+}
+
+// Dispatcher fans collected snapshots out to all configured sinks.
+type Dispatcher struct {
+	mu          sync.Mutex
+	store       Source
+	sinks       []Sink
+	info        []prometheus.Metric
+	logger      *slog.Logger
+	clock       clock.Clock
+	lastVersion uint64
+}
+
+// NewDispatcher creates a dispatcher publishing snapshots from store. clk
+// timestamps the exporter's own metrics (nil: real time).
+func NewDispatcher(store Source, logger *slog.Logger, clk clock.Clock, sinks ...Sink) *Dispatcher {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Dispatcher{store: store, sinks: sinks, logger: logger, clock: clk}
+}
+
+// SetInfo adds metrics about the exporter itself, such as its build info,
+// to every publish alongside the heat pump snapshots.
+func (d *Dispatcher) SetInfo(metrics ...prometheus.Metric) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.info = metrics
+}
+
+// Len returns the number of configured sinks.
+func (d *Dispatcher) Len() int {
+	return len(d.sinks)
+}
+
+// Publish sends the current snapshots to every sink if the store changed
+// since the last publish. A failing sink is logged and does not affect the
+// others.
+func (d *Dispatcher) Publish(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	version := d.store.Version()
+	if version == d.lastVersion {
+		return
+	}
+	d.lastVersion = version
+
+	snaps := d.store.All()
+	if len(d.info) > 0 {
+		snaps = append(snaps, snapshot.Snapshot{CollectedAt: d.clock.Now(), Metrics: d.info})
+	}
+	for _, s := range d.sinks {
+		if err := s.Publish(ctx, snaps); err != nil {
+			d.logger.Warn("Sink publish failed", "sink", s.Name(), "error", err)
+		}
+	}
+}
+
+// Run publishes every interval until ctx is done, for deployments that
+// push on a fixed schedule rather than after every collection.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Publish(ctx)
+		}
+	}
+}
+
+// Close closes all sinks, giving each the remaining time in ctx.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, s := range d.sinks {
+		if err := s.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,268 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/clock"
+	"thermia_exporter/internal/remotewrite"
+	"thermia_exporter/internal/snapshot"
+	"thermia_exporter/internal/types"
+)
+
+type fakeSink struct {
+	name      string
+	published int
+	last      []snapshot.Snapshot
+	closed    bool
+	err       error
+}
+
+func (f *fakeSink) Name() string { return f.name }
+
+func (f *fakeSink) Publish(ctx context.Context, snaps []snapshot.Snapshot) error {
+	f.published++
+	f.last = snaps
+	return f.err
+}
+
+func (f *fakeSink) Close(ctx context.Context) error {
+	f.closed = true
+	return f.err
+}
+
+func TestDispatcher_PublishOnChange(t *testing.T) {
+	store := snapshot.NewStore()
+	a := &fakeSink{name: "a", err: errors.New("down")}
+	b := &fakeSink{name: "b"}
+	d := NewDispatcher(store, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, a, b)
+
+	store.Put(1, time.Now(), types.ThermiaSummary{}, nil)
+	d.Publish(context.Background())
+	d.Publish(context.Background())
+
+	if a.published != 1 || b.published != 1 {
+		t.Errorf("published = %d/%d, want 1/1 (unchanged store is not republished)", a.published, b.published)
+	}
+
+	store.Put(1, time.Now(), types.ThermiaSummary{}, nil)
+	d.Publish(context.Background())
+	if b.published != 2 {
+		t.Errorf("published = %d after store change, want 2", b.published)
+	}
+}
+
+func TestDispatcher_Info(t *testing.T) {
+	store := snapshot.NewStore()
+	s := &fakeSink{name: "a"}
+	clk := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	d := NewDispatcher(store, slog.New(slog.NewTextHandler(io.Discard, nil)), clk, s)
+	info := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "thermia_exporter_build_info",
+		Help:        "Build info",
+		ConstLabels: prometheus.Labels{"instance_id": "4b1c"},
+	})
+	info.Set(1)
+	d.SetInfo(info)
+
+	store.Put(1, time.Now(), types.ThermiaSummary{}, nil)
+	d.Publish(context.Background())
+
+	if len(s.last) != 2 {
+		t.Fatalf("published %d snapshots, want the installation and the info", len(s.last))
+	}
+	if !s.last[1].CollectedAt.Equal(clk.Now()) {
+		t.Errorf("info collected at %v, want %v", s.last[1].CollectedAt, clk.Now())
+	}
+	samples, err := SnapshotSamples(s.last[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Name != "thermia_exporter_build_info" || samples[0].Labels["instance_id"] != "4b1c" {
+		t.Errorf("info samples = %+v, want thermia_exporter_build_info{instance_id=\"4b1c\"}", samples)
+	}
+}
+
+func TestDispatcher_CloseAll(t *testing.T) {
+	a := &fakeSink{name: "a", err: errors.New("flush failed")}
+	b := &fakeSink{name: "b"}
+	d := NewDispatcher(snapshot.NewStore(), slog.New(slog.NewTextHandler(io.Discard, nil)), nil, a, b)
+
+	err := d.Close(context.Background())
+	if err == nil {
+		t.Error("Close() should report the failing sink")
+	}
+	if !a.closed || !b.closed {
+		t.Error("every sink should be closed even if one fails")
+	}
+}
+
+func TestSnapshotSamples(t *testing.T) {
+	desc := prometheus.NewDesc("thermia_outdoor_temperature_celsius", "Outdoor temperature", []string{"heatpump_id"}, nil)
+	snap := snapshot.Snapshot{
+		InstallationID: 1,
+		Metrics: []prometheus.Metric{
+			prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, -3.5, "1"),
+		},
+	}
+
+	samples, err := SnapshotSamples(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("samples = %d, want 1", len(samples))
+	}
+	s := samples[0]
+	if s.Name != "thermia_outdoor_temperature_celsius" || s.Labels["heatpump_id"] != "1" || s.Value != -3.5 {
+		t.Errorf("sample = %+v", s)
+	}
+}
+
+func TestRemoteWrite_QueueReplay(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	writes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusNoContent {
+			writes++
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	desc := prometheus.NewDesc("thermia_outdoor_temperature_celsius", "Outdoor temperature", []string{"heatpump_id"}, nil)
+	snaps := func(v float64) []snapshot.Snapshot {
+		return []snapshot.Snapshot{{
+			InstallationID: 1,
+			CollectedAt:    time.Now(),
+			Metrics:        []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, "1")},
+		}}
+	}
+
+	queue := NewQueue("test_replay", 2, DropOldest)
+	rw := NewRemoteWrite(remotewrite.NewClient(srv.URL, time.Second), nil, queue)
+	for _, v := range []float64{1, 2, 3} {
+		if err := rw.Publish(context.Background(), snaps(v)); err == nil {
+			t.Fatal("Publish() should fail while the endpoint is down")
+		}
+	}
+	if queue.Len() != 2 {
+		t.Fatalf("queued = %d, want 2 (oldest sample dropped)", queue.Len())
+	}
+
+	status = http.StatusNoContent
+	if err := rw.Publish(context.Background(), snaps(4)); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if queue.Len() != 0 || writes != 2 {
+		t.Errorf("queued = %d, writes = %d, want 0 and 2 (the queued batch, then the new one)", queue.Len(), writes)
+	}
+}
+
+func TestRemoteWrite_DeadbandResendsLostSamples(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	writes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusNoContent {
+			writes++
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	temp := prometheus.NewDesc("thermia_outdoor_temperature_celsius", "Outdoor temperature", []string{"heatpump_id"}, nil)
+	online := prometheus.NewDesc("thermia_online", "Online", []string{"heatpump_id"}, nil)
+	snaps := func(descs ...*prometheus.Desc) []snapshot.Snapshot {
+		var metrics []prometheus.Metric
+		for _, desc := range descs {
+			metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, "1"))
+		}
+		return []snapshot.Snapshot{{InstallationID: 1, CollectedAt: time.Now(), Metrics: metrics}}
+	}
+	clk := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("failed write", func(t *testing.T) {
+		status, writes = http.StatusServiceUnavailable, 0
+		rw := NewRemoteWrite(remotewrite.NewClient(srv.URL, time.Second), NewDeadband(0.1, time.Hour, clk), nil)
+		if err := rw.Publish(context.Background(), snaps(temp)); err == nil {
+			t.Fatal("Publish() should fail while the endpoint is down")
+		}
+		status = http.StatusNoContent
+		for i := 0; i < 2; i++ {
+			if err := rw.Publish(context.Background(), snaps(temp)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if writes != 1 {
+			t.Errorf("writes = %d, want 1: the lost sample once, then nothing unchanged", writes)
+		}
+	})
+
+	t.Run("evicted from the queue", func(t *testing.T) {
+		status, writes = http.StatusServiceUnavailable, 0
+		rw := NewRemoteWrite(remotewrite.NewClient(srv.URL, time.Second), NewDeadband(0.1, time.Hour, clk), NewQueue("test_deadband", 1, DropOldest))
+		rw.Publish(context.Background(), snaps(temp))
+		// Evicts the temperature batch
+		rw.Publish(context.Background(), snaps(online))
+
+		// The evicted temperature is selected again and evicts the status,
+		// which is selected again on the next publish
+		status = http.StatusNoContent
+		for i := 0; i < 3; i++ {
+			if err := rw.Publish(context.Background(), snaps(temp, online)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if writes != 2 {
+			t.Errorf("writes = %d, want 2: the temperature, then the status, then nothing unchanged", writes)
+		}
+	})
+}
+
+func TestQueue_DropNewest(t *testing.T) {
+	batch := func(v float64) []remotewrite.TimeSeries {
+		return []remotewrite.TimeSeries{{Samples: []remotewrite.Sample{{Value: v}}}}
+	}
+	q := NewQueue("test_newest", 2, DropNewest)
+	for _, v := range []float64{1, 2, 3} {
+		q.push(batch(v))
+	}
+	if q.Len() != 2 || q.batches[0][0].Samples[0].Value != 1 {
+		t.Errorf("queue = %d samples starting at %v, want the 2 oldest", q.Len(), q.batches[0][0].Samples[0].Value)
+	}
+}
+
+func TestPushgateway_Publish(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	desc := prometheus.NewDesc("thermia_outdoor_temperature_celsius", "Outdoor temperature", []string{"heatpump_id"}, nil)
+	snaps := []snapshot.Snapshot{
+		{InstallationID: 1, Metrics: []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, -3.5, "1")}},
+		{InstallationID: 2, Metrics: []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 4, "2")}},
+	}
+
+	pg := NewPushgateway(srv.URL, "thermia_exporter", time.Second)
+	if err := pg.Publish(context.Background(), snaps); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/thermia_exporter" {
+		t.Errorf("request = %s %s, want PUT /metrics/job/thermia_exporter (replacing the group)", method, path)
+	}
+	if body == "" {
+		t.Error("push body is empty")
+	}
+}
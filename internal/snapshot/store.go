@@ -0,0 +1,169 @@
+// Package snapshot holds the latest collected data per installation. The
+// collector is the only writer; /metrics, JSON endpoints and push sinks all
+// read from the same store instead of triggering their own API fetches.
+package snapshot
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"thermia_exporter/internal/types"
+)
+
+// Snapshot is the result of one collection for one installation.
+// Snapshots are immutable once stored.
+type Snapshot struct {
+	InstallationID int64
+	Version        uint64
+	CollectedAt    time.Time
+	Summary        types.ThermiaSummary
+	Metrics        []prometheus.Metric
+}
+
+// Store is a versioned, concurrency-safe map of installation snapshots.
+type Store struct {
+	mu        sync.RWMutex
+	version   uint64
+	snapshots map[int64]*Snapshot
+	changed   chan struct{}
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{snapshots: make(map[int64]*Snapshot), changed: make(chan struct{})}
+}
+
+// Changed returns a channel that is closed on the next modification of the
+// store, for readers that wait for new data instead of polling Version.
+func (s *Store) Changed() <-chan struct{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.changed
+}
+
+// notify wakes the readers waiting on Changed. Caller must hold mu.
+func (s *Store) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Put stores a new snapshot for an installation and returns its version.
+// Versions increase monotonically across the whole store.
+func (s *Store) Put(id int64, collectedAt time.Time, summary types.ThermiaSummary, metrics []prometheus.Metric) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	s.snapshots[id] = &Snapshot{
+		InstallationID: id,
+		Version:        s.version,
+		CollectedAt:    collectedAt,
+		Summary:        summary,
+		Metrics:        metrics,
+	}
+	s.notify()
+	return s.version
+}
+
+// Retain drops snapshots for installations not in ids, e.g. after an
+// installation was removed from the account.
+func (s *Store) Retain(ids []int64) {
+	keep := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := false
+	for id := range s.snapshots {
+		if !keep[id] {
+			delete(s.snapshots, id)
+			s.version++
+			removed = true
+		}
+	}
+	if removed {
+		s.notify()
+	}
+}
+
+// Get returns the snapshot for an installation.
+func (s *Store) Get(id int64) (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return Snapshot{}, false
+	}
+	return *snap, true
+}
+
+// All returns all snapshots ordered by installation ID.
+func (s *Store) All() []Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]Snapshot, 0, len(s.snapshots))
+	for _, snap := range s.snapshots {
+		all = append(all, *snap)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].InstallationID < all[j].InstallationID })
+	return all
+}
+
+// Version returns the store version, which changes on every modification.
+func (s *Store) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Set reads several stores (e.g. one per account) as one.
+type Set []*Store
+
+// All returns the snapshots of every store ordered by installation ID.
+func (ss Set) All() []Snapshot {
+	var all []Snapshot
+	for _, s := range ss {
+		all = append(all, s.All()...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].InstallationID < all[j].InstallationID })
+	return all
+}
+
+// Version returns a version that changes whenever any store changes.
+func (ss Set) Version() uint64 {
+	var v uint64
+	for _, s := range ss {
+		v += s.Version()
+	}
+	return v
+}
+
+// Changed returns a channel that is closed on the next modification of any
+// store. Until then, or until stop is closed, it holds one goroutine per
+// store, so callers that stop waiting must close stop.
+func (ss Set) Changed(stop <-chan struct{}) <-chan struct{} {
+	if len(ss) == 1 {
+		return ss[0].Changed()
+	}
+	changed := make(chan struct{})
+	var once sync.Once
+	for _, s := range ss {
+		go func(ch <-chan struct{}) {
+			select {
+			case <-ch:
+				once.Do(func() { close(changed) })
+			case <-changed:
+			case <-stop:
+			}
+		}(s.Changed())
+	}
+	return changed
+}
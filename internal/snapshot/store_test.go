@@ -0,0 +1,170 @@
+package snapshot
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/types"
+)
+
+func TestStore_PutGet(t *testing.T) {
+	s := NewStore()
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := s.Get(1); ok {
+		t.Fatal("Get() on empty store returned a snapshot")
+	}
+
+	v1 := s.Put(1, at, types.ThermiaSummary{HeatpumpName: "first"}, nil)
+	v2 := s.Put(1, at.Add(time.Minute), types.ThermiaSummary{HeatpumpName: "second"}, nil)
+	if v2 <= v1 {
+		t.Errorf("versions not increasing: %d then %d", v1, v2)
+	}
+
+	snap, ok := s.Get(1)
+	if !ok {
+		t.Fatal("Get() = not found")
+	}
+	if snap.Summary.HeatpumpName != "second" || snap.Version != v2 {
+		t.Errorf("Get() = %+v, want latest snapshot", snap)
+	}
+	if s.Version() != v2 {
+		t.Errorf("Version() = %d, want %d", s.Version(), v2)
+	}
+}
+
+func TestStore_AllOrderedAndRetain(t *testing.T) {
+	s := NewStore()
+	now := time.Now()
+	s.Put(30, now, types.ThermiaSummary{}, nil)
+	s.Put(10, now, types.ThermiaSummary{}, nil)
+	s.Put(20, now, types.ThermiaSummary{}, nil)
+
+	all := s.All()
+	if len(all) != 3 || all[0].InstallationID != 10 || all[2].InstallationID != 30 {
+		t.Fatalf("All() order = %v", all)
+	}
+
+	before := s.Version()
+	s.Retain([]int64{20})
+	if all := s.All(); len(all) != 1 || all[0].InstallationID != 20 {
+		t.Errorf("after Retain, All() = %v, want only 20", all)
+	}
+	if s.Version() == before {
+		t.Error("Retain() removing snapshots should change the version")
+	}
+}
+
+func TestSet(t *testing.T) {
+	a, b := NewStore(), NewStore()
+	now := time.Now()
+	a.Put(30, now, types.ThermiaSummary{}, nil)
+	b.Put(10, now, types.ThermiaSummary{}, nil)
+	set := Set{a, b}
+
+	all := set.All()
+	if len(all) != 2 || all[0].InstallationID != 10 || all[1].InstallationID != 30 {
+		t.Fatalf("All() = %v, want 10 and 30", all)
+	}
+
+	before := set.Version()
+	b.Put(20, now, types.ThermiaSummary{}, nil)
+	if set.Version() == before {
+		t.Error("Version() should change when any store changes")
+	}
+}
+
+func TestStore_Changed(t *testing.T) {
+	closed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	a, b := NewStore(), NewStore()
+
+	changed := a.Changed()
+	a.Put(1, time.Now(), types.ThermiaSummary{}, nil)
+	if !closed(changed) {
+		t.Error("Changed() not closed by Put()")
+	}
+	changed = a.Changed()
+	a.Retain([]int64{1})
+	select {
+	case <-changed:
+		t.Error("Changed() closed by a Retain() that removed nothing")
+	default:
+	}
+	a.Retain(nil)
+	if !closed(changed) {
+		t.Error("Changed() not closed by Retain()")
+	}
+
+	changed = Set{a, b}.Changed(nil)
+	b.Put(2, time.Now(), types.ThermiaSummary{}, nil)
+	if !closed(changed) {
+		t.Error("Set.Changed() not closed by a change of its second store")
+	}
+}
+
+func TestSet_ChangedStop(t *testing.T) {
+	set := Set{NewStore(), NewStore()}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		stop := make(chan struct{})
+		set.Changed(stop)
+		close(stop)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after closing stop, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStore_ReturnsCopies(t *testing.T) {
+	s := NewStore()
+	s.Put(1, time.Now(), types.ThermiaSummary{HeatpumpName: "orig"}, nil)
+
+	snap, _ := s.Get(1)
+	snap.Summary.HeatpumpName = "mutated"
+
+	if again, _ := s.Get(1); again.Summary.HeatpumpName != "orig" {
+		t.Error("mutating a returned snapshot changed the store")
+	}
+}
+
+func TestStore_ConcurrentAccess(t *testing.T) {
+	s := NewStore()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(id int64) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Put(id, time.Now(), types.ThermiaSummary{}, nil)
+			}
+		}(int64(i))
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.All()
+				s.Version()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Version() != 800 {
+		t.Errorf("Version() = %d, want 800", s.Version())
+	}
+}
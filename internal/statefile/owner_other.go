@@ -0,0 +1,10 @@
+//go:build !unix
+
+package statefile
+
+import "io/fs"
+
+// owner reports no owner where files have no uid.
+func owner(fs.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package statefile
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// owner returns the uid owning the file of info.
+func owner(info fs.FileInfo) (int, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
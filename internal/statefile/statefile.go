@@ -0,0 +1,86 @@
+// Package statefile checks and writes the files the exporter persists state
+// to: the token cache, the instance ID and pending overrides. A file other
+// users can read or that belongs to someone else fails startup instead of
+// leaking tokens, and so does a directory the exporter cannot write to,
+// instead of failing on the first write.
+package statefile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Check verifies that path is usable as a state file: its directory exists
+// and is writable, and the file, if it exists, is a regular file owned by
+// the current user and not accessible by group or others. An empty path is
+// not checked.
+func Check(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory of %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("directory of %s: %s is not a directory", path, dir)
+	}
+	// The files are written to a temporary file and renamed into place
+	probe, err := os.CreateTemp(dir, ".thermia_exporter-*")
+	if err != nil {
+		return fmt.Errorf("directory of %s is not writable: %w", path, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	info, err = os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if uid, ok := owner(info); ok && uid != os.Geteuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by the exporter's uid %d", path, uid, os.Geteuid())
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by group or others (mode %04o), restrict it to mode 0600", path, perm)
+	}
+	return nil
+}
+
+// WriteFile replaces the content of path with data, readable by the
+// exporter's user only. The data is written to a temporary file, synced
+// and renamed into place, so a crash never leaves a truncated file; the
+// temporary file is removed if any step fails.
+func WriteFile(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,93 @@
+package statefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks do not apply to root")
+	}
+	dir := t.TempDir()
+	readOnly := filepath.Join(dir, "readonly")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"not configured", "", false},
+		{"new file", filepath.Join(dir, "token.json"), false},
+		{"private file", write("private.json", 0o600), false},
+		{"group readable file", write("group.json", 0o640), true},
+		{"world readable file", write("world.json", 0o644), true},
+		{"directory instead of file", dir, true},
+		{"missing directory", filepath.Join(dir, "missing", "token.json"), true},
+		{"read-only directory", filepath.Join(readOnly, "token.json"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("Check(%q) error = %v, want error %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 4 {
+		t.Errorf("Check() left files behind: %d entries in %s", len(entries), dir)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("content = %q, %v, want %q", data, err, content)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %04o, want 0600", perm)
+	}
+	if err := Check(path); err != nil {
+		t.Errorf("Check() of a written file = %v", err)
+	}
+
+	// A failed rename leaves no temporary file behind
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(sub, []byte("x")); err == nil {
+		t.Error("WriteFile() over a directory expected error")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d entries, want only the written file and the subdirectory", len(entries))
+	}
+}
@@ -0,0 +1,96 @@
+// Package tlswatch observes the certificates served by upstream endpoints,
+// so TLS interception and certificate rotations show up in metrics and logs
+// instead of as unexplained connection errors.
+package tlswatch
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// certExpiry holds the earliest expiry in the chain served by each host.
+var certExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "thermia_upstream_tls_cert_expiry_timestamp_seconds",
+	Help: "Expiry of the first certificate to expire in the chain served by an upstream host (unix seconds)",
+}, []string{"host"})
+
+// Metrics returns the TLS watcher's self-metrics.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{certExpiry}
+}
+
+// chains holds the fingerprint of the last chain seen per host, shared by
+// all transports.
+var (
+	chains   = make(map[string]string)
+	chainsMu sync.Mutex
+)
+
+// Transport wraps an http.RoundTripper and records the certificate chain of
+// every TLS response.
+type Transport struct {
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+// NewTransport returns base wrapped to observe served certificates.
+func NewTransport(base http.RoundTripper, logger *slog.Logger) *Transport {
+	return &Transport{Base: base, Logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err == nil && resp.TLS != nil {
+		t.observe(req.URL.Hostname(), resp.TLS)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes idle connections of the base transport.
+func (t *Transport) CloseIdleConnections() {
+	if c, ok := t.Base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// observe records the expiry of the chain served by host and logs when the
+// chain differs from the one seen before.
+func (t *Transport) observe(host string, state *tls.ConnectionState) {
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return
+	}
+
+	expiry := certs[0].NotAfter
+	h := sha256.New()
+	for _, cert := range certs {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+		h.Write(cert.Raw)
+	}
+	certExpiry.WithLabelValues(host).Set(float64(expiry.Unix()))
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	chainsMu.Lock()
+	previous, seen := chains[host]
+	chains[host] = fingerprint
+	chainsMu.Unlock()
+
+	leaf := certs[0]
+	switch {
+	case !seen:
+		t.Logger.Debug("Upstream certificate chain", "host", host, "subject", leaf.Subject.String(),
+			"issuer", leaf.Issuer.String(), "expires", expiry, "fingerprint", fingerprint)
+	case previous != fingerprint:
+		t.Logger.Warn("Upstream certificate chain changed", "host", host, "subject", leaf.Subject.String(),
+			"issuer", leaf.Issuer.String(), "expires", expiry, "fingerprint", fingerprint, "previous_fingerprint", previous)
+	}
+}
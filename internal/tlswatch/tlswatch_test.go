@@ -0,0 +1,54 @@
+package tlswatch
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := &http.Client{Transport: NewTransport(srv.Client().Transport, logger)}
+
+	get := func() {
+		t.Helper()
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	get()
+	u, _ := url.Parse(srv.URL)
+	want := float64(srv.Certificate().NotAfter.Unix())
+	if got := testutil.ToFloat64(certExpiry.WithLabelValues(u.Hostname())); got != want {
+		t.Errorf("expiry = %v, want %v", got, want)
+	}
+
+	// The same chain again is not a change
+	get()
+	if strings.Contains(logs.String(), "changed") {
+		t.Errorf("unchanged chain logged as changed:\n%s", logs.String())
+	}
+
+	chainsMu.Lock()
+	chains[u.Hostname()] = "previous"
+	chainsMu.Unlock()
+	get()
+	if !strings.Contains(logs.String(), "Upstream certificate chain changed") {
+		t.Errorf("chain change not logged:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,233 @@
+// Package tracing exports spans to an OpenTelemetry collector or tracing
+// backend (Tempo, Jaeger) over OTLP/HTTP with JSON encoding, so slow or
+// failed collections can be followed from their metrics to a trace. It
+// covers what the exporter needs, one span per collection, without the
+// OpenTelemetry SDK.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+// OTLP span kind and status codes
+const (
+	kindInternal = 1
+	statusOK     = 1
+	statusError  = 2
+)
+
+// Tracer creates spans and exports each when it ends.
+type Tracer struct {
+	endpoint   string
+	service    string
+	httpClient *http.Client
+	logger     *slog.Logger
+	clock      clock.Clock
+	exports    sync.WaitGroup
+}
+
+// NewTracer creates a tracer exporting to the OTLP/HTTP endpoint at
+// baseURL (e.g. http://tempo:4318) under the service name service. Spans
+// are timed by clk; nil uses the wall clock.
+func NewTracer(baseURL, service string, logger *slog.Logger, clk clock.Clock) *Tracer {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Tracer{
+		endpoint:   strings.TrimRight(baseURL, "/") + "/v1/traces",
+		service:    service,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+		clock:      clk,
+	}
+}
+
+// Span is an operation being traced.
+type Span struct {
+	tracer  *Tracer
+	name    string
+	traceID [16]byte
+	spanID  [8]byte
+	parent  [8]byte
+	start   time.Time
+
+	mu    sync.Mutex
+	attrs map[string]string
+}
+
+type spanKey struct{}
+
+// Start starts a span named name, a child of the span in ctx if there is
+// one, and returns a context carrying it.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	s := &Span{tracer: t, name: name, start: t.clock.Now(), attrs: make(map[string]string)}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.traceID = parent.traceID
+		s.parent = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// TraceID returns the hex trace ID of the span in ctx, or "" if there is
+// none.
+func TraceID(ctx context.Context) string {
+	if s, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return hex.EncodeToString(s.traceID[:])
+	}
+	return ""
+}
+
+// SetAttribute sets a string attribute of the span.
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+// End ends the span, failed if err is not nil, and exports it in the
+// background.
+func (s *Span) End(err error) {
+	end := s.tracer.clock.Now()
+	body, merr := json.Marshal(s.tracer.payload(s, end, err))
+	if merr != nil {
+		s.tracer.logger.Warn("Failed to encode span", "span", s.name, "error", merr)
+		return
+	}
+
+	s.tracer.exports.Add(1)
+	go func() {
+		defer s.tracer.exports.Done()
+		if err := s.tracer.export(body); err != nil {
+			s.tracer.logger.Warn("Failed to export span", "span", s.name, "error", err)
+		}
+	}()
+}
+
+// Close waits until the spans that ended have been exported or ctx is done.
+func (t *Tracer) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.exports.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// export sends one OTLP/HTTP JSON request.
+func (t *Tracer) export(body []byte) error {
+	req, err := http.NewRequest("POST", t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(data))
+	}
+	return nil
+}
+
+// OTLP JSON types (opentelemetry-proto, JSON encoding)
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []attribute `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	spanJSON struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		ParentSpanID      string      `json:"parentSpanId,omitempty"`
+		Name              string      `json:"name"`
+		Kind              int         `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []attribute `json:"attributes,omitempty"`
+		Status            status      `json:"status"`
+	}
+	attribute struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// payload builds the export request for one ended span.
+func (t *Tracer) payload(s *Span, end time.Time, err error) exportRequest {
+	span := spanJSON{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              kindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Status:            status{Code: statusOK},
+	}
+	if s.parent != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parent[:])
+	}
+	if err != nil {
+		span.Status = status{Code: statusError, Message: err.Error()}
+	}
+	s.mu.Lock()
+	for k, v := range s.attrs {
+		span.Attributes = append(span.Attributes, newAttribute(k, v))
+	}
+	s.mu.Unlock()
+
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: []attribute{newAttribute("service.name", t.service)}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "thermia_exporter"}, Spans: []spanJSON{span}}},
+	}}}
+}
+
+func newAttribute(key, value string) attribute {
+	a := attribute{Key: key}
+	a.Value.StringValue = value
+	return a
+}
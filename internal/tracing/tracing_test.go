@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"thermia_exporter/internal/clock"
+)
+
+func TestTracer_ExportsSpans(t *testing.T) {
+	var mu sync.Mutex
+	var got []spanJSON
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+			return
+		}
+		if attrs := req.ResourceSpans[0].Resource.Attributes; attrs[0].Key != "service.name" || attrs[0].Value.StringValue != "thermia_exporter" {
+			t.Errorf("resource attributes = %+v", attrs)
+		}
+		mu.Lock()
+		got = append(got, req.ResourceSpans[0].ScopeSpans[0].Spans...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	tracer := NewTracer(srv.URL+"/", "thermia_exporter", slog.New(slog.NewTextHandler(io.Discard, nil)), clk)
+	if TraceID(context.Background()) != "" {
+		t.Error("TraceID() without a span should be empty")
+	}
+
+	ctx, root := tracer.Start(context.Background(), "collect")
+	_, child := tracer.Start(ctx, "installation")
+	child.SetAttribute("heatpump_id", "42")
+	clk.Advance(2 * time.Second)
+	child.End(nil)
+	root.End(errors.New("boom"))
+	if err := tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(got))
+	}
+	spans := map[string]spanJSON{got[0].Name: got[0], got[1].Name: got[1]}
+	collect, installation := spans["collect"], spans["installation"]
+	if id := TraceID(ctx); len(id) != 32 || collect.TraceID != id || installation.TraceID != id {
+		t.Errorf("trace IDs = %q, %q, want %q", collect.TraceID, installation.TraceID, id)
+	}
+	if installation.ParentSpanID != collect.SpanID || collect.ParentSpanID != "" {
+		t.Errorf("parent span IDs = %q, %q", installation.ParentSpanID, collect.ParentSpanID)
+	}
+	if collect.Status.Code != statusError || collect.Status.Message != "boom" {
+		t.Errorf("collect status = %+v, want error boom", collect.Status)
+	}
+	if installation.Status.Code != statusOK || len(installation.Attributes) != 1 {
+		t.Errorf("installation span = %+v", installation)
+	}
+	wantStart := strconv.FormatInt(start.UnixNano(), 10)
+	wantEnd := strconv.FormatInt(start.Add(2*time.Second).UnixNano(), 10)
+	if collect.StartTimeUnixNano != wantStart || collect.EndTimeUnixNano != wantEnd {
+		t.Errorf("collect span time = %s..%s, want %s..%s", collect.StartTimeUnixNano, collect.EndTimeUnixNano, wantStart, wantEnd)
+	}
+}
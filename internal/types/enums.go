@@ -0,0 +1,77 @@
+package types
+
+import "strings"
+
+// OperationMode is an operation mode name with its register value prefix
+// removed, such as "AUTO". Firmwares report modes beyond the constants
+// below; those are kept as reported.
+type OperationMode string
+
+// Operation modes known across models.
+const (
+	ModeAuto         OperationMode = "AUTO"
+	ModeManual       OperationMode = "MANUAL"
+	ModeOff          OperationMode = "OFF"
+	ModeHotWaterOnly OperationMode = "HOT_WATER_ONLY"
+	ModeAddHeatOnly  OperationMode = "ADD_HEAT_ONLY"
+)
+
+// modePrefixes are stripped from operation mode value names, in order.
+var modePrefixes = []string{
+	"REG_VALUE_OPERATION_MODE_",
+	"COMP_VALUE_OPERATION_MODE_",
+	"REG_VALUE_",
+	"COMP_VALUE_",
+}
+
+// ParseOperationMode returns the operation mode of a register value name
+// such as "REG_VALUE_OPERATION_MODE_AUTO" or of a bare name like "AUTO".
+func ParseOperationMode(name string) OperationMode {
+	for _, p := range modePrefixes {
+		name = strings.TrimPrefix(name, p)
+	}
+	return OperationMode(name)
+}
+
+func (m OperationMode) String() string { return string(m) }
+
+// OperationalStatus is an operational or power status name with its
+// register value prefix removed, such as "STATUS_HEAT" or
+// "POWER_COMPRESSOR". Unknown statuses are kept as reported.
+type OperationalStatus string
+
+// Operational statuses known across models.
+const (
+	StatusHeat             OperationalStatus = "STATUS_HEAT"
+	StatusHotWater         OperationalStatus = "STATUS_HOTWATER"
+	StatusCool             OperationalStatus = "STATUS_COOL"
+	StatusPassiveCool      OperationalStatus = "STATUS_PASSIVE_COOL"
+	StatusPool             OperationalStatus = "STATUS_POOL"
+	StatusLegionella       OperationalStatus = "STATUS_LEGIONELLA"
+	StatusStandby          OperationalStatus = "STATUS_STANDBY"
+	StatusNoDemand         OperationalStatus = "STATUS_NO_DEMAND"
+	StatusDefrost          OperationalStatus = "STATUS_DEFROST"
+	StatusOperationModeOff OperationalStatus = "OPERATION_MODE_OFF"
+)
+
+// statusPrefixes are stripped from status value names; only the first
+// matching one.
+var statusPrefixes = []string{"REG_VALUE_", "COMP_VALUE_"}
+
+// ParseOperationalStatus returns the status of a register value name such
+// as "REG_VALUE_STATUS_HEAT" or of a bare name like "STATUS_HEAT".
+func ParseOperationalStatus(name string) OperationalStatus {
+	for _, p := range statusPrefixes {
+		if trimmed, ok := strings.CutPrefix(name, p); ok {
+			return OperationalStatus(trimmed)
+		}
+	}
+	return OperationalStatus(name)
+}
+
+func (s OperationalStatus) String() string { return string(s) }
+
+// Is reports whether s is other, ignoring case as firmwares differ in it.
+func (s OperationalStatus) Is(other OperationalStatus) bool {
+	return strings.EqualFold(string(s), string(other))
+}
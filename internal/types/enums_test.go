@@ -0,0 +1,48 @@
+package types
+
+import "testing"
+
+func TestParseOperationMode(t *testing.T) {
+	tests := []struct {
+		name string
+		want OperationMode
+	}{
+		{"REG_VALUE_OPERATION_MODE_AUTO", ModeAuto},
+		{"COMP_VALUE_OPERATION_MODE_HOT_WATER_ONLY", ModeHotWaterOnly},
+		{"REG_VALUE_OFF", ModeOff},
+		{"MANUAL", ModeManual},
+		{"REG_VALUE_OPERATION_MODE_HOLIDAY", OperationMode("HOLIDAY")},
+	}
+	for _, tt := range tests {
+		if got := ParseOperationMode(tt.name); got != tt.want {
+			t.Errorf("ParseOperationMode(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseOperationalStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		want OperationalStatus
+	}{
+		{"REG_VALUE_STATUS_HEAT", StatusHeat},
+		{"COMP_VALUE_STATUS_HOTWATER", StatusHotWater},
+		{"STATUS_NO_DEMAND", StatusNoDemand},
+		{"REG_VALUE_OPERATION_MODE_OFF", StatusOperationModeOff},
+		{"REG_VALUE_POWER_COMPRESSOR", OperationalStatus("POWER_COMPRESSOR")},
+	}
+	for _, tt := range tests {
+		if got := ParseOperationalStatus(tt.name); got != tt.want {
+			t.Errorf("ParseOperationalStatus(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOperationalStatusIs(t *testing.T) {
+	if !OperationalStatus("status_heat").Is(StatusHeat) {
+		t.Error("Is should ignore case")
+	}
+	if StatusHeat.Is(StatusHotWater) {
+		t.Error("STATUS_HEAT should not be STATUS_HOTWATER")
+	}
+}
@@ -3,24 +3,27 @@ package types
 
 // ThermiaSummary is the public summary returned to the exporter containing all heat pump metrics.
 type ThermiaSummary struct {
-	HeatpumpID                 int64              `json:"heatpump_id"`
-	HeatpumpName               string             `json:"heatpump_name"`
-	HeatpumpModel              string             `json:"heatpump_model"`
-	Online                     bool               `json:"online"`
-	LastOnline                 string             `json:"last_online"`
-	LastOnlineUnix             int64              `json:"last_online_unix"`
-	Temperatures               map[string]float64 `json:"temperatures"`
-	OperationModesAvailable    []string           `json:"operation_modes_available"`
-	OperationMode              string             `json:"operation_mode"`
-	OperationalStatusAvailable []string           `json:"operational_status_available"`
-	OperationalStatusRunning   []string           `json:"operational_status_running"`
-	PowerStatusAvailable       []string           `json:"power_status_available"`
-	PowerStatusRunning         []string           `json:"power_status_running"`
-	HotWaterSwitch             *int               `json:"hot_water_switch"`
-	HotWaterBoost              *int               `json:"hot_water_boost"`
-	OperationalTimeHours       map[string]int     `json:"operational_time_h"`
-	ActiveAlerts               []string           `json:"active_alerts"`
-	ArchivedAlerts             []string           `json:"archived_alerts"`
+	HeatpumpID                 int64               `json:"heatpump_id"`
+	HeatpumpName               string              `json:"heatpump_name"`
+	HeatpumpModel              string              `json:"heatpump_model"`
+	Site                       string              `json:"site,omitempty"`
+	Group                      string              `json:"group,omitempty"`
+	Serial                     string              `json:"serial,omitempty"`
+	Online                     bool                `json:"online"`
+	LastOnline                 string              `json:"last_online"`
+	LastOnlineUnix             int64               `json:"last_online_unix"`
+	Temperatures               map[string]float64  `json:"temperatures"`
+	OperationModesAvailable    []OperationMode     `json:"operation_modes_available"`
+	OperationMode              OperationMode       `json:"operation_mode"`
+	OperationalStatusAvailable []OperationalStatus `json:"operational_status_available"`
+	OperationalStatusRunning   []OperationalStatus `json:"operational_status_running"`
+	PowerStatusAvailable       []OperationalStatus `json:"power_status_available"`
+	PowerStatusRunning         []OperationalStatus `json:"power_status_running"`
+	HotWaterSwitch             *int                `json:"hot_water_switch"`
+	HotWaterBoost              *int                `json:"hot_water_boost"`
+	OperationalTimeHours       map[string]int      `json:"operational_time_h"`
+	ActiveAlerts               []string            `json:"active_alerts"`
+	ArchivedAlerts             []string            `json:"archived_alerts"`
 }
 
 // Config represents the Thermia API configuration response.
@@ -32,6 +35,10 @@ type Config struct {
 type Installation struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
+
+	// Facility grouping, only set for professional (installer) accounts
+	Site  string `json:"site"`
+	Group string `json:"group"`
 }
 
 // InstallationInfo contains detailed information about an installation.
@@ -45,6 +52,10 @@ type InstallationInfo struct {
 		Name string `json:"name"`
 	} `json:"profile"`
 	Name string `json:"name"`
+
+	// Device identity, not reported for every model or account
+	SerialNumber string `json:"serialNumber"`
+	MacAddress   string `json:"macAddress"`
 }
 
 // InstallationStatus contains real-time temperature readings from the heat pump.
@@ -64,12 +75,19 @@ type InstallationStatus struct {
 
 // GroupItem represents a register item from a register group.
 type GroupItem struct {
+	RegisterID    int64        `json:"registerId"`
 	RegisterName  string       `json:"registerName"`
 	RegisterValue *float64     `json:"registerValue"`
 	Unit          string       `json:"unit"`
 	IsReadOnly    bool         `json:"isReadOnly"`
 	ValueNames    []ValueEntry `json:"valueNames"`
 	StringValue   *string      `json:"stringRegisterValue"`
+	MinValue      *float64     `json:"minValue"`
+	MaxValue      *float64     `json:"maxValue"`
+	Step          *float64     `json:"step"`
+
+	// Timestamp is when the cloud last received the value, if reported
+	Timestamp string `json:"timeStamp"`
 }
 
 // ValueEntry represents a possible value for a register.
@@ -89,6 +107,36 @@ type Event struct {
 	IsActive     *bool   `json:"isActive"`
 }
 
+// CalendarFunction is a function (e.g. the operation mode) that can be
+// scheduled in the installation's calendar.
+type CalendarFunction struct {
+	FunctionID int64  `json:"functionId"`
+	Name       string `json:"name"`
+}
+
+// CalendarSchedule is one scheduled value of a calendar function. Recurring
+// schedules repeat weekly from Start.
+type CalendarSchedule struct {
+	ScheduleID int64    `json:"scheduleId"`
+	FunctionID int64    `json:"functionId"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Value      *float64 `json:"value"`
+	Recurring  bool     `json:"isRecurring"`
+}
+
+// HistoryRegister describes a register with historical data.
+type HistoryRegister struct {
+	RegisterID   int64  `json:"registerId"`
+	RegisterName string `json:"registerName"`
+}
+
+// HistorySample is one historical register value.
+type HistorySample struct {
+	At    string  `json:"at"`
+	Value float64 `json:"val"`
+}
+
 // TemperatureData holds all extracted temperature values.
 type TemperatureData struct {
 	Indoor            *float64
@@ -107,13 +155,21 @@ type TemperatureData struct {
 
 // OperationModeData holds operation mode information.
 type OperationModeData struct {
-	Current   string
-	Available []string
+	Register  string
+	Current   OperationMode
+	Available []OperationMode
 	ReadOnly  bool
 }
 
 // StatusData holds bitmask status information.
 type StatusData struct {
-	Running   []string
-	Available []string
+	Running   []OperationalStatus
+	Available []OperationalStatus
+}
+
+// CircuitData holds readings for one mixing valve distribution circuit.
+type CircuitData struct {
+	Circuit           string
+	SupplyTemperature *float64
+	ValvePosition     *float64
 }